@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Batch applies a list of create, update and delete operations and responds
+// with the result of each one. In atomic mode the first operation to fail
+// fails the whole request and nothing is changed.
+func (a *App) Batch(c echo.Context) error {
+	var params todo.BatchParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	results, err := a.TodoCore.Batch(c.Request().Context(), params)
+	if err != nil {
+		var bErr todo.BatchError
+		if errors.As(err, &bErr) {
+			if status := batchStatus(bErr.Err); status != http.StatusInternalServerError {
+				return echo.NewHTTPError(status, bErr.Error())
+			}
+		}
+
+		return fmt.Errorf("batch: %w", err)
+	}
+
+	for i := range results {
+		results[i].Status = batchStatus(results[i].Err)
+
+		if results[i].Err == nil {
+			if results[i].Op == todo.BatchCreate {
+				results[i].Status = http.StatusCreated
+			}
+			continue
+		}
+
+		if results[i].Status == http.StatusInternalServerError {
+			a.Log.Error("error applying batch operation", "index", i, "error", results[i].Err)
+			results[i].Error = http.StatusText(http.StatusInternalServerError)
+			continue
+		}
+
+		results[i].Error = results[i].Err.Error()
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
+
+// batchStatus returns the HTTP status code for the outcome of an operation in
+// a batch, matching the status codes of the single todo endpoints.
+func batchStatus(err error) int {
+	var vErr todo.ValidationError
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, &vErr):
+		return http.StatusBadRequest
+	case errors.Is(err, todo.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, todo.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, todo.ErrConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, todo.ErrOpenChildren):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CompleteAll completes every incomplete todo matching the filter query
+// parameters and responds with the todos that were completed.
+func (a *App) CompleteAll(c echo.Context) error {
+	opts, err := todo.ParseQueryOptions(c.QueryParams())
+	if err != nil {
+		return err
+	}
+
+	todos, err := a.TodoCore.CompleteAll(c.Request().Context(), opts.Filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrOpenChildren):
+			return echo.NewHTTPError(http.StatusConflict, todo.ErrOpenChildren.Error())
+		default:
+			return fmt.Errorf("complete all: %w", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+// DeleteCompleted moves every completed todo matching the filter query
+// parameters to the trash and responds with how many were deleted.
+func (a *App) DeleteCompleted(c echo.Context) error {
+	opts, err := todo.ParseQueryOptions(c.QueryParams())
+	if err != nil {
+		return err
+	}
+
+	completed := true
+	opts.Filter.Completed = &completed
+
+	n, err := a.TodoCore.DeleteAll(c.Request().Context(), opts.Filter)
+	if err != nil {
+		return fmt.Errorf("delete completed: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"deleted": n})
+}
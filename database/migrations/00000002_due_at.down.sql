@@ -0,0 +1,3 @@
+DROP INDEX due_at_index;
+
+ALTER TABLE todos DROP COLUMN due_at;
@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN due_at timestamp;
+
+CREATE INDEX due_at_index ON todos (due_at);
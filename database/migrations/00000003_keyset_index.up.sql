@@ -0,0 +1,3 @@
+DROP INDEX time_created_index;
+
+CREATE INDEX time_created_id_index ON todos (time_created, id);
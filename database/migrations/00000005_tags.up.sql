@@ -0,0 +1,8 @@
+CREATE TABLE todo_tags (
+	todo_id uuid NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	tag text NOT NULL,
+
+	PRIMARY KEY (todo_id, tag)
+);
+
+CREATE INDEX tag_index ON todo_tags (tag);
@@ -0,0 +1,5 @@
+DROP INDEX list_id_index;
+
+ALTER TABLE todos DROP COLUMN list_id;
+
+DROP TABLE lists;
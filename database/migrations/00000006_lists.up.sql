@@ -0,0 +1,18 @@
+CREATE TABLE lists (
+	id uuid,
+	name text NOT NULL,
+	time_created timestamp NOT NULL,
+	time_updated timestamp NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+INSERT INTO lists
+  (id, name, time_created, time_updated)
+VALUES
+  ('00000000-0000-0000-0000-000000000000', 'Inbox', now(), now());
+
+ALTER TABLE todos
+  ADD COLUMN list_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES lists (id);
+
+CREATE INDEX list_id_index ON todos (list_id);
@@ -0,0 +1,3 @@
+DROP INDEX parent_id_index;
+
+ALTER TABLE todos DROP COLUMN parent_id;
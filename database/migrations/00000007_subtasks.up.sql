@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN parent_id uuid REFERENCES todos (id) ON DELETE CASCADE;
+
+CREATE INDEX parent_id_index ON todos (parent_id);
@@ -0,0 +1 @@
+ALTER TABLE todos ADD COLUMN recurrence text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX deleted_at_index;
+
+ALTER TABLE todos DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN deleted_at timestamp;
+
+CREATE INDEX deleted_at_index ON todos (deleted_at);
@@ -0,0 +1,13 @@
+CREATE TABLE todo_events (
+	seq bigserial,
+	id uuid NOT NULL UNIQUE,
+	todo_id uuid NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	type text NOT NULL,
+	actor text NOT NULL,
+	changes jsonb NOT NULL,
+	time_created timestamp NOT NULL,
+
+	PRIMARY KEY (seq)
+);
+
+CREATE INDEX todo_id_seq_index ON todo_events (todo_id, seq);
@@ -0,0 +1,3 @@
+DROP INDEX search_index;
+
+ALTER TABLE todos DROP COLUMN search;
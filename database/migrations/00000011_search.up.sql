@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN search tsvector GENERATED ALWAYS AS (to_tsvector('simple', text)) STORED;
+
+CREATE INDEX search_index ON todos USING GIN (search);
@@ -0,0 +1,2 @@
+DROP TRIGGER notify_todo_event ON todo_events;
+DROP FUNCTION notify_todo_event;
@@ -0,0 +1,14 @@
+-- Every instance listening on the todo_events channel is told about the changes
+-- to todo items once the transaction that made them commits.
+CREATE FUNCTION notify_todo_event() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify(
+		'todo_events',
+		json_build_object('todo_id', NEW.todo_id, 'type', NEW.type)::text
+	);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_todo_event AFTER INSERT ON todo_events
+FOR EACH ROW EXECUTE FUNCTION notify_todo_event();
@@ -0,0 +1,16 @@
+-- Users without a password can only authenticate with API keys.
+ALTER TABLE users ADD COLUMN password_hash text NOT NULL DEFAULT '';
+
+-- Only the SHA-256 hash of a session token is stored.
+CREATE TABLE sessions (
+	id uuid NOT NULL,
+	user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	hash text NOT NULL UNIQUE,
+	csrf_token text NOT NULL,
+	time_created timestamp NOT NULL,
+	time_expires timestamp NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX time_expires_index ON sessions (time_expires);
@@ -0,0 +1,11 @@
+-- Todo items shared with other users, along with their subtasks.
+CREATE TABLE shares (
+	todo_id uuid NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role text NOT NULL,
+	time_created timestamp NOT NULL,
+
+	PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE INDEX user_id_index ON shares (user_id);
@@ -0,0 +1,6 @@
+DROP INDEX workspace_id_index;
+DROP INDEX workspace_id_time_created_index;
+ALTER TABLE todos DROP COLUMN workspace_id;
+ALTER TABLE lists DROP COLUMN workspace_id;
+DROP TABLE workspace_members;
+DROP TABLE workspaces;
@@ -0,0 +1,37 @@
+-- Workspaces keep the todo items and lists of different teams apart.
+CREATE TABLE workspaces (
+	id uuid NOT NULL,
+	name text NOT NULL,
+	allowed_priorities text[] NOT NULL,
+	default_list_id uuid NOT NULL,
+	time_created timestamp NOT NULL,
+	time_updated timestamp NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+-- Everything that existed before workspaces belongs to the default workspace,
+-- whose default list is the inbox.
+INSERT INTO workspaces
+  (id, name, allowed_priorities, default_list_id, time_created, time_updated)
+VALUES
+  ('00000000-0000-0000-0000-000000000000', 'Default', '{}', '00000000-0000-0000-0000-000000000000', now(), now());
+
+CREATE TABLE workspace_members (
+	workspace_id uuid NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+	user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role text NOT NULL,
+	time_created timestamp NOT NULL,
+
+	PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX user_id_workspace_id_index ON workspace_members (user_id, workspace_id);
+
+ALTER TABLE lists
+  ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES workspaces (id) ON DELETE CASCADE;
+ALTER TABLE todos
+  ADD COLUMN workspace_id uuid NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES workspaces (id) ON DELETE CASCADE;
+
+CREATE INDEX workspace_id_time_created_index ON lists (workspace_id, time_created);
+CREATE INDEX workspace_id_index ON todos (workspace_id);
@@ -0,0 +1 @@
+DROP INDEX owner_id_time_created_index;
@@ -0,0 +1,2 @@
+-- Webhooks are looked up by their owner whenever a todo item changes.
+CREATE INDEX owner_id_time_created_index ON webhooks (owner_id, time_created);
@@ -0,0 +1,4 @@
+DROP TABLE todo_events;
+DROP TABLE todo_tags;
+DROP TABLE todos;
+DROP TABLE lists;
@@ -0,0 +1,65 @@
+-- Times are stored as fixed width UTC text so that they compare correctly.
+CREATE TABLE lists (
+	id text NOT NULL,
+	name text NOT NULL,
+	time_created text NOT NULL,
+	time_updated text NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+INSERT INTO lists
+  (id, name, time_created, time_updated)
+VALUES
+  (
+    '00000000-0000-0000-0000-000000000000',
+    'Inbox',
+    strftime('%Y-%m-%dT%H:%M:%S.000000000Z', 'now'),
+    strftime('%Y-%m-%dT%H:%M:%S.000000000Z', 'now')
+  );
+
+CREATE TABLE todos (
+	id text NOT NULL,
+	text text NOT NULL,
+	priority text NOT NULL,
+	completed integer NOT NULL,
+	list_id text NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES lists (id),
+	parent_id text REFERENCES todos (id) ON DELETE CASCADE,
+	due_at text,
+	recurrence text NOT NULL DEFAULT '',
+	version integer NOT NULL DEFAULT 1,
+	time_created text NOT NULL,
+	time_updated text NOT NULL,
+	deleted_at text,
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX priority_index ON todos (priority);
+CREATE INDEX completed_index ON todos (completed);
+CREATE INDEX time_created_id_index ON todos (time_created, id);
+CREATE INDEX due_at_index ON todos (due_at);
+CREATE INDEX list_id_index ON todos (list_id);
+CREATE INDEX parent_id_index ON todos (parent_id);
+CREATE INDEX deleted_at_index ON todos (deleted_at);
+
+CREATE TABLE todo_tags (
+	todo_id text NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	tag text NOT NULL,
+
+	PRIMARY KEY (todo_id, tag)
+);
+
+CREATE INDEX tag_index ON todo_tags (tag);
+
+CREATE TABLE todo_events (
+	seq integer PRIMARY KEY AUTOINCREMENT,
+	id text NOT NULL UNIQUE,
+	todo_id text NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	type text NOT NULL,
+	actor text NOT NULL,
+	changes text NOT NULL,
+	time_created text NOT NULL
+);
+
+CREATE INDEX todo_id_seq_index ON todo_events (todo_id, seq);
@@ -0,0 +1,4 @@
+DROP TRIGGER todos_search_delete;
+DROP TRIGGER todos_search_update;
+DROP TRIGGER todos_search_insert;
+DROP TABLE todos_search;
@@ -0,0 +1,22 @@
+-- The search index holds its own copy of the text of every todo item and is
+-- kept up to date by triggers. Trashed todo items are filtered out by joining
+-- with todos.
+CREATE VIRTUAL TABLE todos_search USING fts5 (
+	id UNINDEXED,
+	text,
+	tokenize = 'unicode61 remove_diacritics 0'
+);
+
+INSERT INTO todos_search (id, text) SELECT id, text FROM todos;
+
+CREATE TRIGGER todos_search_insert AFTER INSERT ON todos BEGIN
+	INSERT INTO todos_search (id, text) VALUES (new.id, new.text);
+END;
+
+CREATE TRIGGER todos_search_update AFTER UPDATE OF text ON todos BEGIN
+	UPDATE todos_search SET text = new.text WHERE id = old.id;
+END;
+
+CREATE TRIGGER todos_search_delete AFTER DELETE ON todos BEGIN
+	DELETE FROM todos_search WHERE id = old.id;
+END;
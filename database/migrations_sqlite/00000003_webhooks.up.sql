@@ -0,0 +1,39 @@
+CREATE TABLE webhooks (
+	id text NOT NULL,
+	url text NOT NULL,
+	secret text NOT NULL,
+	events text NOT NULL,
+	active integer NOT NULL,
+	time_created text NOT NULL,
+	time_updated text NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE webhook_deliveries (
+	id text NOT NULL,
+	webhook_id text NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+	event_id text NOT NULL,
+	event_type text NOT NULL,
+	payload text NOT NULL,
+	status text NOT NULL,
+	tries integer NOT NULL,
+	next_attempt_at text,
+	time_created text NOT NULL,
+	time_updated text NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX status_next_attempt_at_index ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_id_time_created_index ON webhook_deliveries (webhook_id, time_created);
+
+CREATE TABLE webhook_attempts (
+	delivery_id text NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+	number integer NOT NULL,
+	status_code integer NOT NULL,
+	error text NOT NULL,
+	time_created text NOT NULL,
+
+	PRIMARY KEY (delivery_id, number)
+);
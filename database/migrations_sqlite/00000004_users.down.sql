@@ -0,0 +1,5 @@
+DROP INDEX owner_id_index;
+ALTER TABLE webhooks DROP COLUMN owner_id;
+ALTER TABLE todos DROP COLUMN owner_id;
+DROP TABLE api_keys;
+DROP TABLE users;
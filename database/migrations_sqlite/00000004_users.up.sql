@@ -0,0 +1,30 @@
+CREATE TABLE users (
+	id text NOT NULL,
+	name text NOT NULL UNIQUE,
+	time_created text NOT NULL,
+	time_updated text NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+-- Only the SHA-256 hash of an API key is stored. The prefix is kept so that
+-- users can tell their keys apart.
+CREATE TABLE api_keys (
+	id text NOT NULL,
+	user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name text NOT NULL,
+	prefix text NOT NULL,
+	hash text NOT NULL UNIQUE,
+	time_created text NOT NULL,
+	time_revoked text,
+
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX user_id_time_created_index ON api_keys (user_id, time_created);
+
+-- Existing todo items and webhooks belong to the anonymous owner.
+ALTER TABLE todos ADD COLUMN owner_id text NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE webhooks ADD COLUMN owner_id text NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+CREATE INDEX owner_id_index ON todos (owner_id);
@@ -0,0 +1,12 @@
+-- Users who log in with an external identity provider are linked to the
+-- account they have there.
+CREATE TABLE identities (
+	id text NOT NULL,
+	user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	issuer text NOT NULL,
+	subject text NOT NULL,
+	time_created text NOT NULL,
+
+	PRIMARY KEY (id),
+	UNIQUE (issuer, subject)
+);
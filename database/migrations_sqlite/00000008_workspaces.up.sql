@@ -0,0 +1,45 @@
+-- Workspaces keep the todo items and lists of different teams apart. Allowed
+-- priorities are stored as a JSON array.
+CREATE TABLE workspaces (
+	id text NOT NULL,
+	name text NOT NULL,
+	allowed_priorities text NOT NULL,
+	default_list_id text NOT NULL,
+	time_created text NOT NULL,
+	time_updated text NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+-- Everything that existed before workspaces belongs to the default workspace,
+-- whose default list is the inbox.
+INSERT INTO workspaces
+  (id, name, allowed_priorities, default_list_id, time_created, time_updated)
+VALUES
+  (
+    '00000000-0000-0000-0000-000000000000',
+    'Default',
+    '[]',
+    '00000000-0000-0000-0000-000000000000',
+    strftime('%Y-%m-%dT%H:%M:%S.000000000Z', 'now'),
+    strftime('%Y-%m-%dT%H:%M:%S.000000000Z', 'now')
+  );
+
+CREATE TABLE workspace_members (
+	workspace_id text NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+	user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role text NOT NULL,
+	time_created text NOT NULL,
+
+	PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX user_id_workspace_id_index ON workspace_members (user_id, workspace_id);
+
+-- SQLite cannot add a column with a foreign key and a default at once, so the
+-- todo items and lists of a workspace are deleted along with it by the store.
+ALTER TABLE lists ADD COLUMN workspace_id text NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE todos ADD COLUMN workspace_id text NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+CREATE INDEX workspace_id_time_created_index ON lists (workspace_id, time_created);
+CREATE INDEX workspace_id_index ON todos (workspace_id);
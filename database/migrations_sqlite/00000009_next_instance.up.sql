@@ -0,0 +1,2 @@
+-- The next instance of a recurring todo item, created when it was completed.
+ALTER TABLE todos ADD COLUMN next_id text;
@@ -0,0 +1,2 @@
+-- Existing lists belong to the anonymous owner.
+ALTER TABLE lists ADD COLUMN owner_id text NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations_sqlite/*.sql
+var sqliteMigrationsFS embed.FS
+
+// OpenSQLite opens the SQLite database file at path, creating it if needed.
+// Foreign keys are enforced and a single connection is used so that writers
+// never contend for the database lock. Transactions take the write lock as
+// they begin so that rows they read cannot change before they commit.
+func OpenSQLite(path string) (*sql.DB, error) {
+	params := url.Values{
+		"_pragma": {
+			"foreign_keys(1)",
+			"busy_timeout(5000)",
+			"journal_mode(WAL)",
+		},
+		"_txlock": {"immediate"},
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// MigrateSQLite runs the SQLite migrations against db.
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	if err := Ping(ctx, db); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	sourceDriver, err := iofs.New(sqliteMigrationsFS, "migrations_sqlite")
+	if err != nil {
+		return fmt.Errorf("source driver: %w", err)
+	}
+
+	databaseDriver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		return fmt.Errorf("database driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", sourceDriver, "sqlite", databaseDriver)
+	if err != nil {
+		return fmt.Errorf("migrate instance: %w", err)
+	}
+
+	if err := m.Up(); err != nil {
+		if !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("migrate :%w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// eventsKeepAlive is how often a comment is sent on an idle event stream so
+// that proxies do not close it.
+const eventsKeepAlive = 15 * time.Second
+
+// eventsRetry is how long browsers wait before reconnecting to a closed event
+// stream, in milliseconds.
+const eventsRetry = 2000
+
+// Events streams changes to todos as Server-Sent Events. Each event is named
+// after the type of change and carries the todo as it was after the change.
+// Clients reconnecting with a Last-Event-ID header receive the events they
+// missed, or a reset event when those are no longer known and the client has
+// to reload the todos. Only changes to the todos the current user may see in
+// the current workspace are sent, including those shared with them.
+func (a *App) Events(c echo.Context) error {
+	ctx := c.Request().Context()
+	workspace := todo.WorkspaceFrom(ctx)
+
+	visible := func(n todo.Notification) bool {
+		if n.Todo.WorkspaceID != workspace {
+			return false
+		}
+
+		ok, err := a.TodoCore.Visible(ctx, n.Todo)
+		if err != nil {
+			a.Log.Error("events", "todo", n.Todo.ID, "error", err)
+			return false
+		}
+
+		return ok
+	}
+
+	sub, replay, ok := a.Broker.Subscribe(c.Request().Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	// The stream is held open for longer than the server write timeout.
+	rc := http.NewResponseController(c.Response().Writer)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("events: %w", err)
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry)
+
+	if !ok {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	for _, n := range replay {
+		if !visible(n) {
+			continue
+		}
+
+		if err := writeEvent(w, n); err != nil {
+			return nil
+		}
+	}
+
+	w.Flush()
+
+	ticker := time.NewTicker(eventsKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case n, open := <-sub.C():
+			// The broker closes subscriptions that fall behind and
+			// on shutdown. The client reconnects and resumes.
+			if !open {
+				return nil
+			}
+
+			if !visible(n) {
+				continue
+			}
+
+			if err := writeEvent(w, n); err != nil {
+				return nil
+			}
+
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		}
+
+		w.Flush()
+	}
+}
+
+// writeEvent writes n to an event stream.
+func writeEvent(w *echo.Response, n todo.Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", n.ID, n.Type, data)
+
+	return err
+}
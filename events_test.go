@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/hashicorp/go-hclog"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestEventsShared(t *testing.T) {
+	broker := todo.NewBroker(todo.DefaultBrokerBuffer)
+
+	a := &App{
+		Log:      hclog.NewNullLogger(),
+		TodoCore: todo.NewCore(todomemory.NewStore(), todo.WithPublisher(broker)),
+		Broker:   broker,
+	}
+
+	users := make(map[string]context.Context)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user, err := a.TodoCore.CreateUser(context.Background(), todo.UserCreateParams{Name: name})
+		if err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+		users[name] = todo.WithUser(context.Background(), user)
+	}
+
+	sub, _, _ := broker.Subscribe("")
+	defer sub.Close()
+
+	if _, err := a.TodoCore.Create(users["alice"], todo.TodoCreateParams{Text: "setup", Priority: todo.PriorityLow}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	// Streams resuming after the first notification replay the ones after
+	// it and end once the request is done.
+	lastID := (<-sub.C()).ID
+
+	td, err := a.TodoCore.Create(users["alice"], todo.TodoCreateParams{Text: "plan trip", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if _, err := a.TodoCore.Create(users["alice"], todo.TodoCreateParams{Text: "private", Priority: todo.PriorityLow}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if _, err := a.TodoCore.Share(users["alice"], td, todo.ShareParams{User: "bob", Role: todo.RoleViewer}); err != nil {
+		t.Fatalf("share: expected nil error, got %v", err)
+	}
+
+	text := "plan the trip"
+	if _, err := a.TodoCore.Update(users["alice"], td, todo.TodoUpdateParams{Text: &text}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	tests := map[string][]string{
+		"alice": {"plan trip", "private", "plan the trip"},
+		"bob":   {"plan trip", "plan the trip"},
+		"carol": nil,
+	}
+
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(users[name])
+			cancel()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/events", nil).WithContext(ctx)
+			req.Header.Set("Last-Event-ID", lastID)
+			rec := httptest.NewRecorder()
+
+			if err := a.Events(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatalf("events: expected nil error, got %v", err)
+			}
+
+			got := make([]string, 0)
+			for _, line := range strings.Split(rec.Body.String(), "\n") {
+				data, ok := strings.CutPrefix(line, "data: ")
+				if !ok {
+					continue
+				}
+
+				var n todo.Notification
+				if err := json.Unmarshal([]byte(data), &n); err != nil {
+					t.Fatalf("decode event: %v", err)
+				}
+				got = append(got, n.Todo.Text)
+			}
+
+			if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("events: texts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryLists fetches all lists.
+func (a *App) QueryLists(c echo.Context) error {
+	lists, err := a.TodoCore.QueryLists(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("query lists: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, lists)
+}
+
+// QueryListByID fetches a single list by its ID.
+func (a *App) QueryListByID(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	l, err := a.TodoCore.QueryListByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrListNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query list: %w", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, l)
+}
+
+// CreateList creates a list.
+func (a *App) CreateList(c echo.Context) error {
+	var params todo.ListCreateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	l, err := a.TodoCore.CreateList(c.Request().Context(), params)
+	if err != nil {
+		return fmt.Errorf("create list: %w", err)
+	}
+
+	return c.JSON(http.StatusCreated, l)
+}
+
+// UpdateList updates a list.
+func (a *App) UpdateList(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	l, err := a.TodoCore.QueryListByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrListNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query list by id [%s]: %w", id, err)
+		}
+	}
+
+	var params todo.ListUpdateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	l, err = a.TodoCore.UpdateList(c.Request().Context(), l, params)
+	if err != nil {
+		return fmt.Errorf("update list: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, l)
+}
+
+// DeleteList deletes a list. The policy query parameter selects whether the
+// todos of the list are moved to the default list of the workspace, which is
+// the default, or deleted.
+func (a *App) DeleteList(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	policy := todo.DeletePolicy(c.QueryParam("policy"))
+	if policy == "" {
+		policy = todo.DeletePolicyInbox
+	}
+
+	l, err := a.TodoCore.QueryListByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrListNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query list by id [%s]: %w", id, err)
+		}
+	}
+
+	if err := a.TodoCore.DeleteList(c.Request().Context(), l, policy); err != nil {
+		return fmt.Errorf("delete list [%s]: %w", id, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -23,9 +24,13 @@ import (
 	_ "github.com/lib/pq"
 
 	"github.com/sudomateo/todo/database"
+	"github.com/sudomateo/todo/oidc"
+	"github.com/sudomateo/todo/openapi"
 	"github.com/sudomateo/todo/todo"
 	"github.com/sudomateo/todo/todo/stores/tododb"
+	"github.com/sudomateo/todo/todo/stores/todofile"
 	"github.com/sudomateo/todo/todo/stores/todomemory"
+	"github.com/sudomateo/todo/todo/stores/todosqlite"
 )
 
 //go:embed views
@@ -38,6 +43,21 @@ const (
 	defaultAddress  = ":8080"
 	defaultLogLevel = "info"
 	defaultVersion  = "1.0.0"
+	defaultPageSize = 100
+
+	defaultCompletionPolicy = todo.CompletionBlock
+	defaultTrashRetention   = 30 * 24 * time.Hour
+	defaultSessionLifetime  = todo.DefaultSessionLifetime
+	defaultSQLitePath       = "todo.db"
+	defaultFileDir          = "todo-data"
+)
+
+// Supported values for TODO_DATABASE_DRIVER.
+const (
+	driverMemory   = "memory"
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite"
+	driverFile     = "file"
 )
 
 func main() {
@@ -60,9 +80,21 @@ func run(log hclog.Logger) error {
 
 	log.SetLevel(hclog.LevelFromString(cfg.LogLevel))
 
-	todoCore := todo.NewCore(todomemory.NewStore())
+	todoOpts := []todo.Option{
+		todo.WithCompletionPolicy(cfg.CompletionPolicy),
+		todo.WithSessionLifetime(cfg.SessionLifetime),
+	}
+
+	// Changes to todos are streamed to clients through the broker.
+	broker := todo.NewBroker(todo.DefaultBrokerBuffer)
+
+	var todoStore todo.Storer
 
-	if cfg.Database.Host != "" {
+	switch cfg.Database.Driver {
+	case driverMemory:
+		todoStore = todomemory.NewStore()
+
+	case driverPostgres:
 		databaseURL := url.URL{
 			Scheme:   "postgres",
 			User:     url.UserPassword(cfg.Database.User, cfg.Database.Password),
@@ -84,26 +116,99 @@ func run(log hclog.Logger) error {
 			return fmt.Errorf("could not migrate database: %w", err)
 		}
 
-		todoCore = todo.NewCore(tododb.NewStore(db))
+		todoStore = tododb.NewStore(db)
+
+		// Changes reach the broker through the database so that every
+		// instance sees them.
+		listener, err := tododb.NewListener(db, databaseURL.String(), broker)
+		if err != nil {
+			return fmt.Errorf("could not listen for changes: %w", err)
+		}
+		defer listener.Close()
+
+		listenCtx, stopListening := context.WithCancel(context.Background())
+		defer stopListening()
+
+		go listener.Run(listenCtx, func(err error) {
+			log.Error("listen for changes", "error", err)
+		})
+
+	case driverSQLite:
+		log.Info("startup", "status", "initializing database", "path", cfg.Database.Name)
+		db, err := database.OpenSQLite(cfg.Database.Name)
+		if err != nil {
+			return fmt.Errorf("could not open database: %w", err)
+		}
+		defer db.Close()
+
+		log.Info("startup", "status", "running database migrations", "path", cfg.Database.Name)
+		migrateCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := database.MigrateSQLite(migrateCtx, db); err != nil {
+			return fmt.Errorf("could not migrate database: %w", err)
+		}
+
+		todoStore = todosqlite.NewStore(db)
+
+	case driverFile:
+		log.Info("startup", "status", "loading data directory", "path", cfg.Database.Name)
+		store, err := todofile.Open(cfg.Database.Name, todofile.WithLogger(log))
+		if err != nil {
+			return fmt.Errorf("could not open data directory: %w", err)
+		}
+		defer store.Close()
+
+		todoStore = store
+	}
+
+	if cfg.Database.Driver != driverPostgres {
+		todoOpts = append(todoOpts, todo.WithPublisher(broker))
 	}
 
+	todoCore := todo.NewCore(todoStore, todoOpts...)
+
 	log.Info("starting service", "version", cfg.Version)
 	defer log.Info("shutdown complete")
 
 	a := App{
 		Log:      log,
 		TodoCore: todoCore,
+		Broker:   broker,
 		Version:  cfg.Version,
 	}
 
+	if cfg.OIDC.IssuerURL != "" {
+		log.Info("startup", "status", "discovering openid connect provider", "issuer", cfg.OIDC.IssuerURL)
+		discoveryCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		provider, err := oidc.NewProvider(discoveryCtx, cfg.OIDC)
+		if err != nil {
+			return fmt.Errorf("could not discover openid connect provider: %w", err)
+		}
+
+		a.OIDC = provider
+	}
+
+	doc, err := openapi.Load(openAPISpec)
+	if err != nil {
+		return fmt.Errorf("could not load openapi document: %w", err)
+	}
+
 	e := echo.New()
 	e.StaticFS("static", echo.MustSubFS(publicFS, "public"))
 	e.Renderer = &Template{
-		template: template.Must(template.ParseFS(viewsFS, "views/*.tmpl")),
+		template: template.Must(template.New("").Funcs(template.FuncMap{
+			"highlight": highlight,
+		}).ParseFS(viewsFS, "views/*.tmpl")),
 	}
 
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	if len(cfg.CORSOrigins) > 0 {
+		e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowOrigins: cfg.CORSOrigins,
+		}))
+	}
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			if err := next(c); err != nil {
@@ -114,12 +219,27 @@ func run(log hclog.Logger) error {
 					return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 				}
 
+				if errors.Is(err, todo.ErrForbidden) {
+					return echo.NewHTTPError(http.StatusForbidden, todo.ErrForbidden.Error())
+				}
+
 				return err
 			}
 
 			return nil
 		}
 	})
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// Anonymous changes are attributed to the client address.
+			ctx := todo.WithActor(c.Request().Context(), c.RealIP())
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	})
+	e.Use(a.authenticate(cfg.AuthRequired))
+	e.Use(a.enterWorkspace)
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			now := time.Now()
@@ -139,13 +259,9 @@ func run(log hclog.Logger) error {
 			return next(c)
 		}
 	})
+	e.Use(validateRequests(doc))
 
-	e.GET("/", a.Root)
-	e.GET("/api/todo", a.Query)
-	e.GET("/api/todo/:id", a.QueryByID)
-	e.POST("/api/todo", a.Create)
-	e.PATCH("/api/todo/:id", a.Update)
-	e.DELETE("/api/todo/:id", a.Delete)
+	a.routes(e)
 
 	server := http.Server{
 		Addr:         cfg.Address,
@@ -155,6 +271,20 @@ func run(log hclog.Logger) error {
 		IdleTimeout:  30 * time.Second,
 	}
 
+	// Event streams end when the broker closes so that they do not hold up
+	// the shutdown.
+	server.RegisterOnShutdown(broker.Close)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	if cfg.TrashRetention > 0 {
+		go a.purgeTrash(backgroundCtx, cfg.TrashRetention)
+	}
+
+	go a.deliverWebhooks(backgroundCtx)
+	go a.purgeSessions(backgroundCtx)
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGTERM, syscall.SIGINT)
 
@@ -184,34 +314,186 @@ func run(log hclog.Logger) error {
 	return nil
 }
 
+// routes registers the handlers of the application on e. Every route under
+// /api must be described by the operations in openapi.go.
+func (a *App) routes(e *echo.Echo) {
+	e.GET("/", a.Root)
+	e.GET("/login", a.LoginPage)
+	e.POST("/login", a.Login)
+	e.GET("/signup", a.SignupPage)
+	e.POST("/signup", a.Signup)
+	e.POST("/logout", a.Logout)
+	if a.OIDC != nil {
+		e.GET("/login/oidc", a.SSOLogin)
+		e.GET("/login/oidc/callback", a.SSOCallback)
+	}
+	e.GET("/api/openapi.json", a.OpenAPI)
+	e.GET("/api/todo", a.Query)
+	e.GET("/api/todo/overdue", a.QueryOverdue)
+	e.GET("/api/todo/search", a.Search)
+	e.GET("/api/todo/:id", a.QueryByID)
+	e.GET("/api/todo/:id/children", a.QueryChildren)
+	e.GET("/api/todo/:id/history", a.QueryHistory)
+	e.GET("/api/todo/shared", a.QueryShared)
+	e.GET("/api/todo/:id/collaborators", a.QueryCollaborators)
+	e.POST("/api/todo/:id/collaborators", a.Share)
+	e.DELETE("/api/todo/:id/collaborators/:user_id", a.Unshare)
+	e.POST("/api/todo", a.Create)
+	e.POST("/api/todo/batch", a.Batch)
+	e.POST("/api/todo/complete", a.CompleteAll)
+	e.DELETE("/api/todo/completed", a.DeleteCompleted)
+	e.PATCH("/api/todo/:id", a.Update)
+	e.DELETE("/api/todo/:id", a.Delete)
+	e.POST("/api/todo/:id/restore", a.Restore)
+	e.GET("/api/trash", a.QueryTrash)
+	e.DELETE("/api/trash", a.PurgeTrash)
+	e.DELETE("/api/trash/:id", a.Purge)
+	e.GET("/api/tags", a.QueryTags)
+	e.GET("/api/events", a.Events)
+	e.GET("/api/lists", a.QueryLists)
+	e.GET("/api/lists/:id", a.QueryListByID)
+	e.POST("/api/lists", a.CreateList)
+	e.PATCH("/api/lists/:id", a.UpdateList)
+	e.DELETE("/api/lists/:id", a.DeleteList)
+	e.GET("/api/workspaces", a.QueryWorkspaces)
+	e.GET("/api/workspaces/:id", a.QueryWorkspaceByID)
+	e.POST("/api/workspaces", a.CreateWorkspace)
+	e.PATCH("/api/workspaces/:id", a.UpdateWorkspace)
+	e.DELETE("/api/workspaces/:id", a.DeleteWorkspace)
+	e.GET("/api/workspaces/:id/members", a.QueryMembers)
+	e.POST("/api/workspaces/:id/members", a.AddMember)
+	e.DELETE("/api/workspaces/:id/members/:user_id", a.RemoveMember)
+	e.GET("/api/webhooks", a.QueryWebhooks)
+	e.GET("/api/webhooks/:id", a.QueryWebhookByID)
+	e.GET("/api/webhooks/:id/deliveries", a.QueryDeliveries)
+	e.POST("/api/webhooks", a.CreateWebhook)
+	e.PATCH("/api/webhooks/:id", a.UpdateWebhook)
+	e.DELETE("/api/webhooks/:id", a.DeleteWebhook)
+	e.POST("/api/webhooks/:id/deliveries/:delivery_id/redeliver", a.Redeliver)
+	e.POST("/api/users", a.Register)
+	e.GET("/api/users/me", a.CurrentUser)
+	e.GET("/api/keys", a.QueryAPIKeys)
+	e.POST("/api/keys", a.CreateAPIKey)
+	e.DELETE("/api/keys/:id", a.RevokeAPIKey)
+}
+
 // App represents our web application.
 type App struct {
 	Log      hclog.Logger
 	TodoCore *todo.Core
+	Broker   *todo.Broker
 	Version  string
+
+	// OIDC is set when users can log in with an OpenID Connect provider.
+	OIDC *oidc.Provider
 }
 
 // Root serves the web application.
 func (a *App) Root(c echo.Context) error {
-	todos, err := a.TodoCore.Query(c.Request().Context())
+	opts, err := todo.ParseQueryOptions(c.QueryParams())
 	if err != nil {
-		return fmt.Errorf("query: %w", err)
+		return err
+	}
+
+	if opts.Limit == 0 {
+		opts.Limit = defaultPageSize
+	}
+
+	if opts.Filter.ListID == nil && !opts.Filter.Trashed {
+		inbox := todo.InboxID
+		opts.Filter.ListID = &inbox
+	}
+
+	listID := todo.InboxID
+	if opts.Filter.ListID != nil {
+		listID = *opts.Filter.ListID
+	}
+
+	lists, err := a.TodoCore.QueryLists(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("query lists: %w", err)
+	}
+
+	// Scripts send the CSRF token of the session with every change.
+	user, _ := todo.UserFrom(c.Request().Context())
+	session, _ := sessionFrom(c)
+
+	// A search replaces the todos of the list with the search results.
+	query := c.QueryParam("q")
+
+	var results []todo.SearchResult
+	var todos []todo.Todo
+
+	if query != "" {
+		results, err = a.search(c, todo.SearchParams{Query: query})
+		if err != nil {
+			return err
+		}
+	} else {
+		todos, err = a.TodoCore.Query(c.Request().Context(), opts)
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+	}
+
+	var prevURL, nextURL string
+	if opts.Offset > 0 {
+		prev := opts
+		prev.Offset = opts.Offset - opts.Limit
+		if prev.Offset < 0 {
+			prev.Offset = 0
+		}
+		prevURL = "/?" + prev.Values().Encode()
+	}
+	if len(todos) == opts.Limit {
+		next := opts
+		next.Offset = opts.Offset + opts.Limit
+		nextURL = "/?" + next.Values().Encode()
 	}
 
 	data := struct {
+		Lists   []todo.List
+		InboxID uuid.UUID
+		ListID  uuid.UUID
+		Trashed bool
 		Todos   []todo.Todo
+		Query   string
+		Results []todo.SearchResult
+		Now     time.Time
+		PrevURL string
+		NextURL string
 		Version string
+		User    todo.User
+		CSRF    string
 	}{
+		Lists:   lists,
+		InboxID: todo.InboxID,
+		ListID:  listID,
+		Trashed: opts.Filter.Trashed,
 		Todos:   todos,
+		Query:   query,
+		Results: results,
+		Now:     time.Now(),
+		PrevURL: prevURL,
+		NextURL: nextURL,
 		Version: a.Version,
+		User:    user,
+		CSRF:    session.CSRFToken,
 	}
 
 	return c.Render(http.StatusOK, "index.html.tmpl", data)
 }
 
-// Query fetches all todos.
+// Query fetches the todos matching the filter, sort and pagination query
+// parameters. When more todos may follow, a Link header points to the next
+// page.
 func (a *App) Query(c echo.Context) error {
-	todos, err := a.TodoCore.Query(c.Request().Context())
+	opts, err := todo.ParseQueryOptions(c.QueryParams())
+	if err != nil {
+		return err
+	}
+
+	todos, err := a.TodoCore.Query(c.Request().Context(), opts)
 	if err != nil {
 		switch {
 		case errors.Is(err, todo.ErrNotFound):
@@ -221,10 +503,54 @@ func (a *App) Query(c echo.Context) error {
 		}
 	}
 
+	setNextLink(c, opts, todos)
+
 	return c.JSON(http.StatusOK, todos)
 }
 
-// QueryByID fetches a single todo by its ID.
+// setNextLink sets a Link header pointing to the page after todos when more
+// todos may follow.
+func setNextLink(c echo.Context, opts todo.QueryOptions, todos []todo.Todo) {
+	if opts.Limit == 0 || len(todos) < opts.Limit {
+		return
+	}
+
+	next := opts
+	if opts.OrderBy == "" || opts.OrderBy == todo.OrderByTimeCreated {
+		// The cursor already skips the todos before it.
+		cursor := todo.CursorFor(todos[len(todos)-1])
+		next.After = &cursor
+		next.Offset = 0
+	} else {
+		next.Offset += opts.Limit
+	}
+
+	link := c.Request().URL.Path + "?" + next.Values().Encode()
+	c.Response().Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", link))
+}
+
+// QueryOverdue fetches all incomplete todos that are past their due date.
+func (a *App) QueryOverdue(c echo.Context) error {
+	todos, err := a.TodoCore.QueryOverdue(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("query overdue: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+// QueryTags fetches every tag in use along with how many todos use it.
+func (a *App) QueryTags(c echo.Context) error {
+	tags, err := a.TodoCore.QueryTags(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("query tags: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// QueryByID fetches a single todo by its ID. The todo version is returned in
+// the ETag header.
 func (a *App) QueryByID(c echo.Context) error {
 	idParam := c.Param("id")
 
@@ -243,9 +569,64 @@ func (a *App) QueryByID(c echo.Context) error {
 		}
 	}
 
+	c.Response().Header().Set("ETag", etag(t))
+
+	if matchesETag(c.Request().Header.Get("If-None-Match"), t) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
 	return c.JSON(http.StatusOK, t)
 }
 
+// QueryChildren fetches every descendant of a todo, ordered by depth.
+func (a *App) QueryChildren(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	if _, err := a.TodoCore.QueryByID(c.Request().Context(), id); err != nil {
+		switch {
+		case errors.Is(err, todo.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query by id [%s]: %w", id, err)
+		}
+	}
+
+	todos, err := a.TodoCore.QuerySubtree(c.Request().Context(), id)
+	if err != nil {
+		return fmt.Errorf("query subtree [%s]: %w", id, err)
+	}
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+// QueryHistory fetches the change history of a todo, oldest first. The
+// history of todos in the trash is available until they are purged.
+func (a *App) QueryHistory(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	events, err := a.TodoCore.QueryEvents(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query events [%s]: %w", id, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
+
 // Create creates a todo.
 func (a *App) Create(c echo.Context) error {
 	var params todo.TodoCreateParams
@@ -259,10 +640,13 @@ func (a *App) Create(c echo.Context) error {
 		return fmt.Errorf("create: %w", err)
 	}
 
+	c.Response().Header().Set("ETag", etag(t))
+
 	return c.JSON(http.StatusCreated, t)
 }
 
-// Update updates a todo.
+// Update updates a todo. When the If-Match header is set the update is only
+// applied if it matches the current ETag of the todo.
 func (a *App) Update(c echo.Context) error {
 	idParam := c.Param("id")
 
@@ -271,31 +655,50 @@ func (a *App) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
 	}
 
-	t, err := a.TodoCore.QueryByID(c.Request().Context(), id)
-	if err != nil {
-		switch {
-		case errors.Is(err, todo.ErrNotFound):
-			return c.NoContent(http.StatusNotFound)
-		default:
-			return fmt.Errorf("query by id [%s]: %w", id, err)
-		}
-	}
-
 	var params todo.TodoUpdateParams
 
 	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
 
-	t, err = a.TodoCore.Update(c.Request().Context(), t, params)
+	ctx := c.Request().Context()
+
+	// The todo is read and updated in one transaction so that it cannot
+	// change in between.
+	var t todo.Todo
+
+	err = a.TodoCore.WithTx(ctx, func(txCore *todo.Core) error {
+		current, err := txCore.QueryByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if ifMatch := c.Request().Header.Get("If-Match"); ifMatch != "" && !matchesETag(ifMatch, current) {
+			return todo.ErrConflict
+		}
+
+		t, err = txCore.Update(ctx, current, params)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("update: %w", err)
+		switch {
+		case errors.Is(err, todo.ErrConflict):
+			return echo.NewHTTPError(http.StatusPreconditionFailed, todo.ErrConflict.Error())
+		case errors.Is(err, todo.ErrOpenChildren):
+			return echo.NewHTTPError(http.StatusConflict, todo.ErrOpenChildren.Error())
+		case errors.Is(err, todo.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("update [%s]: %w", id, err)
+		}
 	}
 
+	c.Response().Header().Set("ETag", etag(t))
+
 	return c.JSON(http.StatusOK, t)
 }
 
-// Delete deletes a todo.
+// Delete moves a todo and its subtasks to the trash.
 func (a *App) Delete(c echo.Context) error {
 	idParam := c.Param("id")
 
@@ -304,21 +707,44 @@ func (a *App) Delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
 	}
 
-	t, err := a.TodoCore.QueryByID(c.Request().Context(), id)
+	ctx := c.Request().Context()
+
+	err = a.TodoCore.WithTx(ctx, func(txCore *todo.Core) error {
+		t, err := txCore.QueryByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return txCore.Delete(ctx, t)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, todo.ErrNotFound):
-			return c.NoContent(http.StatusNoContent)
+			return c.NoContent(http.StatusNotFound)
 		default:
-			return fmt.Errorf("query by id [%s]: %w", id, err)
+			return fmt.Errorf("delete [%s]: %w", id, err)
 		}
 	}
 
-	if err := a.TodoCore.Delete(c.Request().Context(), t); err != nil {
-		return fmt.Errorf("delete [%s]: %w", id, err)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// etag returns the entity tag for the current version of t.
+func etag(t todo.Todo) string {
+	return strconv.Quote(strconv.Itoa(t.Version))
+}
+
+// matchesETag reports whether the If-Match or If-None-Match header value
+// matches the entity tag of t. Weak entity tags are compared as strong ones.
+func matchesETag(header string, t todo.Todo) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag(t) {
+			return true
+		}
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return false
 }
 
 type Template struct {
@@ -331,13 +757,20 @@ func (t *Template) Render(w io.Writer, name string, data any, c echo.Context) er
 
 // Config represents the application configuration.
 type Config struct {
-	Address  string
-	Database Database
-	LogLevel string
-	Version  string
+	Address          string
+	Database         Database
+	LogLevel         string
+	Version          string
+	CompletionPolicy todo.CompletionPolicy
+	TrashRetention   time.Duration
+	AuthRequired     bool
+	SessionLifetime  time.Duration
+	CORSOrigins      []string
+	OIDC             oidc.Config
 }
 
 type Database struct {
+	Driver     string
 	User       string
 	Password   string
 	Host       string
@@ -348,6 +781,7 @@ type Database struct {
 // configFromEnv reads application configuration from the environment.
 func configFromEnv() (Config, error) {
 	database := Database{
+		Driver:     os.Getenv("TODO_DATABASE_DRIVER"),
 		User:       os.Getenv("TODO_DATABASE_USER"),
 		Password:   os.Getenv("TODO_DATABASE_PASSWORD"),
 		Host:       os.Getenv("TODO_DATABASE_HOST"),
@@ -359,6 +793,29 @@ func configFromEnv() (Config, error) {
 		database.Parameters = "sslmode=disable"
 	}
 
+	// Postgres was the only database before the driver could be chosen, so
+	// setting a host is enough to select it.
+	if database.Driver == "" {
+		database.Driver = driverMemory
+		if database.Host != "" {
+			database.Driver = driverPostgres
+		}
+	}
+
+	switch database.Driver {
+	case driverMemory, driverPostgres:
+	case driverSQLite:
+		if database.Name == "" {
+			database.Name = defaultSQLitePath
+		}
+	case driverFile:
+		if database.Name == "" {
+			database.Name = defaultFileDir
+		}
+	default:
+		return Config{}, fmt.Errorf("invalid database driver %s", database.Driver)
+	}
+
 	address := os.Getenv("TODO_ADDR")
 	if address == "" {
 		address = defaultAddress
@@ -377,11 +834,71 @@ func configFromEnv() (Config, error) {
 		version = defaultVersion
 	}
 
+	completionPolicy := todo.CompletionPolicy(os.Getenv("TODO_COMPLETION_POLICY"))
+	if completionPolicy == "" {
+		completionPolicy = defaultCompletionPolicy
+	}
+	if completionPolicy != todo.CompletionBlock && completionPolicy != todo.CompletionCascade {
+		return Config{}, fmt.Errorf("invalid completion policy %s", completionPolicy)
+	}
+
+	trashRetention := defaultTrashRetention
+	if s := os.Getenv("TODO_TRASH_RETENTION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid trash retention %s", s)
+		}
+		trashRetention = d
+	}
+
+	var authRequired bool
+	if s := os.Getenv("TODO_AUTH_REQUIRED"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid auth required %s", s)
+		}
+		authRequired = b
+	}
+
+	sessionLifetime := defaultSessionLifetime
+	if s := os.Getenv("TODO_SESSION_LIFETIME"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid session lifetime %s", s)
+		}
+		sessionLifetime = d
+	}
+
+	var corsOrigins []string
+	for _, origin := range strings.Split(os.Getenv("TODO_CORS_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			corsOrigins = append(corsOrigins, origin)
+		}
+	}
+
+	// Single sign-on is enabled by setting an issuer.
+	oidcConfig := oidc.Config{
+		IssuerURL:    os.Getenv("TODO_OIDC_ISSUER_URL"),
+		ClientID:     os.Getenv("TODO_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("TODO_OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("TODO_OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(strings.ReplaceAll(os.Getenv("TODO_OIDC_SCOPES"), ",", " ")),
+	}
+	if oidcConfig.IssuerURL != "" && (oidcConfig.ClientID == "" || oidcConfig.RedirectURL == "") {
+		return Config{}, errors.New("openid connect requires a client id and redirect url")
+	}
+
 	cfg := Config{
-		Database: database,
-		Address:  address,
-		Version:  version,
-		LogLevel: logLevel,
+		Database:         database,
+		Address:          address,
+		Version:          version,
+		LogLevel:         logLevel,
+		CompletionPolicy: completionPolicy,
+		TrashRetention:   trashRetention,
+		AuthRequired:     authRequired,
+		SessionLifetime:  sessionLifetime,
+		CORSOrigins:      corsOrigins,
+		OIDC:             oidcConfig,
 	}
 
 	return cfg, nil
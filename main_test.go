@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+// nextLink matches the target of a Link header with rel="next".
+var nextLink = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+func TestQueryNextLink(t *testing.T) {
+	e := echo.New()
+	a := &App{TodoCore: todo.NewCore(todomemory.NewStore())}
+	a.routes(e)
+
+	want := make([]string, 0)
+
+	for i := 0; i < 7; i++ {
+		params := todo.TodoCreateParams{Text: fmt.Sprintf("todo %d", i), Priority: todo.PriorityLow}
+
+		td, err := a.TodoCore.Create(context.Background(), params)
+		if err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+
+		// The first page starts after the offset.
+		if i > 0 {
+			want = append(want, td.Text)
+		}
+	}
+
+	got := make([]string, 0)
+
+	target := "/api/todo?limit=2&offset=1"
+	for page := 0; target != ""; page++ {
+		if page > len(want) {
+			t.Fatalf("query: expected at most %d pages", len(want))
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query %s: expected status %d, got %d: %s", target, http.StatusOK, rec.Code, rec.Body)
+		}
+
+		var todos []todo.Todo
+		if err := json.Unmarshal(rec.Body.Bytes(), &todos); err != nil {
+			t.Fatalf("query %s: decode response: %v", target, err)
+		}
+
+		for _, td := range todos {
+			got = append(got, td.Text)
+		}
+
+		target = ""
+		if link := rec.Header().Get("Link"); link != "" {
+			m := nextLink.FindStringSubmatch(link)
+			if m == nil {
+				t.Fatalf("query: invalid Link header %q", link)
+			}
+			target = m[1]
+		}
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("query: todos mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,250 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// keySet caches the signing keys of a provider by their key ID.
+type keySet struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	keys    map[string]publicKey
+	fetched time.Time
+}
+
+// publicKey is a signing key of a provider along with the algorithm it is
+// restricted to, if any.
+type publicKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// key returns the signing key with the given ID, fetching the keys of the
+// provider when they are stale or do not include it. Tokens without a key ID
+// can only be verified when the provider has a single key.
+func (p *Provider) key(ctx context.Context, kid string) (publicKey, error) {
+	p.keys.mutex.Lock()
+	defer p.keys.mutex.Unlock()
+
+	age := p.now().Sub(p.keys.fetched)
+
+	if p.keys.keys != nil && age < p.keys.ttl {
+		if key, ok := p.keys.lookup(kid); ok {
+			return key, nil
+		}
+
+		if age < minKeySetRefresh {
+			return publicKey{}, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+		}
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return publicKey{}, fmt.Errorf("fetch keys: %w", err)
+	}
+
+	p.keys.keys = keys
+	p.keys.fetched = p.now()
+
+	key, ok := p.keys.lookup(kid)
+	if !ok {
+		return publicKey{}, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+
+	return key, nil
+}
+
+// lookup finds a key in the set. The caller must hold the mutex.
+func (s *keySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// jwk is a JSON Web Key as described in RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// Elliptic curve keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys fetches the signing keys of the provider. Keys that are not for
+// signing or of a type we do not support are skipped.
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := p.getJSON(ctx, p.metadata.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = publicKey{key: key, alg: k.Alg}
+	}
+
+	return keys, nil
+}
+
+// publicKey parses the public key held by k.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("e: too large")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// decodeInt decodes a base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, errors.New("empty")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// algorithm is a way of signing tokens.
+type algorithm struct {
+	hash crypto.Hash
+
+	// curve is set for ECDSA algorithms, which must use a key on it.
+	curve elliptic.Curve
+}
+
+// algorithms are the signing algorithms ID tokens may use, by their name in
+// the token header. Unsigned tokens are never accepted.
+var algorithms = map[string]algorithm{
+	"RS256": {hash: crypto.SHA256},
+	"RS384": {hash: crypto.SHA384},
+	"RS512": {hash: crypto.SHA512},
+	"ES256": {hash: crypto.SHA256, curve: elliptic.P256()},
+	"ES384": {hash: crypto.SHA384, curve: elliptic.P384()},
+	"ES512": {hash: crypto.SHA512, curve: elliptic.P521()},
+}
+
+// verify checks that signature is a signature of signed by key.
+func (a algorithm) verify(key crypto.PublicKey, signed []byte, signature []byte) error {
+	h := a.hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if a.curve != nil {
+			return errors.New("algorithm does not match key")
+		}
+
+		return rsa.VerifyPKCS1v15(key, a.hash, digest, signature)
+
+	case *ecdsa.PublicKey:
+		if a.curve == nil || key.Curve != a.curve {
+			return errors.New("algorithm does not match key")
+		}
+
+		// ECDSA signatures are the two integers r and s, each padded to
+		// the size of the curve.
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("verification failed")
+		}
+
+		return nil
+	}
+
+	return errors.New("unsupported key")
+}
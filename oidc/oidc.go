@@ -0,0 +1,463 @@
+// Package oidc logs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+// DefaultScopes are requested when Config.Scopes is empty.
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+const (
+	// DefaultKeySetTTL is how long the signing keys of a provider are used
+	// before they are fetched again.
+	DefaultKeySetTTL = time.Hour
+
+	// minKeySetRefresh is how long to wait after fetching the signing keys
+	// before fetching them again for a key that was not among them, so that
+	// tokens with made up key IDs cannot make us hammer the provider.
+	minKeySetRefresh = time.Minute
+
+	// leeway is how far the clock of the provider may be off from ours.
+	leeway = time.Minute
+
+	// maxResponseSize limits how much of a response from the provider is
+	// read.
+	maxResponseSize = 1 << 20
+)
+
+// Config represents how to reach an OpenID Connect provider and who we are to
+// it. RedirectURL is where the provider sends users back to after they log in.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithHTTPClient sets the client used to talk to the provider. The default is
+// a client with a 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithClock sets the function used to tell the time when validating tokens
+// and caching keys. The default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(p *Provider) {
+		p.now = now
+	}
+}
+
+// WithKeySetTTL sets how long the signing keys of the provider are used
+// before they are fetched again. The default is DefaultKeySetTTL.
+func WithKeySetTTL(ttl time.Duration) Option {
+	return func(p *Provider) {
+		p.keys.ttl = ttl
+	}
+}
+
+// Provider is an OpenID Connect provider whose endpoints were discovered from
+// its issuer URL.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	now      func() time.Time
+	metadata metadata
+	keys     keySet
+}
+
+// metadata is the part of the discovery document of a provider that we use.
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// NewProvider is a constructor for a Provider. It fetches the discovery
+// document of the provider from its issuer URL.
+func NewProvider(ctx context.Context, cfg Config, opts ...Option) (*Provider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("missing issuer url, client id or redirect url")
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+
+	p := Provider{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+		keys:   keySet{ttl: DefaultKeySetTTL},
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	discoveryURL := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+
+	if err := p.getJSON(ctx, discoveryURL, &p.metadata); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+
+	// The issuer must match exactly so that tokens of another provider
+	// cannot pass as ours.
+	if p.metadata.Issuer != cfg.IssuerURL {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", p.metadata.Issuer, cfg.IssuerURL)
+	}
+
+	if p.metadata.AuthorizationEndpoint == "" || p.metadata.TokenEndpoint == "" || p.metadata.JWKSURI == "" {
+		return nil, errors.New("discovery: missing authorization, token or jwks endpoint")
+	}
+
+	if len(p.metadata.CodeChallengeMethods) > 0 && !contains(p.metadata.CodeChallengeMethods, "S256") {
+		return nil, errors.New("discovery: provider does not support PKCE with S256")
+	}
+
+	return &p, nil
+}
+
+// AuthRequest is the start of a login. Users are sent to URL, and State,
+// Nonce and Verifier must be kept until they come back to check the response.
+type AuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewAuthRequest starts a login.
+func (p *Provider) NewAuthRequest() (AuthRequest, error) {
+	var req AuthRequest
+
+	for _, v := range []*string{&req.State, &req.Nonce, &req.Verifier} {
+		token, err := randomToken()
+		if err != nil {
+			return AuthRequest{}, fmt.Errorf("generate token: %w", err)
+		}
+		*v = token
+	}
+
+	u, err := url.Parse(p.metadata.AuthorizationEndpoint)
+	if err != nil {
+		return AuthRequest{}, fmt.Errorf("parse authorization endpoint: %w", err)
+	}
+
+	scopes := p.config.Scopes
+	if !contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	challenge := sha256.Sum256([]byte(req.Verifier))
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("redirect_uri", p.config.RedirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", req.State)
+	q.Set("nonce", req.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	req.URL = u.String()
+
+	return req, nil
+}
+
+// CheckState reports whether the state a user came back with is the one of
+// the login they started.
+func (r AuthRequest) CheckState(state string) bool {
+	return state != "" && subtle.ConstantTimeCompare([]byte(state), []byte(r.State)) == 1
+}
+
+// tokenResponse is the response of the token endpoint.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades the code a user came back with for their ID token. The
+// verifier is the one of the login they started.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+
+	// Public clients identify themselves with their client ID alone.
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if token.Error != "" {
+			return "", fmt.Errorf("token endpoint: %s: %s", token.Error, token.ErrorDescription)
+		}
+		return "", fmt.Errorf("token endpoint: unexpected status code %d", resp.StatusCode)
+	}
+
+	if token.IDToken == "" {
+		return "", errors.New("token endpoint: missing id token")
+	}
+
+	return token.IDToken, nil
+}
+
+// Claims are the claims of an ID token that we use.
+type Claims struct {
+	Issuer            string      `json:"iss"`
+	Subject           string      `json:"sub"`
+	Audience          audience    `json:"aud"`
+	AuthorizedParty   string      `json:"azp"`
+	Expiry            NumericDate `json:"exp"`
+	IssuedAt          NumericDate `json:"iat"`
+	Nonce             string      `json:"nonce"`
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+	Email             string      `json:"email"`
+	EmailVerified     bool        `json:"email_verified"`
+}
+
+// Username is the name the user would like to be known by: their preferred
+// username, verified email address or name, whichever is set first.
+func (c Claims) Username() string {
+	switch {
+	case c.PreferredUsername != "":
+		return c.PreferredUsername
+	case c.Email != "" && c.EmailVerified:
+		return c.Email
+	default:
+		return c.Name
+	}
+}
+
+// NumericDate is a time in a token, given as seconds since the Unix epoch.
+type NumericDate struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *NumericDate) UnmarshalJSON(b []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(b, &seconds); err != nil {
+		return err
+	}
+
+	d.Time = time.Unix(0, int64(seconds*float64(time.Second)))
+
+	return nil
+}
+
+// audience is the aud claim, which is either a single string or an array of
+// them.
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+
+	*a = ss
+
+	return nil
+}
+
+// Verify checks that an ID token was signed by the provider for us and the
+// login with the given nonce, and that it has not expired, returning its
+// claims. It returns an error wrapping ErrInvalidToken when it does not.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (Claims, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+
+	alg, ok := algorithms[header.Alg]
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if key.alg != "" && key.alg != header.Alg {
+		return Claims{}, fmt.Errorf("%w: key %q is not for %s", ErrInvalidToken, header.Kid, header.Alg)
+	}
+
+	if err := alg.verify(key.key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+
+	if err := p.checkClaims(claims, nonce); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// checkClaims checks the claims of an ID token as described in section 3.1.3.7
+// of OpenID Connect Core.
+func (p *Provider) checkClaims(claims Claims, nonce string) error {
+	now := p.now()
+
+	switch {
+	case claims.Issuer != p.metadata.Issuer:
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	case claims.Subject == "":
+		return errors.New("missing subject")
+	case !contains(claims.Audience, p.config.ClientID):
+		return fmt.Errorf("unexpected audience %q", claims.Audience)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID:
+		return fmt.Errorf("unexpected authorized party %q", claims.AuthorizedParty)
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != p.config.ClientID:
+		return fmt.Errorf("unexpected authorized party %q", claims.AuthorizedParty)
+	case claims.Expiry.IsZero() || !now.Before(claims.Expiry.Add(leeway)):
+		return errors.New("token expired")
+	case claims.IssuedAt.After(now.Add(leeway)):
+		return errors.New("token issued in the future")
+	case nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return errors.New("unexpected nonce")
+	}
+
+	return nil
+}
+
+// getJSON fetches url and decodes its JSON body into v.
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token into v.
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// randomToken returns a random base64url encoded token with 256 bits of
+// entropy.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,500 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sudomateo/todo/oidc"
+)
+
+const (
+	clientID     = "todo"
+	clientSecret = "secret"
+	redirectURL  = "https://todo.example.com/login/oidc/callback"
+)
+
+// fakeProvider is an OpenID Connect provider that logs in whoever is given to
+// login without asking.
+type fakeProvider struct {
+	url string
+
+	mutex sync.Mutex
+	keys  map[string]crypto.Signer
+	kid   string
+	codes map[string]fakeCode
+
+	keyFetches atomic.Int32
+}
+
+// fakeCode is an authorization code handed out by the fake provider along
+// with what it was handed out for.
+type fakeCode struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+
+	f := fakeProvider{
+		keys:  make(map[string]crypto.Signer),
+		codes: make(map[string]fakeCode),
+	}
+
+	f.rotate(t, "rsa-1", newRSAKey(t))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", f.discovery)
+	mux.HandleFunc("/authorize", f.authorize)
+	mux.HandleFunc("/token", f.token)
+	mux.HandleFunc("/jwks", f.jwks)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	f.url = server.URL
+
+	return &f
+}
+
+// rotate adds a signing key, which is used to sign tokens from now on.
+func (f *fakeProvider) rotate(t *testing.T, kid string, key crypto.Signer) {
+	t.Helper()
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.keys[kid] = key
+	f.kid = kid
+}
+
+func (f *fakeProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                           f.url,
+		"authorization_endpoint":           f.url + "/authorize",
+		"token_endpoint":                   f.url + "/token",
+		"jwks_uri":                         f.url + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+// authorize logs in immediately and sends the user back with a code.
+func (f *fakeProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("client_id") != clientID || q.Get("redirect_uri") != redirectURL || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+
+	f.mutex.Lock()
+	f.codes[code] = fakeCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	f.mutex.Unlock()
+
+	http.Redirect(w, r, redirectURL+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+}
+
+func (f *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != clientID || secret != clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	f.mutex.Lock()
+	code, ok := f.codes[r.PostFormValue("code")]
+	delete(f.codes, r.PostFormValue("code"))
+	f.mutex.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+
+	if !ok || code.challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) || r.PostFormValue("redirect_uri") != redirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     f.sign(f.idClaims(code.nonce)),
+	})
+}
+
+func (f *fakeProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	f.keyFetches.Add(1)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	keys := make([]map[string]string, 0, len(f.keys))
+
+	for kid, key := range f.keys {
+		switch pub := key.Public().(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   encodeInt(pub.N, 0),
+				"e":   encodeInt(big.NewInt(int64(pub.E)), 0),
+			})
+		case *ecdsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "EC",
+				"kid": kid,
+				"crv": "P-256",
+				"x":   encodeInt(pub.X, 32),
+				"y":   encodeInt(pub.Y, 32),
+			})
+		}
+	}
+
+	// Encryption keys are skipped.
+	keys = append(keys, map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"})
+
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// idClaims returns the claims of an ID token for the login with the given
+// nonce.
+func (f *fakeProvider) idClaims(nonce string) map[string]any {
+	return f.idClaimsAt(nonce, time.Now())
+}
+
+// idClaimsAt returns the claims of an ID token for the login with the given
+// nonce issued at now.
+func (f *fakeProvider) idClaimsAt(nonce string, now time.Time) map[string]any {
+	return map[string]any{
+		"iss":                f.url,
+		"sub":                "248289761001",
+		"aud":                clientID,
+		"exp":                now.Add(time.Hour).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              nonce,
+		"name":               "Jane Doe",
+		"preferred_username": "jane",
+		"email":              "jane@example.com",
+		"email_verified":     true,
+	}
+}
+
+// sign signs claims with the current key of the provider.
+func (f *fakeProvider) sign(claims map[string]any) string {
+	f.mutex.Lock()
+	kid := f.kid
+	key := f.keys[kid]
+	f.mutex.Unlock()
+
+	return signToken(kid, key, claims)
+}
+
+// signToken signs claims with key, naming it kid in the header.
+func signToken(kid string, key crypto.Signer, claims map[string]any) string {
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// login logs in at the fake provider, returning the code it sent the user back
+// with.
+func (f *fakeProvider) login(t *testing.T, req oidc.AuthRequest) (code string, state string) {
+	t.Helper()
+
+	client := http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(req.URL)
+	if err != nil {
+		t.Fatalf("authorize: expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: expected status %d, got %d", http.StatusFound, resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: invalid redirect: %v", err)
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newProvider(t *testing.T, f *fakeProvider, opts ...oidc.Option) *oidc.Provider {
+	t.Helper()
+
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:    f.url,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	}, opts...)
+	if err != nil {
+		t.Fatalf("new provider: expected nil error, got %v", err)
+	}
+
+	return p
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFakeProvider(t)
+	p := newProvider(t, f)
+
+	req, err := p.NewAuthRequest()
+	if err != nil {
+		t.Fatalf("new auth request: expected nil error, got %v", err)
+	}
+
+	code, state := f.login(t, req)
+
+	if !req.CheckState(state) || req.CheckState("") || req.CheckState(state+"x") {
+		t.Fatal("check state: expected only the state of the request to be accepted")
+	}
+
+	// The code is bound to the verifier of the request.
+	if _, err := p.Exchange(ctx, code, "wrong"); err == nil {
+		t.Fatal("exchange: expected an error for the wrong verifier")
+	}
+
+	code, _ = f.login(t, req)
+
+	rawIDToken, err := p.Exchange(ctx, code, req.Verifier)
+	if err != nil {
+		t.Fatalf("exchange: expected nil error, got %v", err)
+	}
+
+	// Codes can only be used once.
+	if _, err := p.Exchange(ctx, code, req.Verifier); err == nil {
+		t.Fatal("exchange: expected an error for a used code")
+	}
+
+	if _, err := p.Verify(ctx, rawIDToken, "other"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Fatalf("verify: expected %v for another nonce, got %v", oidc.ErrInvalidToken, err)
+	}
+
+	claims, err := p.Verify(ctx, rawIDToken, req.Nonce)
+	if err != nil {
+		t.Fatalf("verify: expected nil error, got %v", err)
+	}
+
+	if claims.Issuer != f.url || claims.Subject != "248289761001" || claims.Username() != "jane" {
+		t.Fatalf("verify: unexpected claims %+v", claims)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	f := newFakeProvider(t)
+
+	tests := map[string]oidc.Config{
+		"issuer mismatch": {IssuerURL: f.url + "/", ClientID: clientID, RedirectURL: redirectURL},
+		"not found":       {IssuerURL: f.url + "/nope", ClientID: clientID, RedirectURL: redirectURL},
+		"missing client":  {IssuerURL: f.url, RedirectURL: redirectURL},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := oidc.NewProvider(context.Background(), cfg); err == nil {
+				t.Fatal("new provider: expected an error")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFakeProvider(t)
+	p := newProvider(t, f)
+
+	const nonce = "n-0S6_WzA2Mj"
+
+	with := func(changes map[string]any) map[string]any {
+		claims := f.idClaims(nonce)
+		for k, v := range changes {
+			if v == nil {
+				delete(claims, k)
+				continue
+			}
+			claims[k] = v
+		}
+		return claims
+	}
+
+	valid := f.sign(with(nil))
+
+	if _, err := p.Verify(ctx, valid, nonce); err != nil {
+		t.Fatalf("verify: expected nil error, got %v", err)
+	}
+
+	parts := strings.Split(valid, ".")
+
+	unsignedHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	otherKey := newRSAKey(t)
+
+	tests := map[string]string{
+		"malformed":         "abc",
+		"unsigned":          unsignedHeader + "." + parts[1] + ".",
+		"tampered claims":   parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2],
+		"signed by other":   signToken("rsa-1", otherKey, with(nil)),
+		"unknown key":       signToken("rsa-2", otherKey, with(nil)),
+		"wrong issuer":      f.sign(with(map[string]any{"iss": "https://evil.example.com"})),
+		"wrong audience":    f.sign(with(map[string]any{"aud": "other"})),
+		"missing azp":       f.sign(with(map[string]any{"aud": []string{clientID, "other"}})),
+		"wrong azp":         f.sign(with(map[string]any{"azp": "other"})),
+		"missing subject":   f.sign(with(map[string]any{"sub": nil})),
+		"expired":           f.sign(with(map[string]any{"exp": time.Now().Add(-2 * time.Minute).Unix()})),
+		"missing expiry":    f.sign(with(map[string]any{"exp": nil})),
+		"issued in future":  f.sign(with(map[string]any{"iat": time.Now().Add(time.Hour).Unix()})),
+		"missing nonce":     f.sign(with(map[string]any{"nonce": nil})),
+		"encryption key id": signToken("enc", otherKey, with(nil)),
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := p.Verify(ctx, token, nonce); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Fatalf("verify: expected %v, got %v", oidc.ErrInvalidToken, err)
+			}
+		})
+	}
+
+	t.Run("multiple audiences", func(t *testing.T) {
+		token := f.sign(with(map[string]any{"aud": []string{"other", clientID}, "azp": clientID}))
+		if _, err := p.Verify(ctx, token, nonce); err != nil {
+			t.Fatalf("verify: expected nil error, got %v", err)
+		}
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	f := newFakeProvider(t)
+	p := newProvider(t, f, oidc.WithClock(clock))
+
+	const nonce = "nonce"
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Verify(ctx, f.sign(f.idClaims(nonce)), nonce); err != nil {
+			t.Fatalf("verify: expected nil error, got %v", err)
+		}
+	}
+
+	if n := f.keyFetches.Load(); n != 1 {
+		t.Fatalf("verify: expected keys to be fetched once, got %d", n)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	f.rotate(t, "ec-1", ecKey)
+	rotated := f.sign(f.idClaims(nonce))
+
+	// Keys were fetched too recently to look for the new key.
+	if _, err := p.Verify(ctx, rotated, nonce); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Fatalf("verify: expected %v, got %v", oidc.ErrInvalidToken, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, err := p.Verify(ctx, rotated, nonce); err != nil {
+		t.Fatalf("verify: expected nil error after rotation, got %v", err)
+	}
+
+	if n := f.keyFetches.Load(); n != 2 {
+		t.Fatalf("verify: expected keys to be fetched twice, got %d", n)
+	}
+
+	// Keys are fetched again once they are stale.
+	now = now.Add(oidc.DefaultKeySetTTL)
+
+	if _, err := p.Verify(ctx, f.sign(f.idClaimsAt(nonce, now)), nonce); err != nil {
+		t.Fatalf("verify: expected nil error, got %v", err)
+	}
+
+	if n := f.keyFetches.Load(); n != 3 {
+		t.Fatalf("verify: expected keys to be fetched three times, got %d", n)
+	}
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	return key
+}
+
+func encodeInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if size > 0 {
+		b = n.FillBytes(make([]byte, size))
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
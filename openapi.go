@@ -0,0 +1,636 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// openAPISpec is the OpenAPI 3.1 document describing every route under /api.
+// The schemas are generated from the types the handlers decode and encode, and
+// the operations from the routes registered in App.routes.
+var openAPISpec = mustMarshal(generateOpenAPI())
+
+// OpenAPI serves the OpenAPI document of the API. It is rendered for humans at
+// /static/api.html.
+func (a *App) OpenAPI(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPISpec)
+}
+
+// document is an OpenAPI document.
+type document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       info                             `json:"info"`
+	Servers    []server                         `json:"servers"`
+	Security   []map[string][]string            `json:"security"`
+	Tags       []tag                            `json:"tags"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas         *properties               `json:"schemas"`
+		Parameters      map[string]*parameter     `json:"parameters"`
+		Responses       map[string]*response      `json:"responses"`
+		SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+	} `json:"components"`
+}
+
+type info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description"`
+}
+
+// operation describes what a route takes and what it responds with.
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags"`
+	Description string               `json:"description,omitempty"`
+	Parameters  []*parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+// parameter is either a parameter of an operation or, when Ref is set, a
+// reference to one under components/parameters.
+type parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema,omitempty"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+// response is either a response of an operation or, when Ref is set, a
+// reference to one under components/responses.
+type response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]*header   `json:"headers,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type header struct {
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+// generateOpenAPI generates the OpenAPI document of the API.
+func generateOpenAPI() document {
+	doc := document{
+		OpenAPI: "3.1.0",
+		Info: info{
+			Title:       "todo",
+			Version:     "1.0.0",
+			Description: "API of the todo web application. Requests work in the workspace given by the X-Workspace-ID header. Requests are checked against this document before they are handled, and those with parameters or body fields it does not describe are rejected with every wrong value listed.",
+		},
+		Servers:  []server{{URL: "/"}},
+		Security: []map[string][]string{{}, {"apiKey": {}}},
+		Tags: []tag{
+			{Name: "meta"},
+			{Name: "todos"},
+			{Name: "trash"},
+			{Name: "sharing"},
+			{Name: "lists"},
+			{Name: "workspaces"},
+			{Name: "webhooks"},
+			{Name: "users"},
+		},
+		Paths: operations(),
+	}
+
+	// Every operation works in the workspace given by the header.
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			op.Parameters = append(op.Parameters, param("workspace"))
+		}
+	}
+
+	doc.Components.Schemas = schemas()
+	doc.Components.Parameters = parameters()
+	doc.Components.Responses = map[string]*response{
+		"BadRequest":          errorResponse("The request is invalid."),
+		"Unauthorized":        errorResponse("The request is not authenticated."),
+		"Forbidden":           errorResponse("The current user may not do this."),
+		"NotFound":            errorResponse("Not found. The body is empty or an error."),
+		"PreconditionFailed":  errorResponse("The If-Match header or version does not match the todo."),
+		"Conflict":            errorResponse("The todo has incomplete subtasks."),
+		"InternalServerError": errorResponse("The server failed to handle the request."),
+	}
+	doc.Components.SecuritySchemes = map[string]securityScheme{
+		"apiKey": {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "API key created with POST /api/users or POST /api/keys. Required when TODO_AUTH_REQUIRED is set.",
+		},
+	}
+
+	return doc
+}
+
+// filterParams are the query parameters that select todo items.
+var filterParams = []string{"trashed", "list", "completed", "priority", "tag", "created_after", "created_before", "updated_after", "updated_before"}
+
+// queryParams are the query parameters of the routes that list todo items.
+var queryParams = append(append([]string{}, filterParams...), "order_by", "direction", "limit", "offset", "cursor")
+
+// parameters returns the parameters under components/parameters.
+func parameters() map[string]*parameter {
+	uuid := &schema{Type: "string", Format: "uuid"}
+	timestamp := &schema{Type: "string", Format: "date-time"}
+
+	return map[string]*parameter{
+		"id":          {Name: "id", In: "path", Required: true, Schema: uuid},
+		"user_id":     {Name: "user_id", In: "path", Required: true, Schema: uuid},
+		"delivery_id": {Name: "delivery_id", In: "path", Required: true, Schema: uuid},
+		"workspace":   {Name: workspaceHeader, In: "header", Description: "Workspace to work in. Defaults to the default workspace.", Schema: uuid},
+
+		"trashed":        query("trashed", &schema{Type: "boolean"}, "Only todos in the trash."),
+		"list":           query("list", uuid, "Only todos in the list."),
+		"completed":      query("completed", &schema{Type: "boolean"}, "Only completed or incomplete todos."),
+		"priority":       query("priority", ref("Priority"), "Only todos with the priority."),
+		"tag":            query("tag", arrayOf("Tag"), "Only todos with every tag. May be repeated."),
+		"created_after":  query("created_after", timestamp, "Only todos created after the time."),
+		"created_before": query("created_before", timestamp, "Only todos created before the time."),
+		"updated_after":  query("updated_after", timestamp, "Only todos updated after the time."),
+		"updated_before": query("updated_before", timestamp, "Only todos updated before the time."),
+		"order_by":       query("order_by", enum(todo.OrderByTimeCreated, todo.OrderByTimeUpdated, todo.OrderByDueAt, todo.OrderByPriority, todo.OrderByText), "Field to sort by. Defaults to time_created."),
+		"direction":      query("direction", enum(todo.DirectionAsc, todo.DirectionDesc), "Sort direction. Defaults to asc."),
+		"limit":          query("limit", &schema{Type: "integer", Minimum: ptr(0)}, "Maximum number of todos. 0 means no limit."),
+		"offset":         query("offset", &schema{Type: "integer", Minimum: ptr(0)}, "Number of todos to skip."),
+		"cursor":         query("cursor", &schema{Type: "string"}, "Position after which to continue, as given in the Link header."),
+	}
+}
+
+// operations returns the operations of every route under /api by path and
+// method.
+func operations() map[string]map[string]*operation {
+	ifMatch := &parameter{Name: "If-Match", In: "header", Description: "Only update the todo when it matches its ETag.", Schema: &schema{Type: "string"}}
+	ifNoneMatch := &parameter{Name: "If-None-Match", In: "header", Schema: &schema{Type: "string"}}
+	lastEventID := &parameter{Name: "Last-Event-ID", In: "header", Description: "Resume after this event.", Schema: &schema{Type: "string"}}
+	deleted := &schema{Type: "object", Required: []string{"deleted"}, Properties: &properties{{name: "deleted", schema: &schema{Type: "integer"}}}}
+	deletePolicy := query("policy", enum(todo.DeletePolicyInbox, todo.DeletePolicyCascade), "Whether the todos of the list move to the default list of the workspace, the default, or are deleted.")
+
+	return map[string]map[string]*operation{
+		"/api/openapi.json": {
+			"get": {
+				OperationID: "openAPI", Summary: "Get this document", Tags: []string{"meta"},
+				Responses: responses(reply(http.StatusOK, "The OpenAPI document of the API.", &schema{Type: "object"})),
+			},
+		},
+		"/api/todo": {
+			"get": {
+				OperationID: "queryTodos", Summary: "List todos", Tags: []string{"todos"},
+				Parameters: params(queryParams...),
+				Responses:  responses(reply(http.StatusOK, "Todos matching the filters.", arrayOf("Todo"), "Link"), badRequestStatus, notFoundStatus),
+			},
+			"post": {
+				OperationID: "createTodo", Summary: "Create a todo", Tags: []string{"todos"},
+				RequestBody: body("TodoCreateParams"),
+				Responses:   responses(reply(http.StatusCreated, "The created todo.", ref("Todo"), "ETag"), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/todo/overdue": {
+			"get": {
+				OperationID: "queryOverdue", Summary: "List overdue todos", Tags: []string{"todos"},
+				Responses: responses(reply(http.StatusOK, "Incomplete todos past their due date.", arrayOf("Todo"))),
+			},
+		},
+		"/api/todo/search": {
+			"get": {
+				OperationID: "searchTodos", Summary: "Search todos", Tags: []string{"todos"},
+				Parameters: []*parameter{
+					query("q", &schema{Type: "string"}, "Words to search for."),
+					query("limit", &schema{Type: "integer", Minimum: ptr(0), Maximum: ptr(todo.MaxSearchLimit)}, "Maximum number of results. Defaults to "+strconv.Itoa(todo.DefaultSearchLimit)+"."),
+				},
+				Responses: responses(
+					reply(http.StatusOK, "Matching todos, best match first.", arrayOf("SearchResult")),
+					badRequestStatus,
+					failure(http.StatusNotImplemented, "The database does not support search."),
+				),
+			},
+		},
+		"/api/todo/shared": {
+			"get": {
+				OperationID: "queryShared", Summary: "List todos shared with you", Tags: []string{"sharing"},
+				Responses: responses(reply(http.StatusOK, "Todos other users shared with the current user.", arrayOf("Todo")), unauthorizedStatus),
+			},
+		},
+		"/api/todo/batch": {
+			"post": {
+				OperationID: "batch", Summary: "Apply several changes at once", Tags: []string{"todos"},
+				Description: "In atomic mode the first operation to fail fails the whole request with its status code and nothing is changed.",
+				RequestBody: body("BatchParams"),
+				Responses:   responses(reply(http.StatusOK, "The result of each operation.", arrayOf("BatchResult")), badRequestStatus, forbiddenStatus, notFoundStatus, conflictStatus, preconditionFailedStatus),
+			},
+		},
+		"/api/todo/complete": {
+			"post": {
+				OperationID: "completeAll", Summary: "Complete todos", Tags: []string{"todos"},
+				Parameters: params(filterParams...),
+				Responses:  responses(reply(http.StatusOK, "The todos that were completed.", arrayOf("Todo")), badRequestStatus, conflictStatus),
+			},
+		},
+		"/api/todo/completed": {
+			"delete": {
+				OperationID: "deleteCompleted", Summary: "Delete completed todos", Tags: []string{"todos"},
+				Parameters: params(filterParams...),
+				Responses:  responses(reply(http.StatusOK, "How many todos were moved to the trash.", deleted), badRequestStatus),
+			},
+		},
+		"/api/todo/{id}": {
+			"get": {
+				OperationID: "queryTodo", Summary: "Get a todo", Tags: []string{"todos"},
+				Parameters: []*parameter{param("id"), ifNoneMatch},
+				Responses: responses(
+					reply(http.StatusOK, "The todo.", ref("Todo"), "ETag"),
+					empty(http.StatusNotModified, "The todo matches the If-None-Match header."),
+					badRequestStatus,
+					notFoundStatus,
+				),
+			},
+			"patch": {
+				OperationID: "updateTodo", Summary: "Update a todo", Tags: []string{"todos"},
+				Parameters:  []*parameter{param("id"), ifMatch},
+				RequestBody: body("TodoUpdateParams"),
+				Responses:   responses(reply(http.StatusOK, "The updated todo.", ref("Todo"), "ETag"), badRequestStatus, forbiddenStatus, notFoundStatus, conflictStatus, preconditionFailedStatus),
+			},
+			"delete": {
+				OperationID: "deleteTodo", Summary: "Move a todo to the trash", Tags: []string{"todos"},
+				Parameters: params("id"),
+				Responses:  responses(empty(http.StatusNoContent, "The todo and its subtasks were moved to the trash."), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/todo/{id}/children": {
+			"get": {
+				OperationID: "queryChildren", Summary: "List subtasks", Tags: []string{"todos"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "Every descendant of the todo, ordered by depth.", arrayOf("Todo")), badRequestStatus, notFoundStatus),
+			},
+		},
+		"/api/todo/{id}/history": {
+			"get": {
+				OperationID: "queryHistory", Summary: "List the changes to a todo", Tags: []string{"todos"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "Changes to the todo, oldest first.", arrayOf("Event")), badRequestStatus, notFoundStatus),
+			},
+		},
+		"/api/todo/{id}/restore": {
+			"post": {
+				OperationID: "restoreTodo", Summary: "Restore a todo from the trash", Tags: []string{"trash"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "The restored todo.", ref("Todo"), "ETag"), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/todo/{id}/collaborators": {
+			"get": {
+				OperationID: "queryCollaborators", Summary: "List who a todo is shared with", Tags: []string{"sharing"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "The users the todo is shared with.", arrayOf("Collaborator")), badRequestStatus, notFoundStatus),
+			},
+			"post": {
+				OperationID: "shareTodo", Summary: "Share a todo", Tags: []string{"sharing"},
+				Parameters:  params("id"),
+				RequestBody: body("ShareParams"),
+				Responses:   responses(reply(http.StatusCreated, "The collaborator.", ref("Collaborator")), badRequestStatus, unauthorizedStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/todo/{id}/collaborators/{user_id}": {
+			"delete": {
+				OperationID: "unshareTodo", Summary: "Stop sharing a todo", Tags: []string{"sharing"},
+				Parameters: params("id", "user_id"),
+				Responses:  responses(empty(http.StatusNoContent, "The todo is no longer shared with the user."), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/trash": {
+			"get": {
+				OperationID: "queryTrash", Summary: "List todos in the trash", Tags: []string{"trash"},
+				Parameters: params(queryParams...),
+				Responses:  responses(reply(http.StatusOK, "Todos in the trash matching the filters.", arrayOf("Todo"), "Link"), badRequestStatus),
+			},
+			"delete": {
+				OperationID: "purgeTrash", Summary: "Empty the trash", Tags: []string{"trash"},
+				Responses: responses(empty(http.StatusNoContent, "The trash was emptied.")),
+			},
+		},
+		"/api/trash/{id}": {
+			"delete": {
+				OperationID: "purgeTodo", Summary: "Permanently delete a todo", Tags: []string{"trash"},
+				Parameters: params("id"),
+				Responses:  responses(empty(http.StatusNoContent, "The todo and its subtasks were deleted."), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/tags": {
+			"get": {
+				OperationID: "queryTags", Summary: "List tags", Tags: []string{"todos"},
+				Responses: responses(reply(http.StatusOK, "Every tag in use and how many todos use it.", arrayOf("TagCount"))),
+			},
+		},
+		"/api/events": {
+			"get": {
+				OperationID: "streamEvents", Summary: "Stream changes to todos", Tags: []string{"todos"},
+				Parameters: []*parameter{lastEventID},
+				Responses: responses(status{
+					code: strconv.Itoa(http.StatusOK),
+					response: &response{
+						Description: "Server-sent events named after the event type, with the type and todo as data.",
+						Content:     map[string]mediaType{"text/event-stream": {Schema: &schema{Type: "string"}}},
+					},
+				}),
+			},
+		},
+		"/api/lists": {
+			"get": {
+				OperationID: "queryLists", Summary: "List lists", Tags: []string{"lists"},
+				Responses: responses(reply(http.StatusOK, "The lists of the workspace the current user can see.", arrayOf("List"))),
+			},
+			"post": {
+				OperationID: "createList", Summary: "Create a list", Tags: []string{"lists"},
+				RequestBody: body("ListCreateParams"),
+				Responses:   responses(reply(http.StatusCreated, "The created list.", ref("List")), badRequestStatus),
+			},
+		},
+		"/api/lists/{id}": {
+			"get": {
+				OperationID: "queryList", Summary: "Get a list", Tags: []string{"lists"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "The list.", ref("List")), badRequestStatus, notFoundStatus),
+			},
+			"patch": {
+				OperationID: "updateList", Summary: "Rename a list", Tags: []string{"lists"},
+				Parameters:  params("id"),
+				RequestBody: body("ListUpdateParams"),
+				Responses:   responses(reply(http.StatusOK, "The updated list.", ref("List")), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+			"delete": {
+				OperationID: "deleteList", Summary: "Delete a list", Tags: []string{"lists"},
+				Parameters: []*parameter{param("id"), deletePolicy},
+				Responses:  responses(empty(http.StatusNoContent, "The list was deleted."), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/workspaces": {
+			"get": {
+				OperationID: "queryWorkspaces", Summary: "List workspaces", Tags: []string{"workspaces"},
+				Responses: responses(reply(http.StatusOK, "The default workspace followed by yours.", arrayOf("Workspace"))),
+			},
+			"post": {
+				OperationID: "createWorkspace", Summary: "Create a workspace", Tags: []string{"workspaces"},
+				RequestBody: body("WorkspaceCreateParams"),
+				Responses:   responses(reply(http.StatusCreated, "The created workspace.", ref("Workspace")), badRequestStatus, unauthorizedStatus),
+			},
+		},
+		"/api/workspaces/{id}": {
+			"get": {
+				OperationID: "queryWorkspace", Summary: "Get a workspace", Tags: []string{"workspaces"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "The workspace.", ref("Workspace")), badRequestStatus, notFoundStatus),
+			},
+			"patch": {
+				OperationID: "updateWorkspace", Summary: "Update a workspace", Tags: []string{"workspaces"},
+				Parameters:  params("id"),
+				RequestBody: body("WorkspaceUpdateParams"),
+				Responses:   responses(reply(http.StatusOK, "The updated workspace.", ref("Workspace")), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+			"delete": {
+				OperationID: "deleteWorkspace", Summary: "Delete a workspace", Tags: []string{"workspaces"},
+				Parameters: params("id"),
+				Responses:  responses(empty(http.StatusNoContent, "The workspace was deleted along with its todos and lists."), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/workspaces/{id}/members": {
+			"get": {
+				OperationID: "queryMembers", Summary: "List members", Tags: []string{"workspaces"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "The members of the workspace.", arrayOf("Member")), badRequestStatus, notFoundStatus),
+			},
+			"post": {
+				OperationID: "addMember", Summary: "Add a member", Tags: []string{"workspaces"},
+				Parameters:  params("id"),
+				RequestBody: body("MemberParams"),
+				Responses:   responses(reply(http.StatusCreated, "The member.", ref("Member")), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/workspaces/{id}/members/{user_id}": {
+			"delete": {
+				OperationID: "removeMember", Summary: "Remove a member", Tags: []string{"workspaces"},
+				Parameters: params("id", "user_id"),
+				Responses:  responses(empty(http.StatusNoContent, "The user is no longer a member."), badRequestStatus, forbiddenStatus, notFoundStatus),
+			},
+		},
+		"/api/webhooks": {
+			"get": {
+				OperationID: "queryWebhooks", Summary: "List webhooks", Tags: []string{"webhooks"},
+				Responses: responses(reply(http.StatusOK, "Webhooks, without their secrets.", arrayOf("Webhook"))),
+			},
+			"post": {
+				OperationID: "createWebhook", Summary: "Create a webhook", Tags: []string{"webhooks"},
+				RequestBody: body("WebhookCreateParams"),
+				Responses:   responses(reply(http.StatusCreated, "The created webhook, including its secret.", ref("Webhook")), badRequestStatus),
+			},
+		},
+		"/api/webhooks/{id}": {
+			"get": {
+				OperationID: "queryWebhook", Summary: "Get a webhook", Tags: []string{"webhooks"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "The webhook, without its secret.", ref("Webhook")), badRequestStatus, notFoundStatus),
+			},
+			"patch": {
+				OperationID: "updateWebhook", Summary: "Update a webhook", Tags: []string{"webhooks"},
+				Parameters:  params("id"),
+				RequestBody: body("WebhookUpdateParams"),
+				Responses:   responses(reply(http.StatusOK, "The updated webhook, without its secret.", ref("Webhook")), badRequestStatus, notFoundStatus),
+			},
+			"delete": {
+				OperationID: "deleteWebhook", Summary: "Delete a webhook", Tags: []string{"webhooks"},
+				Parameters: params("id"),
+				Responses:  responses(empty(http.StatusNoContent, "The webhook was deleted along with its deliveries."), badRequestStatus, notFoundStatus),
+			},
+		},
+		"/api/webhooks/{id}/deliveries": {
+			"get": {
+				OperationID: "queryDeliveries", Summary: "List deliveries", Tags: []string{"webhooks"},
+				Parameters: params("id"),
+				Responses:  responses(reply(http.StatusOK, "Deliveries of the webhook, newest first.", arrayOf("Delivery")), badRequestStatus, notFoundStatus),
+			},
+		},
+		"/api/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+			"post": {
+				OperationID: "redeliver", Summary: "Send a delivery again", Tags: []string{"webhooks"},
+				Parameters: params("id", "delivery_id"),
+				Responses:  responses(reply(http.StatusOK, "The delivery, queued to be sent again.", ref("Delivery")), badRequestStatus, notFoundStatus),
+			},
+		},
+		"/api/users": {
+			"post": {
+				OperationID: "register", Summary: "Register a user", Tags: []string{"users"},
+				RequestBody: body("UserCreateParams"),
+				Responses: responses(
+					reply(http.StatusCreated, "The user and their first API key.", ref("Registration")),
+					badRequestStatus,
+					failure(http.StatusConflict, "A user with the name already exists."),
+				),
+			},
+		},
+		"/api/users/me": {
+			"get": {
+				OperationID: "currentUser", Summary: "Get the current user", Tags: []string{"users"},
+				Responses: responses(reply(http.StatusOK, "The current user.", ref("User")), unauthorizedStatus),
+			},
+		},
+		"/api/keys": {
+			"get": {
+				OperationID: "queryAPIKeys", Summary: "List API keys", Tags: []string{"users"},
+				Responses: responses(reply(http.StatusOK, "API keys of the current user.", arrayOf("APIKey")), unauthorizedStatus),
+			},
+			"post": {
+				OperationID: "createAPIKey", Summary: "Create an API key", Tags: []string{"users"},
+				RequestBody: body("APIKeyCreateParams"),
+				Responses:   responses(reply(http.StatusCreated, "The created key.", ref("NewAPIKey")), badRequestStatus, unauthorizedStatus),
+			},
+		},
+		"/api/keys/{id}": {
+			"delete": {
+				OperationID: "revokeAPIKey", Summary: "Revoke an API key", Tags: []string{"users"},
+				Parameters: params("id"),
+				Responses:  responses(empty(http.StatusNoContent, "The key was revoked."), badRequestStatus, unauthorizedStatus, notFoundStatus),
+			},
+		},
+	}
+}
+
+// status is a response of an operation along with its status code.
+type status struct {
+	code     string
+	response *response
+}
+
+// The responses shared by many operations.
+var (
+	badRequestStatus         = status{code: "400", response: &response{Ref: "#/components/responses/BadRequest"}}
+	unauthorizedStatus       = status{code: "401", response: &response{Ref: "#/components/responses/Unauthorized"}}
+	forbiddenStatus          = status{code: "403", response: &response{Ref: "#/components/responses/Forbidden"}}
+	notFoundStatus           = status{code: "404", response: &response{Ref: "#/components/responses/NotFound"}}
+	conflictStatus           = status{code: "409", response: &response{Ref: "#/components/responses/Conflict"}}
+	preconditionFailedStatus = status{code: "412", response: &response{Ref: "#/components/responses/PreconditionFailed"}}
+)
+
+// responseHeaders are the headers responses can have, by name.
+var responseHeaders = map[string]*header{
+	"ETag": {Description: "Version of the todo.", Schema: &schema{Type: "string"}},
+	"Link": {Description: "Link to the next page when more todos may follow.", Schema: &schema{Type: "string"}},
+}
+
+// responses returns the responses of an operation. Every operation can fail
+// with an internal server error.
+func responses(statuses ...status) map[string]*response {
+	r := map[string]*response{
+		"default": {Ref: "#/components/responses/InternalServerError"},
+	}
+
+	for _, s := range statuses {
+		r[s.code] = s.response
+	}
+
+	return r
+}
+
+// reply returns a response with a JSON body matching s and the given headers.
+func reply(code int, description string, s *schema, headers ...string) status {
+	r := &response{
+		Description: description,
+		Content:     map[string]mediaType{echo.MIMEApplicationJSON: {Schema: s}},
+	}
+
+	for _, name := range headers {
+		if r.Headers == nil {
+			r.Headers = make(map[string]*header)
+		}
+		r.Headers[name] = responseHeaders[name]
+	}
+
+	return status{code: strconv.Itoa(code), response: r}
+}
+
+// empty returns a response without a body.
+func empty(code int, description string) status {
+	return status{code: strconv.Itoa(code), response: &response{Description: description}}
+}
+
+// failure returns a response with an error as its body.
+func failure(code int, description string) status {
+	return status{code: strconv.Itoa(code), response: errorResponse(description)}
+}
+
+// errorResponse returns a response with an error as its body.
+func errorResponse(description string) *response {
+	return &response{
+		Description: description,
+		Content:     map[string]mediaType{echo.MIMEApplicationJSON: {Schema: ref("Error")}},
+	}
+}
+
+// body returns a required JSON request body matching the component name.
+func body(name string) *requestBody {
+	return &requestBody{
+		Required: true,
+		Content:  map[string]mediaType{echo.MIMEApplicationJSON: {Schema: ref(name)}},
+	}
+}
+
+// param returns a reference to the parameter name under
+// components/parameters.
+func param(name string) *parameter {
+	return &parameter{Ref: "#/components/parameters/" + name}
+}
+
+// params returns references to the parameters under components/parameters.
+func params(names ...string) []*parameter {
+	p := make([]*parameter, 0, len(names))
+	for _, name := range names {
+		p = append(p, param(name))
+	}
+
+	return p
+}
+
+// query returns an optional query parameter.
+func query(name string, s *schema, description string) *parameter {
+	return &parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+// mustMarshal encodes v as indented JSON, panicking on failure.
+func mustMarshal(v any) []byte {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
@@ -0,0 +1,227 @@
+// Package openapi validates requests against the operations described by an
+// OpenAPI 3.1 document. Only the parts of the document and of JSON Schema that
+// are needed to validate parameters and JSON request bodies are supported.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// methods are the keys of a path item that describe operations.
+var methods = map[string]bool{
+	"get":     true,
+	"put":     true,
+	"post":    true,
+	"delete":  true,
+	"options": true,
+	"head":    true,
+	"patch":   true,
+	"trace":   true,
+}
+
+// Document is a parsed OpenAPI document.
+type Document struct {
+	operations map[string]*Operation
+}
+
+// Operation describes the parameters and request body of a single route.
+type Operation struct {
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// Parameter describes a path, query or header parameter of an operation.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of an operation. Only JSON bodies are
+// supported.
+type RequestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *Schema `json:"schema"`
+	} `json:"content"`
+}
+
+// Schema is a JSON Schema.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 Types              `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Items                *Schema            `json:"items"`
+	MaxItems             *int               `json:"maxItems"`
+	UniqueItems          bool               `json:"uniqueItems"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	AllOf                []*Schema          `json:"allOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+
+	// target is the schema Ref points to.
+	target *Schema
+
+	// pattern is the compiled Pattern.
+	pattern *regexp.Regexp
+}
+
+// Types are the JSON types a schema allows, written as a single type or a
+// list of them.
+type Types []string
+
+// UnmarshalJSON decodes either form of the type keyword.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = Types{s}
+		return nil
+	}
+
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+
+	*t = types
+
+	return nil
+}
+
+// Load parses an OpenAPI document, resolving its references.
+func Load(data []byte) (*Document, error) {
+	var raw struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas    map[string]*Schema    `json:"schemas"`
+			Parameters map[string]*Parameter `json:"parameters"`
+		} `json:"components"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+
+	doc := Document{operations: make(map[string]*Operation)}
+
+	resolveSchema := func(s *Schema) error {
+		return s.resolve(raw.Components.Schemas, make(map[*Schema]bool))
+	}
+
+	for name, s := range raw.Components.Schemas {
+		if err := resolveSchema(s); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+
+	for path, item := range raw.Paths {
+		for method, data := range item {
+			if !methods[method] {
+				continue
+			}
+
+			var op Operation
+			if err := json.Unmarshal(data, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+
+			for i, p := range op.Parameters {
+				if p.Ref != "" {
+					name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+					if !ok || raw.Components.Parameters[name] == nil {
+						return nil, fmt.Errorf("%s %s: unknown parameter %s", method, path, p.Ref)
+					}
+					p = raw.Components.Parameters[name]
+					op.Parameters[i] = p
+				}
+
+				if p.Schema != nil {
+					if err := resolveSchema(p.Schema); err != nil {
+						return nil, fmt.Errorf("%s %s: parameter %s: %w", method, path, p.Name, err)
+					}
+				}
+			}
+
+			if body := op.bodySchema(); body != nil {
+				if err := resolveSchema(body); err != nil {
+					return nil, fmt.Errorf("%s %s: request body: %w", method, path, err)
+				}
+			}
+
+			doc.operations[strings.ToUpper(method)+" "+path] = &op
+		}
+	}
+
+	return &doc, nil
+}
+
+// Operation returns the operation for method on path, a path template such as
+// /api/todo/{id}.
+func (d *Document) Operation(method string, path string) (*Operation, bool) {
+	op, ok := d.operations[strings.ToUpper(method)+" "+path]
+	return op, ok
+}
+
+// bodySchema returns the schema of a JSON request body, or nil when the
+// operation does not take one.
+func (o *Operation) bodySchema() *Schema {
+	if o.RequestBody == nil {
+		return nil
+	}
+
+	return o.RequestBody.Content["application/json"].Schema
+}
+
+// resolve links s and the schemas within it to the schemas their references
+// point to and compiles their patterns. Schemas already in seen are skipped so
+// that recursive schemas terminate.
+func (s *Schema) resolve(schemas map[string]*Schema, seen map[*Schema]bool) error {
+	if s == nil || seen[s] {
+		return nil
+	}
+	seen[s] = true
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || schemas[name] == nil {
+			return fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		s.target = schemas[name]
+
+		return s.target.resolve(schemas, seen)
+	}
+
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+
+	children := append([]*Schema{s.Items}, s.AllOf...)
+	children = append(children, s.OneOf...)
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+
+	for _, child := range children {
+		if err := child.resolve(schemas, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
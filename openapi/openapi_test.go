@@ -0,0 +1,217 @@
+package openapi_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sudomateo/todo/openapi"
+)
+
+const document = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/things/{id}": {
+      "put": {
+        "parameters": [
+          {"$ref": "#/components/parameters/id"},
+          {"name": "dry_run", "in": "query", "schema": {"type": "boolean"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 10}},
+          {"name": "label", "in": "query", "schema": {"type": "array", "items": {"type": "string", "minLength": 1}}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Thing"}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "id": {"name": "id", "in": "path", "required": true, "schema": {"type": "string", "format": "uuid"}}
+    },
+    "schemas": {
+      "Kind": {"type": "string", "enum": ["small", "large"]},
+      "Thing": {
+        "type": "object",
+        "required": ["name"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 8},
+          "kind": {"oneOf": [{"$ref": "#/components/schemas/Kind"}, {"type": "null"}]},
+          "due_at": {"type": ["string", "null"], "format": "date-time"},
+          "count": {"type": "integer", "minimum": 0},
+          "tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2, "uniqueItems": true},
+          "parts": {"type": "array", "items": {"$ref": "#/components/schemas/Thing"}},
+          "a/b~c": {"type": "boolean"}
+        }
+      }
+    }
+  }
+}`
+
+const thingID = "7f1e4a3c-9d1b-4c55-8f0e-2b6a1d9c0e11"
+
+func TestValidate(t *testing.T) {
+	doc, err := openapi.Load([]byte(document))
+	if err != nil {
+		t.Fatalf("load: expected nil error, got %v", err)
+	}
+
+	if _, ok := doc.Operation("GET", "/things/{id}"); ok {
+		t.Fatalf("operation: expected no operation for GET /things/{id}")
+	}
+
+	op, ok := doc.Operation("PUT", "/things/{id}")
+	if !ok {
+		t.Fatalf("operation: expected an operation for PUT /things/{id}")
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		path  string
+		body  string
+		want  []openapi.FieldError
+	}{
+		{
+			name: "valid",
+			query: url.Values{
+				"dry_run": {"true"},
+				"limit":   {"10"},
+				"label":   {"a", "b"},
+			},
+			body: `{"name": "box", "kind": null, "due_at": "2026-01-02T15:04:05Z", "count": 3, "tags": ["a"], "parts": [{"name": "lid", "kind": "small"}]}`,
+		},
+		{
+			name: "path",
+			path: "42",
+			body: `{"name": "box"}`,
+			want: []openapi.FieldError{
+				{In: openapi.InPath, Pointer: "/id", Message: "must be a UUID"},
+			},
+		},
+		{
+			name: "query",
+			query: url.Values{
+				"dry_run": {"maybe"},
+				"limit":   {"11", "1"},
+				"label":   {"a", ""},
+				"sort":    {"name"},
+			},
+			body: `{"name": "box"}`,
+			want: []openapi.FieldError{
+				{In: openapi.InQuery, Pointer: "/dry_run", Message: "must be a boolean"},
+				{In: openapi.InQuery, Pointer: "/limit", Message: "must not be repeated"},
+				{In: openapi.InQuery, Pointer: "/label/1", Message: "must not be empty"},
+				{In: openapi.InQuery, Pointer: "/sort", Message: "unknown parameter"},
+			},
+		},
+		{
+			name:  "query range",
+			query: url.Values{"limit": {"0"}},
+			body:  `{"name": "box"}`,
+			want: []openapi.FieldError{
+				{In: openapi.InQuery, Pointer: "/limit", Message: "must be at least 1"},
+			},
+		},
+		{
+			name: "missing body",
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "", Message: "missing request body"},
+			},
+		},
+		{
+			name: "malformed body",
+			body: `{"name": "box",}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "", Message: "invalid JSON at offset 16: invalid character '}' looking for beginning of object key string"},
+			},
+		},
+		{
+			name: "trailing data",
+			body: `{"name": "box"} {}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "", Message: "invalid JSON: unexpected data after offset 15"},
+			},
+		},
+		{
+			name: "wrong type",
+			body: `["box"]`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "", Message: "must be an object"},
+			},
+		},
+		{
+			name: "fields",
+			body: `{"kind": "huge", "due_at": "soon", "count": 1.5, "tags": ["a", "a", "B"], "a/b~c": "yes", "colour": "red"}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "/name", Message: "missing required field"},
+				{In: openapi.InBody, Pointer: "/a~1b~0c", Message: "must be a boolean"},
+				{In: openapi.InBody, Pointer: "/colour", Message: "unknown field"},
+				{In: openapi.InBody, Pointer: "/count", Message: "must be an integer"},
+				{In: openapi.InBody, Pointer: "/due_at", Message: "must be an RFC 3339 timestamp"},
+				{In: openapi.InBody, Pointer: "/kind", Message: "must be one of small, large"},
+				{In: openapi.InBody, Pointer: "/tags", Message: "must have at most 2 items"},
+				{In: openapi.InBody, Pointer: "/tags/1", Message: "must not be a duplicate"},
+				{In: openapi.InBody, Pointer: "/tags/2", Message: "must match ^[a-z]+$"},
+			},
+		},
+		{
+			name: "nested",
+			body: `{"name": "box", "kind": 1, "parts": [{"name": "lid"}, {"name": "handle and strap", "count": -1}]}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "/kind", Message: "must be a string or null"},
+				{In: openapi.InBody, Pointer: "/parts/1/count", Message: "must be at least 0"},
+				{In: openapi.InBody, Pointer: "/parts/1/name", Message: "must be at most 8 characters"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = thingID
+			}
+
+			err := op.Validate(openapi.Request{
+				Path:  map[string]string{"id": path},
+				Query: tt.query,
+				Body:  []byte(tt.body),
+			})
+
+			var got []openapi.FieldError
+
+			var vErr openapi.ValidationError
+			if errors.As(err, &vErr) {
+				got = vErr.Errors
+			} else if err != nil {
+				t.Fatalf("validate: expected validation error, got %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("validate: errors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := map[string]string{
+		"invalid json":      `{"paths": [}`,
+		"unknown schema":    `{"paths": {"/": {"post": {"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Nope"}}}}}}}}`,
+		"unknown parameter": `{"paths": {"/": {"get": {"parameters": [{"$ref": "#/components/parameters/nope"}]}}}}`,
+		"invalid pattern":   `{"components": {"schemas": {"Tag": {"type": "string", "pattern": "("}}}}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := openapi.Load([]byte(data)); err == nil {
+				t.Fatalf("load: expected error, got nil")
+			}
+		})
+	}
+}
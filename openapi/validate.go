@@ -0,0 +1,483 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Locations of the values of a request, reported in FieldError.In.
+const (
+	InPath  = "path"
+	InQuery = "query"
+	InBody  = "body"
+)
+
+// FieldError describes a value of a request that does not match its schema.
+type FieldError struct {
+	// In is where the value is: the path, the query string or the body.
+	In string `json:"in"`
+
+	// Pointer is the JSON pointer to the value. For parameters it starts
+	// with the name of the parameter, followed by the index of the value
+	// for parameters that can be repeated.
+	Pointer string `json:"pointer"`
+
+	Message string `json:"message"`
+}
+
+// ValidationError is returned for a request that does not match its operation.
+// It is meant to be sent to the client as is.
+type ValidationError struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fErr := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s %q: %s", fErr.In, fErr.Pointer, fErr.Message))
+	}
+
+	return e.Message + ": " + strings.Join(msgs, ", ")
+}
+
+// Request holds the parts of a request that are validated.
+type Request struct {
+	// Path holds the values of the path parameters by name.
+	Path map[string]string
+
+	Query url.Values
+
+	// Body is the request body, which is only validated for operations
+	// that take one.
+	Body []byte
+}
+
+// Validate checks the path parameters, query parameters and body of req
+// against the operation, returning a ValidationError listing every value that
+// does not match. Query parameters and body fields the operation does not
+// describe are rejected.
+func (o *Operation) Validate(req Request) error {
+	v := validator{}
+
+	known := make(map[string]bool)
+
+	for _, p := range o.Parameters {
+		switch p.In {
+		case InPath:
+			value, ok := req.Path[p.Name]
+			if !ok {
+				if p.Required {
+					v.add(InPath, pointer("", p.Name), "missing required parameter")
+				}
+				continue
+			}
+
+			v.in = InPath
+			v.parameter(p, pointer("", p.Name), value)
+
+		case InQuery:
+			known[p.Name] = true
+
+			values := req.Query[p.Name]
+			if len(values) == 0 {
+				if p.Required {
+					v.add(InQuery, pointer("", p.Name), "missing required parameter")
+				}
+				continue
+			}
+
+			v.in = InQuery
+
+			if p.Schema.resolved().allows("array") {
+				for i, value := range values {
+					v.parameter(p, pointer(pointer("", p.Name), strconv.Itoa(i)), value)
+				}
+				continue
+			}
+
+			if len(values) > 1 {
+				v.add(InQuery, pointer("", p.Name), "must not be repeated")
+				continue
+			}
+
+			v.parameter(p, pointer("", p.Name), values[0])
+		}
+	}
+
+	names := make([]string, 0, len(req.Query))
+	for name := range req.Query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !known[name] {
+			v.add(InQuery, pointer("", name), "unknown parameter")
+		}
+	}
+
+	if schema := o.bodySchema(); schema != nil {
+		v.in = InBody
+		v.body(schema, o.RequestBody.Required, req.Body)
+	}
+
+	if len(v.errs) > 0 {
+		return ValidationError{Message: "invalid request", Errors: v.errs}
+	}
+
+	return nil
+}
+
+// validator collects the errors found while validating a request.
+type validator struct {
+	// in is the part of the request being validated.
+	in   string
+	errs []FieldError
+}
+
+func (v *validator) add(in string, ptr string, msg string) {
+	v.errs = append(v.errs, FieldError{In: in, Pointer: ptr, Message: msg})
+}
+
+// parameter validates a single value of a parameter. Parameter values are
+// strings, so they are converted to the type the schema expects first.
+func (v *validator) parameter(p *Parameter, ptr string, value string) {
+	schema := p.Schema.resolved()
+	if schema.allows("array") && schema.Items != nil {
+		schema = schema.Items.resolved()
+	}
+
+	var instance any = value
+
+	switch {
+	case schema.allows("string"):
+	case schema.allows("boolean"):
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			v.add(v.in, ptr, "must be a boolean")
+			return
+		}
+		instance = b
+	case schema.allows("integer"), schema.allows("number"):
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			v.add(v.in, ptr, "must be "+typeList(schema.Type))
+			return
+		}
+		instance = json.Number(value)
+	}
+
+	v.validate(schema, ptr, instance)
+}
+
+// body decodes and validates a JSON request body.
+func (v *validator) body(schema *Schema, required bool, data []byte) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		if required {
+			v.add(InBody, "", "missing request body")
+		}
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var instance any
+	if err := dec.Decode(&instance); err != nil {
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &syntaxErr):
+			v.add(InBody, "", fmt.Sprintf("invalid JSON at offset %d: %v", syntaxErr.Offset, err))
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			v.add(InBody, "", "invalid JSON: unexpected end of body")
+		default:
+			v.add(InBody, "", fmt.Sprintf("invalid JSON: %v", err))
+		}
+		return
+	}
+
+	end := dec.InputOffset()
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		v.add(InBody, "", fmt.Sprintf("invalid JSON: unexpected data after offset %d", end))
+		return
+	}
+
+	v.validate(schema, "", instance)
+}
+
+// validate checks instance, found at ptr, against schema.
+func (v *validator) validate(schema *Schema, ptr string, instance any) {
+	schema = schema.resolved()
+
+	for _, sub := range schema.AllOf {
+		v.validate(sub, ptr, instance)
+	}
+
+	if len(schema.OneOf) > 0 {
+		v.oneOf(schema.OneOf, ptr, instance)
+	}
+
+	if len(schema.Type) > 0 && !schema.allows(typeOf(instance)) {
+		v.add(v.in, ptr, "must be "+typeList(schema.Type))
+		return
+	}
+
+	if len(schema.Enum) > 0 && !contains(schema.Enum, instance) {
+		v.add(v.in, ptr, "must be one of "+enumList(schema.Enum))
+		return
+	}
+
+	switch instance := instance.(type) {
+	case string:
+		v.string(schema, ptr, instance)
+	case json.Number:
+		v.number(schema, ptr, instance)
+	case []any:
+		v.array(schema, ptr, instance)
+	case map[string]any:
+		v.object(schema, ptr, instance)
+	}
+}
+
+// oneOf checks that instance matches exactly one of schemas. When it matches
+// none, the errors of the first schema that allows its type are reported, as
+// that is most likely the one that was meant.
+func (v *validator) oneOf(schemas []*Schema, ptr string, instance any) {
+	var matches int
+	var closest []FieldError
+	var types Types
+
+	for _, sub := range schemas {
+		subV := validator{in: v.in}
+		subV.validate(sub, ptr, instance)
+
+		if len(subV.errs) == 0 {
+			matches++
+			continue
+		}
+
+		types = append(types, sub.resolved().Type...)
+		if closest == nil && sub.resolved().allows(typeOf(instance)) {
+			closest = subV.errs
+		}
+	}
+
+	switch {
+	case matches == 1:
+	case matches > 1:
+		v.add(v.in, ptr, "must match exactly one schema")
+	case closest != nil:
+		v.errs = append(v.errs, closest...)
+	default:
+		v.add(v.in, ptr, "must be "+typeList(types))
+	}
+}
+
+func (v *validator) string(schema *Schema, ptr string, s string) {
+	length := utf8.RuneCountInString(s)
+
+	if schema.MinLength != nil && length < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			v.add(v.in, ptr, "must not be empty")
+		} else {
+			v.add(v.in, ptr, fmt.Sprintf("must be at least %d characters", *schema.MinLength))
+		}
+	}
+
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.add(v.in, ptr, fmt.Sprintf("must be at most %d characters", *schema.MaxLength))
+	}
+
+	if schema.pattern != nil && !schema.pattern.MatchString(s) {
+		v.add(v.in, ptr, fmt.Sprintf("must match %s", schema.Pattern))
+	}
+
+	switch schema.Format {
+	case "uuid":
+		if _, err := uuid.Parse(s); err != nil {
+			v.add(v.in, ptr, "must be a UUID")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			v.add(v.in, ptr, "must be an RFC 3339 timestamp")
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			v.add(v.in, ptr, "must be an absolute URI")
+		}
+	}
+}
+
+func (v *validator) number(schema *Schema, ptr string, n json.Number) {
+	f, err := n.Float64()
+	if err != nil {
+		v.add(v.in, ptr, "must be "+typeList(schema.Type))
+		return
+	}
+
+	if schema.Minimum != nil && f < *schema.Minimum {
+		v.add(v.in, ptr, fmt.Sprintf("must be at least %v", *schema.Minimum))
+	}
+
+	if schema.Maximum != nil && f > *schema.Maximum {
+		v.add(v.in, ptr, fmt.Sprintf("must be at most %v", *schema.Maximum))
+	}
+}
+
+func (v *validator) array(schema *Schema, ptr string, items []any) {
+	if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+		v.add(v.in, ptr, fmt.Sprintf("must have at most %d items", *schema.MaxItems))
+	}
+
+	seen := make(map[string]bool, len(items))
+
+	for i, item := range items {
+		itemPtr := pointer(ptr, strconv.Itoa(i))
+
+		if schema.Items != nil {
+			v.validate(schema.Items, itemPtr, item)
+		}
+
+		if schema.UniqueItems {
+			key, _ := json.Marshal(item)
+			if seen[string(key)] {
+				v.add(v.in, itemPtr, "must not be a duplicate")
+			}
+			seen[string(key)] = true
+		}
+	}
+}
+
+func (v *validator) object(schema *Schema, ptr string, fields map[string]any) {
+	for _, name := range schema.Required {
+		if _, ok := fields[name]; !ok {
+			v.add(v.in, pointer(ptr, name), "missing required field")
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				v.add(v.in, pointer(ptr, name), "unknown field")
+			}
+			continue
+		}
+
+		v.validate(prop, pointer(ptr, name), fields[name])
+	}
+}
+
+// resolved returns the schema s refers to, or s itself when it is not a
+// reference.
+func (s *Schema) resolved() *Schema {
+	for s.target != nil {
+		s = s.target
+	}
+	return s
+}
+
+// allows reports whether s allows values of type typ. Schemas without a type
+// allow every type, and integers are numbers too.
+func (s *Schema) allows(typ string) bool {
+	if len(s.Type) == 0 {
+		return true
+	}
+
+	for _, t := range s.Type {
+		if t == typ || (t == "number" && typ == "integer") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeOf returns the JSON Schema type of a decoded JSON value.
+func typeOf(instance any) string {
+	switch instance := instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := strconv.ParseInt(instance.String(), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// typeList describes types for an error message, such as "a string or null".
+func typeList(types Types) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		switch t {
+		case "null":
+			names = append(names, t)
+		case "array", "integer", "object":
+			names = append(names, "an "+t)
+		default:
+			names = append(names, "a "+t)
+		}
+	}
+
+	return strings.Join(names, " or ")
+}
+
+// enumList describes the values of an enum for an error message.
+func enumList(enum []any) string {
+	values := make([]string, 0, len(enum))
+	for _, e := range enum {
+		b, _ := json.Marshal(e)
+		values = append(values, strings.Trim(string(b), `"`))
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// contains reports whether enum contains instance.
+func contains(enum []any, instance any) bool {
+	want, _ := json.Marshal(instance)
+
+	for _, e := range enum {
+		if got, _ := json.Marshal(e); bytes.Equal(got, want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pointer appends a reference token to the JSON pointer ptr, escaping it as
+// described by RFC 6901.
+func pointer(ptr string, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+
+	return ptr + "/" + token
+}
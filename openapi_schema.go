@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/openapi"
+	"github.com/sudomateo/todo/todo"
+)
+
+// schema is a JSON Schema in the OpenAPI document. Type is either a single type
+// or, for values that can be null, a list of types.
+type schema struct {
+	Ref                  string      `json:"$ref,omitempty"`
+	Description          string      `json:"description,omitempty"`
+	Type                 any         `json:"type,omitempty"`
+	Format               string      `json:"format,omitempty"`
+	Enum                 []string    `json:"enum,omitempty"`
+	Pattern              string      `json:"pattern,omitempty"`
+	MinLength            *int        `json:"minLength,omitempty"`
+	MaxLength            *int        `json:"maxLength,omitempty"`
+	Minimum              *int        `json:"minimum,omitempty"`
+	Maximum              *int        `json:"maximum,omitempty"`
+	Items                *schema     `json:"items,omitempty"`
+	MaxItems             *int        `json:"maxItems,omitempty"`
+	UniqueItems          bool        `json:"uniqueItems,omitempty"`
+	Required             []string    `json:"required,omitempty"`
+	Properties           *properties `json:"properties,omitempty"`
+	AdditionalProperties *bool       `json:"additionalProperties,omitempty"`
+	OneOf                []*schema   `json:"oneOf,omitempty"`
+}
+
+// property is a named schema, such as a field of an object.
+type property struct {
+	name   string
+	schema *schema
+}
+
+// properties are encoded as a JSON object that keeps their order, which is the
+// order the fields are declared in.
+type properties []property
+
+// MarshalJSON implements the json.Marshaler interface.
+func (p properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(prop.name)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := json.Marshal(prop.schema)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(s)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// component is a schema under components/schemas. Schemas of structs are
+// generated from their fields, every other schema is given as is.
+type component struct {
+	name string
+
+	// value is either a struct the schema is generated from or a *schema.
+	value any
+
+	// request marks the bodies of requests. They may only contain the fields
+	// they declare.
+	request bool
+
+	// required lists the required fields. Unless it is set, the fields of
+	// requests are optional and the fields of responses that are not
+	// omitted when empty are required.
+	required []string
+
+	description string
+}
+
+// components are the schemas of the document, in the order they are listed.
+var components = []component{
+	{
+		name:        "Error",
+		value:       openapi.ValidationError{},
+		required:    []string{"message"},
+		description: "Error returned by every endpoint. Some 404 responses have an empty body instead.",
+	},
+	{name: "FieldError", value: openapi.FieldError{}},
+	{name: "Priority", value: enum(todo.PriorityLow, todo.PriorityMedium, todo.PriorityHigh)},
+	{name: "Tag", value: &schema{Type: "string", Pattern: "^[a-z0-9][a-z0-9_-]{0,31}$"}},
+	{name: "Todo", value: todo.Todo{}},
+	{name: "TodoCreateParams", value: todo.TodoCreateParams{}, request: true, required: []string{"text", "priority"}},
+	{name: "TodoUpdateParams", value: todo.TodoUpdateParams{}, request: true, description: "Fields that are left out or null are not changed."},
+	{name: "TagCount", value: todo.TagCount{}},
+	{name: "SearchResult", value: todo.SearchResult{}},
+	{name: "BatchParams", value: todo.BatchParams{}, request: true, required: []string{"mode", "operations"}},
+	{name: "BatchOperation", value: todo.BatchOperation{}, request: true, required: []string{"op"}},
+	{name: "BatchResult", value: todo.BatchResult{}},
+	{name: "Event", value: todo.Event{}},
+	{name: "EventType", value: enum(todo.EventCreated, todo.EventUpdated, todo.EventDeleted, todo.EventRestored)},
+	{name: "Change", value: todo.Change{}},
+	{name: "List", value: todo.List{}},
+	{name: "ListCreateParams", value: todo.ListCreateParams{}, request: true, required: []string{"name"}},
+	{name: "ListUpdateParams", value: todo.ListUpdateParams{}, request: true},
+	{name: "Collaborator", value: todo.Collaborator{}},
+	{name: "Role", value: enum(todo.RoleViewer, todo.RoleEditor, todo.RoleOwner)},
+	{name: "ShareParams", value: todo.ShareParams{}, request: true, required: []string{"user", "role"}},
+	{name: "Workspace", value: todo.Workspace{}},
+	{name: "WorkspaceSettings", value: todo.WorkspaceSettings{}},
+	{name: "WorkspaceCreateParams", value: todo.WorkspaceCreateParams{}, request: true, required: []string{"name"}},
+	{name: "WorkspaceUpdateParams", value: todo.WorkspaceUpdateParams{}, request: true},
+	{name: "Member", value: todo.MemberWithName{}},
+	{name: "MemberRole", value: enum(todo.MemberRoleMember, todo.MemberRoleAdmin)},
+	{name: "MemberParams", value: todo.MemberParams{}, request: true, required: []string{"user", "role"}},
+	{name: "Webhook", value: todo.Webhook{}},
+	{name: "WebhookCreateParams", value: todo.WebhookCreateParams{}, request: true, required: []string{"url"}},
+	{name: "WebhookUpdateParams", value: todo.WebhookUpdateParams{}, request: true},
+	{name: "Delivery", value: todo.Delivery{}},
+	{name: "DeliveryAttempt", value: todo.DeliveryAttempt{}},
+	{name: "User", value: todo.User{}},
+	{name: "UserCreateParams", value: todo.UserCreateParams{}, request: true, required: []string{"name"}},
+	{name: "Registration", value: todo.Registration{}},
+	{name: "APIKey", value: todo.APIKey{}},
+	{name: "NewAPIKey", value: todo.NewAPIKey{}},
+	{name: "APIKeyCreateParams", value: todo.APIKeyCreateParams{}, request: true, required: []string{"name"}},
+}
+
+// typeSchemas are the schemas of the types that are not generated from their
+// kind. Types with a component of their own refer to it.
+var typeSchemas = map[reflect.Type]*schema{
+	reflect.TypeOf(uuid.UUID{}):             {Type: "string", Format: "uuid"},
+	reflect.TypeOf(time.Time{}):             {Type: "string", Format: "date-time"},
+	reflect.TypeOf(json.RawMessage{}):       {},
+	reflect.TypeOf(todo.Priority("")):       ref("Priority"),
+	reflect.TypeOf(todo.EventType("")):      ref("EventType"),
+	reflect.TypeOf(todo.Role("")):           ref("Role"),
+	reflect.TypeOf(todo.MemberRole("")):     ref("MemberRole"),
+	reflect.TypeOf(todo.DeliveryStatus("")): enum(todo.DeliveryPending, todo.DeliverySucceeded, todo.DeliveryDead),
+	reflect.TypeOf(todo.BatchMode("")):      enum(todo.BatchAtomic, todo.BatchBestEffort),
+	reflect.TypeOf(todo.BatchOp("")):        enum(todo.BatchCreate, todo.BatchUpdate, todo.BatchDelete),
+}
+
+// tags is the schema of the tags of a todo item.
+var tags = schema{Items: ref("Tag"), MaxItems: ptr(todo.MaxTags), UniqueItems: true}
+
+// fieldSchemas add to the schemas generated for the fields of structs, by the
+// name of the struct and of the JSON field.
+var fieldSchemas = map[string]schema{
+	"ValidationError.errors": {Description: "Every value that does not match its schema, for requests that are rejected before they are handled."},
+	"FieldError.in":          {Description: "Part of the request the value is in.", Enum: []string{openapi.InPath, openapi.InQuery, openapi.InBody}},
+	"FieldError.pointer":     {Description: "JSON pointer to the value. For parameters it starts with the parameter name, followed by the index of the value for repeated parameters."},
+
+	"TagCount.tag":                  *ref("Tag"),
+	"Todo.recurrence":               {Description: "RFC 5545 recurrence rule, such as FREQ=WEEKLY;BYDAY=MO."},
+	"Todo.next_id":                  {Description: "Next instance of a recurring todo, created the first time it was completed."},
+	"Todo.tags":                     tags,
+	"TodoCreateParams.text":         {MinLength: ptr(1)},
+	"TodoCreateParams.list_id":      {Description: "Defaults to the default list of the workspace."},
+	"TodoCreateParams.recurrence":   {Description: "RFC 5545 recurrence rule. Requires due_at."},
+	"TodoCreateParams.tags":         tags,
+	"TodoUpdateParams.text":         {MinLength: ptr(1)},
+	"TodoUpdateParams.clear_parent": {Description: "Moves the todo to the top level."},
+	"TodoUpdateParams.clear_due_at": {Description: "Removes the due date."},
+	"TodoUpdateParams.tags":         tags,
+
+	"SearchResult.snippet": {Description: "Matched words are wrapped in " + todo.HighlightStart + " and " + todo.HighlightEnd + "."},
+
+	"BatchParams.operations": {MaxItems: ptr(todo.MaxBatchOperations)},
+	"BatchOperation.id":      {Description: "Todo to update or delete."},
+	"BatchOperation.version": {Description: "Version the todo must be at to update or delete it."},
+	"BatchResult.status":     {Description: "HTTP status code the operation would have had on its own."},
+
+	"ListCreateParams.name": {MinLength: ptr(1)},
+	"ListUpdateParams.name": {MinLength: ptr(1)},
+
+	"ShareParams.user": {Description: "Name of the user to share with.", MinLength: ptr(1)},
+
+	"WorkspaceSettings.allowed_priorities":     {Description: "Priorities todos may use. Empty allows all of them.", UniqueItems: true},
+	"WorkspaceCreateParams.name":               {MinLength: ptr(1)},
+	"WorkspaceCreateParams.allowed_priorities": {UniqueItems: true},
+	"WorkspaceUpdateParams.name":               {MinLength: ptr(1)},
+	"WorkspaceUpdateParams.allowed_priorities": {UniqueItems: true},
+
+	"MemberParams.user": {Description: "Name of the user to add.", MinLength: ptr(1)},
+
+	"Webhook.url":                {Format: "uri"},
+	"Webhook.secret":             {Description: "Only included when the webhook is created."},
+	"WebhookCreateParams.url":    {Format: "uri"},
+	"WebhookCreateParams.secret": {Description: "Generated when empty."},
+	"WebhookCreateParams.events": {Description: "Event types to deliver. Empty delivers all of them."},
+	"WebhookUpdateParams.url":    {Format: "uri"},
+	"Delivery.payload":           {Type: "object", Description: "Body sent to the webhook."},
+
+	"UserCreateParams.name":     {MinLength: ptr(1)},
+	"UserCreateParams.password": {Description: "Password to log in to the web application with. Users without one can only use API keys.", MaxLength: ptr(72)},
+	"APIKey.prefix":             {Description: "Start of the key, to tell keys apart."},
+	"NewAPIKey.key":             {Description: "The key itself, which is only ever shown once."},
+	"APIKeyCreateParams.name":   {MinLength: ptr(1)},
+}
+
+// hiddenFields are the fields of structs that are never included in responses.
+var hiddenFields = map[string]bool{
+	"User.password_hash": true,
+	"APIKey.hash":        true,
+}
+
+// schemas generates the schemas of every component.
+func schemas() *properties {
+	names := make(map[reflect.Type]string)
+	for _, c := range components {
+		if _, ok := c.value.(*schema); !ok {
+			names[reflect.TypeOf(c.value)] = c.name
+		}
+	}
+
+	g := schemaGenerator{names: names}
+
+	props := make(properties, 0, len(components))
+	for _, c := range components {
+		s, ok := c.value.(*schema)
+		if !ok {
+			s = g.object(reflect.TypeOf(c.value), c)
+		}
+
+		props = append(props, property{name: c.name, schema: s})
+	}
+
+	return &props
+}
+
+// schemaGenerator generates schemas from Go types.
+type schemaGenerator struct {
+	// names are the components of structs by their type.
+	names map[reflect.Type]string
+}
+
+// object generates the schema of the struct t of component c.
+func (g schemaGenerator) object(t reflect.Type, c component) *schema {
+	s := &schema{
+		Description: c.description,
+		Type:        "object",
+		Required:    c.required,
+		Properties:  &properties{},
+	}
+
+	derive := !c.request && c.required == nil
+	g.fields(s, t, c.request, derive)
+
+	if c.request {
+		s.AdditionalProperties = ptr(false)
+	}
+
+	return s
+}
+
+// fields adds the fields of the struct t to the properties of s. Embedded
+// structs without a JSON name have their fields added to s. When derive is
+// set, the fields that are not omitted when empty are required.
+func (g schemaGenerator) fields(s *schema, t reflect.Type, request bool, derive bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			g.fields(s, f.Type, request, derive)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		key := t.Name() + "." + name
+		if hiddenFields[key] {
+			continue
+		}
+
+		// Fields that are omitted when empty are left out rather than
+		// null.
+		omitEmpty := strings.Contains(","+opts+",", ",omitempty,")
+		ft := f.Type
+		if omitEmpty && ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		fs := g.schema(ft)
+
+		required := contains(s.Required, name)
+		if derive && !omitEmpty {
+			s.Required = append(s.Required, name)
+			required = true
+		}
+
+		// Lists left out of requests decode the same as null.
+		if request && !required && ft.Kind() == reflect.Slice {
+			fs = nullable(fs)
+		}
+
+		if extra, ok := fieldSchemas[key]; ok {
+			fs = merge(fs, extra)
+		}
+
+		*s.Properties = append(*s.Properties, property{name: name, schema: fs})
+	}
+}
+
+// schema generates the schema of a value of type t.
+func (g schemaGenerator) schema(t reflect.Type) *schema {
+	if s, ok := typeSchemas[t]; ok {
+		c := *s
+		return &c
+	}
+
+	if name, ok := g.names[t]; ok {
+		return ref(name)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Slice:
+		return &schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &schema{Type: "integer"}
+	case reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.Struct:
+		return g.object(t, component{})
+	}
+
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+// ref returns a schema that refers to the component name.
+func ref(name string) *schema {
+	return &schema{Ref: "#/components/schemas/" + name}
+}
+
+// arrayOf returns the schema of a list of the component name.
+func arrayOf(name string) *schema {
+	return &schema{Type: "array", Items: ref(name)}
+}
+
+// enum returns the schema of a string that is one of values.
+func enum[T ~string](values ...T) *schema {
+	s := &schema{Type: "string"}
+	for _, v := range values {
+		s.Enum = append(s.Enum, string(v))
+	}
+
+	return s
+}
+
+// nullable returns a schema that also allows null.
+func nullable(s *schema) *schema {
+	if s.Ref != "" {
+		return &schema{OneOf: []*schema{s, {Type: "null"}}}
+	}
+
+	c := *s
+	c.Type = []string{s.Type.(string), "null"}
+
+	return &c
+}
+
+// merge returns a copy of s with the keywords set in extra.
+func merge(s *schema, extra schema) *schema {
+	c := *s
+
+	if extra.Ref != "" {
+		c = schema{Ref: extra.Ref}
+	}
+	if extra.Description != "" {
+		c.Description = extra.Description
+	}
+	if extra.Type != nil {
+		c.Type = extra.Type
+	}
+	if extra.Format != "" {
+		c.Format = extra.Format
+	}
+	if extra.Enum != nil {
+		c.Enum = extra.Enum
+	}
+	if extra.MinLength != nil {
+		c.MinLength = extra.MinLength
+	}
+	if extra.MaxLength != nil {
+		c.MaxLength = extra.MaxLength
+	}
+	if extra.Items != nil {
+		c.Items = extra.Items
+	}
+	if extra.MaxItems != nil {
+		c.MaxItems = extra.MaxItems
+	}
+	if extra.UniqueItems {
+		c.UniqueItems = true
+	}
+
+	return &c
+}
+
+// contains reports whether s contains v.
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/openapi"
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+// openAPIDocument is the part of an OpenAPI document the tests look at.
+type openAPIDocument struct {
+	OpenAPI    string                    `json:"openapi"`
+	Paths      map[string]map[string]any `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDocument {
+	t.Helper()
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("decode OpenAPI document: %v", err)
+	}
+
+	return doc
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.1.") {
+		t.Fatalf("expected OpenAPI 3.1, got %q", doc.OpenAPI)
+	}
+
+	e := echo.New()
+	a := &App{}
+	a.routes(e)
+
+	registered := make(map[string]bool)
+
+	for _, r := range e.Routes() {
+		if !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+
+		path := openAPIPath(r.Path)
+		method := strings.ToLower(r.Method)
+		registered[method+" "+path] = true
+
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("route %s %s is missing from the OpenAPI document as %s %s", r.Method, r.Path, method, path)
+		}
+	}
+
+	// Operations for routes that no longer exist are just as misleading.
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !registered[method+" "+path] {
+				t.Errorf("the OpenAPI document describes %s %s, which is not a registered route", method, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	tests := map[string]any{
+		"Todo":                  todo.Todo{},
+		"TodoCreateParams":      todo.TodoCreateParams{},
+		"TodoUpdateParams":      todo.TodoUpdateParams{},
+		"BatchParams":           todo.BatchParams{},
+		"BatchOperation":        todo.BatchOperation{},
+		"TagCount":              todo.TagCount{},
+		"SearchResult":          todo.SearchResult{},
+		"Event":                 todo.Event{},
+		"List":                  todo.List{},
+		"ListCreateParams":      todo.ListCreateParams{},
+		"ListUpdateParams":      todo.ListUpdateParams{},
+		"Collaborator":          todo.Collaborator{},
+		"ShareParams":           todo.ShareParams{},
+		"Workspace":             todo.Workspace{},
+		"WorkspaceSettings":     todo.WorkspaceSettings{},
+		"WorkspaceCreateParams": todo.WorkspaceCreateParams{},
+		"WorkspaceUpdateParams": todo.WorkspaceUpdateParams{},
+		"Member":                todo.MemberWithName{},
+		"MemberParams":          todo.MemberParams{},
+		"Webhook":               todo.Webhook{},
+		"WebhookCreateParams":   todo.WebhookCreateParams{},
+		"WebhookUpdateParams":   todo.WebhookUpdateParams{},
+		"Delivery":              todo.Delivery{},
+		"DeliveryAttempt":       todo.DeliveryAttempt{},
+		"UserCreateParams":      todo.UserCreateParams{},
+		"APIKeyCreateParams":    todo.APIKeyCreateParams{},
+		"Error":                 openapi.ValidationError{},
+		"FieldError":            openapi.FieldError{},
+	}
+
+	for name, v := range tests {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := doc.Components.Schemas[name]
+			if !ok {
+				t.Fatalf("schema %s is missing from the OpenAPI document", name)
+			}
+
+			got := make([]string, 0, len(schema.Properties))
+			for prop := range schema.Properties {
+				got = append(got, prop)
+			}
+			sort.Strings(got)
+
+			want := jsonFields(reflect.TypeOf(v))
+			sort.Strings(want)
+
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("properties of %s mismatch (-want +got):\n%s", name, diff)
+			}
+		})
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	e := echo.New()
+	a := &App{}
+	a.routes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Fatalf("expected a JSON document, got %q", rec.Body.String())
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	doc, err := openapi.Load(openAPISpec)
+	if err != nil {
+		t.Fatalf("load OpenAPI document: %v", err)
+	}
+
+	e := echo.New()
+	e.Use(validateRequests(doc))
+
+	a := &App{TodoCore: todo.NewCore(todomemory.NewStore())}
+	a.routes(e)
+
+	serve := func(method string, target string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/api/todo", `{"text": "write tests", "priority": "high", "tags": ["go"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body)
+	}
+
+	var created todo.Todo
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("create: decode response: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   []openapi.FieldError
+	}{
+		{
+			name:   "unknown field",
+			method: http.MethodPatch,
+			target: "/api/todo/" + created.ID.String(),
+			body:   `{"complete": true}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "/complete", Message: "unknown field"},
+			},
+		},
+		{
+			name:   "invalid fields",
+			method: http.MethodPost,
+			target: "/api/todo",
+			body:   `{"priority": "urgent", "tags": ["go", "Go"], "due_at": "tomorrow"}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "/text", Message: "missing required field"},
+				{In: openapi.InBody, Pointer: "/due_at", Message: "must be an RFC 3339 timestamp"},
+				{In: openapi.InBody, Pointer: "/priority", Message: "must be one of low, medium, high"},
+				{In: openapi.InBody, Pointer: "/tags/1", Message: "must match ^[a-z0-9][a-z0-9_-]{0,31}$"},
+			},
+		},
+		{
+			name:   "nested field",
+			method: http.MethodPost,
+			target: "/api/todo/batch",
+			body:   `{"mode": "atomic", "operations": [{"op": "update", "update": {"text": 1}}]}`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "/operations/0/update/text", Message: "must be a string or null"},
+			},
+		},
+		{
+			name:   "malformed body",
+			method: http.MethodPost,
+			target: "/api/lists",
+			body:   `{"name": "work"`,
+			want: []openapi.FieldError{
+				{In: openapi.InBody, Pointer: "", Message: "invalid JSON: unexpected end of body"},
+			},
+		},
+		{
+			name:   "query parameters",
+			method: http.MethodGet,
+			target: "/api/todo?limit=ten&tag=go&tag=Go&sort=text",
+			want: []openapi.FieldError{
+				{In: openapi.InQuery, Pointer: "/tag/1", Message: "must match ^[a-z0-9][a-z0-9_-]{0,31}$"},
+				{In: openapi.InQuery, Pointer: "/limit", Message: "must be an integer"},
+				{In: openapi.InQuery, Pointer: "/sort", Message: "unknown parameter"},
+			},
+		},
+		{
+			name:   "path parameter",
+			method: http.MethodGet,
+			target: "/api/todo/42",
+			want: []openapi.FieldError{
+				{In: openapi.InPath, Pointer: "/id", Message: "must be a UUID"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.method, tt.target, tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body)
+			}
+
+			var got openapi.ValidationError
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got.Errors); diff != "" {
+				t.Errorf("errors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// The todo was left alone by the rejected update.
+	rec = serve(http.MethodGet, "/api/todo/"+created.ID.String(), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("query by id: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var got todo.Todo
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("query by id: decode response: %v", err)
+	}
+	if got.Version != created.Version {
+		t.Errorf("query by id: expected version %d, got %d", created.Version, got.Version)
+	}
+}
+
+// jsonFields returns the names of the fields of the struct type typ as encoded
+// by encoding/json, including those of embedded structs.
+func jsonFields(typ reflect.Type) []string {
+	fields := make([]string, 0, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, name)
+	}
+
+	return fields
+}
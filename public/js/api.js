@@ -0,0 +1,182 @@
+// api.js renders the OpenAPI document served at /api/openapi.json as a
+// reference of every operation and schema of the API. It stands in for a
+// vendored Swagger UI or Redoc bundle and only renders the document, without
+// sending requests.
+
+const methods = ["get", "post", "put", "patch", "delete"]
+
+renderReference()
+
+async function renderReference() {
+    const container = document.querySelector("#api-reference")
+
+    try {
+        const res = await fetch("/api/openapi.json")
+        if (res.status != 200) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+
+        const spec = await res.json()
+
+        container.replaceChildren(
+            element("p", {}, spec.info.description ?? ""),
+            ...renderOperations(spec),
+            element("h2", {}, "Schemas"),
+            ...Object.entries(spec.components.schemas).map(([name, schema]) => renderSchema(name, schema)),
+        )
+    }
+    catch (e) {
+        console.error(e)
+        container.replaceChildren(element("p", {}, "Could not load the API reference."))
+    }
+}
+
+// renderOperations renders the operations of spec grouped by their first tag.
+function renderOperations(spec) {
+    const groups = new Map((spec.tags ?? []).map((tag) => [tag.name, []]))
+
+    for (const [path, item] of Object.entries(spec.paths)) {
+        for (const method of methods) {
+            const op = item[method]
+            if (!op) {
+                continue
+            }
+
+            const tag = op.tags?.[0] ?? "other"
+            if (!groups.has(tag)) {
+                groups.set(tag, [])
+            }
+            groups.get(tag).push(renderOperation(spec, method, path, op))
+        }
+    }
+
+    return Array.from(groups).flatMap(([tag, ops]) => [element("h2", {}, tag), ...ops])
+}
+
+function renderOperation(spec, method, path, op) {
+    const children = [
+        element("summary", {},
+            element("span", { class: `method method-${method}` }, method),
+            element("span", { class: "path" }, path),
+            ` ${op.summary ?? ""}`,
+        ),
+    ]
+
+    if (op.description) {
+        children.push(element("p", {}, op.description))
+    }
+
+    const params = (op.parameters ?? []).map((param) => resolve(spec, param))
+    if (params.length > 0) {
+        children.push(element("h4", {}, "Parameters"), table(
+            ["Name", "In", "Type", "Description"],
+            params.map((param) => [
+                param.name + (param.required ? " *" : ""),
+                param.in,
+                typeName(param.schema),
+                param.description ?? "",
+            ]),
+        ))
+    }
+
+    const body = op.requestBody?.content?.["application/json"]?.schema
+    if (body) {
+        children.push(element("h4", {}, "Request body"), element("p", { class: "schema-type" }, typeName(body)))
+    }
+
+    children.push(element("h4", {}, "Responses"), table(
+        ["Status", "Type", "Description"],
+        Object.entries(op.responses).map(([status, response]) => {
+            response = resolve(spec, response)
+            const content = Object.values(response.content ?? {})[0]
+            return [status, content ? typeName(content.schema) : "", response.description ?? ""]
+        }),
+    ))
+
+    return element("details", { class: "operation" }, ...children)
+}
+
+function renderSchema(name, schema) {
+    const children = [element("summary", {}, element("span", { class: "path" }, name))]
+
+    if (schema.description) {
+        children.push(element("p", {}, schema.description))
+    }
+
+    if (schema.properties) {
+        const required = new Set(schema.required ?? [])
+        children.push(table(
+            ["Field", "Type", "Description"],
+            Object.entries(schema.properties).map(([field, prop]) => [
+                field + (required.has(field) ? " *" : ""),
+                typeName(prop),
+                prop.description ?? "",
+            ]),
+        ))
+    } else {
+        children.push(element("p", { class: "schema-type" }, typeName(schema)))
+    }
+
+    return element("details", { class: "operation", id: `schema-${name}` }, ...children)
+}
+
+// resolve follows a local $ref to the object it points to.
+function resolve(spec, obj) {
+    if (!obj?.$ref) {
+        return obj
+    }
+
+    return obj.$ref.replace(/^#\//, "").split("/").reduce((o, key) => o[key], spec)
+}
+
+// typeName describes a schema in a single line, such as "array of Todo".
+function typeName(schema) {
+    if (!schema) {
+        return ""
+    }
+    if (schema.$ref) {
+        return schema.$ref.split("/").pop()
+    }
+    if (schema.allOf) {
+        return schema.allOf.map(typeName).join(" & ")
+    }
+    if (schema.oneOf) {
+        return schema.oneOf.map(typeName).join(" | ")
+    }
+    if (schema.enum) {
+        return schema.enum.join(" | ")
+    }
+
+    const types = [].concat(schema.type ?? "any")
+
+    return types.map((type) => {
+        if (type == "array") {
+            return `array of ${typeName(schema.items)}`
+        }
+        if (schema.format && type != "null") {
+            return `${type} (${schema.format})`
+        }
+        return type
+    }).join(" | ")
+}
+
+function table(headings, rows) {
+    return element("table", {},
+        element("tr", {}, ...headings.map((heading) => element("th", {}, heading))),
+        ...rows.map((row) => element("tr", {}, ...row.map((cell) => element("td", {}, cell)))),
+    )
+}
+
+// element creates an HTML element with the given attributes and children.
+// Strings are added as text so that the document cannot inject markup.
+function element(tag, attrs, ...children) {
+    const el = document.createElement(tag)
+
+    for (const [name, value] of Object.entries(attrs)) {
+        el.setAttribute(name, value)
+    }
+
+    el.append(...children)
+
+    return el
+}
@@ -1,4 +1,13 @@
+// csrfToken is the CSRF token of the session of a logged in browser, which
+// must accompany every change.
+const csrfToken = document.querySelector('meta[name="csrf-token"]')?.content
+
 document.querySelector("#create-form").addEventListener("submit", createTodo)
+document.querySelector("#create-list-form").addEventListener("submit", createList)
+
+Array.from(document.querySelectorAll(".delete-list-btn")).forEach((element) => {
+    element.addEventListener("click", deleteList)
+})
 
 Array.from(document.querySelectorAll(".complete-btn")).forEach((element) => {
     element.addEventListener("click", updateTodo)
@@ -8,21 +17,55 @@ Array.from(document.querySelectorAll(".delete-btn")).forEach((element) => {
     element.addEventListener("click", deleteTodo)
 })
 
+Array.from(document.querySelectorAll(".restore-btn")).forEach((element) => {
+    element.addEventListener("click", restoreTodo)
+})
+
+Array.from(document.querySelectorAll(".purge-btn")).forEach((element) => {
+    element.addEventListener("click", purgeTodo)
+})
+
+document.querySelector("#empty-trash-btn")?.addEventListener("click", emptyTrash)
+document.querySelector("#complete-all-btn")?.addEventListener("click", completeAll)
+document.querySelector("#clear-completed-btn")?.addEventListener("click", clearCompleted)
+
+subscribeToEvents()
+
+// request is fetch with the CSRF token of the session added.
+function request(url, options = {}) {
+    const headers = new Headers(options.headers)
+    if (csrfToken) {
+        headers.set("X-CSRF-Token", csrfToken)
+    }
+
+    return fetch(url, { ...options, headers })
+}
+
 async function createTodo(e) {
     e.preventDefault()
 
     const todoText = document.querySelector("#todo-text").value
     const todoPriority = document.querySelector("#todo-priority").value
+    const todoDue = document.querySelector("#todo-due").value
+    const todoRecurrence = document.querySelector("#todo-recurrence").value
+    const todoTags = document.querySelector("#todo-tags").value
+        .split(",")
+        .map((tag) => tag.trim())
+        .filter((tag) => tag !== "")
 
     try {
-        const res = await fetch("/api/todo", {
+        const res = await request("/api/todo", {
             method: "POST",
             headers: {
                 "Content-Type": "application/json",
             },
             body: JSON.stringify({
+                list_id: e.target.dataset.listId,
                 text: todoText,
                 priority: todoPriority,
+                due_at: todoDue ? new Date(todoDue).toISOString() : null,
+                recurrence: todoRecurrence,
+                tags: todoTags,
             })
         })
 
@@ -42,7 +85,7 @@ async function updateTodo(e) {
     const todoID = e.target.parentElement.id
 
     try {
-        const res = await fetch(`/api/todo/${todoID}`, {
+        const res = await request(`/api/todo/${todoID}`, {
             method: "PATCH",
             headers: {
                 "Content-Type": "application/json",
@@ -67,7 +110,7 @@ async function deleteTodo(e) {
     const todoID = e.target.parentElement.id
 
     try {
-        const res = await fetch(`/api/todo/${todoID}`, {
+        const res = await request(`/api/todo/${todoID}`, {
             method: "DELETE",
         })
 
@@ -81,3 +124,233 @@ async function deleteTodo(e) {
 
     location.reload()
 }
+
+async function restoreTodo(e) {
+    const todoID = e.target.parentElement.id
+
+    try {
+        const res = await request(`/api/todo/${todoID}/restore`, {
+            method: "POST",
+        })
+
+        if (res.status != 200) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+    }
+    catch (e) {
+        console.error(e)
+    }
+
+    location.reload()
+}
+
+async function purgeTodo(e) {
+    const todoID = e.target.parentElement.id
+
+    try {
+        const res = await request(`/api/trash/${todoID}`, {
+            method: "DELETE",
+        })
+
+        if (res.status != 204) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+    }
+    catch (e) {
+        console.error(e)
+    }
+
+    location.reload()
+}
+
+async function emptyTrash(e) {
+    try {
+        const res = await request("/api/trash", {
+            method: "DELETE",
+        })
+
+        if (res.status != 204) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+    }
+    catch (e) {
+        console.error(e)
+    }
+
+    location.reload()
+}
+
+async function completeAll(e) {
+    const listID = e.target.dataset.listId
+
+    try {
+        const res = await request(`/api/todo/complete?list=${listID}`, {
+            method: "POST",
+        })
+
+        if (res.status != 200) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+    }
+    catch (e) {
+        console.error(e)
+    }
+
+    location.reload()
+}
+
+async function clearCompleted(e) {
+    const listID = e.target.dataset.listId
+
+    try {
+        const res = await request(`/api/todo/completed?list=${listID}`, {
+            method: "DELETE",
+        })
+
+        if (res.status != 200) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+    }
+    catch (e) {
+        console.error(e)
+    }
+
+    location.reload()
+}
+
+async function createList(e) {
+    e.preventDefault()
+
+    const listName = document.querySelector("#list-name").value
+
+    try {
+        const res = await request("/api/lists", {
+            method: "POST",
+            headers: {
+                "Content-Type": "application/json",
+            },
+            body: JSON.stringify({
+                name: listName,
+            })
+        })
+
+        if (res.status != 201) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+
+        const list = await res.json()
+        location.assign(`/?list=${list.id}`)
+    }
+    catch (e) {
+        console.error(e)
+    }
+}
+
+async function deleteList(e) {
+    const listID = e.target.parentElement.id
+
+    try {
+        const res = await request(`/api/lists/${listID}?policy=inbox`, {
+            method: "DELETE",
+        })
+
+        if (res.status != 204) {
+            throw new Error(`invalid response code: ${res.status}`)
+        }
+    }
+    catch (e) {
+        console.error(e)
+    }
+
+    location.assign("/")
+}
+
+// subscribeToEvents applies the changes made to todos elsewhere to the list
+// being shown. Changes are only applied to an unfiltered list; anything else
+// shows the changes on the next reload.
+function subscribeToEvents() {
+    const list = document.querySelector(".todo-list[data-list-id]")
+    if (!list) {
+        return
+    }
+
+    const params = new URLSearchParams(location.search)
+    params.delete("list")
+    const filtered = params.toString() !== ""
+
+    const events = new EventSource("/api/events")
+
+    // The server no longer knows what was missed while disconnected.
+    events.addEventListener("reset", () => location.reload())
+
+    const apply = (e) => {
+        const { type, todo } = JSON.parse(e.data)
+        const current = document.getElementById(todo.id)
+
+        if (type === "deleted" || todo.list_id !== list.dataset.listId) {
+            current?.remove()
+            return
+        }
+
+        if (current) {
+            current.replaceWith(renderTodo(todo, list.dataset.listId))
+        } else if (!filtered) {
+            list.append(renderTodo(todo, list.dataset.listId))
+        }
+    }
+
+    for (const type of ["created", "updated", "deleted", "restored"]) {
+        events.addEventListener(type, apply)
+    }
+}
+
+// renderTodo builds the list item for a todo the same way the server renders
+// it.
+function renderTodo(todo, listID) {
+    const li = document.createElement("li")
+    li.id = todo.id
+
+    const button = (className, text, handler) => {
+        const el = document.createElement("button")
+        el.className = className
+        el.textContent = text
+        el.addEventListener("click", handler)
+        li.append(el, " ")
+    }
+
+    const span = (className, text) => {
+        const el = document.createElement("span")
+        el.className = className
+        el.textContent = text
+        li.append(el, " ")
+        return el
+    }
+
+    button("complete-btn", "Complete", updateTodo)
+    button("delete-btn", "Delete", deleteTodo)
+
+    span(todo.completed ? "complete" : "", `${todo.text} - ${todo.priority}`)
+
+    if (todo.parent_id) {
+        span("subtask", "subtask")
+    }
+
+    for (const tag of todo.tags) {
+        const a = document.createElement("a")
+        a.className = "tag"
+        a.href = `/?${new URLSearchParams({ list: listID, tag: tag })}`
+        a.textContent = tag
+        li.append(a, " ")
+    }
+
+    if (todo.recurrence) {
+        span("recurrence", "repeats").title = todo.recurrence
+    }
+
+    if (todo.due_at) {
+        const overdue = !todo.completed && new Date(todo.due_at) < new Date()
+        span(overdue ? "due overdue" : "due", `due ${todo.due_at.slice(0, 10)}`)
+    }
+
+    return li
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Search fetches the todos whose text matches the q query parameter, best
+// match first. The limit query parameter caps the number of results.
+func (a *App) Search(c echo.Context) error {
+	params := todo.SearchParams{
+		Query: c.QueryParam("q"),
+	}
+
+	if s := c.QueryParam("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be an integer", s))
+		}
+		params.Limit = limit
+	}
+
+	results, err := a.search(c, params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
+
+// search runs a search for the handlers, mapping an unsupported store to a
+// 501 response.
+func (a *App) search(c echo.Context, params todo.SearchParams) ([]todo.SearchResult, error) {
+	results, err := a.TodoCore.Search(c.Request().Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrSearchUnsupported):
+			return nil, echo.NewHTTPError(http.StatusNotImplemented, todo.ErrSearchUnsupported.Error())
+		default:
+			return nil, fmt.Errorf("search: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// highlight escapes a search result snippet for HTML while keeping the markers
+// around the matched words as mark elements.
+func highlight(snippet string) template.HTML {
+	s := template.HTMLEscapeString(snippet)
+	s = strings.ReplaceAll(s, template.HTMLEscapeString(todo.HighlightStart), todo.HighlightStart)
+	s = strings.ReplaceAll(s, template.HTMLEscapeString(todo.HighlightEnd), todo.HighlightEnd)
+
+	return template.HTML(s)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+const (
+	// sessionCookie is the name of the cookie holding the session token of
+	// browsers that logged in.
+	sessionCookie = "todo_session"
+
+	// csrfHeader and csrfField carry the CSRF token of the session on requests
+	// that make changes, from scripts and forms respectively.
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+
+	// sessionContextKey is where the session of the request is kept in the
+	// echo context.
+	sessionContextKey = "session"
+
+	// sessionPurgeInterval is how often expired sessions are deleted.
+	sessionPurgeInterval = time.Hour
+)
+
+// authenticateSession authenticates the request with its session cookie,
+// reporting whether it had a valid session. Invalid cookies are cleared and
+// the request carries on anonymously. Requests that make changes must carry
+// the CSRF token of the session, apart from those exempt from it.
+func (a *App) authenticateSession(c echo.Context) (bool, error) {
+	cookie, err := c.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return false, nil
+	}
+
+	ctx := c.Request().Context()
+
+	user, session, err := a.TodoCore.AuthenticateSession(ctx, cookie.Value)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUnauthenticated):
+			clearSessionCookie(c)
+			return false, nil
+		default:
+			return false, fmt.Errorf("authenticate session: %w", err)
+		}
+	}
+
+	if !safeMethod(c.Request().Method) && !csrfExempt(c) {
+		token := c.Request().Header.Get(csrfHeader)
+		if token == "" {
+			token = c.FormValue(csrfField)
+		}
+
+		if !session.CheckCSRF(token) {
+			return false, echo.NewHTTPError(http.StatusForbidden, "invalid csrf token")
+		}
+	}
+
+	ctx = todo.WithUser(ctx, user)
+	ctx = todo.WithActor(ctx, user.Name)
+	c.SetRequest(c.Request().WithContext(ctx))
+	c.Set(sessionContextKey, session)
+
+	return true, nil
+}
+
+// safeMethod reports whether requests with the method do not make changes.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// csrfExempt reports whether the request may make changes without the CSRF
+// token of its session. The login and signup forms start a new session
+// rather than act on behalf of the current one, and are served without the
+// token since browsers may be logged out by the time they are submitted.
+func csrfExempt(c echo.Context) bool {
+	req := c.Request()
+
+	if req.Method != http.MethodPost {
+		return false
+	}
+
+	return req.URL.Path == "/login" || req.URL.Path == "/signup"
+}
+
+// sessionFrom returns the session the request was authenticated with, if any.
+func sessionFrom(c echo.Context) (todo.Session, bool) {
+	session, ok := c.Get(sessionContextKey).(todo.Session)
+	return session, ok
+}
+
+// setSessionCookie hands the token of a new session to the browser.
+func setSessionCookie(c echo.Context, session todo.NewSession) {
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.TimeExpires,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearSessionCookie removes the session cookie from the browser.
+func clearSessionCookie(c echo.Context) {
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// accountPage is the data of the login and signup pages. SSO is set when
+// users can log in with an OpenID Connect provider.
+type accountPage struct {
+	Name    string
+	Error   string
+	SSO     bool
+	Version string
+}
+
+// accountPage returns the data of the login and signup pages with the name
+// that was entered and why it was rejected, if it was.
+func (a *App) accountPage(name string, message string) accountPage {
+	return accountPage{
+		Name:    name,
+		Error:   message,
+		SSO:     a.OIDC != nil,
+		Version: a.Version,
+	}
+}
+
+// LoginPage serves the login form.
+func (a *App) LoginPage(c echo.Context) error {
+	return c.Render(http.StatusOK, "login.html.tmpl", a.accountPage("", ""))
+}
+
+// Login logs a browser in with the name and password from the login form.
+func (a *App) Login(c echo.Context) error {
+	name := c.FormValue("name")
+
+	session, err := a.TodoCore.Login(c.Request().Context(), name, c.FormValue("password"))
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrInvalidCredentials):
+			return c.Render(http.StatusUnauthorized, "login.html.tmpl", a.accountPage(name, todo.ErrInvalidCredentials.Error()))
+		default:
+			return fmt.Errorf("login: %w", err)
+		}
+	}
+
+	setSessionCookie(c, session)
+
+	return c.Redirect(http.StatusSeeOther, "/")
+}
+
+// SignupPage serves the signup form.
+func (a *App) SignupPage(c echo.Context) error {
+	return c.Render(http.StatusOK, "signup.html.tmpl", a.accountPage("", ""))
+}
+
+// Signup creates a user with the name and password from the signup form and
+// logs the browser in as them.
+func (a *App) Signup(c echo.Context) error {
+	params := todo.UserCreateParams{
+		Name:     c.FormValue("name"),
+		Password: c.FormValue("password"),
+	}
+
+	ctx := c.Request().Context()
+
+	// Without a password the account could never log in.
+	if params.Password == "" {
+		return c.Render(http.StatusBadRequest, "signup.html.tmpl", a.accountPage(params.Name, "missing required field password"))
+	}
+
+	user, err := a.TodoCore.CreateUser(ctx, params)
+	if err != nil {
+		var vErr todo.ValidationError
+
+		switch {
+		case errors.As(err, &vErr):
+			return c.Render(http.StatusBadRequest, "signup.html.tmpl", a.accountPage(params.Name, err.Error()))
+		case errors.Is(err, todo.ErrUserExists):
+			return c.Render(http.StatusConflict, "signup.html.tmpl", a.accountPage(params.Name, todo.ErrUserExists.Error()))
+		default:
+			return fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	session, err := a.TodoCore.Login(ctx, user.Name, params.Password)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	setSessionCookie(c, session)
+
+	return c.Redirect(http.StatusSeeOther, "/")
+}
+
+// Logout ends the session of the browser and sends it back to the login page.
+func (a *App) Logout(c echo.Context) error {
+	if session, ok := sessionFrom(c); ok {
+		if err := a.TodoCore.Logout(c.Request().Context(), session); err != nil {
+			return fmt.Errorf("logout: %w", err)
+		}
+	}
+
+	clearSessionCookie(c)
+
+	return c.Redirect(http.StatusSeeOther, "/login")
+}
+
+// purgeSessions deletes expired sessions until ctx is canceled.
+func (a *App) purgeSessions(ctx context.Context) {
+	ticker := time.NewTicker(sessionPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := a.TodoCore.PurgeSessions(ctx, time.Now())
+		if err != nil {
+			a.Log.Error("purge sessions", "error", err)
+		} else if n > 0 {
+			a.Log.Info("purge sessions", "purged", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestSessionCSRF(t *testing.T) {
+	a := &App{
+		Log:      hclog.NewNullLogger(),
+		TodoCore: todo.NewCore(todomemory.NewStore()),
+	}
+
+	e := echo.New()
+	e.Use(a.authenticate(false))
+	a.routes(e)
+
+	const password = "correct horse battery staple"
+
+	if _, err := a.TodoCore.CreateUser(context.Background(), todo.UserCreateParams{Name: "alice", Password: password}); err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	createList := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/lists", strings.NewReader(`{"name": "work"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(csrfHeader, token)
+		}
+		return req
+	}
+
+	form := func(target string, values url.Values) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		return req
+	}
+
+	// Every request is sent with a new session and built with its CSRF token.
+	tests := []struct {
+		name string
+		req  func(token string) *http.Request
+		want int
+	}{
+		{
+			name: "missing token",
+			req:  func(string) *http.Request { return createList("") },
+			want: http.StatusForbidden,
+		},
+		{
+			name: "wrong token",
+			req:  func(string) *http.Request { return createList("not-the-token") },
+			want: http.StatusForbidden,
+		},
+		{
+			name: "valid header",
+			req:  createList,
+			want: http.StatusCreated,
+		},
+		{
+			name: "wrong field",
+			req: func(string) *http.Request {
+				return form("/logout", url.Values{csrfField: {"not-the-token"}})
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "valid field",
+			req: func(token string) *http.Request {
+				return form("/logout", url.Values{csrfField: {token}})
+			},
+			want: http.StatusSeeOther,
+		},
+		{
+			name: "login form",
+			req: func(string) *http.Request {
+				return form("/login", url.Values{"name": {"alice"}, "password": {password}})
+			},
+			want: http.StatusSeeOther,
+		},
+		{
+			name: "signup form",
+			req: func(string) *http.Request {
+				return form("/signup", url.Values{"name": {"bob"}, "password": {password}})
+			},
+			want: http.StatusSeeOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := a.TodoCore.Login(context.Background(), "alice", password)
+			if err != nil {
+				t.Fatalf("login: expected nil error, got %v", err)
+			}
+
+			req := tt.req(session.CSRFToken)
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: session.Token})
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
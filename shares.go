@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryShared fetches the todos other users shared with the current user.
+func (a *App) QueryShared(c echo.Context) error {
+	todos, err := a.TodoCore.QueryShared(c.Request().Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUnauthenticated):
+			return unauthorized(c, "missing api key")
+		default:
+			return fmt.Errorf("query shared: %w", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+// QueryCollaborators fetches the users a todo is shared with.
+func (a *App) QueryCollaborators(c echo.Context) error {
+	t, err := a.sharedTodo(c)
+	if err != nil {
+		return err
+	}
+
+	collaborators, err := a.TodoCore.QueryCollaborators(c.Request().Context(), t)
+	if err != nil {
+		return fmt.Errorf("query collaborators [%s]: %w", t.ID, err)
+	}
+
+	return c.JSON(http.StatusOK, collaborators)
+}
+
+// Share shares a todo and its subtasks with another user, or changes their
+// role when it is already shared with them.
+func (a *App) Share(c echo.Context) error {
+	t, err := a.sharedTodo(c)
+	if err != nil {
+		return err
+	}
+
+	var params todo.ShareParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	collaborator, err := a.TodoCore.Share(c.Request().Context(), t, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUnauthenticated):
+			return unauthorized(c, "missing api key")
+		default:
+			return fmt.Errorf("share [%s]: %w", t.ID, err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, collaborator)
+}
+
+// Unshare stops sharing a todo with a user.
+func (a *App) Unshare(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user id format")
+	}
+
+	t, err := a.sharedTodo(c)
+	if err != nil {
+		return err
+	}
+
+	if err := a.TodoCore.Unshare(c.Request().Context(), t, userID); err != nil {
+		switch {
+		case errors.Is(err, todo.ErrShareNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("unshare [%s]: %w", t.ID, err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// sharedTodo fetches the todo given by the id path parameter, responding with
+// a 404 when the current user may not see it.
+func (a *App) sharedTodo(c echo.Context) (todo.Todo, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return todo.Todo{}, echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	t, err := a.TodoCore.QueryByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrNotFound):
+			return todo.Todo{}, echo.NewHTTPError(http.StatusNotFound)
+		default:
+			return todo.Todo{}, fmt.Errorf("query by id [%s]: %w", id, err)
+		}
+	}
+
+	return t, nil
+}
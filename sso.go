@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/oidc"
+	"github.com/sudomateo/todo/todo"
+)
+
+const (
+	// ssoCookie holds the state, nonce and PKCE verifier of a single sign-on
+	// login in progress until the provider sends the browser back.
+	ssoCookie     = "todo_sso"
+	ssoCookiePath = "/login/oidc"
+	ssoTimeout    = 10 * time.Minute
+)
+
+// SSOLogin sends the browser to log in with the OpenID Connect provider.
+func (a *App) SSOLogin(c echo.Context) error {
+	req, err := a.OIDC.NewAuthRequest()
+	if err != nil {
+		return fmt.Errorf("new auth request: %w", err)
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     ssoCookie,
+		Value:    strings.Join([]string{req.State, req.Nonce, req.Verifier}, "."),
+		Path:     ssoCookiePath,
+		MaxAge:   int(ssoTimeout / time.Second),
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return c.Redirect(http.StatusFound, req.URL)
+}
+
+// SSOCallback finishes logging in with the OpenID Connect provider, creating a
+// user the first time someone logs in.
+func (a *App) SSOCallback(c echo.Context) error {
+	cookie, err := c.Cookie(ssoCookie)
+	if err != nil {
+		return a.ssoFailed(c, errors.New("missing login state"))
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     ssoCookie,
+		Path:     ssoCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	var req oidc.AuthRequest
+
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return a.ssoFailed(c, errors.New("invalid login state"))
+	}
+	req.State, req.Nonce, req.Verifier = parts[0], parts[1], parts[2]
+
+	if !req.CheckState(c.QueryParam("state")) {
+		return a.ssoFailed(c, errors.New("state mismatch"))
+	}
+
+	if providerErr := c.QueryParam("error"); providerErr != "" {
+		return a.ssoFailed(c, fmt.Errorf("provider: %s: %s", providerErr, c.QueryParam("error_description")))
+	}
+
+	ctx := c.Request().Context()
+
+	rawIDToken, err := a.OIDC.Exchange(ctx, c.QueryParam("code"), req.Verifier)
+	if err != nil {
+		return a.ssoFailed(c, fmt.Errorf("exchange: %w", err))
+	}
+
+	claims, err := a.OIDC.Verify(ctx, rawIDToken, req.Nonce)
+	if err != nil {
+		return a.ssoFailed(c, fmt.Errorf("verify: %w", err))
+	}
+
+	session, err := a.TodoCore.LoginExternal(ctx, todo.ExternalUser{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Name:    claims.Username(),
+	})
+	if err != nil {
+		return fmt.Errorf("login external: %w", err)
+	}
+
+	setSessionCookie(c, session)
+
+	return c.Redirect(http.StatusSeeOther, "/")
+}
+
+// ssoFailed logs why a single sign-on login failed and shows the login page
+// again without the details.
+func (a *App) ssoFailed(c echo.Context, err error) error {
+	a.Log.Error("single sign-on", "error", err)
+
+	return c.Render(http.StatusUnauthorized, "login.html.tmpl", a.accountPage("", "single sign-on failed"))
+}
@@ -0,0 +1,327 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchOperations is the maximum number of operations in a single batch.
+const MaxBatchOperations = 100
+
+// BatchOp is an enum that represents the kind of an operation in a batch.
+type BatchOp string
+
+// Kinds of operations in a batch.
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchMode is an enum that represents what happens to a batch when one of its
+// operations fails.
+type BatchMode string
+
+const (
+	// BatchAtomic applies either every operation in the batch or none of
+	// them. This is the default.
+	BatchAtomic BatchMode = "atomic"
+
+	// BatchBestEffort applies every operation in the batch that succeeds and
+	// reports the failures in the results.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// BatchOperation represents a single operation in a batch. ID is required to
+// update or delete a todo item. When Version is set, the todo item is only
+// updated or deleted if it is still at that version.
+type BatchOperation struct {
+	Op      BatchOp           `json:"op"`
+	ID      *uuid.UUID        `json:"id,omitempty"`
+	Version int               `json:"version,omitempty"`
+	Create  *TodoCreateParams `json:"create,omitempty"`
+	Update  *TodoUpdateParams `json:"update,omitempty"`
+}
+
+// BatchParams represents a batch of operations that are applied in order.
+type BatchParams struct {
+	Mode       BatchMode        `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// Validate validates the BatchParams. The parameters of the operations are
+// validated as each operation is applied.
+func (b BatchParams) Validate() error {
+	errs := make([]error, 0)
+
+	if b.Mode != "" && b.Mode != BatchAtomic && b.Mode != BatchBestEffort {
+		errs = append(errs, fmt.Errorf(
+			"invalid mode %q: must be one of [%v, %v]",
+			string(b.Mode),
+			BatchAtomic,
+			BatchBestEffort,
+		))
+	}
+
+	if len(b.Operations) == 0 {
+		errs = append(errs, errors.New("missing required field operations"))
+	}
+
+	if len(b.Operations) > MaxBatchOperations {
+		errs = append(errs, fmt.Errorf("too many operations: must be at most %d", MaxBatchOperations))
+	}
+
+	for i, op := range b.Operations {
+		switch op.Op {
+		case BatchCreate:
+			if op.Create == nil {
+				errs = append(errs, fmt.Errorf("operations[%d]: missing required field create", i))
+			}
+		case BatchUpdate:
+			if op.ID == nil {
+				errs = append(errs, fmt.Errorf("operations[%d]: missing required field id", i))
+			}
+			if op.Update == nil {
+				errs = append(errs, fmt.Errorf("operations[%d]: missing required field update", i))
+			}
+		case BatchDelete:
+			if op.ID == nil {
+				errs = append(errs, fmt.Errorf("operations[%d]: missing required field id", i))
+			}
+		default:
+			errs = append(errs, fmt.Errorf(
+				"operations[%d]: invalid op %q: must be one of [%v, %v, %v]",
+				i,
+				string(op.Op),
+				BatchCreate,
+				BatchUpdate,
+				BatchDelete,
+			))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// BatchResult represents the outcome of a single operation in a batch. Todo is
+// the todo item as it was left by a successful create or update. Err is set
+// when the operation failed. The API reports the HTTP status code of the
+// operation in Status and the message of Err in Error.
+type BatchResult struct {
+	Op     BatchOp   `json:"op"`
+	ID     uuid.UUID `json:"id"`
+	Status int       `json:"status"`
+	Todo   *Todo     `json:"todo,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Err    error     `json:"-"`
+}
+
+// BatchError is returned when an operation in a batch applied in BatchAtomic
+// mode fails.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface for BatchError.
+func (b BatchError) Error() string {
+	return fmt.Sprintf("operations[%d]: %v", b.Index, b.Err)
+}
+
+// Unwrap returns the error of the failed operation so that errors.Is and
+// errors.As can inspect it.
+func (b BatchError) Unwrap() error {
+	return b.Err
+}
+
+// Batch applies the operations in params in order and returns the result of
+// each one. In BatchAtomic mode the operations share a single transaction and
+// the first one to fail rolls all of them back, in which case a BatchError is
+// returned. In BatchBestEffort mode every operation runs in a transaction of
+// its own and failures are only reported in the results.
+func (s *Core) Batch(ctx context.Context, params BatchParams) ([]BatchResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	results := make([]BatchResult, len(params.Operations))
+
+	if params.Mode == BatchBestEffort {
+		for i, op := range params.Operations {
+			// The error is already recorded in the result.
+			_ = s.WithTx(ctx, func(txCore *Core) error {
+				results[i] = txCore.applyOperation(ctx, op)
+				return results[i].Err
+			})
+		}
+
+		return results, nil
+	}
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		for i, op := range params.Operations {
+			results[i] = txCore.applyOperation(ctx, op)
+			if results[i].Err != nil {
+				return BatchError{Index: i, Err: results[i].Err}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// applyOperation applies a single operation of a batch.
+func (s *Core) applyOperation(ctx context.Context, op BatchOperation) BatchResult {
+	res := BatchResult{Op: op.Op}
+	if op.ID != nil {
+		res.ID = *op.ID
+	}
+
+	if op.Op == BatchCreate {
+		td, err := s.Create(ctx, *op.Create)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+
+		res.ID = td.ID
+		res.Todo = &td
+		return res
+	}
+
+	td, err := s.QueryByID(ctx, *op.ID)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	if op.Version != 0 && op.Version != td.Version {
+		res.Err = ErrConflict
+		return res
+	}
+
+	if op.Op == BatchDelete {
+		res.Err = s.Delete(ctx, td)
+		return res
+	}
+
+	td, err = s.Update(ctx, td, *op.Update)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	res.Todo = &td
+	return res
+}
+
+// CompleteAll completes every incomplete todo item matching filter and returns
+// them. Either all of them are completed or none are.
+func (s *Core) CompleteAll(ctx context.Context, filter QueryFilter) ([]Todo, error) {
+	if filter.Trashed {
+		return nil, NewValidationError(errors.New("todo items in the trash cannot be completed"))
+	}
+
+	if err := (QueryOptions{Filter: filter}).Validate(); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	incomplete := false
+	filter.Completed = &incomplete
+	filter.OwnerID = ownerFilter(ctx)
+
+	completed := make([]Todo, 0)
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		// Subtasks are usually created after their parents, so completing
+		// the newest todo items first lets the block policy succeed when the
+		// subtasks match the filter as well.
+		todos, err := txCore.storer.Query(ctx, QueryOptions{
+			Filter:    filter,
+			Direction: DirectionDesc,
+		}.withDefaults())
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		done := true
+
+		for _, td := range todos {
+			// Completing a todo item may have completed some of the others
+			// along with it, so each one is read again before it is updated.
+			current, err := txCore.storer.QueryByID(ctx, td.ID)
+			if err != nil {
+				return fmt.Errorf("query by id [%s]: %w", td.ID, err)
+			}
+
+			if current.Completed {
+				continue
+			}
+
+			updated, err := txCore.update(ctx, current, TodoUpdateParams{Completed: &done})
+			if err != nil {
+				return fmt.Errorf("complete [%s]: %w", td.ID, err)
+			}
+
+			completed = append(completed, updated)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return completed, nil
+}
+
+// DeleteAll moves every todo item matching filter to the trash along with its
+// descendants and returns how many todo items matched. Either all of them are
+// moved to the trash or none are.
+func (s *Core) DeleteAll(ctx context.Context, filter QueryFilter) (int, error) {
+	if filter.Trashed {
+		return 0, NewValidationError(errors.New("todo items in the trash cannot be deleted again"))
+	}
+
+	if err := (QueryOptions{Filter: filter}).Validate(); err != nil {
+		return 0, fmt.Errorf("validate: %w", err)
+	}
+
+	filter.OwnerID = ownerFilter(ctx)
+
+	n := 0
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		todos, err := txCore.storer.Query(ctx, QueryOptions{Filter: filter}.withDefaults())
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		for _, td := range todos {
+			if err := txCore.Delete(ctx, td); err != nil {
+				return fmt.Errorf("delete [%s]: %w", td.ID, err)
+			}
+		}
+
+		n = len(todos)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,222 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Broker buffer and subscription limits.
+const (
+	DefaultBrokerBuffer = 1000
+	subscriptionBuffer  = 64
+)
+
+// Notification is a change to a todo item that has been committed. Deleted
+// notifications are only sent for the todo item that was deleted and not for
+// the descendants moved to the trash along with it.
+type Notification struct {
+	// ID is assigned by the Broker and identifies the notification to
+	// subscribers resuming after it.
+	ID   string    `json:"-"`
+	Type EventType `json:"type"`
+	Todo Todo      `json:"todo"`
+}
+
+// Publisher receives the notifications of a Core. Publish must not block.
+type Publisher interface {
+	Publish(n Notification)
+}
+
+// WithPublisher sends a notification to p for every change to a todo item
+// once it has been committed.
+func WithPublisher(p Publisher) Option {
+	return func(c *Core) {
+		c.publisher = p
+	}
+}
+
+// changed is called once ev has been recorded for todo. It queues deliveries
+// for the webhooks in the same transaction and publishes a notification once
+// the transaction commits.
+func (s *Core) changed(ctx context.Context, ev Event, todo Todo) error {
+	if err := s.enqueue(ctx, ev, todo); err != nil {
+		return err
+	}
+
+	s.notify(ev.Type, todo)
+
+	return nil
+}
+
+// notify publishes a notification for todo, or holds on to it until the
+// transaction the Core is part of commits.
+func (s *Core) notify(typ EventType, todo Todo) {
+	if s.publisher == nil {
+		return
+	}
+
+	n := Notification{Type: typ, Todo: todo}
+
+	if s.pending != nil {
+		*s.pending = append(*s.pending, n)
+		return
+	}
+
+	s.publisher.Publish(n)
+}
+
+// Broker is an in-process Publisher that fans notifications out to
+// subscribers. It keeps the most recent notifications so that subscribers can
+// resume from the last one they saw.
+type Broker struct {
+	mutex  sync.Mutex
+	epoch  string
+	seq    uint64
+	buffer []Notification
+	size   int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker is a constructor for a Broker that keeps the last size
+// notifications.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		epoch: strings.SplitN(uuid.NewString(), "-", 2)[0],
+		size:  size,
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the notifications published to a Broker after it was
+// created.
+type Subscription struct {
+	broker *Broker
+	c      chan Notification
+}
+
+// C returns the channel notifications are delivered on. It is closed when the
+// subscription is closed, when the broker is closed, or when the subscriber
+// falls too far behind, in which case it should resubscribe from the last
+// notification it saw.
+func (s *Subscription) C() <-chan Notification {
+	return s.c
+}
+
+// Close stops the delivery of notifications to the subscription.
+func (s *Subscription) Close() {
+	s.broker.mutex.Lock()
+	defer s.broker.mutex.Unlock()
+
+	s.broker.remove(s)
+}
+
+// Publish assigns n the next ID and delivers it to every subscriber.
+func (b *Broker) Publish(n Notification) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.seq++
+	n.ID = fmt.Sprintf("%s-%d", b.epoch, b.seq)
+
+	b.buffer = append(b.buffer, n)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.c <- n:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the notifications published from now on
+// along with the notifications published after the one given by lastID. An
+// empty lastID subscribes without replaying anything. The returned bool is
+// false when the notifications after lastID are no longer known, either
+// because too many have been published since or because lastID comes from
+// another broker, in which case the subscriber has missed changes. Once the
+// broker is closed, the subscription is closed from the start.
+func (b *Broker) Subscribe(lastID string) (*Subscription, []Notification, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	sub := &Subscription{
+		broker: b,
+		c:      make(chan Notification, subscriptionBuffer),
+	}
+
+	if b.closed {
+		close(sub.c)
+		return sub, nil, true
+	}
+
+	b.subs[sub] = struct{}{}
+
+	if lastID == "" {
+		return sub, nil, true
+	}
+
+	replay, ok := b.since(lastID)
+
+	return sub, replay, ok
+}
+
+// since returns the buffered notifications published after the one given by
+// id.
+func (b *Broker) since(id string) ([]Notification, bool) {
+	epoch, s, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return nil, false
+	}
+
+	seq, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || seq > b.seq {
+		return nil, false
+	}
+
+	// The buffer holds the notifications up to b.seq without gaps.
+	missed := int(b.seq - seq)
+	if missed > len(b.buffer) {
+		return nil, false
+	}
+
+	replay := make([]Notification, missed)
+	copy(replay, b.buffer[len(b.buffer)-missed:])
+
+	return replay, true
+}
+
+// Close closes every subscription and stops accepting new ones.
+func (b *Broker) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// remove closes sub and forgets about it. The mutex must be held.
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.c)
+}
@@ -0,0 +1,151 @@
+package todo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+// receive returns the notifications waiting on sub.
+func receive(sub *todo.Subscription) []todo.Notification {
+	var ns []todo.Notification
+
+	for {
+		select {
+		case n, ok := <-sub.C():
+			if !ok {
+				return ns
+			}
+			ns = append(ns, n)
+		default:
+			return ns
+		}
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := todo.NewBroker(1)
+
+	sub, replay, ok := b.Subscribe("")
+	if !ok || len(replay) != 0 {
+		t.Fatalf("subscribe: expected nothing to replay, got %v, %v", replay, ok)
+	}
+
+	for _, text := range []string{"one", "two", "three"} {
+		b.Publish(todo.Notification{Type: todo.EventCreated, Todo: todo.Todo{Text: text}})
+	}
+
+	got := receive(sub)
+	if len(got) != 3 {
+		t.Fatalf("receive: expected 3 notifications, got %v", got)
+	}
+
+	// Resuming replays what was published after the given notification.
+	resumed, replay, ok := b.Subscribe(got[1].ID)
+	if !ok || len(replay) != 1 || replay[0].Todo.Text != "three" {
+		t.Fatalf("subscribe: expected to replay the last notification, got %v, %v", replay, ok)
+	}
+	resumed.Close()
+
+	// Notifications that are no longer buffered cannot be replayed.
+	if _, _, ok := b.Subscribe(got[0].ID); ok {
+		t.Fatal("subscribe: expected the first notification to be gone")
+	}
+	if _, _, ok := todo.NewBroker(2).Subscribe(got[2].ID); ok {
+		t.Fatal("subscribe: expected an ID from another broker to be unknown")
+	}
+
+	b.Close()
+
+	if _, ok := <-sub.C(); ok {
+		t.Fatal("close: expected the subscription to be closed")
+	}
+}
+
+func TestBrokerSlowSubscriber(t *testing.T) {
+	b := todo.NewBroker(todo.DefaultBrokerBuffer)
+	sub, _, _ := b.Subscribe("")
+
+	// A subscriber that does not keep up is dropped instead of blocking the
+	// publisher.
+	for i := 0; i < todo.DefaultBrokerBuffer; i++ {
+		b.Publish(todo.Notification{Type: todo.EventCreated})
+	}
+
+	got := receive(sub)
+	if len(got) == 0 || len(got) == todo.DefaultBrokerBuffer {
+		t.Fatalf("receive: expected the subscription to be cut short, got %d notifications", len(got))
+	}
+
+	if _, ok := <-sub.C(); ok {
+		t.Fatal("receive: expected the subscription to be closed")
+	}
+
+	// The subscriber catches up by resuming from the last notification it saw.
+	_, replay, ok := b.Subscribe(got[len(got)-1].ID)
+	if !ok || len(got)+len(replay) != todo.DefaultBrokerBuffer {
+		t.Fatalf("subscribe: expected to replay the missed notifications, got %d, %v", len(replay), ok)
+	}
+}
+
+func TestTodoNotifications(t *testing.T) {
+	ctx := context.Background()
+	broker := todo.NewBroker(todo.DefaultBrokerBuffer)
+	todoCore := todo.NewCore(todomemory.NewStore(), todo.WithPublisher(broker))
+
+	sub, _, _ := broker.Subscribe("")
+
+	td, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "notify", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	text := "notified"
+	if _, err := todoCore.Update(ctx, td, todo.TodoUpdateParams{Text: &text}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	got := receive(sub)
+
+	// Nothing is published for a transaction that is rolled back.
+	errRollback := errors.New("rollback")
+	err = todoCore.WithTx(ctx, func(txCore *todo.Core) error {
+		if _, err := txCore.Create(ctx, todo.TodoCreateParams{Text: "rolled back", Priority: todo.PriorityLow}); err != nil {
+			return err
+		}
+
+		if pending := receive(sub); len(pending) != 0 {
+			t.Fatalf("receive: expected nothing before commit, got %v", pending)
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("with tx: expected %v, got %v", errRollback, err)
+	}
+
+	updated, err := todoCore.QueryByID(ctx, td.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.Delete(ctx, updated); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	want := []todo.EventType{todo.EventCreated, todo.EventUpdated, todo.EventDeleted}
+
+	got = append(got, receive(sub)...)
+	if len(got) != len(want) {
+		t.Fatalf("receive: expected %d notifications, got %v", len(want), got)
+	}
+
+	for i, n := range got {
+		if n.Type != want[i] || n.Todo.ID != td.ID {
+			t.Fatalf("receive: expected %s notification for %s, got %s for %s", want[i], td.ID, n.Type, n.Todo.ID)
+		}
+	}
+}
@@ -3,22 +3,50 @@ package todo
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// defaultPageSize is the number of todos ListTodosIter requests per page when
+// no limit is given.
+const defaultPageSize = 100
+
 // Client is a Go HTTP client to interact with the Todo API.
 type Client struct {
-	baseURL *url.URL
-	http    *http.Client
+	baseURL   *url.URL
+	http      *http.Client
+	apiKey    string
+	workspace string
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithAPIKey authenticates every request of the Client with the given API
+// key.
+func WithAPIKey(key string) ClientOption {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// InWorkspace makes every request of the Client work in the workspace given by
+// id rather than the default workspace.
+func InWorkspace(id string) ClientOption {
+	return func(c *Client) {
+		c.workspace = id
+	}
 }
 
 // NewClient creates a new Client using rawURL as the base URL for the Todo
 // API.
-func NewClient(rawURL string) (*Client, error) {
+func NewClient(rawURL string, opts ...ClientOption) (*Client, error) {
 	baseURL, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
@@ -29,39 +57,178 @@ func NewClient(rawURL string) (*Client, error) {
 		http:    &http.Client{Timeout: 10 * time.Second},
 	}
 
+	for _, opt := range opts {
+		opt(&c)
+	}
+
 	return &c, nil
 }
 
-// ListTodos retrieves a list of all todos from the API.
+// ListTodos retrieves a list of todos from the API.
 func (c *Client) ListTodos() ([]Todo, error) {
+	return c.ListTodosWithOptions(QueryOptions{})
+}
+
+// ListTodosWithOptions retrieves a list of the todos matching opts from the
+// API.
+func (c *Client) ListTodosWithOptions(opts QueryOptions) ([]Todo, error) {
 	u := c.baseURL.JoinPath("/api/todo")
+	u.RawQuery = opts.Values().Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	todos, _, err := c.listTodos(u)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.http.Do(req)
+	return todos, nil
+}
+
+// ListTodosIter returns an iterator over every todo matching opts. The
+// iterator follows the pagination links returned by the API, fetching
+// opts.Limit todos per request or defaultPageSize when no limit is set.
+func (c *Client) ListTodosIter(opts QueryOptions) *TodoIterator {
+	if opts.Limit == 0 {
+		opts.Limit = defaultPageSize
+	}
+
+	u := c.baseURL.JoinPath("/api/todo")
+	u.RawQuery = opts.Values().Encode()
+
+	return &TodoIterator{
+		client: c,
+		next:   u,
+	}
+}
+
+// listTodos retrieves a single page of todos from u along with the URL of
+// the next page, if any.
+func (c *Client) listTodos(u *url.URL) ([]Todo, *url.URL, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		buf := new(bytes.Buffer)
 		if _, err := io.Copy(buf, resp.Body); err != nil {
-			return nil, fmt.Errorf("failed listing todos: received status code %v", resp.StatusCode)
+			return nil, nil, fmt.Errorf("failed listing todos: received status code %v", resp.StatusCode)
 		}
 
-		return nil, fmt.Errorf("failed listing todos: %v", buf.String())
+		return nil, nil, fmt.Errorf("failed listing todos: %v", buf.String())
 	}
 
 	todos := make([]Todo, 0)
 	if err := json.NewDecoder(resp.Body).Decode(&todos); err != nil {
+		return nil, nil, err
+	}
+
+	var next *url.URL
+	if link := nextLink(resp.Header.Values("Link")); link != "" {
+		ref, err := url.Parse(link)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed listing todos: invalid link %q: %w", link, err)
+		}
+		next = u.ResolveReference(ref)
+	}
+
+	return todos, next, nil
+}
+
+// nextLink returns the target of the rel="next" link in the given Link header
+// values, or an empty string if there is none.
+func nextLink(headers []string) string {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				if strings.TrimSpace(param) == `rel="next"` {
+					return strings.Trim(strings.TrimSpace(target), "<>")
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// TodoIterator iterates over todos across every page of a listing. Call Next
+// to advance the iterator and Todo to read the current todo. Once Next returns
+// false, Err reports any error that stopped the iteration.
+type TodoIterator struct {
+	client *Client
+	next   *url.URL
+	page   []Todo
+	todo   Todo
+	err    error
+}
+
+// Next advances the iterator, fetching the next page from the API when the
+// current page is exhausted. It returns false when there are no more todos or
+// an error occurred.
+func (it *TodoIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || it.next == nil {
+			return false
+		}
+
+		it.page, it.next, it.err = it.client.listTodos(it.next)
+	}
+
+	it.todo, it.page = it.page[0], it.page[1:]
+
+	return true
+}
+
+// Todo returns the current todo.
+func (it *TodoIterator) Todo() Todo {
+	return it.todo
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *TodoIterator) Err() error {
+	return it.err
+}
+
+// ListTags retrieves every tag in use along with how many todos use it.
+func (c *Client) ListTags() ([]TagCount, error) {
+	u := c.baseURL.JoinPath("/api/tags")
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
 		return nil, err
 	}
 
-	return todos, nil
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(buf, resp.Body); err != nil {
+			return nil, fmt.Errorf("failed listing tags: received status code %v", resp.StatusCode)
+		}
+
+		return nil, fmt.Errorf("failed listing tags: %v", buf.String())
+	}
+
+	tags := make([]TagCount, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
 }
 
 // GetTodo retrieves a single todo by its id from the API.
@@ -73,7 +240,7 @@ func (c *Client) GetTodo(id string) (Todo, error) {
 		return Todo{}, err
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return Todo{}, err
 	}
@@ -110,7 +277,7 @@ func (c *Client) CreateTodo(params TodoCreateParams) (Todo, error) {
 		return Todo{}, err
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return Todo{}, err
 	}
@@ -135,6 +302,18 @@ func (c *Client) CreateTodo(params TodoCreateParams) (Todo, error) {
 
 // UpdateTodo updates an existing todo given by id.
 func (c *Client) UpdateTodo(id string, params TodoUpdateParams) (Todo, error) {
+	return c.updateTodo(id, 0, params)
+}
+
+// UpdateTodoIfMatch updates an existing todo given by id only if it is still
+// at the given version, returning an error wrapping ErrConflict otherwise.
+func (c *Client) UpdateTodoIfMatch(id string, version int, params TodoUpdateParams) (Todo, error) {
+	return c.updateTodo(id, version, params)
+}
+
+// updateTodo updates an existing todo given by id, sending version in the
+// If-Match header unless it is zero.
+func (c *Client) updateTodo(id string, version int, params TodoUpdateParams) (Todo, error) {
 	u := c.baseURL.JoinPath("/api/todo", id)
 
 	buf := new(bytes.Buffer)
@@ -147,12 +326,20 @@ func (c *Client) UpdateTodo(id string, params TodoUpdateParams) (Todo, error) {
 		return Todo{}, err
 	}
 
-	resp, err := c.http.Do(req)
+	if version != 0 {
+		req.Header.Set("If-Match", strconv.Quote(strconv.Itoa(version)))
+	}
+
+	resp, err := c.send(req)
 	if err != nil {
 		return Todo{}, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return Todo{}, fmt.Errorf("failed updating todo: %w", ErrConflict)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		buf := new(bytes.Buffer)
 		if _, err := io.Copy(buf, resp.Body); err != nil {
@@ -170,7 +357,7 @@ func (c *Client) UpdateTodo(id string, params TodoUpdateParams) (Todo, error) {
 	return td, nil
 }
 
-// DeleteTodo deletes a todo by its id.
+// DeleteTodo moves a todo given by id to the trash.
 func (c *Client) DeleteTodo(id string) error {
 	u := c.baseURL.JoinPath("/api/todo", id)
 
@@ -179,7 +366,7 @@ func (c *Client) DeleteTodo(id string) error {
 		return err
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return err
 	}
@@ -196,3 +383,469 @@ func (c *Client) DeleteTodo(id string) error {
 
 	return nil
 }
+
+// GetTodoHistory retrieves the change history of a todo given by id, oldest
+// first.
+func (c *Client) GetTodoHistory(id string) ([]Event, error) {
+	events := make([]Event, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/todo", id, "history"), nil, http.StatusOK, &events); err != nil {
+		return nil, fmt.Errorf("failed getting todo history: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListTrash retrieves the todos in the trash matching opts from the API.
+func (c *Client) ListTrash(opts QueryOptions) ([]Todo, error) {
+	u := c.baseURL.JoinPath("/api/trash")
+	u.RawQuery = opts.Values().Encode()
+
+	todos := make([]Todo, 0)
+	if err := c.do(http.MethodGet, u, nil, http.StatusOK, &todos); err != nil {
+		return nil, fmt.Errorf("failed listing trash: %w", err)
+	}
+
+	return todos, nil
+}
+
+// RestoreTodo takes a todo given by id out of the trash.
+func (c *Client) RestoreTodo(id string) (Todo, error) {
+	var td Todo
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/todo", id, "restore"), nil, http.StatusOK, &td); err != nil {
+		return Todo{}, fmt.Errorf("failed restoring todo: %w", err)
+	}
+
+	return td, nil
+}
+
+// PurgeTodo permanently deletes a todo given by id from the trash.
+func (c *Client) PurgeTodo(id string) error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/trash", id), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed purging todo: %w", err)
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently deletes every todo in the trash.
+func (c *Client) EmptyTrash() error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/trash"), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed emptying trash: %w", err)
+	}
+
+	return nil
+}
+
+// Batch applies the operations in params and returns the result of each one.
+// In BatchAtomic mode an error is returned instead when any operation fails.
+func (c *Client) Batch(params BatchParams) ([]BatchResult, error) {
+	results := make([]BatchResult, 0)
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/todo/batch"), params, http.StatusOK, &results); err != nil {
+		return nil, fmt.Errorf("failed applying batch: %w", err)
+	}
+
+	return results, nil
+}
+
+// CompleteTodos completes every incomplete todo matching filter and returns
+// the todos that were completed.
+func (c *Client) CompleteTodos(filter QueryFilter) ([]Todo, error) {
+	u := c.baseURL.JoinPath("/api/todo/complete")
+	u.RawQuery = QueryOptions{Filter: filter}.Values().Encode()
+
+	todos := make([]Todo, 0)
+	if err := c.do(http.MethodPost, u, nil, http.StatusOK, &todos); err != nil {
+		return nil, fmt.Errorf("failed completing todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+// DeleteCompletedTodos moves every completed todo matching filter to the trash
+// and returns how many were deleted.
+func (c *Client) DeleteCompletedTodos(filter QueryFilter) (int, error) {
+	u := c.baseURL.JoinPath("/api/todo/completed")
+	u.RawQuery = QueryOptions{Filter: filter}.Values().Encode()
+
+	var res struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := c.do(http.MethodDelete, u, nil, http.StatusOK, &res); err != nil {
+		return 0, fmt.Errorf("failed deleting completed todos: %w", err)
+	}
+
+	return res.Deleted, nil
+}
+
+// SearchTodos retrieves the todos whose text matches query from the API, best
+// match first. A limit of 0 uses the server default.
+func (c *Client) SearchTodos(query string, limit int) ([]SearchResult, error) {
+	u := c.baseURL.JoinPath("/api/todo/search")
+
+	v := url.Values{"q": {query}}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	u.RawQuery = v.Encode()
+
+	results := make([]SearchResult, 0)
+	if err := c.do(http.MethodGet, u, nil, http.StatusOK, &results); err != nil {
+		return nil, fmt.Errorf("failed searching todos: %w", err)
+	}
+
+	return results, nil
+}
+
+// ListLists retrieves all lists from the API.
+func (c *Client) ListLists() ([]List, error) {
+	lists := make([]List, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/lists"), nil, http.StatusOK, &lists); err != nil {
+		return nil, fmt.Errorf("failed listing lists: %w", err)
+	}
+
+	return lists, nil
+}
+
+// GetList retrieves a single list by its id from the API.
+func (c *Client) GetList(id string) (List, error) {
+	var l List
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/lists", id), nil, http.StatusOK, &l); err != nil {
+		return List{}, fmt.Errorf("failed getting list: %w", err)
+	}
+
+	return l, nil
+}
+
+// CreateList creates a list.
+func (c *Client) CreateList(params ListCreateParams) (List, error) {
+	var l List
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/lists"), params, http.StatusCreated, &l); err != nil {
+		return List{}, fmt.Errorf("failed creating list: %w", err)
+	}
+
+	return l, nil
+}
+
+// UpdateList updates an existing list given by id.
+func (c *Client) UpdateList(id string, params ListUpdateParams) (List, error) {
+	var l List
+	if err := c.do(http.MethodPatch, c.baseURL.JoinPath("/api/lists", id), params, http.StatusOK, &l); err != nil {
+		return List{}, fmt.Errorf("failed updating list: %w", err)
+	}
+
+	return l, nil
+}
+
+// DeleteList deletes a list by its id, handling its todos according to
+// policy.
+func (c *Client) DeleteList(id string, policy DeletePolicy) error {
+	u := c.baseURL.JoinPath("/api/lists", id)
+	u.RawQuery = url.Values{"policy": {string(policy)}}.Encode()
+
+	if err := c.do(http.MethodDelete, u, nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed deleting list: %w", err)
+	}
+
+	return nil
+}
+
+// ListWebhooks retrieves all webhooks from the API. Their secrets are not
+// included.
+func (c *Client) ListWebhooks() ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/webhooks"), nil, http.StatusOK, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed listing webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook retrieves a single webhook by its id from the API.
+func (c *Client) GetWebhook(id string) (Webhook, error) {
+	var wh Webhook
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/webhooks", id), nil, http.StatusOK, &wh); err != nil {
+		return Webhook{}, fmt.Errorf("failed getting webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// CreateWebhook creates a webhook. The returned webhook includes the secret
+// its deliveries are signed with.
+func (c *Client) CreateWebhook(params WebhookCreateParams) (Webhook, error) {
+	var wh Webhook
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/webhooks"), params, http.StatusCreated, &wh); err != nil {
+		return Webhook{}, fmt.Errorf("failed creating webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// UpdateWebhook updates an existing webhook given by id.
+func (c *Client) UpdateWebhook(id string, params WebhookUpdateParams) (Webhook, error) {
+	var wh Webhook
+	if err := c.do(http.MethodPatch, c.baseURL.JoinPath("/api/webhooks", id), params, http.StatusOK, &wh); err != nil {
+		return Webhook{}, fmt.Errorf("failed updating webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// DeleteWebhook deletes a webhook by its id along with its deliveries.
+func (c *Client) DeleteWebhook(id string) error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/webhooks", id), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed deleting webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries retrieves the deliveries of a webhook given by id, newest
+// first.
+func (c *Client) ListDeliveries(id string) ([]Delivery, error) {
+	deliveries := make([]Delivery, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/webhooks", id, "deliveries"), nil, http.StatusOK, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed listing deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues a delivery given by deliveryID of the webhook given by id
+// to be sent again.
+func (c *Client) Redeliver(id string, deliveryID string) (Delivery, error) {
+	var d Delivery
+	u := c.baseURL.JoinPath("/api/webhooks", id, "deliveries", deliveryID, "redeliver")
+	if err := c.do(http.MethodPost, u, nil, http.StatusOK, &d); err != nil {
+		return Delivery{}, fmt.Errorf("failed redelivering: %w", err)
+	}
+
+	return d, nil
+}
+
+// Register creates a user along with their first API key. Pass the key to
+// WithAPIKey to make requests as the user.
+func (c *Client) Register(params UserCreateParams) (Registration, error) {
+	var reg Registration
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/users"), params, http.StatusCreated, &reg); err != nil {
+		return Registration{}, fmt.Errorf("failed registering: %w", err)
+	}
+
+	return reg, nil
+}
+
+// GetCurrentUser retrieves the user the API key of the Client belongs to.
+func (c *Client) GetCurrentUser() (User, error) {
+	var user User
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/users/me"), nil, http.StatusOK, &user); err != nil {
+		return User{}, fmt.Errorf("failed getting current user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ListAPIKeys retrieves the API keys of the current user, including revoked
+// ones.
+func (c *Client) ListAPIKeys() ([]APIKey, error) {
+	keys := make([]APIKey, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/keys"), nil, http.StatusOK, &keys); err != nil {
+		return nil, fmt.Errorf("failed listing api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CreateAPIKey creates an API key for the current user. The returned key is
+// the only time it can be read.
+func (c *Client) CreateAPIKey(params APIKeyCreateParams) (NewAPIKey, error) {
+	var key NewAPIKey
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/keys"), params, http.StatusCreated, &key); err != nil {
+		return NewAPIKey{}, fmt.Errorf("failed creating api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// RevokeAPIKey revokes an API key of the current user given by id.
+func (c *Client) RevokeAPIKey(id string) error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/keys", id), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed revoking api key: %w", err)
+	}
+
+	return nil
+}
+
+// ListSharedTodos retrieves the todos other users shared with the current
+// user.
+func (c *Client) ListSharedTodos() ([]Todo, error) {
+	todos := make([]Todo, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/todo/shared"), nil, http.StatusOK, &todos); err != nil {
+		return nil, fmt.Errorf("failed listing shared todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+// ListCollaborators retrieves the users a todo given by id is shared with.
+func (c *Client) ListCollaborators(id string) ([]Collaborator, error) {
+	collaborators := make([]Collaborator, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/todo", id, "collaborators"), nil, http.StatusOK, &collaborators); err != nil {
+		return nil, fmt.Errorf("failed listing collaborators: %w", err)
+	}
+
+	return collaborators, nil
+}
+
+// ShareTodo shares a todo given by id with another user, or changes their
+// role when it is already shared with them.
+func (c *Client) ShareTodo(id string, params ShareParams) (Collaborator, error) {
+	var collaborator Collaborator
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/todo", id, "collaborators"), params, http.StatusCreated, &collaborator); err != nil {
+		return Collaborator{}, fmt.Errorf("failed sharing todo: %w", err)
+	}
+
+	return collaborator, nil
+}
+
+// UnshareTodo stops sharing a todo given by id with the user given by userID.
+func (c *Client) UnshareTodo(id string, userID string) error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/todo", id, "collaborators", userID), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed unsharing todo: %w", err)
+	}
+
+	return nil
+}
+
+// ListWorkspaces retrieves the default workspace followed by the workspaces
+// the current user is a member of.
+func (c *Client) ListWorkspaces() ([]Workspace, error) {
+	workspaces := make([]Workspace, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/workspaces"), nil, http.StatusOK, &workspaces); err != nil {
+		return nil, fmt.Errorf("failed listing workspaces: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+// GetWorkspace retrieves a single workspace given by id.
+func (c *Client) GetWorkspace(id string) (Workspace, error) {
+	var ws Workspace
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/workspaces", id), nil, http.StatusOK, &ws); err != nil {
+		return Workspace{}, fmt.Errorf("failed getting workspace: %w", err)
+	}
+
+	return ws, nil
+}
+
+// CreateWorkspace creates a workspace with the current user as its admin.
+func (c *Client) CreateWorkspace(params WorkspaceCreateParams) (Workspace, error) {
+	var ws Workspace
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/workspaces"), params, http.StatusCreated, &ws); err != nil {
+		return Workspace{}, fmt.Errorf("failed creating workspace: %w", err)
+	}
+
+	return ws, nil
+}
+
+// UpdateWorkspace updates the name or settings of a workspace given by id.
+func (c *Client) UpdateWorkspace(id string, params WorkspaceUpdateParams) (Workspace, error) {
+	var ws Workspace
+	if err := c.do(http.MethodPatch, c.baseURL.JoinPath("/api/workspaces", id), params, http.StatusOK, &ws); err != nil {
+		return Workspace{}, fmt.Errorf("failed updating workspace: %w", err)
+	}
+
+	return ws, nil
+}
+
+// DeleteWorkspace permanently deletes a workspace given by id along with its
+// todos and lists.
+func (c *Client) DeleteWorkspace(id string) error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/workspaces", id), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed deleting workspace: %w", err)
+	}
+
+	return nil
+}
+
+// ListMembers retrieves the members of a workspace given by id.
+func (c *Client) ListMembers(id string) ([]MemberWithName, error) {
+	members := make([]MemberWithName, 0)
+	if err := c.do(http.MethodGet, c.baseURL.JoinPath("/api/workspaces", id, "members"), nil, http.StatusOK, &members); err != nil {
+		return nil, fmt.Errorf("failed listing members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember adds a user to a workspace given by id, or changes their role
+// when they are already a member.
+func (c *Client) AddMember(id string, params MemberParams) (MemberWithName, error) {
+	var member MemberWithName
+	if err := c.do(http.MethodPost, c.baseURL.JoinPath("/api/workspaces", id, "members"), params, http.StatusCreated, &member); err != nil {
+		return MemberWithName{}, fmt.Errorf("failed adding member: %w", err)
+	}
+
+	return member, nil
+}
+
+// RemoveMember removes the user given by userID from a workspace given by id.
+func (c *Client) RemoveMember(id string, userID string) error {
+	if err := c.do(http.MethodDelete, c.baseURL.JoinPath("/api/workspaces", id, "members", userID), nil, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("failed removing member: %w", err)
+	}
+
+	return nil
+}
+
+// send sends req, authenticated with the API key of the Client if it has one
+// and in the workspace of the Client if it was given one.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.workspace != "" {
+		req.Header.Set("X-Workspace-ID", c.workspace)
+	}
+
+	return c.http.Do(req)
+}
+
+// do sends a request with body encoded as JSON, if any, and decodes the
+// response into out, if any. An error containing the response body is
+// returned when the response status code is not wantStatus.
+func (c *Client) do(method string, u *url.URL, body any, wantStatus int, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return err
+		}
+		reqBody = buf
+	}
+
+	req, err := http.NewRequest(method, u.String(), reqBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		buf := new(bytes.Buffer)
+		if _, err := io.Copy(buf, resp.Body); err != nil {
+			return fmt.Errorf("received status code %v", resp.StatusCode)
+		}
+
+		return errors.New(buf.String())
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
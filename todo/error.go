@@ -16,3 +16,9 @@ func NewValidationError(err error) error {
 func (v ValidationError) Error() string {
 	return v.Err.Error()
 }
+
+// Unwrap returns the underlying error so that errors.Is and errors.As can
+// inspect it.
+func (v ValidationError) Unwrap() error {
+	return v.Err
+}
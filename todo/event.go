@@ -0,0 +1,133 @@
+package todo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType is an enum that represents the kind of change recorded by an
+// Event.
+type EventType string
+
+const (
+	EventCreated  EventType = "created"
+	EventUpdated  EventType = "updated"
+	EventDeleted  EventType = "deleted"
+	EventRestored EventType = "restored"
+)
+
+// Event is an entry in the history of a todo item. It records who changed the
+// todo item, when, and how each changed field looked before and after.
+type Event struct {
+	ID          uuid.UUID `json:"id"`
+	TodoID      uuid.UUID `json:"todo_id"`
+	Type        EventType `json:"type"`
+	Actor       string    `json:"actor"`
+	Changes     []Change  `json:"changes"`
+	TimeCreated time.Time `json:"time_created"`
+}
+
+// Change is the before and after JSON value of a single field of a todo item.
+// Before is null for todo items that were just created.
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// actorKey is the context key for the actor of a change.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that attributes changes made with it to
+// actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx by WithActor, or an empty string.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// newEvent returns an event of type typ for todo attributed to the actor in
+// ctx.
+func newEvent(ctx context.Context, typ EventType, todo Todo, changes []Change, now time.Time) Event {
+	return Event{
+		ID:          uuid.New(),
+		TodoID:      todo.ID,
+		Type:        typ,
+		Actor:       ActorFrom(ctx),
+		Changes:     changes,
+		TimeCreated: now,
+	}
+}
+
+// diff returns the changes between the tracked fields of before and after.
+// The version and timestamps other than TimeDeleted are not tracked since
+// they change with every update.
+func diff(before, after Todo) []Change {
+	fields := []struct {
+		name          string
+		before, after any
+	}{
+		{"text", before.Text, after.Text},
+		{"priority", before.Priority, after.Priority},
+		{"completed", before.Completed, after.Completed},
+		{"list_id", before.ListID, after.ListID},
+		{"parent_id", before.ParentID, after.ParentID},
+		{"due_at", before.DueAt, after.DueAt},
+		{"recurrence", before.Recurrence, after.Recurrence},
+		{"next_id", before.NextID, after.NextID},
+		{"tags", before.Tags, after.Tags},
+		{"time_deleted", before.TimeDeleted, after.TimeDeleted},
+	}
+
+	changes := make([]Change, 0)
+
+	for _, f := range fields {
+		b := marshal(f.before)
+		a := marshal(f.after)
+
+		if bytes.Equal(b, a) {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Field:  f.name,
+			Before: b,
+			After:  a,
+		})
+	}
+
+	return changes
+}
+
+// created returns the changes that bring a todo item into existence. Every
+// tracked field that is not empty is recorded with a null Before value.
+func created(todo Todo) []Change {
+	changes := diff(Todo{}, todo)
+	for i := range changes {
+		changes[i].Before = json.RawMessage("null")
+	}
+	return changes
+}
+
+// marshal encodes v as JSON. Nil slices are encoded like empty ones so that
+// they are not reported as changes.
+func marshal(v any) json.RawMessage {
+	if tags, ok := v.([]string); ok && tags == nil {
+		v = []string{}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+
+	return b
+}
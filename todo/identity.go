@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrIdentityExists   = errors.New("identity already exists")
+)
+
+// IdentityStorer represents the behavior this package needs to link users to
+// the accounts they log in with at external identity providers.
+//
+// An identity is unique by its issuer and subject. CreateIdentity returns
+// ErrIdentityExists when the issuer and subject are already linked.
+type IdentityStorer interface {
+	QueryIdentity(ctx context.Context, issuer string, subject string) (Identity, error)
+	CreateIdentity(ctx context.Context, identity Identity) error
+}
+
+// Identity links a user to their account at an external identity provider,
+// which is identified by the issuer of the provider and the subject the
+// provider knows the account by.
+type Identity struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	TimeCreated time.Time `json:"time_created"`
+}
+
+// ExternalUser is someone who was authenticated by an external identity
+// provider. Name is the name they would like to have, which is used when a
+// user is created for them.
+type ExternalUser struct {
+	Issuer  string
+	Subject string
+	Name    string
+}
+
+// LoginExternal starts a session for a user authenticated by an external
+// identity provider. The first time they log in a user is created for them,
+// named after them unless the name is taken. Existing users are never
+// linked by name alone, since the provider cannot vouch for who owns them.
+func (s *Core) LoginExternal(ctx context.Context, ext ExternalUser) (NewSession, error) {
+	if ext.Issuer == "" || ext.Subject == "" {
+		return NewSession{}, ErrUnauthenticated
+	}
+
+	var user User
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		identity, err := txCore.storer.QueryIdentity(ctx, ext.Issuer, ext.Subject)
+		if err == nil {
+			user, err = txCore.storer.QueryUserByID(ctx, identity.UserID)
+			if err != nil {
+				return fmt.Errorf("query user [%s]: %w", identity.UserID, err)
+			}
+			return nil
+		}
+		if !errors.Is(err, ErrIdentityNotFound) {
+			return fmt.Errorf("query identity: %w", err)
+		}
+
+		user, err = txCore.provisionUser(ctx, ext)
+		return err
+	})
+	if err != nil {
+		return NewSession{}, err
+	}
+
+	user.PasswordHash = ""
+
+	return s.startSession(ctx, user)
+}
+
+// provisionUser creates a user for someone logging in with an external
+// identity provider for the first time and links them to it.
+func (s *Core) provisionUser(ctx context.Context, ext ExternalUser) (User, error) {
+	name := strings.TrimSpace(ext.Name)
+	if name == "" {
+		name = ext.Subject
+	}
+
+	// A taken name gets a suffix derived from the identity so that logging
+	// in again does not depend on what else was created in between.
+	sum := sha256.Sum256([]byte(ext.Issuer + "\x00" + ext.Subject))
+	names := []string{name, name + "-" + hex.EncodeToString(sum[:4])}
+
+	var user User
+	var err error
+
+	for _, name := range names {
+		user, err = s.CreateUser(ctx, UserCreateParams{Name: name})
+		if !errors.Is(err, ErrUserExists) {
+			break
+		}
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("create user: %w", err)
+	}
+
+	identity := Identity{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Issuer:      ext.Issuer,
+		Subject:     ext.Subject,
+		TimeCreated: user.TimeCreated,
+	}
+
+	if err := s.storer.CreateIdentity(ctx, identity); err != nil {
+		return User{}, fmt.Errorf("create identity: %w", err)
+	}
+
+	return user, nil
+}
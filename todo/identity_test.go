@@ -0,0 +1,68 @@
+package todo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestLoginExternal(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	// A local user with the same name is not taken over.
+	local, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "alice", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	ext := todo.ExternalUser{Issuer: "https://id.example.com", Subject: "1234", Name: "alice"}
+
+	first, err := todoCore.LoginExternal(ctx, ext)
+	if err != nil {
+		t.Fatalf("login external: expected nil error, got %v", err)
+	}
+
+	user, _, err := todoCore.AuthenticateSession(ctx, first.Token)
+	if err != nil {
+		t.Fatalf("authenticate session: expected nil error, got %v", err)
+	}
+	if user.ID == local.ID || user.Name == local.Name {
+		t.Fatalf("login external: expected a new user, got %v", user)
+	}
+
+	// Logging in again finds the same user, even when their name changed.
+	ext.Name = "alice.smith"
+
+	second, err := todoCore.LoginExternal(ctx, ext)
+	if err != nil {
+		t.Fatalf("login external: expected nil error, got %v", err)
+	}
+
+	again, _, err := todoCore.AuthenticateSession(ctx, second.Token)
+	if err != nil {
+		t.Fatalf("authenticate session: expected nil error, got %v", err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("login external: expected user %v, got %v", user.ID, again.ID)
+	}
+
+	// The same subject at another issuer is someone else.
+	other, err := todoCore.LoginExternal(ctx, todo.ExternalUser{Issuer: "https://other.example.com", Subject: "1234", Name: "bob"})
+	if err != nil {
+		t.Fatalf("login external: expected nil error, got %v", err)
+	}
+
+	bob, _, err := todoCore.AuthenticateSession(ctx, other.Token)
+	if err != nil || bob.ID == user.ID || bob.Name != "bob" {
+		t.Fatalf("login external: expected a new user named bob, got %v, %v", bob, err)
+	}
+
+	if _, err := todoCore.LoginExternal(ctx, todo.ExternalUser{Issuer: ext.Issuer}); !errors.Is(err, todo.ErrUnauthenticated) {
+		t.Fatalf("login external: expected %v without a subject, got %v", todo.ErrUnauthenticated, err)
+	}
+}
@@ -0,0 +1,332 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrListNotFound = errors.New("list not found")
+)
+
+// ListStorer represents the behavior this package needs to manage lists.
+type ListStorer interface {
+	QueryLists(ctx context.Context) ([]List, error)
+	QueryListByID(ctx context.Context, id uuid.UUID) (List, error)
+	CreateList(ctx context.Context, list List) error
+	UpdateList(ctx context.Context, list List) error
+	DeleteList(ctx context.Context, list List) error
+}
+
+// QueryLists retrieves the lists of the workspace in ctx the user in ctx can
+// see, starting with the inbox of the default workspace.
+func (s *Core) QueryLists(ctx context.Context) ([]List, error) {
+	ws, err := s.workspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.storer.QueryLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query lists: %w", err)
+	}
+
+	visible := make([]List, 0, len(lists))
+	for _, l := range lists {
+		ok, err := s.listVisible(ctx, ws, l)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, l)
+		}
+	}
+
+	return visible, nil
+}
+
+// QueryListByID retrieves a list by its ID. Lists the user in ctx cannot see
+// are not found.
+func (s *Core) QueryListByID(ctx context.Context, id uuid.UUID) (List, error) {
+	l, err := s.visibleList(ctx, id)
+	if err != nil {
+		return List{}, fmt.Errorf("query list by id: %w", err)
+	}
+
+	return l, nil
+}
+
+// CreateList adds a list into the store.
+func (s *Core) CreateList(ctx context.Context, params ListCreateParams) (List, error) {
+	if err := params.Validate(); err != nil {
+		return List{}, fmt.Errorf("validate: %w", err)
+	}
+
+	now := time.Now()
+
+	list := List{
+		ID:          uuid.New(),
+		WorkspaceID: WorkspaceFrom(ctx),
+		OwnerID:     OwnerFrom(ctx),
+		Name:        params.Name,
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	if err := s.storer.CreateList(ctx, list); err != nil {
+		return List{}, fmt.Errorf("create list: %w", err)
+	}
+
+	return list, nil
+}
+
+// UpdateList modifies an existing list. Only the owner of the list and the
+// admins of its workspace may change it.
+func (s *Core) UpdateList(ctx context.Context, list List, params ListUpdateParams) (List, error) {
+	if err := params.Validate(); err != nil {
+		return List{}, fmt.Errorf("validate: %w", err)
+	}
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		var err error
+		list, err = txCore.currentList(ctx, list)
+		if err != nil {
+			return fmt.Errorf("update list: %w", err)
+		}
+
+		if params.Name != nil {
+			list.Name = *params.Name
+		}
+
+		list.TimeUpdated = time.Now()
+
+		if err := txCore.storer.UpdateList(ctx, list); err != nil {
+			return fmt.Errorf("update list: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return List{}, err
+	}
+
+	return list, nil
+}
+
+// DeleteList deletes the specified list. The todo items of the list are moved
+// to the default list of the workspace and, with DeletePolicyCascade, to the
+// trash as well so that they can still be restored. The default list itself
+// cannot be deleted. Either all of the todo items are moved and the list is
+// deleted or nothing changes.
+//
+// Only the owner of the list and the admins of its workspace may delete it,
+// and only the todo items of the user in ctx are moved. Lists that still hold
+// todo items of other users cannot be deleted.
+func (s *Core) DeleteList(ctx context.Context, list List, policy DeletePolicy) error {
+	ws, err := s.workspace(ctx)
+	if err != nil {
+		return err
+	}
+
+	if list.ID == ws.Settings.DefaultListID {
+		return NewValidationError(errors.New("the default list cannot be deleted"))
+	}
+
+	if policy != DeletePolicyInbox && policy != DeletePolicyCascade {
+		return NewValidationError(fmt.Errorf(
+			"invalid policy %q: must be one of [%v, %v]",
+			string(policy),
+			DeletePolicyInbox,
+			DeletePolicyCascade,
+		))
+	}
+
+	return s.WithTx(ctx, func(txCore *Core) error {
+		list, err := txCore.currentList(ctx, list)
+		if err != nil {
+			return fmt.Errorf("delete list: %w", err)
+		}
+
+		return txCore.deleteList(ctx, list, ws.Settings.DefaultListID, policy)
+	})
+}
+
+// deleteList deletes the specified list after its policy has been validated,
+// moving its todo items to the list given by defaultListID.
+func (s *Core) deleteList(ctx context.Context, list List, defaultListID uuid.UUID, policy DeletePolicy) error {
+	listID := list.ID
+	todos := make([]Todo, 0)
+
+	// Todo items in the trash still belong to the list, so they have to be
+	// moved out of it as well.
+	for _, trashed := range []bool{false, true} {
+		found, err := s.storer.Query(ctx, QueryOptions{
+			Filter: QueryFilter{OwnerID: ownerFilter(ctx), ListID: &listID, Trashed: trashed},
+		}.withDefaults())
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		todos = append(todos, found...)
+
+		others, err := s.storer.Query(ctx, QueryOptions{
+			Filter: QueryFilter{ListID: &listID, Trashed: trashed},
+			Limit:  len(found) + 1,
+		}.withDefaults())
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		if len(others) > len(found) {
+			return NewValidationError(errors.New("the list holds todo items of other users"))
+		}
+	}
+
+	now := time.Now()
+
+	for _, todo := range todos {
+		before := todo
+
+		todo.ListID = defaultListID
+		todo.Version++
+		todo.TimeUpdated = now
+
+		ev := newEvent(ctx, EventUpdated, todo, diff(before, todo), now)
+
+		if err := s.storer.Update(ctx, todo, ev); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+
+		if err := s.changed(ctx, ev, todo); err != nil {
+			return err
+		}
+
+		if policy == DeletePolicyCascade && todo.TimeDeleted == nil {
+			before := todo
+			todo.TimeDeleted = &now
+
+			ev := newEvent(ctx, EventDeleted, todo, diff(before, todo), now)
+
+			if err := s.storer.Delete(ctx, todo, ev); err != nil {
+				return fmt.Errorf("delete: %w", err)
+			}
+
+			if err := s.changed(ctx, ev, todo); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.storer.DeleteList(ctx, list); err != nil {
+		return fmt.Errorf("delete list: %w", err)
+	}
+
+	return nil
+}
+
+// currentList re-reads list from the store. It returns ErrListNotFound when
+// the user in ctx cannot see it and ErrForbidden unless they own it or are an
+// admin of its workspace.
+func (s *Core) currentList(ctx context.Context, list List) (List, error) {
+	stored, err := s.visibleList(ctx, list.ID)
+	if err != nil {
+		return List{}, err
+	}
+
+	if stored.OwnerID == OwnerFrom(ctx) {
+		return stored, nil
+	}
+
+	ws, err := s.workspace(ctx)
+	if err != nil {
+		return List{}, err
+	}
+
+	if err := s.authorizeWorkspace(ctx, ws); err != nil {
+		return List{}, err
+	}
+
+	return stored, nil
+}
+
+// visibleList retrieves the list given by id, returning ErrListNotFound when
+// the user in ctx cannot see it.
+func (s *Core) visibleList(ctx context.Context, id uuid.UUID) (List, error) {
+	l, err := s.storer.QueryListByID(ctx, id)
+	if err != nil {
+		return List{}, err
+	}
+
+	ws, err := s.workspace(ctx)
+	if err != nil {
+		return List{}, err
+	}
+
+	ok, err := s.listVisible(ctx, ws, l)
+	if err != nil {
+		return List{}, err
+	}
+	if !ok {
+		return List{}, ErrListNotFound
+	}
+
+	return l, nil
+}
+
+// listVisible reports whether the user in ctx can see list. Lists are private
+// to their owner, apart from the default list of a workspace, which is shared
+// with everyone in it. The admins of a workspace see all of its lists.
+func (s *Core) listVisible(ctx context.Context, ws Workspace, list List) (bool, error) {
+	if list.ID == ws.Settings.DefaultListID || list.OwnerID == OwnerFrom(ctx) {
+		return true, nil
+	}
+
+	err := s.authorizeWorkspace(ctx, ws)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrWorkspaceNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// checkList returns a validation error wrapping ErrListNotFound if the list
+// given by id does not exist or the user in ctx cannot see it, and
+// ErrForbidden if the todo items of ownerID may not go into it. Todo items
+// only go into the lists of their owner and the default list of their
+// workspace, so that nobody can keep a list of someone else from being
+// deleted.
+func (s *Core) checkList(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error {
+	l, err := s.storer.QueryListByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrListNotFound) {
+			return NewValidationError(fmt.Errorf("list %s does not exist: %w", id, ErrListNotFound))
+		}
+		return fmt.Errorf("query list by id: %w", err)
+	}
+
+	ws, err := s.workspace(ctx)
+	if err != nil {
+		return err
+	}
+
+	if l.ID == ws.Settings.DefaultListID || l.OwnerID == ownerID {
+		return nil
+	}
+
+	ok, err := s.listVisible(ctx, ws, l)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewValidationError(fmt.Errorf("list %s does not exist: %w", id, ErrListNotFound))
+	}
+
+	return ErrForbidden
+}
@@ -3,6 +3,7 @@ package todo
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,12 +11,28 @@ import (
 
 // Todo represents a todo item.
 type Todo struct {
-	ID          uuid.UUID `json:"id"`
-	Text        string    `json:"text"`
-	Priority    Priority  `json:"priority"`
-	Completed   bool      `json:"completed"`
-	TimeCreated time.Time `json:"time_created"`
-	TimeUpdated time.Time `json:"time_updated"`
+	ID          uuid.UUID  `json:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	Text        string     `json:"text"`
+	Priority    Priority   `json:"priority"`
+	Completed   bool       `json:"completed"`
+	ListID      uuid.UUID  `json:"list_id"`
+	ParentID    *uuid.UUID `json:"parent_id"`
+	DueAt       *time.Time `json:"due_at"`
+	Recurrence  string     `json:"recurrence"`
+	NextID      *uuid.UUID `json:"next_id"`
+	Tags        []string   `json:"tags"`
+	Version     int        `json:"version"`
+	TimeCreated time.Time  `json:"time_created"`
+	TimeUpdated time.Time  `json:"time_updated"`
+	TimeDeleted *time.Time `json:"time_deleted"`
+}
+
+// Overdue reports whether the todo item has passed its due date as of now
+// without being completed.
+func (t Todo) Overdue(now time.Time) bool {
+	return !t.Completed && t.DueAt != nil && t.DueAt.Before(now)
 }
 
 // Priority is an enum that represents the different priorities a todo item can
@@ -28,10 +45,69 @@ const (
 	PriorityHigh   Priority = "high"
 )
 
+// MaxTags is the maximum number of tags a todo item can have.
+const MaxTags = 16
+
+// tagPattern is the syntax tags must follow: lowercase letters, digits,
+// hyphens and underscores, starting with a letter or digit, and at most 32
+// characters long.
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// TagCount represents a tag and the number of todo items that use it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// validateTags returns an error for each rule the tags violate.
+func validateTags(tags []string) []error {
+	errs := make([]error, 0)
+
+	if len(tags) > MaxTags {
+		errs = append(errs, fmt.Errorf("too many tags: must be at most %d", MaxTags))
+	}
+
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fmt.Errorf(
+				"invalid tag %q: must be 1-32 lowercase letters, digits, hyphens or underscores starting with a letter or digit",
+				tag,
+			))
+		}
+		if seen[tag] {
+			errs = append(errs, fmt.Errorf("duplicate tag %q", tag))
+		}
+		seen[tag] = true
+	}
+
+	return errs
+}
+
+// Rank orders priorities from lowest to highest. Unknown priorities rank
+// lowest.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	default:
+		return 0
+	}
+}
+
 // TodoCreateParams are what we require from clients to create a todo item.
 type TodoCreateParams struct {
-	Text     string   `json:"text"`
-	Priority Priority `json:"priority"`
+	Text       string     `json:"text"`
+	Priority   Priority   `json:"priority"`
+	ListID     *uuid.UUID `json:"list_id"`
+	ParentID   *uuid.UUID `json:"parent_id"`
+	DueAt      *time.Time `json:"due_at"`
+	Recurrence string     `json:"recurrence"`
+	Tags       []string   `json:"tags"`
 }
 
 // Validate validates the TodoCreateOptions.
@@ -52,6 +128,14 @@ func (t TodoCreateParams) Validate() error {
 		))
 	}
 
+	if t.Recurrence != "" {
+		if _, err := ParseRecurrence(t.Recurrence); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, validateTags(t.Tags)...)
+
 	err := errors.Join(errs...)
 
 	if err != nil {
@@ -63,11 +147,20 @@ func (t TodoCreateParams) Validate() error {
 
 // TodoUpdateParams represents the information that clients can modify for a
 // todo item. Pointers are used to determine whether or not a field was
-// provided by the client.
+// provided by the client. Since a null value cannot be told apart from a
+// missing one, ClearParent and ClearDueAt are used to remove an existing
+// parent or due date. An empty Recurrence stops the todo item from recurring.
 type TodoUpdateParams struct {
-	Text      *string   `json:"text"`
-	Priority  *Priority `json:"priority"`
-	Completed *bool     `json:"completed"`
+	Text        *string    `json:"text"`
+	Priority    *Priority  `json:"priority"`
+	Completed   *bool      `json:"completed"`
+	ListID      *uuid.UUID `json:"list_id"`
+	ParentID    *uuid.UUID `json:"parent_id"`
+	ClearParent bool       `json:"clear_parent"`
+	DueAt       *time.Time `json:"due_at"`
+	ClearDueAt  bool       `json:"clear_due_at"`
+	Recurrence  *string    `json:"recurrence"`
+	Tags        *[]string  `json:"tags"`
 }
 
 // Validate validates the TodoUpdateOptions.
@@ -90,6 +183,24 @@ func (t TodoUpdateParams) Validate() error {
 		}
 	}
 
+	if t.ParentID != nil && t.ClearParent {
+		errs = append(errs, errors.New("parent_id and clear_parent are mutually exclusive"))
+	}
+
+	if t.DueAt != nil && t.ClearDueAt {
+		errs = append(errs, errors.New("due_at and clear_due_at are mutually exclusive"))
+	}
+
+	if t.Recurrence != nil && *t.Recurrence != "" {
+		if _, err := ParseRecurrence(*t.Recurrence); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if t.Tags != nil {
+		errs = append(errs, validateTags(*t.Tags)...)
+	}
+
 	err := errors.Join(errs...)
 
 	if err != nil {
@@ -98,3 +209,72 @@ func (t TodoUpdateParams) Validate() error {
 
 	return nil
 }
+
+// InboxID is the ID of the inbox list of the default workspace. The inbox
+// always exists, cannot be deleted, and holds todo items that were not created
+// in a specific list.
+var InboxID = uuid.Nil
+
+// List represents a list of todo items, such as a project.
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	TimeCreated time.Time `json:"time_created"`
+	TimeUpdated time.Time `json:"time_updated"`
+}
+
+// ListCreateParams are what we require from clients to create a list.
+type ListCreateParams struct {
+	Name string `json:"name"`
+}
+
+// Validate validates the ListCreateParams.
+func (l ListCreateParams) Validate() error {
+	if l.Name == "" {
+		return NewValidationError(errors.New("missing required field name"))
+	}
+
+	return nil
+}
+
+// ListUpdateParams represents the information that clients can modify for a
+// list.
+type ListUpdateParams struct {
+	Name *string `json:"name"`
+}
+
+// Validate validates the ListUpdateParams.
+func (l ListUpdateParams) Validate() error {
+	if l.Name != nil && *l.Name == "" {
+		return NewValidationError(errors.New("missing required field name"))
+	}
+
+	return nil
+}
+
+// DeletePolicy is an enum that represents what happens to the todo items of a
+// list when the list is deleted.
+type DeletePolicy string
+
+const (
+	// DeletePolicyInbox moves the todo items of the list to the default list
+	// of the workspace, which is its inbox unless changed.
+	DeletePolicyInbox DeletePolicy = "inbox"
+	// DeletePolicyCascade moves the todo items of the list to the trash.
+	DeletePolicyCascade DeletePolicy = "cascade"
+)
+
+// CompletionPolicy is an enum that represents what happens when a todo item
+// with incomplete children is completed.
+type CompletionPolicy string
+
+const (
+	// CompletionBlock refuses to complete a todo item while any of its
+	// descendants are incomplete.
+	CompletionBlock CompletionPolicy = "block"
+	// CompletionCascade completes every incomplete descendant along with the
+	// todo item.
+	CompletionCascade CompletionPolicy = "cascade"
+)
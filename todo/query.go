@@ -0,0 +1,342 @@
+package todo
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderField is an enum that represents the fields todo items can be sorted
+// by.
+type OrderField string
+
+const (
+	OrderByTimeCreated OrderField = "time_created"
+	OrderByTimeUpdated OrderField = "time_updated"
+	OrderByDueAt       OrderField = "due_at"
+	OrderByPriority    OrderField = "priority"
+	OrderByText        OrderField = "text"
+)
+
+// Direction is an enum that represents the direction todo items are sorted in.
+type Direction string
+
+const (
+	DirectionAsc  Direction = "asc"
+	DirectionDesc Direction = "desc"
+)
+
+// QueryFilter narrows down the todo items returned by a query. Fields that are
+// nil are not filtered on. Time ranges include their After bound and exclude
+// their Before bound. Todo items must have every tag in Tags to match. Only
+// todo items in the trash are returned when Trashed is set, and only those
+// outside of it otherwise. Core always sets OwnerID to the user the query is
+// made for.
+type QueryFilter struct {
+	OwnerID       *uuid.UUID
+	Trashed       bool
+	ListID        *uuid.UUID
+	Tags          []string
+	Completed     *bool
+	Priority      *Priority
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+}
+
+// QueryOptions controls which todo items a query returns and in what order.
+// The zero value returns every todo item ordered by creation time. A Limit of
+// 0 means no limit.
+//
+// After is a keyset alternative to Offset that only returns todo items
+// positioned after the cursor. It is stable when todo items are created or
+// deleted between pages but requires ordering by creation time.
+type QueryOptions struct {
+	Filter    QueryFilter
+	OrderBy   OrderField
+	Direction Direction
+	Limit     int
+	Offset    int
+	After     *Cursor
+}
+
+// Cursor is a position in a listing of todo items ordered by creation time.
+// Todo items created at the same instant are ordered by ID.
+type Cursor struct {
+	TimeCreated time.Time
+	ID          uuid.UUID
+}
+
+// CursorFor returns the cursor positioned at td.
+func CursorFor(td Todo) Cursor {
+	return Cursor{
+		TimeCreated: td.TimeCreated,
+		ID:          td.ID,
+	}
+}
+
+// String encodes the cursor into an opaque token.
+func (c Cursor) String() string {
+	raw := c.TimeCreated.UTC().Format(time.RFC3339Nano) + "," + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a token created by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, NewValidationError(fmt.Errorf("invalid cursor %q", s))
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return Cursor{}, NewValidationError(fmt.Errorf("invalid cursor %q", s))
+	}
+
+	var c Cursor
+
+	if c.TimeCreated, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+		return Cursor{}, NewValidationError(fmt.Errorf("invalid cursor %q", s))
+	}
+
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return Cursor{}, NewValidationError(fmt.Errorf("invalid cursor %q", s))
+	}
+
+	return c, nil
+}
+
+// Validate validates the QueryOptions.
+func (o QueryOptions) Validate() error {
+	errs := make([]error, 0)
+
+	if p := o.Filter.Priority; p != nil {
+		if *p != PriorityHigh && *p != PriorityMedium && *p != PriorityLow {
+			errs = append(errs, fmt.Errorf(
+				"invalid priority %q: must be one of [%v, %v, %v]",
+				string(*p),
+				PriorityLow,
+				PriorityMedium,
+				PriorityHigh,
+			))
+		}
+	}
+
+	switch o.OrderBy {
+	case "", OrderByTimeCreated, OrderByTimeUpdated, OrderByDueAt, OrderByPriority, OrderByText:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"invalid order_by %q: must be one of [%v, %v, %v, %v, %v]",
+			string(o.OrderBy),
+			OrderByTimeCreated,
+			OrderByTimeUpdated,
+			OrderByDueAt,
+			OrderByPriority,
+			OrderByText,
+		))
+	}
+
+	switch o.Direction {
+	case "", DirectionAsc, DirectionDesc:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"invalid direction %q: must be one of [%v, %v]",
+			string(o.Direction),
+			DirectionAsc,
+			DirectionDesc,
+		))
+	}
+
+	if o.Limit < 0 {
+		errs = append(errs, errors.New("limit must not be negative"))
+	}
+
+	if o.Offset < 0 {
+		errs = append(errs, errors.New("offset must not be negative"))
+	}
+
+	if o.After != nil {
+		if o.Offset != 0 {
+			errs = append(errs, errors.New("cursor and offset are mutually exclusive"))
+		}
+		if o.OrderBy != "" && o.OrderBy != OrderByTimeCreated {
+			errs = append(errs, fmt.Errorf("cursor requires order_by %v", OrderByTimeCreated))
+		}
+	}
+
+	for _, tag := range o.Filter.Tags {
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fmt.Errorf("invalid tag %q", tag))
+		}
+	}
+
+	err := errors.Join(errs...)
+
+	if err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// withDefaults returns a copy of the QueryOptions with the default order
+// filled in so that stores never have to guess.
+func (o QueryOptions) withDefaults() QueryOptions {
+	if o.OrderBy == "" {
+		o.OrderBy = OrderByTimeCreated
+	}
+	if o.Direction == "" {
+		o.Direction = DirectionAsc
+	}
+	return o
+}
+
+// Values encodes the QueryOptions as URL query parameters.
+func (o QueryOptions) Values() url.Values {
+	v := make(url.Values)
+
+	if o.Filter.Trashed {
+		v.Set("trashed", "true")
+	}
+	if o.Filter.ListID != nil {
+		v.Set("list", o.Filter.ListID.String())
+	}
+	if o.Filter.Completed != nil {
+		v.Set("completed", strconv.FormatBool(*o.Filter.Completed))
+	}
+	if o.Filter.Priority != nil {
+		v.Set("priority", string(*o.Filter.Priority))
+	}
+	for _, tag := range o.Filter.Tags {
+		v.Add("tag", tag)
+	}
+
+	setTime := func(key string, t *time.Time) {
+		if t != nil {
+			v.Set(key, t.Format(time.RFC3339Nano))
+		}
+	}
+	setTime("created_after", o.Filter.CreatedAfter)
+	setTime("created_before", o.Filter.CreatedBefore)
+	setTime("updated_after", o.Filter.UpdatedAfter)
+	setTime("updated_before", o.Filter.UpdatedBefore)
+
+	if o.OrderBy != "" {
+		v.Set("order_by", string(o.OrderBy))
+	}
+	if o.Direction != "" {
+		v.Set("direction", string(o.Direction))
+	}
+	if o.Limit != 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset != 0 {
+		v.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.After != nil {
+		v.Set("cursor", o.After.String())
+	}
+
+	return v
+}
+
+// ParseQueryOptions decodes QueryOptions from URL query parameters as encoded
+// by QueryOptions.Values.
+func ParseQueryOptions(v url.Values) (QueryOptions, error) {
+	var o QueryOptions
+	errs := make([]error, 0)
+
+	if s := v.Get("trashed"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid trashed %q: must be a boolean", s))
+		}
+		o.Filter.Trashed = b
+	}
+
+	if s := v.Get("list"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid list %q: must be a UUID", s))
+		}
+		o.Filter.ListID = &id
+	}
+
+	if s := v.Get("completed"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid completed %q: must be a boolean", s))
+		}
+		o.Filter.Completed = &b
+	}
+
+	if s := v.Get("priority"); s != "" {
+		p := Priority(s)
+		o.Filter.Priority = &p
+	}
+
+	o.Filter.Tags = v["tag"]
+
+	parseTime := func(key string) *time.Time {
+		s := v.Get(key)
+		if s == "" {
+			return nil
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: must be an RFC 3339 timestamp", key, s))
+			return nil
+		}
+
+		return &t
+	}
+	o.Filter.CreatedAfter = parseTime("created_after")
+	o.Filter.CreatedBefore = parseTime("created_before")
+	o.Filter.UpdatedAfter = parseTime("updated_after")
+	o.Filter.UpdatedBefore = parseTime("updated_before")
+
+	o.OrderBy = OrderField(v.Get("order_by"))
+	o.Direction = Direction(v.Get("direction"))
+
+	parseInt := func(key string) int {
+		s := v.Get(key)
+		if s == "" {
+			return 0
+		}
+
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: must be an integer", key, s))
+		}
+
+		return i
+	}
+	o.Limit = parseInt("limit")
+	o.Offset = parseInt("offset")
+
+	if s := v.Get("cursor"); s != "" {
+		c, err := ParseCursor(s)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		o.After = &c
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return QueryOptions{}, NewValidationError(err)
+	}
+
+	if err := o.Validate(); err != nil {
+		return QueryOptions{}, err
+	}
+
+	return o, nil
+}
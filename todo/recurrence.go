@@ -0,0 +1,313 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is an enum that represents how often a todo item recurs.
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// Recurrence is a schedule for a recurring todo item. It supports the FREQ,
+// INTERVAL, BYDAY, COUNT and UNTIL parts of an RFC 5545 recurrence rule.
+// BYDAY is limited to plain weekdays and is only supported for daily and
+// weekly schedules. Weeks start on Monday.
+//
+// Count is the number of occurrences left including the current one, so each
+// generated instance carries a count one lower than the instance it replaces.
+// A Count of 0 means the schedule does not end after a number of occurrences.
+type Recurrence struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time
+}
+
+// weekdays maps RFC 5545 weekday names to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// untilLayout and untilDateLayout are the UNTIL formats that are supported.
+const (
+	untilLayout     = "20060102T150405Z"
+	untilDateLayout = "20060102"
+)
+
+// ParseRecurrence parses a recurrence rule such as
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10". An optional "RRULE:" prefix
+// is ignored.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+	errs := make([]error, 0)
+
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			errs = append(errs, fmt.Errorf("invalid recurrence part %q: must be NAME=VALUE", part))
+			continue
+		}
+
+		name = strings.ToUpper(name)
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("duplicate recurrence part %s", name))
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			switch r.Freq {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+			default:
+				errs = append(errs, fmt.Errorf(
+					"invalid FREQ %q: must be one of [%v, %v, %v, %v]",
+					value,
+					FrequencyDaily,
+					FrequencyWeekly,
+					FrequencyMonthly,
+					FrequencyYearly,
+				))
+			}
+
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				errs = append(errs, fmt.Errorf("invalid INTERVAL %q: must be a positive integer", value))
+			}
+			r.Interval = interval
+
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				errs = append(errs, fmt.Errorf("invalid COUNT %q: must be a positive integer", value))
+			}
+			r.Count = count
+
+		case "UNTIL":
+			until, err := time.Parse(untilLayout, value)
+			if err != nil {
+				// A date without a time includes the whole day.
+				until, err = time.Parse(untilDateLayout, value)
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid UNTIL %q: must be formatted as %s or %s", value, untilLayout, untilDateLayout))
+			}
+			r.Until = &until
+
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					errs = append(errs, fmt.Errorf("invalid BYDAY %q: must be one of [MO, TU, WE, TH, FR, SA, SU]", day))
+					continue
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+
+		default:
+			errs = append(errs, fmt.Errorf("unsupported recurrence part %s", name))
+		}
+	}
+
+	if r.Freq == "" {
+		errs = append(errs, errors.New("missing required recurrence part FREQ"))
+	}
+
+	if len(r.ByDay) > 0 && r.Freq != FrequencyDaily && r.Freq != FrequencyWeekly {
+		errs = append(errs, fmt.Errorf("BYDAY is only supported with FREQ %v or %v", FrequencyDaily, FrequencyWeekly))
+	}
+
+	if r.Count != 0 && r.Until != nil {
+		errs = append(errs, errors.New("COUNT and UNTIL are mutually exclusive"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Recurrence{}, NewValidationError(err)
+	}
+
+	return r, nil
+}
+
+// String formats the recurrence as a recurrence rule.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			days = append(days, strings.ToUpper(wd.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after prev, which must itself be an
+// occurrence of the schedule, along with the schedule for that occurrence.
+// It returns false when the schedule has ended.
+func (r Recurrence) Next(prev time.Time) (time.Time, Recurrence, bool) {
+	if r.Count == 1 {
+		return time.Time{}, Recurrence{}, false
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var (
+		next time.Time
+		ok   bool
+	)
+
+	switch r.Freq {
+	case FrequencyDaily:
+		next, ok = r.nextDaily(prev, interval)
+	case FrequencyWeekly:
+		next, ok = r.nextWeekly(prev, interval)
+	case FrequencyMonthly:
+		next, ok = nextMonthly(prev, interval)
+	case FrequencyYearly:
+		next, ok = nextMonthly(prev, 12*interval)
+	}
+
+	if !ok || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, Recurrence{}, false
+	}
+
+	if r.Count > 1 {
+		r.Count--
+	}
+
+	return next, r, true
+}
+
+// nextDaily steps forward interval days at a time until it lands on one of
+// the BYDAY weekdays, if any.
+func (r Recurrence) nextDaily(prev time.Time, interval int) (time.Time, bool) {
+	next := prev
+	for i := 0; i < 7; i++ {
+		next = next.AddDate(0, 0, interval)
+		if r.onDay(next) {
+			return next, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// nextWeekly finds the next BYDAY weekday in a week that is a multiple of
+// interval weeks after the week of prev. Without BYDAY the weekday of prev is
+// used.
+func (r Recurrence) nextWeekly(prev time.Time, interval int) (time.Time, bool) {
+	if len(r.ByDay) == 0 {
+		return prev.AddDate(0, 0, 7*interval), true
+	}
+
+	start := startOfWeek(prev)
+
+	for i := 1; i <= 7*(interval+1); i++ {
+		next := prev.AddDate(0, 0, i)
+
+		weeks := daysBetween(start, startOfWeek(next)) / 7
+		if weeks%interval == 0 && r.onDay(next) {
+			return next, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// nextMonthly steps forward interval months at a time, skipping months that
+// do not have the day of the month of prev as RFC 5545 requires.
+func nextMonthly(prev time.Time, interval int) (time.Time, bool) {
+	year, month, day := prev.Date()
+	hour, minute, sec := prev.Clock()
+
+	// Bound the search so that a schedule that never matches ends instead of
+	// looping forever. Even February 29 is found well within the bound.
+	for i := 1; i <= 8*12; i++ {
+		m := int(month) - 1 + i*interval
+		y := year + m/12
+		mo := time.Month(m%12 + 1)
+
+		if day <= daysIn(y, mo) {
+			return time.Date(y, mo, day, hour, minute, sec, prev.Nanosecond(), prev.Location()), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// onDay reports whether t falls on one of the BYDAY weekdays. Every day
+// matches when BYDAY is empty.
+func (r Recurrence) onDay(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	for _, wd := range r.ByDay {
+		if t.Weekday() == wd {
+			return true
+		}
+	}
+
+	return false
+}
+
+// startOfWeek returns the Monday of the week t falls in.
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// daysBetween returns the number of calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+
+	da := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	db := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// daysIn returns the number of days in the given month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
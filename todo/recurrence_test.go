@@ -0,0 +1,221 @@
+package todo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := map[string]struct {
+		rule     string
+		prev     time.Time
+		want     time.Time
+		wantRule string
+		wantEnd  bool
+	}{
+		"daily with interval": {
+			rule:     "FREQ=DAILY;INTERVAL=3",
+			prev:     date(2026, time.October, 17),
+			want:     date(2026, time.October, 20),
+			wantRule: "FREQ=DAILY;INTERVAL=3",
+		},
+		"daily on weekdays skips the weekend": {
+			rule:     "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			prev:     date(2026, time.October, 16),
+			want:     date(2026, time.October, 19),
+			wantRule: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+		},
+		"weekly on the same weekday": {
+			rule:     "FREQ=WEEKLY",
+			prev:     date(2026, time.October, 15),
+			want:     date(2026, time.October, 22),
+			wantRule: "FREQ=WEEKLY",
+		},
+		"weekly by day wraps into the next week": {
+			rule:     "FREQ=WEEKLY;BYDAY=MO,TH",
+			prev:     date(2026, time.October, 15),
+			want:     date(2026, time.October, 19),
+			wantRule: "FREQ=WEEKLY;BYDAY=MO,TH",
+		},
+		"biweekly by day within the week": {
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			prev:     date(2026, time.October, 19),
+			want:     date(2026, time.October, 23),
+			wantRule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		},
+		"biweekly by day skips a week": {
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			prev:     date(2026, time.October, 23),
+			want:     date(2026, time.November, 2),
+			wantRule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		},
+		"monthly skips months without the day": {
+			rule:     "FREQ=MONTHLY",
+			prev:     date(2026, time.January, 31),
+			want:     date(2026, time.March, 31),
+			wantRule: "FREQ=MONTHLY",
+		},
+		"yearly on a leap day": {
+			rule:     "FREQ=YEARLY",
+			prev:     date(2024, time.February, 29),
+			want:     date(2028, time.February, 29),
+			wantRule: "FREQ=YEARLY",
+		},
+		"count is decremented": {
+			rule:     "FREQ=DAILY;COUNT=2",
+			prev:     date(2026, time.October, 17),
+			want:     date(2026, time.October, 18),
+			wantRule: "FREQ=DAILY;COUNT=1",
+		},
+		"count ends the schedule": {
+			rule:    "FREQ=DAILY;COUNT=1",
+			prev:    date(2026, time.October, 17),
+			wantEnd: true,
+		},
+		"until includes the whole day": {
+			rule:     "FREQ=DAILY;UNTIL=20261020",
+			prev:     date(2026, time.October, 19),
+			want:     date(2026, time.October, 20),
+			wantRule: "FREQ=DAILY;UNTIL=20261020T235959Z",
+		},
+		"until ends the schedule": {
+			rule:    "FREQ=DAILY;UNTIL=20261020T000000Z",
+			prev:    date(2026, time.October, 19),
+			wantEnd: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("parse: expected nil error, got %v", err)
+			}
+
+			next, nextRule, ok := r.Next(tt.prev)
+			if ok == tt.wantEnd {
+				t.Fatalf("next: expected ok to be %v, got %v", !tt.wantEnd, ok)
+			}
+			if tt.wantEnd {
+				return
+			}
+
+			if !next.Equal(tt.want) {
+				t.Fatalf("next: expected %v, got %v", tt.want, next)
+			}
+			if nextRule.String() != tt.wantRule {
+				t.Fatalf("next: expected rule %q, got %q", tt.wantRule, nextRule.String())
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261020",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		if _, err := todo.ParseRecurrence(rule); err == nil {
+			t.Errorf("parse %q: expected error, got nil", rule)
+		}
+	}
+}
+
+func TestTodoRecurring(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	due := time.Date(2026, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	td, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:       "take out the trash",
+		Priority:   todo.PriorityMedium,
+		DueAt:      &due,
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=2",
+		Tags:       []string{"chores"},
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	completed := true
+	if _, err := todoCore.Update(context.Background(), td, todo.TodoUpdateParams{Completed: &completed}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	open := false
+	todos, err := todoCore.Query(context.Background(), todo.QueryOptions{
+		Filter: todo.QueryFilter{Completed: &open},
+	})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 1 {
+		t.Fatalf("query: expected 1 open todo, got %v", len(todos))
+	}
+
+	next := todos[0]
+	want := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	if next.DueAt == nil || !next.DueAt.Equal(want) {
+		t.Fatalf("next instance: expected due date %v, got %v", want, next.DueAt)
+	}
+	if next.Recurrence != "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=1" {
+		t.Fatalf("next instance: expected count to be decremented, got %q", next.Recurrence)
+	}
+
+	td, err = todoCore.QueryByID(context.Background(), td.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+	if td.NextID == nil || *td.NextID != next.ID {
+		t.Fatalf("completed instance: expected next id %v, got %v", next.ID, td.NextID)
+	}
+
+	// Reopening and completing the todo again does not create another
+	// instance.
+	td, err = todoCore.Update(context.Background(), td, todo.TodoUpdateParams{Completed: &open})
+	if err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+	if _, err := todoCore.Update(context.Background(), td, todo.TodoUpdateParams{Completed: &completed}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	todos, err = todoCore.Query(context.Background(), todo.QueryOptions{
+		Filter: todo.QueryFilter{Completed: &open},
+	})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 1 {
+		t.Fatalf("query: expected 1 open todo after completing again, got %v", len(todos))
+	}
+
+	// The last occurrence does not create another instance.
+	if _, err := todoCore.Update(context.Background(), next, todo.TodoUpdateParams{Completed: &completed}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	todos, err = todoCore.Query(context.Background(), todo.QueryOptions{
+		Filter: todo.QueryFilter{Completed: &open},
+	})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 0 {
+		t.Fatalf("query: expected no open todos, got %v", len(todos))
+	}
+}
@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Markers that surround the matched words in the snippet of a search result.
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// Search result limits.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ErrSearchUnsupported is returned when the store does not implement Searcher.
+var ErrSearchUnsupported = errors.New("search is not supported by the store")
+
+// Searcher is implemented by stores that support full-text search over the
+// text of todo items.
+//
+// Search returns the todo items outside of the trash whose text contains every
+// term of SearchTerms(params.Query), where a term matches any word it is a
+// prefix of. Results are ordered by descending rank, then by the time the todo
+// item was last updated, newest first. Each result carries a snippet of the
+// text with the matched words surrounded by HighlightStart and HighlightEnd.
+// At most params.Limit results are returned, and Core always sets it.
+type Searcher interface {
+	Search(ctx context.Context, params SearchParams) ([]SearchResult, error)
+}
+
+// SearchParams represents a full-text search over the todo items of OwnerID,
+// which Core always sets to the user the search is made for.
+type SearchParams struct {
+	OwnerID uuid.UUID
+	Query   string
+	Limit   int
+}
+
+// Validate validates the SearchParams.
+func (p SearchParams) Validate() error {
+	errs := make([]error, 0)
+
+	if len(SearchTerms(p.Query)) == 0 {
+		errs = append(errs, errors.New("search query must contain at least one word"))
+	}
+
+	if p.Limit < 0 || p.Limit > MaxSearchLimit {
+		errs = append(errs, fmt.Errorf("limit must be between 0 and %d", MaxSearchLimit))
+	}
+
+	err := errors.Join(errs...)
+
+	if err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// SearchResult represents a todo item matching a search. A higher rank is a
+// better match. Ranks are only comparable within the results of one search.
+type SearchResult struct {
+	Todo    Todo    `json:"todo"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchTerms splits a search query into lowercase terms. Terms are runs of
+// letters and digits, and every other character separates them. Duplicate
+// terms are dropped.
+func SearchTerms(query string) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, term := range strings.FieldsFunc(strings.ToLower(query), IsSeparator) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// IsSeparator reports whether r separates the words of a todo item for
+// full-text search.
+func IsSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Search returns the todo items whose text matches params.Query, best match
+// first. It returns ErrSearchUnsupported when the store cannot search.
+func (s *Core) Search(ctx context.Context, params SearchParams) ([]SearchResult, error) {
+	searcher, ok := s.storer.(Searcher)
+	if !ok {
+		return nil, ErrSearchUnsupported
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	if params.Limit == 0 {
+		params.Limit = DefaultSearchLimit
+	}
+
+	params.OwnerID = OwnerFrom(ctx)
+
+	results, err := searcher.Search(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,163 @@
+package todo
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// DefaultSessionLifetime is how long a session lasts after logging in.
+const DefaultSessionLifetime = 7 * 24 * time.Hour
+
+// SessionStorer represents the behavior this package needs to manage the
+// sessions of users logged in to the web application.
+//
+// Sessions are stored by the SHA-256 hash of their token and
+// QuerySessionByHash looks them up by that hash, including expired sessions.
+// PurgeSessions deletes every session that expired before the given time,
+// returning how many were deleted.
+type SessionStorer interface {
+	QuerySessionByHash(ctx context.Context, hash string) (Session, error)
+	CreateSession(ctx context.Context, session Session) error
+	DeleteSession(ctx context.Context, session Session) error
+	PurgeSessions(ctx context.Context, before time.Time) (int, error)
+}
+
+// Session is a login of a user to the web application. Browsers hold the
+// token of the session in a cookie, of which only a hash is stored. Requests
+// that make changes must also carry the CSRFToken of the session.
+type Session struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Hash        string    `json:"hash"`
+	CSRFToken   string    `json:"csrf_token"`
+	TimeCreated time.Time `json:"time_created"`
+	TimeExpires time.Time `json:"time_expires"`
+}
+
+// CheckCSRF reports whether token is the CSRF token of the session.
+func (s Session) CheckCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken)) == 1
+}
+
+// NewSession is a session that was just created along with its token, which
+// cannot be retrieved again.
+type NewSession struct {
+	Session
+	Token string
+}
+
+// WithSessionLifetime sets how long sessions last after logging in. The
+// default is DefaultSessionLifetime.
+func WithSessionLifetime(lifetime time.Duration) Option {
+	return func(c *Core) {
+		c.sessionLifetime = lifetime
+	}
+}
+
+// Login starts a session for the user with the given name and password. It
+// returns ErrInvalidCredentials when there is no such user or the password
+// is wrong.
+func (s *Core) Login(ctx context.Context, name string, password string) (NewSession, error) {
+	user, err := s.storer.QueryUserByName(ctx, name)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return NewSession{}, fmt.Errorf("query user by name: %w", err)
+	}
+
+	// A hash is always compared so that unknown names cannot be told apart
+	// by how long they take.
+	hash := user.PasswordHash
+	if hash == "" {
+		hash = dummyPasswordHash
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil || user.PasswordHash == "" {
+		return NewSession{}, ErrInvalidCredentials
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// startSession creates a session for a user that was authenticated.
+func (s *Core) startSession(ctx context.Context, user User) (NewSession, error) {
+	token, err := newToken("", 32)
+	if err != nil {
+		return NewSession{}, fmt.Errorf("generate session token: %w", err)
+	}
+
+	csrfToken, err := newToken("", 32)
+	if err != nil {
+		return NewSession{}, fmt.Errorf("generate csrf token: %w", err)
+	}
+
+	now := time.Now()
+
+	session := Session{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Hash:        hashSecret(token),
+		CSRFToken:   csrfToken,
+		TimeCreated: now,
+		TimeExpires: now.Add(s.sessionLifetime),
+	}
+
+	if err := s.storer.CreateSession(ctx, session); err != nil {
+		return NewSession{}, fmt.Errorf("create session: %w", err)
+	}
+
+	return NewSession{Session: session, Token: token}, nil
+}
+
+// AuthenticateSession returns the session with the given token along with its
+// user. It returns ErrUnauthenticated when the session is unknown or has
+// expired.
+func (s *Core) AuthenticateSession(ctx context.Context, token string) (User, Session, error) {
+	session, err := s.storer.QuerySessionByHash(ctx, hashSecret(token))
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return User{}, Session{}, ErrUnauthenticated
+		}
+		return User{}, Session{}, fmt.Errorf("query session: %w", err)
+	}
+
+	if !time.Now().Before(session.TimeExpires) {
+		return User{}, Session{}, ErrUnauthenticated
+	}
+
+	user, err := s.storer.QueryUserByID(ctx, session.UserID)
+	if err != nil {
+		return User{}, Session{}, fmt.Errorf("query user: %w", err)
+	}
+
+	user.PasswordHash = ""
+
+	return user, session, nil
+}
+
+// Logout ends a session. Ending a session that has already ended does
+// nothing.
+func (s *Core) Logout(ctx context.Context, session Session) error {
+	if err := s.storer.DeleteSession(ctx, session); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeSessions deletes every session that expired before the given time and
+// returns how many were deleted.
+func (s *Core) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	n, err := s.storer.PurgeSessions(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge sessions: %w", err)
+	}
+
+	return n, nil
+}
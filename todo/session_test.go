@@ -0,0 +1,113 @@
+package todo_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	var vErr todo.ValidationError
+	for _, password := range []string{"short", strings.Repeat("x", todo.MaxPasswordLength+1)} {
+		if _, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "alice", Password: password}); !errors.As(err, &vErr) {
+			t.Fatalf("create user: expected validation error for a %d byte password, got %v", len(password), err)
+		}
+	}
+
+	user, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "alice", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+	if user.PasswordHash != "" {
+		t.Fatal("create user: expected the password hash to be cleared")
+	}
+
+	// Users without a password cannot log in at all.
+	if _, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "bob"}); err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	for _, tc := range []struct{ name, password string }{
+		{"alice", "wrong horse"},
+		{"alice", ""},
+		{"bob", ""},
+		{"carol", "correct horse"},
+	} {
+		if _, err := todoCore.Login(ctx, tc.name, tc.password); !errors.Is(err, todo.ErrInvalidCredentials) {
+			t.Fatalf("login as %q with %q: expected %v, got %v", tc.name, tc.password, todo.ErrInvalidCredentials, err)
+		}
+	}
+
+	session, err := todoCore.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login: expected nil error, got %v", err)
+	}
+	if session.Token == "" || session.Hash == session.Token {
+		t.Fatal("login: expected the session token to be stored hashed")
+	}
+
+	got, gotSession, err := todoCore.AuthenticateSession(ctx, session.Token)
+	if err != nil {
+		t.Fatalf("authenticate session: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(user, got); diff != "" {
+		t.Fatalf("authenticate session: mismatch (-want +got):\n%s", diff)
+	}
+	if !gotSession.CheckCSRF(session.CSRFToken) || gotSession.CheckCSRF("") || gotSession.CheckCSRF("nope") {
+		t.Fatal("authenticate session: expected only the session's csrf token to be accepted")
+	}
+
+	for _, token := range []string{"", "nope", session.Hash} {
+		if _, _, err := todoCore.AuthenticateSession(ctx, token); !errors.Is(err, todo.ErrUnauthenticated) {
+			t.Fatalf("authenticate session %q: expected %v, got %v", token, todo.ErrUnauthenticated, err)
+		}
+	}
+
+	if err := todoCore.Logout(ctx, gotSession); err != nil {
+		t.Fatalf("logout: expected nil error, got %v", err)
+	}
+
+	if _, _, err := todoCore.AuthenticateSession(ctx, session.Token); !errors.Is(err, todo.ErrUnauthenticated) {
+		t.Fatalf("authenticate session: expected %v after logout, got %v", todo.ErrUnauthenticated, err)
+	}
+
+	// Logging out twice is fine.
+	if err := todoCore.Logout(ctx, gotSession); err != nil {
+		t.Fatalf("logout: expected nil error, got %v", err)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore(), todo.WithSessionLifetime(-time.Minute))
+
+	if _, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "alice", Password: "correct horse"}); err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	session, err := todoCore.Login(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("login: expected nil error, got %v", err)
+	}
+
+	if _, _, err := todoCore.AuthenticateSession(ctx, session.Token); !errors.Is(err, todo.ErrUnauthenticated) {
+		t.Fatalf("authenticate session: expected %v for an expired session, got %v", todo.ErrUnauthenticated, err)
+	}
+
+	n, err := todoCore.PurgeSessions(ctx, time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("purge sessions: expected 1 purged, got %d, %v", n, err)
+	}
+}
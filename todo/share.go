@@ -0,0 +1,366 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrShareNotFound = errors.New("share not found")
+
+	// ErrForbidden is returned when the user in ctx may see a todo item but
+	// not do what was asked with it. Todo items they may not see at all are
+	// reported as ErrNotFound instead so that they are not given away.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// ShareStorer represents the behavior this package needs to share todo items
+// with other users.
+//
+// A user has at most one share of a todo item. PutShare creates the share or
+// replaces the role of an existing one. QueryShares and QuerySharesByUser
+// return shares oldest first. Shares are deleted along with their todo item
+// when it is purged.
+type ShareStorer interface {
+	QueryShares(ctx context.Context, todoID uuid.UUID) ([]Share, error)
+	QuerySharesByUser(ctx context.Context, userID uuid.UUID) ([]Share, error)
+	QueryShare(ctx context.Context, todoID uuid.UUID, userID uuid.UUID) (Share, error)
+	PutShare(ctx context.Context, share Share) error
+	DeleteShare(ctx context.Context, share Share) error
+}
+
+// Role is how much a user may do with a todo item and its subtasks. Each role
+// may do everything the ones before it may.
+//
+// Viewers may read todo items along with their subtasks, history and
+// collaborators. Editors may also change them and add subtasks. Owners may
+// also move them between parents, move them to the trash, restore and purge
+// them, and share them with others.
+type Role string
+
+// Roles a todo item can be shared with.
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+// roleRanks orders the roles from least to most allowed.
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether r may do everything other may.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// Share gives a user a role on a todo item and its subtasks.
+type Share struct {
+	TodoID      uuid.UUID `json:"todo_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Role        Role      `json:"role"`
+	TimeCreated time.Time `json:"time_created"`
+}
+
+// Collaborator is a share along with the name of the user it is for.
+type Collaborator struct {
+	Share
+	Name string `json:"name"`
+}
+
+// ShareParams represents the parameters for sharing a todo item with the user
+// named User.
+type ShareParams struct {
+	User string `json:"user"`
+	Role Role   `json:"role"`
+}
+
+// Validate validates ShareParams.
+func (p ShareParams) Validate() error {
+	if p.User == "" {
+		return NewValidationError(errors.New("missing required field user"))
+	}
+
+	if !p.Role.Valid() {
+		return NewValidationError(fmt.Errorf("invalid role %q", p.Role))
+	}
+
+	return nil
+}
+
+// QueryShared retrieves the todo items shared with the user in ctx, in the
+// order they were shared. Shared todo items in the trash are left out.
+func (s *Core) QueryShared(ctx context.Context) ([]Todo, error) {
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	shares, err := s.storer.QuerySharesByUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query shares by user: %w", err)
+	}
+
+	todos := make([]Todo, 0, len(shares))
+
+	for _, share := range shares {
+		td, err := s.storer.QueryByID(ctx, share.TodoID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("query by id [%s]: %w", share.TodoID, err)
+		}
+
+		todos = append(todos, td)
+	}
+
+	return todos, nil
+}
+
+// QueryCollaborators retrieves the users a todo item is shared with, in the
+// order it was shared with them. Shares of its parents are not included.
+func (s *Core) QueryCollaborators(ctx context.Context, todo Todo) ([]Collaborator, error) {
+	todo, err := s.current(ctx, todo, false, RoleViewer)
+	if err != nil {
+		return nil, fmt.Errorf("query collaborators: %w", err)
+	}
+
+	shares, err := s.storer.QueryShares(ctx, todo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query shares: %w", err)
+	}
+
+	collaborators := make([]Collaborator, 0, len(shares))
+
+	for _, share := range shares {
+		user, err := s.storer.QueryUserByID(ctx, share.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("query user [%s]: %w", share.UserID, err)
+		}
+
+		collaborators = append(collaborators, Collaborator{Share: share, Name: user.Name})
+	}
+
+	return collaborators, nil
+}
+
+// Share shares a todo item and its subtasks with another user, or changes
+// their role when it is already shared with them.
+func (s *Core) Share(ctx context.Context, todo Todo, params ShareParams) (Collaborator, error) {
+	if err := params.Validate(); err != nil {
+		return Collaborator{}, fmt.Errorf("validate: %w", err)
+	}
+
+	if _, ok := UserFrom(ctx); !ok {
+		return Collaborator{}, ErrUnauthenticated
+	}
+
+	var collaborator Collaborator
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		todo, err := txCore.current(ctx, todo, false, RoleOwner)
+		if err != nil {
+			return fmt.Errorf("share: %w", err)
+		}
+
+		user, err := txCore.storer.QueryUserByName(ctx, params.User)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return NewValidationError(fmt.Errorf("user %s does not exist", params.User))
+			}
+			return fmt.Errorf("query user by name: %w", err)
+		}
+
+		if user.ID == todo.OwnerID {
+			return NewValidationError(fmt.Errorf("user %s owns the todo item", params.User))
+		}
+
+		if ws := WorkspaceFrom(ctx); ws != DefaultWorkspaceID {
+			if _, err := txCore.storer.QueryMember(ctx, ws, user.ID); err != nil {
+				if errors.Is(err, ErrMemberNotFound) {
+					return NewValidationError(fmt.Errorf("user %s is not a member of the workspace", params.User))
+				}
+				return fmt.Errorf("query member: %w", err)
+			}
+		}
+
+		share, err := txCore.storer.QueryShare(ctx, todo.ID, user.ID)
+		if err != nil {
+			if !errors.Is(err, ErrShareNotFound) {
+				return fmt.Errorf("query share: %w", err)
+			}
+
+			share = Share{
+				TodoID:      todo.ID,
+				UserID:      user.ID,
+				TimeCreated: time.Now(),
+			}
+		}
+
+		share.Role = params.Role
+
+		if err := txCore.storer.PutShare(ctx, share); err != nil {
+			return fmt.Errorf("put share: %w", err)
+		}
+
+		collaborator = Collaborator{Share: share, Name: user.Name}
+
+		return nil
+	})
+	if err != nil {
+		return Collaborator{}, err
+	}
+
+	return collaborator, nil
+}
+
+// Unshare stops sharing a todo item with the user given by userID. Users may
+// always stop a todo item from being shared with themselves. It returns
+// ErrShareNotFound when the todo item is not shared with them.
+func (s *Core) Unshare(ctx context.Context, todo Todo, userID uuid.UUID) error {
+	need := RoleOwner
+	if userID == OwnerFrom(ctx) {
+		need = RoleViewer
+	}
+
+	return s.WithTx(ctx, func(txCore *Core) error {
+		todo, err := txCore.current(ctx, todo, false, need)
+		if err != nil {
+			return fmt.Errorf("unshare: %w", err)
+		}
+
+		share, err := txCore.storer.QueryShare(ctx, todo.ID, userID)
+		if err != nil {
+			return fmt.Errorf("query share: %w", err)
+		}
+
+		if err := txCore.storer.DeleteShare(ctx, share); err != nil {
+			return fmt.Errorf("delete share: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// maxShareDepth bounds how many parents are looked at for shares, in case the
+// parents of a todo item form a cycle.
+const maxShareDepth = 100
+
+// roleFor returns the role of the user in ctx on a todo item, or an empty
+// role when they may not see it at all. Owning a todo item or any of its
+// parents makes them its owner, and otherwise the most allowing role they
+// were given on the todo item or any of its parents applies.
+func (s *Core) roleFor(ctx context.Context, todo Todo) (Role, error) {
+	user := OwnerFrom(ctx)
+
+	var role Role
+
+	for depth := 0; depth < maxShareDepth; depth++ {
+		if todo.OwnerID == user {
+			return RoleOwner, nil
+		}
+
+		// Anonymous users are never shared with.
+		if user != uuid.Nil {
+			share, err := s.storer.QueryShare(ctx, todo.ID, user)
+			switch {
+			case err == nil:
+				if !role.Includes(share.Role) {
+					role = share.Role
+				}
+			case !errors.Is(err, ErrShareNotFound):
+				return "", fmt.Errorf("query share: %w", err)
+			}
+		}
+
+		if todo.ParentID == nil {
+			break
+		}
+
+		parent, err := s.storer.QueryByID(ctx, *todo.ParentID)
+		if errors.Is(err, ErrNotFound) {
+			parent, err = s.storer.QueryTrashByID(ctx, *todo.ParentID)
+		}
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				break
+			}
+			return "", fmt.Errorf("query parent: %w", err)
+		}
+
+		todo = parent
+	}
+
+	return role, nil
+}
+
+// Visible reports whether the user in ctx may see todo, either because they
+// own it or because it was shared with them. The todo item has to come from
+// the store, such as one a notification was published for.
+func (s *Core) Visible(ctx context.Context, todo Todo) (bool, error) {
+	role, err := s.roleFor(ctx, todo)
+	if err != nil {
+		return false, err
+	}
+
+	return role.Includes(RoleViewer), nil
+}
+
+// current re-reads todo from the store, or from the trash when trashed is
+// true, and authorizes the user in ctx against the stored copy. Only the ID of
+// todo is trusted, so that callers cannot pass for the owner of a todo item by
+// changing their copy of it.
+func (s *Core) current(ctx context.Context, todo Todo, trashed bool, need Role) (Todo, error) {
+	var (
+		stored Todo
+		err    error
+	)
+
+	if trashed {
+		stored, err = s.storer.QueryTrashByID(ctx, todo.ID)
+	} else {
+		stored, err = s.storer.QueryByID(ctx, todo.ID)
+	}
+	if err != nil {
+		return Todo{}, err
+	}
+
+	if err := s.authorize(ctx, stored, need); err != nil {
+		return Todo{}, err
+	}
+
+	return stored, nil
+}
+
+// authorize returns ErrNotFound when the user in ctx may not see a todo item
+// and ErrForbidden when they may see it but their role does not include need.
+func (s *Core) authorize(ctx context.Context, todo Todo, need Role) error {
+	role, err := s.roleFor(ctx, todo)
+	if err != nil {
+		return err
+	}
+
+	if role == "" {
+		return ErrNotFound
+	}
+
+	if !role.Includes(need) {
+		return ErrForbidden
+	}
+
+	return nil
+}
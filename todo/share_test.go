@@ -0,0 +1,258 @@
+package todo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestShares(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	users := make(map[string]context.Context)
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		user, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: name})
+		if err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+		users[name] = todo.WithUser(ctx, user)
+	}
+
+	alice, bob, carol, dave := users["alice"], users["bob"], users["carol"], users["dave"]
+
+	parent, err := todoCore.Create(alice, todo.TodoCreateParams{Text: "plan trip", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	child, err := todoCore.Create(alice, todo.TodoCreateParams{Text: "book hotel", Priority: todo.PriorityLow, ParentID: &parent.ID})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	var vErr todo.ValidationError
+
+	for _, params := range []todo.ShareParams{
+		{User: "bob", Role: "admin"},
+		{User: "", Role: todo.RoleViewer},
+		{User: "nobody", Role: todo.RoleViewer},
+		{User: "alice", Role: todo.RoleViewer},
+	} {
+		if _, err := todoCore.Share(alice, parent, params); !errors.As(err, &vErr) {
+			t.Fatalf("share %+v: expected validation error, got %v", params, err)
+		}
+	}
+
+	// Nobody else may share the todo item before it is shared with them.
+	if _, err := todoCore.Share(bob, parent, todo.ShareParams{User: "bob", Role: todo.RoleOwner}); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("share: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := todoCore.Share(alice, parent, todo.ShareParams{User: "bob", Role: todo.RoleViewer}); err != nil {
+		t.Fatalf("share: expected nil error, got %v", err)
+	}
+
+	collaborator, err := todoCore.Share(alice, parent, todo.ShareParams{User: "carol", Role: todo.RoleEditor})
+	if err != nil {
+		t.Fatalf("share: expected nil error, got %v", err)
+	}
+	if collaborator.Name != "carol" || collaborator.Role != todo.RoleEditor {
+		t.Fatalf("share: unexpected collaborator %+v", collaborator)
+	}
+
+	// Viewers may read the todo item and its subtasks, but not change them.
+	if _, err := todoCore.QueryByID(bob, child.ID); err != nil {
+		t.Fatalf("query by id: expected a viewer to see a subtask, got %v", err)
+	}
+
+	subtree, err := todoCore.QuerySubtree(bob, parent.ID)
+	if err != nil || len(subtree) != 1 {
+		t.Fatalf("query subtree: expected the subtask, got %v, %v", subtree, err)
+	}
+
+	if _, err := todoCore.QueryEvents(bob, parent.ID); err != nil {
+		t.Fatalf("query events: expected nil error, got %v", err)
+	}
+
+	text := "changed"
+	if _, err := todoCore.Update(bob, parent, todo.TodoUpdateParams{Text: &text}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("update: expected %v for a viewer, got %v", todo.ErrForbidden, err)
+	}
+
+	if _, err := todoCore.Create(bob, todo.TodoCreateParams{Text: "sub", Priority: todo.PriorityLow, ParentID: &parent.ID}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("create: expected %v for a viewer, got %v", todo.ErrForbidden, err)
+	}
+
+	// Editors may change the todo item and add subtasks, which belong to
+	// its owner.
+	updated, err := todoCore.Update(carol, parent, todo.TodoUpdateParams{Text: &text})
+	if err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	sub, err := todoCore.Create(carol, todo.TodoCreateParams{Text: "rent car", Priority: todo.PriorityLow, ParentID: &parent.ID})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+	if sub.OwnerID != parent.OwnerID {
+		t.Fatalf("create: expected the subtask to belong to %v, got %v", parent.OwnerID, sub.OwnerID)
+	}
+
+	if err := todoCore.Delete(carol, updated); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("delete: expected %v for an editor, got %v", todo.ErrForbidden, err)
+	}
+
+	if _, err := todoCore.Update(carol, sub, todo.TodoUpdateParams{ClearParent: true}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("update: expected %v for an editor moving a subtask, got %v", todo.ErrForbidden, err)
+	}
+
+	if _, err := todoCore.Share(carol, updated, todo.ShareParams{User: "dave", Role: todo.RoleViewer}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("share: expected %v for an editor, got %v", todo.ErrForbidden, err)
+	}
+
+	// Users it is not shared with cannot tell it exists.
+	if _, err := todoCore.QueryByID(dave, parent.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := todoCore.QueryCollaborators(dave, updated); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query collaborators: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	collaborators, err := todoCore.QueryCollaborators(bob, updated)
+	if err != nil || len(collaborators) != 2 || collaborators[0].Name != "bob" || collaborators[1].Name != "carol" {
+		t.Fatalf("query collaborators: expected bob and carol, got %v, %v", collaborators, err)
+	}
+
+	shared, err := todoCore.QueryShared(bob)
+	if err != nil || len(shared) != 1 || shared[0].ID != parent.ID {
+		t.Fatalf("query shared: expected the parent, got %v, %v", shared, err)
+	}
+
+	// A share on a subtask does not give access to its parent.
+	if _, err := todoCore.Share(alice, child, todo.ShareParams{User: "dave", Role: todo.RoleOwner}); err != nil {
+		t.Fatalf("share: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.QueryByID(dave, parent.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	// Sharing again changes the role.
+	if _, err := todoCore.Share(alice, updated, todo.ShareParams{User: "bob", Role: todo.RoleOwner}); err != nil {
+		t.Fatalf("share: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.Delete(bob, updated); err != nil {
+		t.Fatalf("delete: expected an owner to delete, got %v", err)
+	}
+
+	trashed, err := todoCore.QueryTrashByID(bob, updated.ID)
+	if err != nil {
+		t.Fatalf("query trash by id: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.Restore(carol, trashed); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("restore: expected %v for an editor, got %v", todo.ErrForbidden, err)
+	}
+
+	restored, err := todoCore.Restore(alice, trashed)
+	if err != nil {
+		t.Fatalf("restore: expected nil error, got %v", err)
+	}
+
+	// Only owners may unshare others, but anyone may leave.
+	if err := todoCore.Unshare(carol, restored, todo.OwnerFrom(bob)); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("unshare: expected %v for an editor, got %v", todo.ErrForbidden, err)
+	}
+
+	if err := todoCore.Unshare(carol, restored, todo.OwnerFrom(carol)); err != nil {
+		t.Fatalf("unshare: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.QueryByID(carol, parent.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v after leaving, got %v", todo.ErrNotFound, err)
+	}
+
+	if err := todoCore.Unshare(alice, restored, todo.OwnerFrom(carol)); !errors.Is(err, todo.ErrShareNotFound) {
+		t.Fatalf("unshare: expected %v, got %v", todo.ErrShareNotFound, err)
+	}
+}
+
+func TestSharesForged(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	users := make(map[string]context.Context)
+	for _, name := range []string{"alice", "bob", "mallory"} {
+		user, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: name})
+		if err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+		users[name] = todo.WithUser(ctx, user)
+	}
+
+	alice, bob, mallory := users["alice"], users["bob"], users["mallory"]
+
+	td, err := todoCore.Create(alice, todo.TodoCreateParams{Text: "plan trip", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.Share(alice, td, todo.ShareParams{User: "bob", Role: todo.RoleViewer}); err != nil {
+		t.Fatalf("share: expected nil error, got %v", err)
+	}
+
+	// Claiming the todo item in a copy of it gives away nothing.
+	forged := td
+	forged.OwnerID = todo.OwnerFrom(mallory)
+
+	text := "mine now"
+	if _, err := todoCore.Update(mallory, forged, todo.TodoUpdateParams{Text: &text}); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("update: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if err := todoCore.Delete(mallory, forged); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("delete: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := todoCore.Share(mallory, forged, todo.ShareParams{User: "bob", Role: todo.RoleOwner}); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("share: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := todoCore.QueryCollaborators(mallory, forged); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query collaborators: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	forged.OwnerID = todo.OwnerFrom(bob)
+	if _, err := todoCore.Update(bob, forged, todo.TodoUpdateParams{Text: &text}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("update: expected %v for a viewer, got %v", todo.ErrForbidden, err)
+	}
+
+	// Nothing from the copy is stored either.
+	forged = td
+	forged.OwnerID = todo.OwnerFrom(mallory)
+	updated, err := todoCore.Update(alice, forged, todo.TodoUpdateParams{Text: &text})
+	if err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+	if updated.OwnerID != td.OwnerID {
+		t.Fatalf("update: expected owner %v, got %v", td.OwnerID, updated.OwnerID)
+	}
+
+	for name, want := range map[string]bool{"alice": true, "bob": true, "mallory": false} {
+		got, err := todoCore.Visible(users[name], updated)
+		if err != nil {
+			t.Fatalf("visible: expected nil error, got %v", err)
+		}
+		if got != want {
+			t.Errorf("visible: expected %v for %s, got %v", want, name, got)
+		}
+	}
+}
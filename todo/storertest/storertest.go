@@ -0,0 +1,1987 @@
+// Package storertest provides a conformance test suite for implementations of
+// todo.Storer.
+package storertest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Run runs the conformance test suite against the stores returned by
+// newStore. Every test gets its own store, which must start out empty apart
+// from the default workspace and its inbox list. newStore is called from the
+// test the store is for and may panic when it cannot create the store, which
+// fails that test. Stores that implement io.Closer are closed when their test
+// ends.
+func Run(t *testing.T, newStore func() todo.Storer) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s todo.Storer)
+	}{
+		{"Create", testCreate},
+		{"Update", testUpdate},
+		{"NotFound", testNotFound},
+		{"Order", testOrder},
+		{"Pagination", testPagination},
+		{"Filter", testFilter},
+		{"Isolation", testIsolation},
+		{"Concurrency", testConcurrency},
+		{"Subtree", testSubtree},
+		{"Trash", testTrash},
+		{"Events", testEvents},
+		{"Tags", testTags},
+		{"Lists", testLists},
+		{"Tx", testTx},
+		{"TxConcurrency", testTxConcurrency},
+		{"Search", testSearch},
+		{"Webhooks", testWebhooks},
+		{"WebhookFilter", testWebhookFilter},
+		{"Deliveries", testDeliveries},
+		{"Owners", testOwners},
+		{"Users", testUsers},
+		{"Sessions", testSessions},
+		{"Identities", testIdentities},
+		{"Shares", testShares},
+		{"Workspaces", testWorkspaces},
+		{"WorkspaceIsolation", testWorkspaceIsolation},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, store(t, newStore))
+		})
+	}
+}
+
+// store returns a store from newStore for the test t, failing t when newStore
+// panics and closing the store when t ends.
+func store(t *testing.T, newStore func() todo.Storer) (s todo.Storer) {
+	t.Helper()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("new store: %v", r)
+		}
+	}()
+
+	s = newStore()
+	if c, ok := s.(io.Closer); ok {
+		t.Cleanup(func() { c.Close() })
+	}
+
+	return s
+}
+
+// base is the time every todo item in the suite is created relative to. It is
+// in UTC and has no sub-microsecond part so that it survives a round trip
+// through every store.
+var base = time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+
+// at returns the time n minutes after base.
+func at(n int) time.Time {
+	return base.Add(time.Duration(n) * time.Minute)
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// id returns a UUID that sorts by n.
+func id(n int) uuid.UUID {
+	var u uuid.UUID
+	u[14] = byte(n >> 8)
+	u[15] = byte(n)
+	u[6] = 0x40
+	u[8] = 0x80
+	return u
+}
+
+// newTodo returns a todo item in the inbox created at the given time.
+func newTodo(text string, created time.Time) todo.Todo {
+	return todo.Todo{
+		ID:          uuid.New(),
+		Text:        text,
+		Priority:    todo.PriorityLow,
+		ListID:      todo.InboxID,
+		Tags:        []string{},
+		Version:     1,
+		TimeCreated: created,
+		TimeUpdated: created,
+	}
+}
+
+// event returns an event of type typ for td.
+func event(td todo.Todo, typ todo.EventType) todo.Event {
+	return todo.Event{
+		ID:          uuid.New(),
+		TodoID:      td.ID,
+		Type:        typ,
+		Actor:       "storertest",
+		Changes:     []todo.Change{},
+		TimeCreated: td.TimeUpdated,
+	}
+}
+
+// create adds every todo item to s.
+func create(t *testing.T, s todo.Storer, todos ...todo.Todo) {
+	t.Helper()
+
+	for _, td := range todos {
+		if err := s.Create(context.Background(), td, event(td, todo.EventCreated)); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+	}
+}
+
+// update stores td as its next version.
+func update(t *testing.T, s todo.Storer, td todo.Todo) todo.Todo {
+	t.Helper()
+
+	td.Version++
+	if err := s.Update(context.Background(), td, event(td, todo.EventUpdated)); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	return td
+}
+
+// trash moves td to the trash at the given time.
+func trash(t *testing.T, s todo.Storer, td todo.Todo, deleted time.Time) todo.Todo {
+	t.Helper()
+
+	td.TimeDeleted = &deleted
+	if err := s.Delete(context.Background(), td, event(td, todo.EventDeleted)); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	return td
+}
+
+// query runs opts against s, filling in the default order.
+func query(t *testing.T, s todo.Storer, opts todo.QueryOptions) []todo.Todo {
+	t.Helper()
+
+	if opts.OrderBy == "" {
+		opts.OrderBy = todo.OrderByTimeCreated
+	}
+	if opts.Direction == "" {
+		opts.Direction = todo.DirectionAsc
+	}
+
+	todos, err := s.Query(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+
+	return todos
+}
+
+// texts returns the text of every todo item.
+func texts(todos []todo.Todo) []string {
+	s := make([]string, 0, len(todos))
+	for _, td := range todos {
+		s = append(s, td.Text)
+	}
+	return s
+}
+
+// equal compares todo items by value. Times are compared by instant since
+// stores may return them in another location, and empty and nil slices are
+// treated alike.
+var equal = cmp.Options{
+	cmpopts.EquateEmpty(),
+	cmp.Comparer(func(a, b time.Time) bool {
+		return a.Equal(b)
+	}),
+}
+
+func testCreate(t *testing.T, s todo.Storer) {
+	parent := newTodo("parent", at(0))
+	parent.Priority = todo.PriorityHigh
+	parent.Completed = true
+	parent.DueAt = ptr(at(60))
+	parent.Recurrence = "FREQ=WEEKLY"
+	parent.NextID = ptr(uuid.New())
+	parent.Tags = []string{"home", "ops"}
+
+	child := newTodo("child", at(1))
+	child.ParentID = &parent.ID
+
+	create(t, s, parent, child)
+
+	for _, want := range []todo.Todo{parent, child} {
+		got, err := s.QueryByID(context.Background(), want.ID)
+		if err != nil {
+			t.Fatalf("query by id: expected nil error, got %v", err)
+		}
+
+		if diff := cmp.Diff(want, got, equal); diff != "" {
+			t.Fatalf("query by id: mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, s todo.Storer) {
+	parent := newTodo("parent", at(0))
+	child := newTodo("child", at(1))
+	child.ParentID = &parent.ID
+	create(t, s, parent, child)
+
+	want := parent
+	want.Text = "updated"
+	want.Priority = todo.PriorityMedium
+	want.Completed = true
+	want.DueAt = ptr(at(60))
+	want.Recurrence = "FREQ=DAILY"
+	want.NextID = ptr(uuid.New())
+	want.Tags = []string{"ops"}
+	want.TimeUpdated = at(2)
+	want = update(t, s, want)
+
+	got, err := s.QueryByID(context.Background(), parent.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff(want, got, equal); diff != "" {
+		t.Fatalf("query by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	stale := parent
+	stale.Version++
+	if err := s.Update(context.Background(), stale, event(stale, todo.EventUpdated)); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("update: expected %v for a stale version, got %v", todo.ErrConflict, err)
+	}
+
+	for name, parentID := range map[string]uuid.UUID{"itself": parent.ID, "a descendant": child.ID} {
+		cycle := want
+		cycle.ParentID = &parentID
+		cycle.Version++
+		if err := s.Update(context.Background(), cycle, event(cycle, todo.EventUpdated)); !errors.Is(err, todo.ErrCycle) {
+			t.Fatalf("update: expected %v with %s as parent, got %v", todo.ErrCycle, name, err)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+	missing := newTodo("missing", at(0))
+
+	if _, err := s.QueryByID(ctx, missing.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := s.QueryTrashByID(ctx, missing.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("query trash by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	missing.Version++
+	if err := s.Update(ctx, missing, event(missing, todo.EventUpdated)); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("update: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	missing.TimeDeleted = ptr(at(1))
+	if err := s.Restore(ctx, missing, event(missing, todo.EventRestored)); !errors.Is(err, todo.ErrNotFound) {
+		t.Errorf("restore: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := s.QueryListByID(ctx, uuid.New()); !errors.Is(err, todo.ErrListNotFound) {
+		t.Errorf("query list by id: expected %v, got %v", todo.ErrListNotFound, err)
+	}
+
+	l := todo.List{ID: uuid.New(), Name: "missing", TimeCreated: at(0), TimeUpdated: at(0)}
+
+	if err := s.UpdateList(ctx, l); !errors.Is(err, todo.ErrListNotFound) {
+		t.Errorf("update list: expected %v, got %v", todo.ErrListNotFound, err)
+	}
+
+	if err := s.DeleteList(ctx, l); !errors.Is(err, todo.ErrListNotFound) {
+		t.Errorf("delete list: expected %v, got %v", todo.ErrListNotFound, err)
+	}
+
+	events, err := s.QueryEvents(ctx, missing.ID)
+	if err != nil || len(events) != 0 {
+		t.Errorf("query events: expected no events, got %v, %v", events, err)
+	}
+}
+
+// orderFixture creates four todo items that sort differently by every field.
+// Two of them are created at the same time so that ties are broken by ID.
+func orderFixture(t *testing.T, s todo.Storer) {
+	a := newTodo("c", at(0))
+	a.ID = id(1)
+	a.Priority = todo.PriorityHigh
+	a.TimeUpdated = at(13)
+	a.DueAt = ptr(at(25))
+
+	b := newTodo("a", at(1))
+	b.ID = id(2)
+	b.Priority = todo.PriorityLow
+	b.TimeUpdated = at(11)
+
+	c := newTodo("d", at(1))
+	c.ID = id(3)
+	c.Priority = todo.PriorityMedium
+	c.TimeUpdated = at(12)
+	c.DueAt = ptr(at(24))
+
+	d := newTodo("b", at(2))
+	d.ID = id(4)
+	d.Priority = todo.PriorityLow
+	d.TimeUpdated = at(10)
+
+	// Create them out of order so that insertion order does not help.
+	create(t, s, d, b, a, c)
+}
+
+func testOrder(t *testing.T, s todo.Storer) {
+	orderFixture(t, s)
+
+	// The fixture texts are "c", "a", "d" and "b" in order of creation.
+	tests := []struct {
+		field todo.OrderField
+		asc   []string
+		desc  []string
+	}{
+		{todo.OrderByTimeCreated, []string{"c", "a", "d", "b"}, []string{"b", "d", "a", "c"}},
+		{todo.OrderByTimeUpdated, []string{"b", "a", "d", "c"}, []string{"c", "d", "a", "b"}},
+		{todo.OrderByDueAt, []string{"d", "c", "a", "b"}, []string{"c", "d", "b", "a"}},
+		{todo.OrderByPriority, []string{"a", "b", "d", "c"}, []string{"c", "d", "b", "a"}},
+		{todo.OrderByText, []string{"a", "b", "c", "d"}, []string{"d", "c", "b", "a"}},
+	}
+
+	for _, tc := range tests {
+		for direction, want := range map[todo.Direction][]string{
+			todo.DirectionAsc:  tc.asc,
+			todo.DirectionDesc: tc.desc,
+		} {
+			got := texts(query(t, s, todo.QueryOptions{OrderBy: tc.field, Direction: direction}))
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("query by %s %s: mismatch (-want +got):\n%s", tc.field, direction, diff)
+			}
+		}
+	}
+}
+
+func testPagination(t *testing.T, s todo.Storer) {
+	orderFixture(t, s)
+
+	tests := map[string]struct {
+		opts todo.QueryOptions
+		want []string
+	}{
+		"limit": {
+			opts: todo.QueryOptions{Limit: 2},
+			want: []string{"c", "a"},
+		},
+		"offset": {
+			opts: todo.QueryOptions{Offset: 3},
+			want: []string{"b"},
+		},
+		"limit and offset": {
+			opts: todo.QueryOptions{Limit: 2, Offset: 1},
+			want: []string{"a", "d"},
+		},
+		"offset past the end": {
+			opts: todo.QueryOptions{Offset: 10},
+			want: []string{},
+		},
+		"cursor": {
+			opts: todo.QueryOptions{After: &todo.Cursor{TimeCreated: at(1), ID: id(2)}},
+			want: []string{"d", "b"},
+		},
+		"cursor descending": {
+			opts: todo.QueryOptions{
+				Direction: todo.DirectionDesc,
+				After:     &todo.Cursor{TimeCreated: at(1), ID: id(3)},
+			},
+			want: []string{"a", "c"},
+		},
+		"cursor and limit": {
+			opts: todo.QueryOptions{Limit: 1, After: &todo.Cursor{TimeCreated: at(0), ID: id(1)}},
+			want: []string{"a"},
+		},
+	}
+
+	for name, tc := range tests {
+		got := texts(query(t, s, tc.opts))
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("query with %s: mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func testFilter(t *testing.T, s todo.Storer) {
+	l := todo.List{ID: uuid.New(), Name: "work", TimeCreated: at(0), TimeUpdated: at(0)}
+	if err := s.CreateList(context.Background(), l); err != nil {
+		t.Fatalf("create list: expected nil error, got %v", err)
+	}
+
+	a := newTodo("a", at(0))
+	a.Tags = []string{"home", "ops"}
+
+	b := newTodo("b", at(1))
+	b.Completed = true
+	b.Priority = todo.PriorityHigh
+	b.Tags = []string{"ops"}
+	b.TimeUpdated = at(5)
+
+	c := newTodo("c", at(2))
+	c.ListID = l.ID
+
+	d := newTodo("d", at(3))
+
+	create(t, s, a, b, c, d)
+	trash(t, s, d, at(4))
+
+	tests := map[string]struct {
+		filter todo.QueryFilter
+		want   []string
+	}{
+		"none":           {todo.QueryFilter{}, []string{"a", "b", "c"}},
+		"trashed":        {todo.QueryFilter{Trashed: true}, []string{"d"}},
+		"list":           {todo.QueryFilter{ListID: &l.ID}, []string{"c"}},
+		"inbox":          {todo.QueryFilter{ListID: ptr(todo.InboxID)}, []string{"a", "b"}},
+		"completed":      {todo.QueryFilter{Completed: ptr(true)}, []string{"b"}},
+		"incomplete":     {todo.QueryFilter{Completed: ptr(false)}, []string{"a", "c"}},
+		"priority":       {todo.QueryFilter{Priority: ptr(todo.PriorityHigh)}, []string{"b"}},
+		"tag":            {todo.QueryFilter{Tags: []string{"ops"}}, []string{"a", "b"}},
+		"all tags":       {todo.QueryFilter{Tags: []string{"ops", "home"}}, []string{"a"}},
+		"unused tag":     {todo.QueryFilter{Tags: []string{"work"}}, []string{}},
+		"created after":  {todo.QueryFilter{CreatedAfter: ptr(at(1))}, []string{"b", "c"}},
+		"created before": {todo.QueryFilter{CreatedBefore: ptr(at(1))}, []string{"a"}},
+		"updated after":  {todo.QueryFilter{UpdatedAfter: ptr(at(3))}, []string{"b"}},
+		"updated before": {todo.QueryFilter{UpdatedBefore: ptr(at(5))}, []string{"a", "c"}},
+		"combined": {
+			todo.QueryFilter{Tags: []string{"ops"}, Completed: ptr(false)},
+			[]string{"a"},
+		},
+	}
+
+	for name, tc := range tests {
+		got := texts(query(t, s, todo.QueryOptions{Filter: tc.filter}))
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("query with %s filter: mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func testIsolation(t *testing.T, s todo.Storer) {
+	parent := newTodo("parent", at(0))
+
+	td := newTodo("foo", at(1))
+	td.ParentID = ptr(parent.ID)
+	td.DueAt = ptr(at(60))
+	td.Tags = []string{"home", "ops"}
+
+	create(t, s, parent, td)
+
+	want := td
+	want.ParentID = ptr(parent.ID)
+	want.DueAt = ptr(at(60))
+	want.Tags = []string{"home", "ops"}
+
+	// Changing the todo item after handing it to the store must not change
+	// the stored copy.
+	td.Tags[0] = "changed"
+	*td.DueAt = at(120)
+	*td.ParentID = uuid.New()
+
+	mutate := func(td todo.Todo) {
+		if len(td.Tags) > 0 {
+			td.Tags[0] = "changed"
+		}
+		if td.DueAt != nil {
+			*td.DueAt = at(120)
+		}
+		if td.ParentID != nil {
+			*td.ParentID = uuid.New()
+		}
+	}
+
+	got, err := s.QueryByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+	mutate(got)
+
+	for _, got := range query(t, s, todo.QueryOptions{}) {
+		mutate(got)
+	}
+
+	subtree, err := s.QuerySubtree(context.Background(), parent.ID)
+	if err != nil {
+		t.Fatalf("query subtree: expected nil error, got %v", err)
+	}
+	for _, got := range subtree {
+		mutate(got)
+	}
+
+	got, err = s.QueryByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff(want, got, equal); diff != "" {
+		t.Fatalf("query by id: stored todo changed through a returned copy (-want +got):\n%s", diff)
+	}
+}
+
+func testConcurrency(t *testing.T, s todo.Storer) {
+	const workers = 16
+
+	shared := newTodo("shared", at(0))
+	create(t, s, shared)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		updated   int
+		conflicts int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			td := newTodo("worker", at(i+1))
+			if err := s.Create(context.Background(), td, event(td, todo.EventCreated)); err != nil {
+				t.Errorf("create: expected nil error, got %v", err)
+			}
+
+			if _, err := s.Query(context.Background(), todo.QueryOptions{
+				OrderBy:   todo.OrderByTimeCreated,
+				Direction: todo.DirectionAsc,
+			}); err != nil {
+				t.Errorf("query: expected nil error, got %v", err)
+			}
+
+			// Every worker races to store the same next version of the
+			// shared todo item, so exactly one of them may succeed.
+			next := shared
+			next.Version++
+			err := s.Update(context.Background(), next, event(next, todo.EventUpdated))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				updated++
+			case errors.Is(err, todo.ErrConflict):
+				conflicts++
+			default:
+				t.Errorf("update: expected nil or %v, got %v", todo.ErrConflict, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if updated != 1 || conflicts != workers-1 {
+		t.Fatalf("update: expected 1 update and %d conflicts, got %d and %d", workers-1, updated, conflicts)
+	}
+
+	if got := query(t, s, todo.QueryOptions{}); len(got) != workers+1 {
+		t.Fatalf("query: expected %d todos, got %d", workers+1, len(got))
+	}
+}
+
+func testSubtree(t *testing.T, s todo.Storer) {
+	root := newTodo("root", at(0))
+
+	first := newTodo("first", at(1))
+	first.ParentID = &root.ID
+
+	grandchild := newTodo("grandchild", at(2))
+	grandchild.ParentID = &first.ID
+
+	second := newTodo("second", at(3))
+	second.ParentID = &root.ID
+
+	other := newTodo("other", at(4))
+
+	create(t, s, root, first, grandchild, second, other)
+
+	got, err := s.QuerySubtree(context.Background(), root.ID)
+	if err != nil {
+		t.Fatalf("query subtree: expected nil error, got %v", err)
+	}
+
+	want := []string{"first", "second", "grandchild"}
+	if diff := cmp.Diff(want, texts(got)); diff != "" {
+		t.Fatalf("query subtree: mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = s.QuerySubtree(context.Background(), other.ID)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("query subtree: expected no descendants, got %v, %v", got, err)
+	}
+}
+
+func testTrash(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	root := newTodo("root", at(0))
+	root.DueAt = ptr(at(1))
+	root.Tags = []string{"ops"}
+
+	child := newTodo("child", at(1))
+	child.ParentID = &root.ID
+
+	earlier := newTodo("earlier", at(2))
+	earlier.ParentID = &root.ID
+
+	create(t, s, root, child, earlier)
+
+	// A descendant that was already in the trash keeps its own deletion time
+	// and is not restored along with its parent.
+	trash(t, s, earlier, at(3))
+	root = trash(t, s, root, at(4))
+
+	if _, err := s.QueryByID(ctx, child.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v for a trashed todo, got %v", todo.ErrNotFound, err)
+	}
+
+	if got := query(t, s, todo.QueryOptions{}); len(got) != 0 {
+		t.Fatalf("query: expected no todos, got %v", texts(got))
+	}
+
+	if got, err := s.QuerySubtree(ctx, root.ID); err != nil || len(got) != 0 {
+		t.Fatalf("query subtree: expected no todos, got %v, %v", got, err)
+	}
+
+	if got, err := s.QueryOverdue(ctx, uuid.Nil, at(10)); err != nil || len(got) != 0 {
+		t.Fatalf("query overdue: expected no todos, got %v, %v", got, err)
+	}
+
+	if got, err := s.QueryTags(ctx, uuid.Nil); err != nil || len(got) != 0 {
+		t.Fatalf("query tags: expected no tags, got %v, %v", got, err)
+	}
+
+	want := []string{"root", "child", "earlier"}
+	if diff := cmp.Diff(want, texts(query(t, s, todo.QueryOptions{Filter: todo.QueryFilter{Trashed: true}}))); diff != "" {
+		t.Fatalf("query trash: mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := s.QueryTrashByID(ctx, child.ID)
+	if err != nil || got.TimeDeleted == nil || !got.TimeDeleted.Equal(at(4)) {
+		t.Fatalf("query trash by id: expected todo deleted with its parent, got %v, %v", got, err)
+	}
+
+	// Deleting a todo item that is already in the trash does nothing.
+	trash(t, s, root, at(5))
+
+	if err := s.Restore(ctx, root, event(root, todo.EventRestored)); err != nil {
+		t.Fatalf("restore: expected nil error, got %v", err)
+	}
+
+	want = []string{"root", "child"}
+	if diff := cmp.Diff(want, texts(query(t, s, todo.QueryOptions{}))); diff != "" {
+		t.Fatalf("query after restore: mismatch (-want +got):\n%s", diff)
+	}
+
+	if got, err := s.QueryTags(ctx, uuid.Nil); err != nil || len(got) != 1 {
+		t.Fatalf("query tags: expected restored tag, got %v, %v", got, err)
+	}
+
+	n, err := s.PurgeTrash(ctx, nil, at(3))
+	if err != nil || n != 0 {
+		t.Fatalf("purge trash: expected nothing purged, got %d, %v", n, err)
+	}
+
+	n, err = s.PurgeTrash(ctx, nil, at(4))
+	if err != nil || n != 1 {
+		t.Fatalf("purge trash: expected 1 purged, got %d, %v", n, err)
+	}
+
+	if _, err := s.QueryTrashByID(ctx, earlier.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query trash by id: expected %v after purge, got %v", todo.ErrNotFound, err)
+	}
+
+	if err := s.Purge(ctx, root); err != nil {
+		t.Fatalf("purge: expected nil error, got %v", err)
+	}
+
+	for _, td := range []todo.Todo{root, child} {
+		if _, err := s.QueryByID(ctx, td.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("query by id: expected %v after purge, got %v", todo.ErrNotFound, err)
+		}
+	}
+}
+
+func testEvents(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	td := newTodo("foo", at(0))
+	other := newTodo("other", at(0))
+	create(t, s, td, other)
+
+	changed := todo.Change{
+		Field:  "text",
+		Before: []byte(`"foo"`),
+		After:  []byte(`"bar"`),
+	}
+
+	next := td
+	next.Text = "bar"
+	next.Version++
+	next.TimeUpdated = at(1)
+	ev := event(next, todo.EventUpdated)
+	ev.Changes = []todo.Change{changed}
+	if err := s.Update(ctx, next, ev); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	stale := next
+	if err := s.Update(ctx, stale, event(stale, todo.EventUpdated)); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("update: expected %v, got %v", todo.ErrConflict, err)
+	}
+
+	next = trash(t, s, next, at(2))
+
+	events, err := s.QueryEvents(ctx, td.ID)
+	if err != nil {
+		t.Fatalf("query events: expected nil error, got %v", err)
+	}
+
+	want := []todo.EventType{todo.EventCreated, todo.EventUpdated, todo.EventDeleted}
+	got := make([]todo.EventType, 0, len(events))
+	for _, ev := range events {
+		got = append(got, ev.Type)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("query events: mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(ev, events[1], equal); diff != "" {
+		t.Fatalf("query events: mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := s.Purge(ctx, next); err != nil {
+		t.Fatalf("purge: expected nil error, got %v", err)
+	}
+
+	if events, err := s.QueryEvents(ctx, td.ID); err != nil || len(events) != 0 {
+		t.Fatalf("query events: expected history to be purged, got %v, %v", events, err)
+	}
+
+	if events, err := s.QueryEvents(ctx, other.ID); err != nil || len(events) != 1 {
+		t.Fatalf("query events: expected other history to be kept, got %v, %v", events, err)
+	}
+}
+
+func testTags(t *testing.T, s todo.Storer) {
+	a := newTodo("a", at(0))
+	a.Tags = []string{"home", "ops"}
+
+	b := newTodo("b", at(1))
+	b.Tags = []string{"ops"}
+
+	create(t, s, a, b)
+
+	b.Tags = []string{"work"}
+	update(t, s, b)
+
+	got, err := s.QueryTags(context.Background(), uuid.Nil)
+	if err != nil {
+		t.Fatalf("query tags: expected nil error, got %v", err)
+	}
+
+	want := []todo.TagCount{
+		{Tag: "home", Count: 1},
+		{Tag: "ops", Count: 1},
+		{Tag: "work", Count: 1},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("query tags: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testLists(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	work := todo.List{ID: uuid.New(), OwnerID: uuid.New(), Name: "work", TimeCreated: at(1), TimeUpdated: at(1)}
+	home := todo.List{ID: uuid.New(), Name: "home", TimeCreated: at(0), TimeUpdated: at(0)}
+
+	for _, l := range []todo.List{work, home} {
+		if err := s.CreateList(ctx, l); err != nil {
+			t.Fatalf("create list: expected nil error, got %v", err)
+		}
+	}
+
+	work.Name = "office"
+	work.TimeUpdated = at(2)
+	if err := s.UpdateList(ctx, work); err != nil {
+		t.Fatalf("update list: expected nil error, got %v", err)
+	}
+
+	got, err := s.QueryListByID(ctx, work.ID)
+	if err != nil {
+		t.Fatalf("query list by id: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff(work, got, equal); diff != "" {
+		t.Fatalf("query list by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := s.DeleteList(ctx, home); err != nil {
+		t.Fatalf("delete list: expected nil error, got %v", err)
+	}
+
+	lists, err := s.QueryLists(ctx)
+	if err != nil {
+		t.Fatalf("query lists: expected nil error, got %v", err)
+	}
+
+	names := make([]string, 0, len(lists))
+	for _, l := range lists {
+		names = append(names, l.Name)
+	}
+
+	if diff := cmp.Diff([]string{"Inbox", "office"}, names); diff != "" {
+		t.Fatalf("query lists: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testTx(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	committed := newTodo("committed", at(0))
+	rolledBack := newTodo("rolled back", at(1))
+	nested := newTodo("nested", at(2))
+
+	err := s.WithTx(ctx, func(tx todo.Storer) error {
+		create(t, tx, committed)
+
+		// Changes are visible inside the transaction before it commits.
+		if _, err := tx.QueryByID(ctx, committed.ID); err != nil {
+			t.Fatalf("query by id: expected nil error inside transaction, got %v", err)
+		}
+
+		return tx.WithTx(ctx, func(tx todo.Storer) error {
+			create(t, tx, nested)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("with tx: expected nil error, got %v", err)
+	}
+
+	err = s.WithTx(ctx, func(tx todo.Storer) error {
+		create(t, tx, rolledBack)
+		update(t, tx, committed)
+
+		l := todo.List{ID: uuid.New(), Name: "rolled back", TimeCreated: at(1), TimeUpdated: at(1)}
+		if err := tx.CreateList(ctx, l); err != nil {
+			t.Fatalf("create list: expected nil error, got %v", err)
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("with tx: expected %v, got %v", errRollback, err)
+	}
+
+	want := []string{"committed", "nested"}
+	if diff := cmp.Diff(want, texts(query(t, s, todo.QueryOptions{}))); diff != "" {
+		t.Fatalf("query: mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := s.QueryByID(ctx, committed.ID)
+	if err != nil || got.Version != committed.Version {
+		t.Fatalf("query by id: expected version %d, got %v, %v", committed.Version, got.Version, err)
+	}
+
+	events, err := s.QueryEvents(ctx, committed.ID)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("query events: expected only the created event, got %v, %v", events, err)
+	}
+
+	if lists, err := s.QueryLists(ctx); err != nil || len(lists) != 1 {
+		t.Fatalf("query lists: expected only the inbox, got %v, %v", lists, err)
+	}
+
+	// The store is still usable after a rollback.
+	create(t, s, rolledBack)
+}
+
+func testTxConcurrency(t *testing.T, s todo.Storer) {
+	const workers = 8
+
+	shared := newTodo("shared", at(0))
+	create(t, s, shared)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Reading and writing in one transaction never conflicts since
+			// nobody can change the todo item in between.
+			err := s.WithTx(context.Background(), func(tx todo.Storer) error {
+				td, err := tx.QueryByID(context.Background(), shared.ID)
+				if err != nil {
+					return err
+				}
+
+				td.Version++
+				return tx.Update(context.Background(), td, event(td, todo.EventUpdated))
+			})
+			if err != nil {
+				t.Errorf("with tx: expected nil error, got %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	got, err := s.QueryByID(context.Background(), shared.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+
+	if got.Version != workers+1 {
+		t.Fatalf("query by id: expected version %d, got %d", workers+1, got.Version)
+	}
+}
+
+// testSearch runs only against stores that implement todo.Searcher.
+func testSearch(t *testing.T, s todo.Storer) {
+	searcher, ok := s.(todo.Searcher)
+	if !ok {
+		t.Skip("store does not implement todo.Searcher")
+	}
+
+	search := func(q string, limit int) []todo.SearchResult {
+		t.Helper()
+
+		results, err := searcher.Search(context.Background(), todo.SearchParams{Query: q, Limit: limit})
+		if err != nil {
+			t.Fatalf("search %q: expected nil error, got %v", q, err)
+		}
+
+		return results
+	}
+
+	resultTexts := func(results []todo.SearchResult) []string {
+		s := make([]string, 0, len(results))
+		for _, r := range results {
+			s = append(s, r.Todo.Text)
+		}
+		return s
+	}
+
+	groceries := newTodo("Buy groceries for the week", at(0))
+	milk := newTodo("milk milk milk", at(1))
+	buyMilk := newTodo("Buy milk", at(2))
+	walkOld := newTodo("Walk the dog", at(3))
+	walkNew := newTodo("Walk the dog", at(4))
+	trashed := newTodo("Buy milk and bread", at(5))
+	create(t, s, groceries, milk, buyMilk, walkOld, walkNew, trashed)
+	trashed = trash(t, s, trashed, at(6))
+
+	// Terms match the words they are a prefix of, ignoring case.
+	results := search("GROC", todo.DefaultSearchLimit)
+	if diff := cmp.Diff([]string{"Buy groceries for the week"}, resultTexts(results)); diff != "" {
+		t.Fatalf("search: mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(groceries, results[0].Todo, equal); diff != "" {
+		t.Fatalf("search: todo mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(results[0].Snippet, todo.HighlightStart+"groceries"+todo.HighlightEnd) {
+		t.Fatalf("search: expected the match to be highlighted, got snippet %q", results[0].Snippet)
+	}
+
+	// Every term has to match, and trashed todo items are never found.
+	if diff := cmp.Diff([]string{"Buy milk"}, resultTexts(search("buy mil", todo.DefaultSearchLimit))); diff != "" {
+		t.Fatalf("search: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Todo items with more matches rank higher.
+	results = search("milk", todo.DefaultSearchLimit)
+	if diff := cmp.Diff([]string{"milk milk milk", "Buy milk"}, resultTexts(results)); diff != "" {
+		t.Fatalf("search: mismatch (-want +got):\n%s", diff)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Fatalf("search: expected descending ranks, got %v and %v", results[0].Rank, results[1].Rank)
+	}
+
+	// Equal matches are ordered by the time they were last updated.
+	walkOld.TimeUpdated = at(7)
+	walkOld = update(t, s, walkOld)
+
+	results = search("dog", 1)
+	if len(results) != 1 || results[0].Todo.ID != walkOld.ID {
+		t.Fatalf("search: expected only the most recently updated match, got %v", resultTexts(results))
+	}
+
+	// The index follows changes to the text.
+	walkOld.Text = "Feed the cat"
+	walkOld = update(t, s, walkOld)
+
+	if diff := cmp.Diff([]string{"Walk the dog"}, resultTexts(search("dog", todo.DefaultSearchLimit))); diff != "" {
+		t.Fatalf("search: mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Feed the cat"}, resultTexts(search("cat", todo.DefaultSearchLimit))); diff != "" {
+		t.Fatalf("search: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Restored todo items are found again.
+	if err := s.Restore(context.Background(), trashed, event(trashed, todo.EventRestored)); err != nil {
+		t.Fatalf("restore: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"Buy milk and bread"}, resultTexts(search("bread", todo.DefaultSearchLimit))); diff != "" {
+		t.Fatalf("search: mismatch (-want +got):\n%s", diff)
+	}
+
+	if results := search("nothing", todo.DefaultSearchLimit); len(results) != 0 {
+		t.Fatalf("search: expected no results, got %v", resultTexts(results))
+	}
+}
+
+// newWebhook returns an active webhook created at the given time.
+func newWebhook(url string, created time.Time) todo.Webhook {
+	return todo.Webhook{
+		ID:          uuid.New(),
+		OwnerID:     uuid.New(),
+		URL:         url,
+		Secret:      "secret",
+		Events:      []todo.EventType{todo.EventCreated, todo.EventDeleted},
+		Active:      true,
+		TimeCreated: created,
+		TimeUpdated: created,
+	}
+}
+
+// newDelivery returns a pending delivery for wh that is due at the given time.
+func newDelivery(wh todo.Webhook, created time.Time) todo.Delivery {
+	return todo.Delivery{
+		ID:            uuid.New(),
+		WebhookID:     wh.ID,
+		EventID:       uuid.New(),
+		EventType:     todo.EventCreated,
+		Payload:       []byte(`{"event":{"type":"created"},"todo":{"text":"deliver"}}`),
+		Status:        todo.DeliveryPending,
+		NextAttemptAt: ptr(created),
+		TimeCreated:   created,
+		TimeUpdated:   created,
+	}
+}
+
+func testWebhooks(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	first := newWebhook("https://example.com/first", at(0))
+	second := newWebhook("https://example.com/second", at(1))
+
+	for _, wh := range []todo.Webhook{second, first} {
+		if err := s.CreateWebhook(ctx, wh); err != nil {
+			t.Fatalf("create webhook: expected nil error, got %v", err)
+		}
+	}
+
+	first.URL = "https://example.com/updated"
+	first.Events = []todo.EventType{}
+	first.Active = false
+	first.TimeUpdated = at(2)
+	if err := s.UpdateWebhook(ctx, first); err != nil {
+		t.Fatalf("update webhook: expected nil error, got %v", err)
+	}
+
+	got, err := s.QueryWebhookByID(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("query webhook by id: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff(first, got, equal); diff != "" {
+		t.Fatalf("query webhook by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	webhooks, err := s.QueryWebhooks(ctx, todo.WebhookFilter{})
+	if err != nil {
+		t.Fatalf("query webhooks: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff([]todo.Webhook{first, second}, webhooks, equal); diff != "" {
+		t.Fatalf("query webhooks: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Deleting a webhook deletes its deliveries.
+	dl := newDelivery(second, at(3))
+	if err := s.CreateDelivery(ctx, dl); err != nil {
+		t.Fatalf("create delivery: expected nil error, got %v", err)
+	}
+
+	if err := s.DeleteWebhook(ctx, second); err != nil {
+		t.Fatalf("delete webhook: expected nil error, got %v", err)
+	}
+
+	if _, err := s.QueryWebhookByID(ctx, second.ID); !errors.Is(err, todo.ErrWebhookNotFound) {
+		t.Fatalf("query webhook by id: expected %v, got %v", todo.ErrWebhookNotFound, err)
+	}
+	if _, err := s.QueryDeliveryByID(ctx, dl.ID); !errors.Is(err, todo.ErrDeliveryNotFound) {
+		t.Fatalf("query delivery by id: expected %v, got %v", todo.ErrDeliveryNotFound, err)
+	}
+
+	if err := s.UpdateWebhook(ctx, second); !errors.Is(err, todo.ErrWebhookNotFound) {
+		t.Fatalf("update webhook: expected %v, got %v", todo.ErrWebhookNotFound, err)
+	}
+	if err := s.DeleteWebhook(ctx, second); !errors.Is(err, todo.ErrWebhookNotFound) {
+		t.Fatalf("delete webhook: expected %v, got %v", todo.ErrWebhookNotFound, err)
+	}
+}
+
+func testWebhookFilter(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	owner := uuid.New()
+
+	some := newWebhook("https://example.com/some", at(0))
+	some.OwnerID = owner
+
+	all := newWebhook("https://example.com/all", at(1))
+	all.OwnerID = owner
+	all.Events = []todo.EventType{}
+
+	inactive := newWebhook("https://example.com/inactive", at(2))
+	inactive.OwnerID = owner
+	inactive.Active = false
+
+	other := newWebhook("https://example.com/other", at(3))
+
+	for _, wh := range []todo.Webhook{some, all, inactive, other} {
+		if err := s.CreateWebhook(ctx, wh); err != nil {
+			t.Fatalf("create webhook: expected nil error, got %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		filter todo.WebhookFilter
+		want   []todo.Webhook
+	}{
+		"none":          {todo.WebhookFilter{}, []todo.Webhook{some, all, inactive, other}},
+		"owner":         {todo.WebhookFilter{OwnerID: &owner}, []todo.Webhook{some, all, inactive}},
+		"subscribed":    {todo.WebhookFilter{Subscribed: ptr(todo.EventCreated)}, []todo.Webhook{some, all, other}},
+		"owner and all": {todo.WebhookFilter{OwnerID: &owner, Subscribed: ptr(todo.EventUpdated)}, []todo.Webhook{all}},
+		"unknown owner": {todo.WebhookFilter{OwnerID: ptr(uuid.New())}, []todo.Webhook{}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.QueryWebhooks(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("query webhooks: expected nil error, got %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got, equal); diff != "" {
+				t.Fatalf("query webhooks: mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func testDeliveries(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	wh := newWebhook("https://example.com/deliveries", at(0))
+	if err := s.CreateWebhook(ctx, wh); err != nil {
+		t.Fatalf("create webhook: expected nil error, got %v", err)
+	}
+
+	older := newDelivery(wh, at(1))
+	newer := newDelivery(wh, at(2))
+	later := newDelivery(wh, at(10))
+
+	for _, dl := range []todo.Delivery{newer, later, older} {
+		if err := s.CreateDelivery(ctx, dl); err != nil {
+			t.Fatalf("create delivery: expected nil error, got %v", err)
+		}
+	}
+
+	// Claiming returns the deliveries that are due, oldest first, up to the
+	// limit and keeps them from being claimed again until their lease runs
+	// out.
+	claimed, err := s.ClaimDeliveries(ctx, at(5), at(20), 1)
+	if err != nil {
+		t.Fatalf("claim deliveries: expected nil error, got %v", err)
+	}
+
+	older.NextAttemptAt = ptr(at(20))
+	if diff := cmp.Diff([]todo.Delivery{older}, claimed, equal); diff != "" {
+		t.Fatalf("claim deliveries: mismatch (-want +got):\n%s", diff)
+	}
+
+	claimed, err = s.ClaimDeliveries(ctx, at(5), at(20), 10)
+	if err != nil {
+		t.Fatalf("claim deliveries: expected nil error, got %v", err)
+	}
+
+	newer.NextAttemptAt = ptr(at(20))
+	if diff := cmp.Diff([]todo.Delivery{newer}, claimed, equal); diff != "" {
+		t.Fatalf("claim deliveries: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Attempts are appended by every update.
+	older.Tries = 1
+	older.NextAttemptAt = ptr(at(6))
+	older.Attempts = []todo.DeliveryAttempt{{Number: 1, Error: "connection refused", TimeCreated: at(5)}}
+	older.TimeUpdated = at(5)
+	if err := s.UpdateDelivery(ctx, older); err != nil {
+		t.Fatalf("update delivery: expected nil error, got %v", err)
+	}
+
+	older.Tries = 2
+	older.Status = todo.DeliverySucceeded
+	older.NextAttemptAt = nil
+	older.Attempts = append(older.Attempts, todo.DeliveryAttempt{Number: 2, StatusCode: 204, TimeCreated: at(6)})
+	older.TimeUpdated = at(6)
+	if err := s.UpdateDelivery(ctx, older); err != nil {
+		t.Fatalf("update delivery: expected nil error, got %v", err)
+	}
+
+	got, err := s.QueryDeliveryByID(ctx, older.ID)
+	if err != nil {
+		t.Fatalf("query delivery by id: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff(older, got, equal); diff != "" {
+		t.Fatalf("query delivery by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Only pending deliveries are claimed.
+	claimed, err = s.ClaimDeliveries(ctx, at(30), at(40), 10)
+	if err != nil {
+		t.Fatalf("claim deliveries: expected nil error, got %v", err)
+	}
+
+	later.NextAttemptAt = ptr(at(40))
+	newer.NextAttemptAt = ptr(at(40))
+	if diff := cmp.Diff([]todo.Delivery{later, newer}, claimed, equal); diff != "" {
+		t.Fatalf("claim deliveries: mismatch (-want +got):\n%s", diff)
+	}
+
+	deliveries, err := s.QueryDeliveries(ctx, wh.ID)
+	if err != nil {
+		t.Fatalf("query deliveries: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff([]todo.Delivery{later, newer, older}, deliveries, equal); diff != "" {
+		t.Fatalf("query deliveries: mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := s.UpdateDelivery(ctx, newDelivery(wh, at(0))); !errors.Is(err, todo.ErrDeliveryNotFound) {
+		t.Fatalf("update delivery: expected %v, got %v", todo.ErrDeliveryNotFound, err)
+	}
+}
+
+func testOwners(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	alice, bob := id(1), id(2)
+
+	mine := newTodo("alice milk", at(0))
+	mine.OwnerID = alice
+	mine.DueAt = ptr(at(1))
+	mine.Tags = []string{"home"}
+
+	theirs := newTodo("bob milk", at(1))
+	theirs.OwnerID = bob
+	theirs.DueAt = ptr(at(1))
+	theirs.Tags = []string{"work"}
+
+	trashed := newTodo("alice trash", at(2))
+	trashed.OwnerID = alice
+
+	create(t, s, mine, theirs, trashed)
+	trash(t, s, trashed, at(3))
+
+	got, err := s.QueryByID(ctx, mine.ID)
+	if err != nil || got.OwnerID != alice {
+		t.Fatalf("query by id: expected owner %v, got %v, %v", alice, got.OwnerID, err)
+	}
+
+	if diff := cmp.Diff([]string{"alice milk"}, texts(query(t, s, todo.QueryOptions{Filter: todo.QueryFilter{OwnerID: &alice}}))); diff != "" {
+		t.Fatalf("query: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Without an owner the todo items of every owner are returned.
+	if diff := cmp.Diff([]string{"alice milk", "bob milk"}, texts(query(t, s, todo.QueryOptions{}))); diff != "" {
+		t.Fatalf("query: mismatch (-want +got):\n%s", diff)
+	}
+
+	overdue, err := s.QueryOverdue(ctx, bob, at(10))
+	if err != nil {
+		t.Fatalf("query overdue: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"bob milk"}, texts(overdue)); diff != "" {
+		t.Fatalf("query overdue: mismatch (-want +got):\n%s", diff)
+	}
+
+	tags, err := s.QueryTags(ctx, alice)
+	if err != nil {
+		t.Fatalf("query tags: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.TagCount{{Tag: "home", Count: 1}}, tags); diff != "" {
+		t.Fatalf("query tags: mismatch (-want +got):\n%s", diff)
+	}
+
+	if searcher, ok := s.(todo.Searcher); ok {
+		results, err := searcher.Search(ctx, todo.SearchParams{OwnerID: bob, Query: "milk", Limit: todo.DefaultSearchLimit})
+		if err != nil || len(results) != 1 || results[0].Todo.ID != theirs.ID {
+			t.Fatalf("search: expected only the todo of the owner, got %v, %v", results, err)
+		}
+	}
+
+	n, err := s.PurgeTrash(ctx, &bob, at(10))
+	if err != nil || n != 0 {
+		t.Fatalf("purge trash: expected nothing purged for another owner, got %d, %v", n, err)
+	}
+
+	n, err = s.PurgeTrash(ctx, &alice, at(10))
+	if err != nil || n != 1 {
+		t.Fatalf("purge trash: expected 1 purged, got %d, %v", n, err)
+	}
+}
+
+func testUsers(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	alice := todo.User{ID: uuid.New(), Name: "alice", PasswordHash: "hash", TimeCreated: at(0), TimeUpdated: at(0)}
+	bob := todo.User{ID: uuid.New(), Name: "bob", TimeCreated: at(0), TimeUpdated: at(0)}
+
+	for _, u := range []todo.User{alice, bob} {
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+	}
+
+	dup := todo.User{ID: uuid.New(), Name: "alice", TimeCreated: at(1), TimeUpdated: at(1)}
+	if err := s.CreateUser(ctx, dup); !errors.Is(err, todo.ErrUserExists) {
+		t.Fatalf("create user: expected %v, got %v", todo.ErrUserExists, err)
+	}
+
+	got, err := s.QueryUserByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("query user by id: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(alice, got, equal); diff != "" {
+		t.Fatalf("query user by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := s.QueryUserByID(ctx, dup.ID); !errors.Is(err, todo.ErrUserNotFound) {
+		t.Fatalf("query user by id: expected %v, got %v", todo.ErrUserNotFound, err)
+	}
+
+	got, err = s.QueryUserByName(ctx, "bob")
+	if err != nil {
+		t.Fatalf("query user by name: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(bob, got, equal); diff != "" {
+		t.Fatalf("query user by name: mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := s.QueryUserByName(ctx, "carol"); !errors.Is(err, todo.ErrUserNotFound) {
+		t.Fatalf("query user by name: expected %v, got %v", todo.ErrUserNotFound, err)
+	}
+
+	newKey := func(u todo.User, name string, created time.Time) todo.APIKey {
+		return todo.APIKey{
+			ID:          uuid.New(),
+			UserID:      u.ID,
+			Name:        name,
+			Prefix:      "todo_" + name,
+			Hash:        "hash-" + name,
+			TimeCreated: created,
+		}
+	}
+
+	laptop := newKey(alice, "laptop", at(2))
+	phone := newKey(alice, "phone", at(1))
+	other := newKey(bob, "other", at(0))
+
+	for _, key := range []todo.APIKey{laptop, phone, other} {
+		if err := s.CreateAPIKey(ctx, key); err != nil {
+			t.Fatalf("create api key: expected nil error, got %v", err)
+		}
+	}
+
+	keys, err := s.QueryAPIKeys(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("query api keys: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.APIKey{phone, laptop}, keys, equal); diff != "" {
+		t.Fatalf("query api keys: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Revoked keys are still found by their hash.
+	laptop.TimeRevoked = ptr(at(3))
+	if err := s.UpdateAPIKey(ctx, laptop); err != nil {
+		t.Fatalf("update api key: expected nil error, got %v", err)
+	}
+
+	key, err := s.QueryAPIKeyByHash(ctx, laptop.Hash)
+	if err != nil {
+		t.Fatalf("query api key by hash: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(laptop, key, equal); diff != "" {
+		t.Fatalf("query api key by hash: mismatch (-want +got):\n%s", diff)
+	}
+
+	key, err = s.QueryAPIKeyByID(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("query api key by id: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(other, key, equal); diff != "" {
+		t.Fatalf("query api key by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	missing := newKey(bob, "missing", at(4))
+	if _, err := s.QueryAPIKeyByID(ctx, missing.ID); !errors.Is(err, todo.ErrAPIKeyNotFound) {
+		t.Fatalf("query api key by id: expected %v, got %v", todo.ErrAPIKeyNotFound, err)
+	}
+	if _, err := s.QueryAPIKeyByHash(ctx, missing.Hash); !errors.Is(err, todo.ErrAPIKeyNotFound) {
+		t.Fatalf("query api key by hash: expected %v, got %v", todo.ErrAPIKeyNotFound, err)
+	}
+	if err := s.UpdateAPIKey(ctx, missing); !errors.Is(err, todo.ErrAPIKeyNotFound) {
+		t.Fatalf("update api key: expected %v, got %v", todo.ErrAPIKeyNotFound, err)
+	}
+}
+
+func testSessions(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	user := todo.User{ID: uuid.New(), Name: "alice", TimeCreated: at(0), TimeUpdated: at(0)}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	newSession := func(name string, expires time.Time) todo.Session {
+		return todo.Session{
+			ID:          uuid.New(),
+			UserID:      user.ID,
+			Hash:        "hash-" + name,
+			CSRFToken:   "csrf-" + name,
+			TimeCreated: at(0),
+			TimeExpires: expires,
+		}
+	}
+
+	old := newSession("old", at(1))
+	current := newSession("current", at(3))
+
+	for _, session := range []todo.Session{old, current} {
+		if err := s.CreateSession(ctx, session); err != nil {
+			t.Fatalf("create session: expected nil error, got %v", err)
+		}
+	}
+
+	got, err := s.QuerySessionByHash(ctx, current.Hash)
+	if err != nil {
+		t.Fatalf("query session by hash: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(current, got, equal); diff != "" {
+		t.Fatalf("query session by hash: mismatch (-want +got):\n%s", diff)
+	}
+
+	n, err := s.PurgeSessions(ctx, at(2))
+	if err != nil || n != 1 {
+		t.Fatalf("purge sessions: expected 1 purged, got %d, %v", n, err)
+	}
+
+	if _, err := s.QuerySessionByHash(ctx, old.Hash); !errors.Is(err, todo.ErrSessionNotFound) {
+		t.Fatalf("query session by hash: expected %v after purge, got %v", todo.ErrSessionNotFound, err)
+	}
+
+	if err := s.DeleteSession(ctx, current); err != nil {
+		t.Fatalf("delete session: expected nil error, got %v", err)
+	}
+
+	if _, err := s.QuerySessionByHash(ctx, current.Hash); !errors.Is(err, todo.ErrSessionNotFound) {
+		t.Fatalf("query session by hash: expected %v after delete, got %v", todo.ErrSessionNotFound, err)
+	}
+
+	if err := s.DeleteSession(ctx, current); !errors.Is(err, todo.ErrSessionNotFound) {
+		t.Fatalf("delete session: expected %v, got %v", todo.ErrSessionNotFound, err)
+	}
+}
+
+func testIdentities(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	user := todo.User{ID: uuid.New(), Name: "alice", TimeCreated: at(0), TimeUpdated: at(0)}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	identity := todo.Identity{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Issuer:      "https://id.example.com",
+		Subject:     "1234",
+		TimeCreated: at(0),
+	}
+
+	if err := s.CreateIdentity(ctx, identity); err != nil {
+		t.Fatalf("create identity: expected nil error, got %v", err)
+	}
+
+	dup := identity
+	dup.ID = uuid.New()
+	if err := s.CreateIdentity(ctx, dup); !errors.Is(err, todo.ErrIdentityExists) {
+		t.Fatalf("create identity: expected %v, got %v", todo.ErrIdentityExists, err)
+	}
+
+	got, err := s.QueryIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		t.Fatalf("query identity: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(identity, got, equal); diff != "" {
+		t.Fatalf("query identity: mismatch (-want +got):\n%s", diff)
+	}
+
+	// The same subject at another issuer is someone else.
+	if _, err := s.QueryIdentity(ctx, "https://other.example.com", identity.Subject); !errors.Is(err, todo.ErrIdentityNotFound) {
+		t.Fatalf("query identity: expected %v, got %v", todo.ErrIdentityNotFound, err)
+	}
+}
+
+func testShares(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	alice := todo.User{ID: uuid.New(), Name: "alice", TimeCreated: at(0), TimeUpdated: at(0)}
+	bob := todo.User{ID: uuid.New(), Name: "bob", TimeCreated: at(0), TimeUpdated: at(0)}
+
+	for _, u := range []todo.User{alice, bob} {
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+	}
+
+	first := newTodo("first", at(0))
+	second := newTodo("second", at(1))
+	create(t, s, first, second)
+
+	aliceFirst := todo.Share{TodoID: first.ID, UserID: alice.ID, Role: todo.RoleViewer, TimeCreated: at(2)}
+	bobFirst := todo.Share{TodoID: first.ID, UserID: bob.ID, Role: todo.RoleEditor, TimeCreated: at(3)}
+	aliceSecond := todo.Share{TodoID: second.ID, UserID: alice.ID, Role: todo.RoleOwner, TimeCreated: at(4)}
+
+	for _, share := range []todo.Share{aliceFirst, bobFirst, aliceSecond} {
+		if err := s.PutShare(ctx, share); err != nil {
+			t.Fatalf("put share: expected nil error, got %v", err)
+		}
+	}
+
+	// Putting a share again only changes its role.
+	aliceFirst.Role = todo.RoleEditor
+	if err := s.PutShare(ctx, todo.Share{TodoID: first.ID, UserID: alice.ID, Role: todo.RoleEditor, TimeCreated: at(5)}); err != nil {
+		t.Fatalf("put share: expected nil error, got %v", err)
+	}
+
+	got, err := s.QueryShare(ctx, first.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("query share: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(aliceFirst, got, equal); diff != "" {
+		t.Fatalf("query share: mismatch (-want +got):\n%s", diff)
+	}
+
+	shares, err := s.QueryShares(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("query shares: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.Share{aliceFirst, bobFirst}, shares, equal); diff != "" {
+		t.Fatalf("query shares: mismatch (-want +got):\n%s", diff)
+	}
+
+	shares, err = s.QuerySharesByUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("query shares by user: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.Share{aliceFirst, aliceSecond}, shares, equal); diff != "" {
+		t.Fatalf("query shares by user: mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := s.DeleteShare(ctx, bobFirst); err != nil {
+		t.Fatalf("delete share: expected nil error, got %v", err)
+	}
+
+	if err := s.DeleteShare(ctx, bobFirst); !errors.Is(err, todo.ErrShareNotFound) {
+		t.Fatalf("delete share: expected %v, got %v", todo.ErrShareNotFound, err)
+	}
+
+	if _, err := s.QueryShare(ctx, first.ID, bob.ID); !errors.Is(err, todo.ErrShareNotFound) {
+		t.Fatalf("query share: expected %v, got %v", todo.ErrShareNotFound, err)
+	}
+
+	// Purging a todo item deletes its shares.
+	if err := s.Purge(ctx, first); err != nil {
+		t.Fatalf("purge: expected nil error, got %v", err)
+	}
+
+	shares, err = s.QuerySharesByUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("query shares by user: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.Share{aliceSecond}, shares, equal); diff != "" {
+		t.Fatalf("query shares by user: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testWorkspaces(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	alice := todo.User{ID: uuid.New(), Name: "alice", TimeCreated: at(0), TimeUpdated: at(0)}
+	bob := todo.User{ID: uuid.New(), Name: "bob", TimeCreated: at(0), TimeUpdated: at(0)}
+
+	for _, u := range []todo.User{alice, bob} {
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+	}
+
+	ops := todo.Workspace{
+		ID:   uuid.New(),
+		Name: "ops",
+		Settings: todo.WorkspaceSettings{
+			AllowedPriorities: []todo.Priority{todo.PriorityLow, todo.PriorityHigh},
+			DefaultListID:     uuid.New(),
+		},
+		TimeCreated: at(0),
+		TimeUpdated: at(0),
+	}
+	dev := todo.Workspace{
+		ID:          uuid.New(),
+		Name:        "dev",
+		Settings:    todo.WorkspaceSettings{DefaultListID: uuid.New()},
+		TimeCreated: at(1),
+		TimeUpdated: at(1),
+	}
+
+	for _, ws := range []todo.Workspace{ops, dev} {
+		if err := s.CreateWorkspace(ctx, ws); err != nil {
+			t.Fatalf("create workspace: expected nil error, got %v", err)
+		}
+	}
+
+	ops.Name = "operations"
+	ops.Settings.AllowedPriorities = []todo.Priority{todo.PriorityMedium}
+	ops.TimeUpdated = at(2)
+	if err := s.UpdateWorkspace(ctx, ops); err != nil {
+		t.Fatalf("update workspace: expected nil error, got %v", err)
+	}
+
+	got, err := s.QueryWorkspaceByID(ctx, ops.ID)
+	if err != nil {
+		t.Fatalf("query workspace by id: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(ops, got, equal); diff != "" {
+		t.Fatalf("query workspace by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := s.QueryWorkspaceByID(ctx, uuid.New()); !errors.Is(err, todo.ErrWorkspaceNotFound) {
+		t.Fatalf("query workspace by id: expected %v, got %v", todo.ErrWorkspaceNotFound, err)
+	}
+
+	if err := s.UpdateWorkspace(ctx, todo.Workspace{ID: uuid.New(), Name: "missing"}); !errors.Is(err, todo.ErrWorkspaceNotFound) {
+		t.Fatalf("update workspace: expected %v, got %v", todo.ErrWorkspaceNotFound, err)
+	}
+
+	// Every store starts out with the default workspace.
+	workspaces, err := s.QueryWorkspaces(ctx, nil)
+	if err != nil {
+		t.Fatalf("query workspaces: expected nil error, got %v", err)
+	}
+
+	names := make([]string, 0, len(workspaces))
+	for _, ws := range workspaces {
+		names = append(names, ws.Name)
+	}
+	if diff := cmp.Diff([]string{"Default", "dev", "operations"}, names); diff != "" {
+		t.Fatalf("query workspaces: mismatch (-want +got):\n%s", diff)
+	}
+
+	aliceOps := todo.Member{WorkspaceID: ops.ID, UserID: alice.ID, Role: todo.MemberRoleAdmin, TimeCreated: at(3)}
+	bobOps := todo.Member{WorkspaceID: ops.ID, UserID: bob.ID, Role: todo.MemberRoleMember, TimeCreated: at(4)}
+	bobDev := todo.Member{WorkspaceID: dev.ID, UserID: bob.ID, Role: todo.MemberRoleAdmin, TimeCreated: at(5)}
+
+	for _, m := range []todo.Member{aliceOps, bobOps, bobDev} {
+		if err := s.PutMember(ctx, m); err != nil {
+			t.Fatalf("put member: expected nil error, got %v", err)
+		}
+	}
+
+	// Putting a member again only changes their role.
+	bobOps.Role = todo.MemberRoleAdmin
+	if err := s.PutMember(ctx, todo.Member{WorkspaceID: ops.ID, UserID: bob.ID, Role: todo.MemberRoleAdmin, TimeCreated: at(6)}); err != nil {
+		t.Fatalf("put member: expected nil error, got %v", err)
+	}
+
+	member, err := s.QueryMember(ctx, ops.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("query member: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(bobOps, member, equal); diff != "" {
+		t.Fatalf("query member: mismatch (-want +got):\n%s", diff)
+	}
+
+	members, err := s.QueryMembers(ctx, ops.ID)
+	if err != nil {
+		t.Fatalf("query members: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.Member{aliceOps, bobOps}, members, equal); diff != "" {
+		t.Fatalf("query members: mismatch (-want +got):\n%s", diff)
+	}
+
+	workspaces, err = s.QueryWorkspaces(ctx, &alice.ID)
+	if err != nil {
+		t.Fatalf("query workspaces: expected nil error, got %v", err)
+	}
+	if len(workspaces) != 1 || workspaces[0].ID != ops.ID {
+		t.Fatalf("query workspaces: expected only %s, got %v", ops.ID, workspaces)
+	}
+
+	if err := s.DeleteMember(ctx, aliceOps); err != nil {
+		t.Fatalf("delete member: expected nil error, got %v", err)
+	}
+
+	if err := s.DeleteMember(ctx, aliceOps); !errors.Is(err, todo.ErrMemberNotFound) {
+		t.Fatalf("delete member: expected %v, got %v", todo.ErrMemberNotFound, err)
+	}
+
+	if _, err := s.QueryMember(ctx, ops.ID, alice.ID); !errors.Is(err, todo.ErrMemberNotFound) {
+		t.Fatalf("query member: expected %v, got %v", todo.ErrMemberNotFound, err)
+	}
+
+	// Deleting a workspace deletes its todo items, lists and members.
+	devCtx := todo.WithWorkspace(ctx, dev.ID)
+
+	list := todo.List{ID: dev.Settings.DefaultListID, WorkspaceID: dev.ID, Name: "Inbox", TimeCreated: at(6), TimeUpdated: at(6)}
+	if err := s.CreateList(devCtx, list); err != nil {
+		t.Fatalf("create list: expected nil error, got %v", err)
+	}
+
+	td := newTodo("deploy", at(7))
+	td.WorkspaceID = dev.ID
+	td.ListID = list.ID
+	if err := s.Create(devCtx, td, event(td, todo.EventCreated)); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if err := s.DeleteWorkspace(ctx, dev); err != nil {
+		t.Fatalf("delete workspace: expected nil error, got %v", err)
+	}
+
+	if err := s.DeleteWorkspace(ctx, dev); !errors.Is(err, todo.ErrWorkspaceNotFound) {
+		t.Fatalf("delete workspace: expected %v, got %v", todo.ErrWorkspaceNotFound, err)
+	}
+
+	if _, err := s.QueryByID(devCtx, td.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := s.QueryListByID(devCtx, list.ID); !errors.Is(err, todo.ErrListNotFound) {
+		t.Fatalf("query list by id: expected %v, got %v", todo.ErrListNotFound, err)
+	}
+
+	members, err = s.QueryMembers(ctx, dev.ID)
+	if err != nil {
+		t.Fatalf("query members: expected nil error, got %v", err)
+	}
+	if len(members) != 0 {
+		t.Fatalf("query members: expected no members, got %v", members)
+	}
+}
+
+func testWorkspaceIsolation(t *testing.T, s todo.Storer) {
+	ctx := context.Background()
+
+	alice := todo.User{ID: uuid.New(), Name: "alice", TimeCreated: at(0), TimeUpdated: at(0)}
+	if err := s.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	ws := todo.Workspace{
+		ID:          uuid.New(),
+		Name:        "ops",
+		Settings:    todo.WorkspaceSettings{DefaultListID: uuid.New()},
+		TimeCreated: at(0),
+		TimeUpdated: at(0),
+	}
+	if err := s.CreateWorkspace(ctx, ws); err != nil {
+		t.Fatalf("create workspace: expected nil error, got %v", err)
+	}
+
+	wsCtx := todo.WithWorkspace(ctx, ws.ID)
+
+	list := todo.List{ID: ws.Settings.DefaultListID, WorkspaceID: ws.ID, Name: "Inbox", TimeCreated: at(0), TimeUpdated: at(0)}
+	if err := s.CreateList(wsCtx, list); err != nil {
+		t.Fatalf("create list: expected nil error, got %v", err)
+	}
+
+	parent := newTodo("deploy", at(1))
+	parent.WorkspaceID = ws.ID
+	parent.OwnerID = alice.ID
+	parent.ListID = list.ID
+	parent.DueAt = ptr(at(2))
+	parent.Tags = []string{"ops"}
+
+	child := newTodo("deploy the database", at(2))
+	child.WorkspaceID = ws.ID
+	child.OwnerID = alice.ID
+	child.ListID = list.ID
+	child.ParentID = &parent.ID
+
+	for _, td := range []todo.Todo{parent, child} {
+		if err := s.Create(wsCtx, td, event(td, todo.EventCreated)); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+	}
+
+	share := todo.Share{TodoID: parent.ID, UserID: alice.ID, Role: todo.RoleViewer, TimeCreated: at(3)}
+	if err := s.PutShare(wsCtx, share); err != nil {
+		t.Fatalf("put share: expected nil error, got %v", err)
+	}
+
+	// Inside the workspace everything is there.
+	got, err := s.QueryByID(wsCtx, parent.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(parent, got, equal); diff != "" {
+		t.Fatalf("query by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Outside of it nothing is.
+	if _, err := s.QueryByID(ctx, parent.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := s.QueryListByID(ctx, list.ID); !errors.Is(err, todo.ErrListNotFound) {
+		t.Fatalf("query list by id: expected %v, got %v", todo.ErrListNotFound, err)
+	}
+
+	lists, err := s.QueryLists(ctx)
+	if err != nil {
+		t.Fatalf("query lists: expected nil error, got %v", err)
+	}
+	for _, l := range lists {
+		if l.ID == list.ID {
+			t.Fatalf("query lists: expected %s to be left out", list.ID)
+		}
+	}
+
+	subtree, err := s.QuerySubtree(ctx, parent.ID)
+	if err != nil {
+		t.Fatalf("query subtree: expected nil error, got %v", err)
+	}
+	if len(subtree) != 0 {
+		t.Fatalf("query subtree: expected no todo items, got %d", len(subtree))
+	}
+
+	overdue, err := s.QueryOverdue(ctx, alice.ID, at(60))
+	if err != nil {
+		t.Fatalf("query overdue: expected nil error, got %v", err)
+	}
+	if len(overdue) != 0 {
+		t.Fatalf("query overdue: expected no todo items, got %d", len(overdue))
+	}
+
+	tags, err := s.QueryTags(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("query tags: expected nil error, got %v", err)
+	}
+	if len(tags) != 0 {
+		t.Fatalf("query tags: expected no tags, got %v", tags)
+	}
+
+	events, err := s.QueryEvents(ctx, parent.ID)
+	if err != nil {
+		t.Fatalf("query events: expected nil error, got %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("query events: expected no events, got %d", len(events))
+	}
+
+	if searcher, ok := s.(todo.Searcher); ok {
+		results, err := searcher.Search(ctx, todo.SearchParams{Query: "deploy", OwnerID: alice.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("search: expected nil error, got %v", err)
+		}
+		if len(results) != 0 {
+			t.Fatalf("search: expected no results, got %d", len(results))
+		}
+	}
+
+	shares, err := s.QuerySharesByUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("query shares by user: expected nil error, got %v", err)
+	}
+	if len(shares) != 0 {
+		t.Fatalf("query shares by user: expected no shares, got %v", shares)
+	}
+
+	if _, err := s.QueryShare(ctx, parent.ID, alice.ID); !errors.Is(err, todo.ErrShareNotFound) {
+		t.Fatalf("query share: expected %v, got %v", todo.ErrShareNotFound, err)
+	}
+
+	// Changes from outside of the workspace do not reach it.
+	renamed := parent
+	renamed.Text = "renamed"
+	renamed.Version++
+	if err := s.Update(ctx, renamed, event(renamed, todo.EventUpdated)); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("update: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if err := s.UpdateList(ctx, todo.List{ID: list.ID, Name: "renamed", TimeUpdated: at(4)}); !errors.Is(err, todo.ErrListNotFound) {
+		t.Fatalf("update list: expected %v, got %v", todo.ErrListNotFound, err)
+	}
+
+	trashed := parent
+	trashed.TimeDeleted = ptr(at(4))
+	if err := s.Delete(ctx, trashed, event(trashed, todo.EventDeleted)); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	if err := s.Purge(ctx, parent); err != nil {
+		t.Fatalf("purge: expected nil error, got %v", err)
+	}
+
+	if _, err := s.PurgeTrash(ctx, nil, at(60)); err != nil {
+		t.Fatalf("purge trash: expected nil error, got %v", err)
+	}
+
+	got, err = s.QueryByID(wsCtx, parent.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(parent, got, equal); diff != "" {
+		t.Fatalf("query by id: mismatch (-want +got):\n%s", diff)
+	}
+
+	todos := query(t, s, todo.QueryOptions{})
+	if len(todos) != 0 {
+		t.Fatalf("query: expected no todo items, got %d", len(todos))
+	}
+}
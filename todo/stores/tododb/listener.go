@@ -0,0 +1,116 @@
+package tododb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// notifyChannel is the channel the trigger on todo_events notifies.
+const notifyChannel = "todo_events"
+
+// Reconnection and health check intervals of a Listener.
+const (
+	listenerMinReconnect = 100 * time.Millisecond
+	listenerMaxReconnect = 10 * time.Second
+	listenerPing         = time.Minute
+)
+
+// Listener forwards the changes committed by every instance sharing the
+// database to a todo.Publisher using LISTEN/NOTIFY. It takes the place of
+// todo.WithPublisher for stores backed by the database, so that instances
+// learn about each other's changes.
+type Listener struct {
+	store     *Store
+	listener  *pq.Listener
+	publisher todo.Publisher
+}
+
+// NewListener is a constructor for a Listener. It listens on its own
+// connection to the database given by url.
+func NewListener(db *sql.DB, url string, publisher todo.Publisher) (*Listener, error) {
+	l := pq.NewListener(url, listenerMinReconnect, listenerMaxReconnect, nil)
+
+	if err := l.Listen(notifyChannel); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	return &Listener{
+		store:     NewStore(db),
+		listener:  l,
+		publisher: publisher,
+	}, nil
+}
+
+// Run forwards notifications until ctx is canceled. Errors forwarding a
+// notification are passed to onError and do not stop the listener.
+// Notifications sent while the connection is being reestablished are lost.
+func (l *Listener) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(listenerPing)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case n := <-l.listener.Notify:
+			// A nil notification means the connection was
+			// reestablished.
+			if n == nil {
+				continue
+			}
+
+			if err := l.forward(ctx, n.Extra); err != nil {
+				onError(err)
+			}
+
+		case <-ticker.C:
+			go l.listener.Ping()
+		}
+	}
+}
+
+// Close closes the connection of the Listener.
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+// forward publishes the change described by payload along with the todo item
+// as it is now. Todo items purged since are sent as deleted with only their
+// ID.
+func (l *Listener) forward(ctx context.Context, payload string) error {
+	var p struct {
+		TodoID uuid.UUID      `json:"todo_id"`
+		Type   todo.EventType `json:"type"`
+	}
+
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return fmt.Errorf("decode notification: %w", err)
+	}
+
+	const query = `SELECT ` + todoColumns + ` FROM todos WHERE id = $1`
+
+	n := todo.Notification{Type: p.Type}
+
+	if err := scanTodo(l.store.conn().QueryRowContext(ctx, query, p.TodoID.String()), &n.Todo); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n = todo.Notification{Type: todo.EventDeleted, Todo: todo.Todo{ID: p.TodoID}}
+	}
+
+	l.publisher.Publish(n)
+
+	return nil
+}
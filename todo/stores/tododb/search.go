@@ -0,0 +1,65 @@
+package tododb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// headlineOptions configures ts_headline to highlight matches with the markers
+// expected by todo.Searcher.
+const headlineOptions = `StartSel="` + todo.HighlightStart + `", StopSel="` + todo.HighlightEnd + `", MaxWords=20, MinWords=10, ShortWord=0`
+
+// Search retrieves the todo items matching params from the database using the
+// full-text search index. Todo items are ranked with ts_rank.
+func (d *Store) Search(ctx context.Context, params todo.SearchParams) ([]todo.SearchResult, error) {
+	const query = `
+	SELECT ` + todoColumns + `,
+	  ts_rank(search, q),
+	  ts_headline('simple', text, q, $2)
+	FROM
+	  todos, to_tsquery('simple', $1) AS q
+	WHERE
+	  workspace_id = $5 AND owner_id = $4 AND search @@ q AND deleted_at IS NULL
+	ORDER BY
+	  ts_rank(search, q) DESC, time_updated DESC, id
+	LIMIT $3`
+
+	rows, err := d.conn().QueryContext(ctx, query, tsquery(params.Query), headlineOptions, params.Limit, params.OwnerID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	results := make([]todo.SearchResult, 0)
+
+	for rows.Next() {
+		var res todo.SearchResult
+		if err := scanTodo(rows, &res.Todo, &res.Rank, &res.Snippet); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return results, nil
+}
+
+// tsquery builds a text search query that matches the words starting with
+// every term of query. Terms only hold letters and digits, so they never need
+// to be quoted.
+func tsquery(query string) string {
+	terms := todo.SearchTerms(query)
+	for i := range terms {
+		terms[i] += ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
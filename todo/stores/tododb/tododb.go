@@ -3,10 +3,15 @@ package tododb
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/sudomateo/todo/todo"
 )
@@ -14,6 +19,9 @@ import (
 // Store exposes the APIs needed to interface with todo items in the database.
 type Store struct {
 	db *sql.DB
+
+	// tx is set on the store given to the function passed to WithTx.
+	tx *sql.Tx
 }
 
 // NewStore is a constructor for a Store.
@@ -23,55 +31,112 @@ func NewStore(db *sql.DB) *Store {
 	}
 }
 
-// Query retrieves all the todo items from the database.
-func (d *Store) Query(ctx context.Context) ([]todo.Todo, error) {
-	query := `SELECT * FROM todos ORDER BY time_created`
+// Query retrieves the todo items matching opts from the database.
+func (d *Store) Query(ctx context.Context, opts todo.QueryOptions) ([]todo.Todo, error) {
+	var (
+		where []string
+		args  []any
+	)
 
-	todos := make([]todo.Todo, 0)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
 
-	rows, err := d.db.QueryContext(ctx, query)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, todo.ErrNotFound
+	where = append(where, "workspace_id = "+arg(todo.WorkspaceFrom(ctx)))
+
+	f := opts.Filter
+	if f.OwnerID != nil {
+		where = append(where, "owner_id = "+arg(*f.OwnerID))
+	}
+	if f.Trashed {
+		where = append(where, "deleted_at IS NOT NULL")
+	} else {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if f.ListID != nil {
+		where = append(where, "list_id = "+arg(*f.ListID))
+	}
+	if f.Completed != nil {
+		where = append(where, "completed = "+arg(*f.Completed))
+	}
+	if f.Priority != nil {
+		where = append(where, "priority = "+arg(*f.Priority))
+	}
+	if len(f.Tags) > 0 {
+		where = append(where, fmt.Sprintf(
+			"id IN (SELECT todo_id FROM todo_tags WHERE tag = ANY(%s) GROUP BY todo_id HAVING COUNT(*) = %s)",
+			arg(pq.Array(f.Tags)),
+			arg(len(f.Tags)),
+		))
+	}
+	if f.CreatedAfter != nil {
+		where = append(where, "time_created >= "+arg(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		where = append(where, "time_created < "+arg(*f.CreatedBefore))
+	}
+	if f.UpdatedAfter != nil {
+		where = append(where, "time_updated >= "+arg(*f.UpdatedAfter))
+	}
+	if f.UpdatedBefore != nil {
+		where = append(where, "time_updated < "+arg(*f.UpdatedBefore))
+	}
+
+	if opts.After != nil {
+		cmp := ">"
+		if opts.Direction == todo.DirectionDesc {
+			cmp = "<"
 		}
+		where = append(where, fmt.Sprintf("(time_created, id) %s (%s, %s)",
+			cmp,
+			arg(opts.After.TimeCreated),
+			arg(opts.After.ID),
+		))
+	}
+
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE ` + strings.Join(where, ` AND `)
+	query += ` ORDER BY ` + orderBy(opts.OrderBy, opts.Direction)
+	if opts.Limit > 0 {
+		query += ` LIMIT ` + arg(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		query += ` OFFSET ` + arg(opts.Offset)
+	}
+
+	rows, err := d.conn().QueryContext(ctx, query, args...)
+	if err != nil {
 		return nil, fmt.Errorf("db: %w", err)
 	}
 
 	defer rows.Close()
 
-	for rows.Next() {
-		td := new(todo.Todo)
-		if err := rows.Scan(
-			&td.ID,
-			&td.Text,
-			&td.Priority,
-			&td.Completed,
-			&td.TimeCreated,
-			&td.TimeUpdated,
-		); err != nil {
-			return nil, err
-		}
+	return scanTodos(rows)
+}
+
+// QueryByID retrieves a todo item from the database.
+func (d *Store) QueryByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	const query = `SELECT ` + todoColumns + ` FROM todos WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NULL LIMIT 1`
+
+	var t todo.Todo
 
-		todos = append(todos, *td)
+	if err := scanTodo(d.conn().QueryRowContext(ctx, query+d.forUpdate(), id.String(), todo.WorkspaceFrom(ctx)), &t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Todo{}, todo.ErrNotFound
+		}
+		return todo.Todo{}, fmt.Errorf("db: %w", err)
 	}
 
-	return todos, nil
+	return t, nil
 }
 
-// QueryByID retrieves a todo item from the database.
-func (d *Store) QueryByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
-	const query = `SELECT * FROM todos WHERE id = $1 LIMIT 1`
+// QueryTrashByID retrieves a todo item in the trash from the database.
+func (d *Store) QueryTrashByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	const query = `SELECT ` + todoColumns + ` FROM todos WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NOT NULL LIMIT 1`
 
 	var t todo.Todo
 
-	if err := d.db.QueryRowContext(ctx, query, id.String()).Scan(
-		&t.ID,
-		&t.Text,
-		&t.Priority,
-		&t.Completed,
-		&t.TimeCreated,
-		&t.TimeUpdated,
-	); err != nil {
+	if err := scanTodo(d.conn().QueryRowContext(ctx, query+d.forUpdate(), id.String(), todo.WorkspaceFrom(ctx)), &t); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return todo.Todo{}, todo.ErrNotFound
 		}
@@ -81,30 +146,138 @@ func (d *Store) QueryByID(ctx context.Context, id uuid.UUID) (todo.Todo, error)
 	return t, nil
 }
 
+// QuerySubtree retrieves every descendant of the todo item given by id from
+// the database, ordered by depth.
+func (d *Store) QuerySubtree(ctx context.Context, id uuid.UUID) ([]todo.Todo, error) {
+	const query = `
+	WITH RECURSIVE subtree (id, depth) AS (
+	  SELECT id, 1 FROM todos WHERE parent_id = $1 AND workspace_id = $2 AND deleted_at IS NULL
+	  UNION
+	  SELECT todos.id, subtree.depth + 1 FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	  WHERE todos.deleted_at IS NULL
+	)
+	SELECT ` + todoColumns + `
+	FROM
+	  todos JOIN subtree USING (id)
+	ORDER BY
+	  subtree.depth, time_created, id`
+
+	rows, err := d.conn().QueryContext(ctx, query, id, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanTodos(rows)
+}
+
+// QueryOverdue retrieves all incomplete todo items owned by ownerID from the
+// database that were due before now.
+func (d *Store) QueryOverdue(ctx context.Context, ownerID uuid.UUID, now time.Time) ([]todo.Todo, error) {
+	const query = `
+	SELECT ` + todoColumns + `
+	FROM
+	  todos
+	WHERE
+	  workspace_id = $3 AND owner_id = $1 AND completed = false AND due_at < $2 AND deleted_at IS NULL
+	ORDER BY
+	  due_at`
+
+	rows, err := d.conn().QueryContext(ctx, query, ownerID, now, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanTodos(rows)
+}
+
+// QueryEvents retrieves the history of a todo item from the database.
+func (d *Store) QueryEvents(ctx context.Context, todoID uuid.UUID) ([]todo.Event, error) {
+	const query = `
+	SELECT
+	  id, todo_id, type, actor, changes, time_created
+	FROM
+	  todo_events
+	WHERE
+	  todo_id = $1 AND todo_id IN (SELECT id FROM todos WHERE workspace_id = $2)
+	ORDER BY
+	  seq`
+
+	rows, err := d.conn().QueryContext(ctx, query, todoID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	events := make([]todo.Event, 0)
+
+	for rows.Next() {
+		var (
+			ev      todo.Event
+			changes []byte
+		)
+
+		if err := rows.Scan(&ev.ID, &ev.TodoID, &ev.Type, &ev.Actor, &changes, &ev.TimeCreated); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(changes, &ev.Changes); err != nil {
+			return nil, fmt.Errorf("decode changes: %w", err)
+		}
+
+		events = append(events, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
 // Create adds a todo item to the database.
-func (d *Store) Create(ctx context.Context, td todo.Todo) error {
+func (d *Store) Create(ctx context.Context, td todo.Todo, ev todo.Event) error {
 	const query = `
 	INSERT INTO todos
-	  (id, text, priority, completed, time_created, time_updated)
+	  (id, workspace_id, owner_id, text, priority, completed, list_id, parent_id, due_at, recurrence, next_id, version, time_created, time_updated, deleted_at)
 	VALUES
-	  ($1, $2, $3, $4, $5, $6)`
+	  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
 
-	if _, err := d.db.ExecContext(ctx, query,
-		td.ID,
-		td.Text,
-		td.Priority,
-		td.Completed,
-		td.TimeCreated,
-		td.TimeUpdated,
-	); err != nil {
-		return fmt.Errorf("db: %w", err)
-	}
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query,
+			td.ID,
+			todo.WorkspaceFrom(ctx),
+			td.OwnerID,
+			td.Text,
+			td.Priority,
+			td.Completed,
+			td.ListID,
+			td.ParentID,
+			td.DueAt,
+			td.Recurrence,
+			td.NextID,
+			td.Version,
+			td.TimeCreated,
+			td.TimeUpdated,
+			td.TimeDeleted,
+		); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
 
-	return nil
+		if err := setTags(ctx, tx, td); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
 }
 
 // Update modifies an existing todo item in the database.
-func (d *Store) Update(ctx context.Context, td todo.Todo) error {
+func (d *Store) Update(ctx context.Context, td todo.Todo, ev todo.Event) error {
 	const query = `
 	UPDATE
 	  todos
@@ -112,16 +285,192 @@ func (d *Store) Update(ctx context.Context, td todo.Todo) error {
 		text = $1,
 		priority = $2,
 		completed = $3,
-		time_updated = $4
+		list_id = $4,
+		parent_id = $5,
+		due_at = $6,
+		recurrence = $7,
+		next_id = $8,
+		version = $9,
+		time_updated = $10
+	WHERE
+	  id = $11 AND workspace_id = $12 AND version = $9 - 1`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		if td.ParentID != nil {
+			if err := checkCycle(ctx, tx, td); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, query,
+			td.Text,
+			td.Priority,
+			td.Completed,
+			td.ListID,
+			td.ParentID,
+			td.DueAt,
+			td.Recurrence,
+			td.NextID,
+			td.Version,
+			td.TimeUpdated,
+			td.ID,
+			todo.WorkspaceFrom(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if n == 0 {
+			if _, err := d.QueryByID(ctx, td.ID); err != nil {
+				return err
+			}
+			return todo.ErrConflict
+		}
+
+		if err := setTags(ctx, tx, td); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// QueryTags retrieves every tag in use by the todo items owned by ownerID
+// from the database along with the number of todo items using it.
+func (d *Store) QueryTags(ctx context.Context, ownerID uuid.UUID) ([]todo.TagCount, error) {
+	const query = `
+	SELECT
+	  tag, COUNT(*)
+	FROM
+	  todo_tags JOIN todos ON todos.id = todo_tags.todo_id
+	WHERE
+	  todos.workspace_id = $2 AND todos.owner_id = $1 AND todos.deleted_at IS NULL
+	GROUP BY
+	  tag
+	ORDER BY
+	  tag`
+
+	rows, err := d.conn().QueryContext(ctx, query, ownerID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	tags := make([]todo.TagCount, 0)
+
+	for rows.Next() {
+		var tc todo.TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Delete moves a todo item and its descendants to the trash in the database.
+func (d *Store) Delete(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	const query = `
+	WITH RECURSIVE subtree (id) AS (
+	  SELECT id FROM todos WHERE id = $1 AND workspace_id = $3 AND deleted_at IS NULL
+	  UNION
+	  SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	)
+	UPDATE
+	  todos
+	SET
+	  deleted_at = $2
+	WHERE
+	  id IN (SELECT id FROM subtree) AND deleted_at IS NULL`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query,
+			td.ID,
+			td.TimeDeleted,
+			todo.WorkspaceFrom(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if n == 0 {
+			return nil
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// Restore takes a todo item and the descendants that were moved to the trash
+// along with it out of the trash in the database.
+func (d *Store) Restore(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	const query = `
+	WITH RECURSIVE subtree (id) AS (
+	  SELECT id FROM todos WHERE id = $1 AND workspace_id = $3 AND deleted_at = $2
+	  UNION
+	  SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	  WHERE todos.deleted_at = $2
+	)
+	UPDATE
+	  todos
+	SET
+	  deleted_at = NULL
+	WHERE
+	  id IN (SELECT id FROM subtree)`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query,
+			td.ID,
+			td.TimeDeleted,
+			todo.WorkspaceFrom(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if n == 0 {
+			return todo.ErrNotFound
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// Purge permanently deletes a todo item and, through the foreign keys on
+// parent_id and todo_events, its descendants and their history from the
+// database.
+func (d *Store) Purge(ctx context.Context, td todo.Todo) error {
+	const query = `
+	DELETE FROM
+	  todos
 	WHERE
-	  id = $5`
+	  id = $1 AND workspace_id = $2`
 
-	if _, err := d.db.ExecContext(ctx, query,
-		td.Text,
-		td.Priority,
-		td.Completed,
-		td.TimeUpdated,
+	if _, err := d.conn().ExecContext(ctx, query,
 		td.ID,
+		todo.WorkspaceFrom(ctx),
 	); err != nil {
 		return fmt.Errorf("db: %w", err)
 	}
@@ -129,19 +478,250 @@ func (d *Store) Update(ctx context.Context, td todo.Todo) error {
 	return nil
 }
 
-// Delete deletes a todo item from the database.
-func (d *Store) Delete(ctx context.Context, td todo.Todo) error {
+// PurgeTrash permanently deletes the todo items of ownerID, or of every owner
+// when it is nil, that were moved to the trash before the given time from the
+// database.
+func (d *Store) PurgeTrash(ctx context.Context, ownerID *uuid.UUID, before time.Time) (int, error) {
 	const query = `
 	DELETE FROM
 	  todos
 	WHERE
-	  id = $1`
+	  workspace_id = $3 AND deleted_at < $1 AND ($2::uuid IS NULL OR owner_id = $2::uuid)`
 
-	if _, err := d.db.ExecContext(ctx, query,
-		td.ID,
+	res, err := d.conn().ExecContext(ctx, query, before, ownerID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// WithTx calls fn with a store that runs every query in a database
+// transaction. Todo items and lists queried by ID are locked with
+// SELECT ... FOR UPDATE until the transaction ends.
+func (d *Store) WithTx(ctx context.Context, fn func(todo.Storer) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		return fn(&Store{db: d.db, tx: tx})
+	})
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction the store is bound to, or the database when
+// there is none.
+func (d *Store) conn() querier {
+	if d.tx != nil {
+		return d.tx
+	}
+
+	return d.db
+}
+
+// forUpdate returns the locking clause for queries that read a single row,
+// which only applies inside a transaction.
+func (d *Store) forUpdate() string {
+	if d.tx != nil {
+		return " FOR UPDATE"
+	}
+
+	return ""
+}
+
+// inTx runs fn in a database transaction that is committed when fn returns nil
+// and rolled back otherwise. Inside a transaction started by WithTx, fn runs in
+// that transaction instead and a failed statement aborts all of it.
+func (d *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if d.tx != nil {
+		return fn(d.tx)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// checkCycle returns todo.ErrCycle if the new parent of td is td itself or one
+// of its descendants.
+func checkCycle(ctx context.Context, tx *sql.Tx, td todo.Todo) error {
+	const query = `
+	WITH RECURSIVE subtree (id) AS (
+	  SELECT id FROM todos WHERE id = $1
+	  UNION
+	  SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	)
+	SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, query, td.ID, *td.ParentID).Scan(&cycle); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if cycle {
+		return todo.ErrCycle
+	}
+
+	return nil
+}
+
+// setTags replaces the tags of td in the database with td.Tags.
+func setTags(ctx context.Context, tx *sql.Tx, td todo.Todo) error {
+	const deleteQuery = `DELETE FROM todo_tags WHERE todo_id = $1`
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, td.ID); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if len(td.Tags) == 0 {
+		return nil
+	}
+
+	const insertQuery = `
+	INSERT INTO todo_tags
+	  (todo_id, tag)
+	SELECT
+	  $1, unnest($2::text[])`
+
+	if _, err := tx.ExecContext(ctx, insertQuery, td.ID, pq.Array(td.Tags)); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// insertEvent records ev in the history of its todo item.
+func insertEvent(ctx context.Context, tx *sql.Tx, ev todo.Event) error {
+	const query = `
+	INSERT INTO todo_events
+	  (id, todo_id, type, actor, changes, time_created)
+	VALUES
+	  ($1, $2, $3, $4, $5, $6)`
+
+	changes, err := json.Marshal(ev.Changes)
+	if err != nil {
+		return fmt.Errorf("encode changes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, query,
+		ev.ID,
+		ev.TodoID,
+		ev.Type,
+		ev.Actor,
+		changes,
+		ev.TimeCreated,
 	); err != nil {
 		return fmt.Errorf("db: %w", err)
 	}
 
 	return nil
 }
+
+// todoColumns lists the columns of the todos table in the order expected by
+// scanTodo. Tags are aggregated from the todo_tags table.
+const todoColumns = `
+	  id, workspace_id, owner_id, text, priority, completed, list_id, parent_id, due_at, recurrence, next_id,
+	  ARRAY(SELECT tag FROM todo_tags WHERE todo_id = todos.id ORDER BY tag),
+	  version, time_created, time_updated, deleted_at`
+
+// orderColumns maps the fields todo items can be sorted by to SQL expressions.
+var orderColumns = map[todo.OrderField]string{
+	todo.OrderByTimeCreated: "time_created",
+	todo.OrderByTimeUpdated: "time_updated",
+	todo.OrderByDueAt:       "due_at",
+	todo.OrderByText:        "text",
+	todo.OrderByPriority:    "CASE priority WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 ELSE 0 END",
+}
+
+// orderBy builds an ORDER BY clause for field and direction. Ties are broken
+// by creation time and then ID so that the order is always deterministic.
+func orderBy(field todo.OrderField, direction todo.Direction) string {
+	dir := "ASC"
+	if direction == todo.DirectionDesc {
+		dir = "DESC"
+	}
+
+	clause := orderColumns[field] + " " + dir
+	if field == todo.OrderByDueAt {
+		clause += " NULLS LAST"
+	}
+	if field != todo.OrderByTimeCreated {
+		clause += ", time_created " + dir
+	}
+
+	return clause + ", id " + dir
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTodo scans a row selected with todoColumns into td. Any columns selected
+// after todoColumns are scanned into extra.
+func scanTodo(row scanner, td *todo.Todo, extra ...any) error {
+	dest := []any{
+		&td.ID,
+		&td.WorkspaceID,
+		&td.OwnerID,
+		&td.Text,
+		&td.Priority,
+		&td.Completed,
+		&td.ListID,
+		&td.ParentID,
+		&td.DueAt,
+		&td.Recurrence,
+		&td.NextID,
+		pq.Array(&td.Tags),
+		&td.Version,
+		&td.TimeCreated,
+		&td.TimeUpdated,
+		&td.TimeDeleted,
+	}
+
+	return row.Scan(append(dest, extra...)...)
+}
+
+// scanTodos scans all rows selected with todoColumns.
+func scanTodos(rows *sql.Rows) ([]todo.Todo, error) {
+	todos := make([]todo.Todo, 0)
+
+	for rows.Next() {
+		var td todo.Todo
+		if err := scanTodo(rows, &td); err != nil {
+			return nil, err
+		}
+
+		todos = append(todos, td)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
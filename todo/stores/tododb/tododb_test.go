@@ -0,0 +1,45 @@
+package tododb_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/sudomateo/todo/database"
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/storertest"
+	"github.com/sudomateo/todo/todo/stores/tododb"
+)
+
+// TestStore runs against the PostgreSQL database at TODO_TEST_DATABASE_URL.
+// Every table in that database is emptied, so it must not hold real data.
+func TestStore(t *testing.T) {
+	url := os.Getenv("TODO_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TODO_TEST_DATABASE_URL is not set")
+	}
+
+	db, err := database.Open(url)
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: expected nil error, got %v", err)
+	}
+
+	storertest.Run(t, func() todo.Storer {
+		const reset = `
+		TRUNCATE todos, todo_tags, todo_events, webhooks, webhook_deliveries, webhook_attempts, users, api_keys, sessions, identities, shares, workspace_members;
+		DELETE FROM lists WHERE id <> '00000000-0000-0000-0000-000000000000';
+		DELETE FROM workspaces WHERE id <> '00000000-0000-0000-0000-000000000000';`
+
+		if _, err := db.ExecContext(context.Background(), reset); err != nil {
+			panic(fmt.Sprintf("reset: %v", err))
+		}
+
+		return tododb.NewStore(db)
+	})
+}
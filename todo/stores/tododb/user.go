@@ -0,0 +1,218 @@
+package tododb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// userColumns lists the columns of the users table in the order expected by
+// scanUser.
+const userColumns = `id, name, password_hash, time_created, time_updated`
+
+// apiKeyColumns lists the columns of the api_keys table in the order expected
+// by scanAPIKey.
+const apiKeyColumns = `id, user_id, name, prefix, hash, time_created, time_revoked`
+
+// QueryUserByID retrieves a user from the database.
+func (d *Store) QueryUserByID(ctx context.Context, id uuid.UUID) (todo.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	return d.queryUser(ctx, query, id)
+}
+
+// QueryUserByName retrieves a user from the database by their name.
+func (d *Store) QueryUserByName(ctx context.Context, name string) (todo.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE name = $1`
+
+	return d.queryUser(ctx, query, name)
+}
+
+// queryUser retrieves the user selected by query.
+func (d *Store) queryUser(ctx context.Context, query string, args ...any) (todo.User, error) {
+	var u todo.User
+
+	if err := scanUser(d.conn().QueryRowContext(ctx, query, args...), &u); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.User{}, todo.ErrUserNotFound
+		}
+		return todo.User{}, fmt.Errorf("db: %w", err)
+	}
+
+	return u, nil
+}
+
+// CreateUser adds a user to the database.
+func (d *Store) CreateUser(ctx context.Context, user todo.User) error {
+	const query = `
+	INSERT INTO users
+	  (id, name, password_hash, time_created, time_updated)
+	VALUES
+	  ($1, $2, $3, $4, $5)
+	ON CONFLICT (name) DO NOTHING`
+
+	res, err := d.conn().ExecContext(ctx, query,
+		user.ID,
+		user.Name,
+		user.PasswordHash,
+		user.TimeCreated,
+		user.TimeUpdated,
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrUserExists
+	}
+
+	return nil
+}
+
+// QueryAPIKeys retrieves the API keys of a user from the database in the
+// order they were created.
+func (d *Store) QueryAPIKeys(ctx context.Context, userID uuid.UUID) ([]todo.APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY time_created, id`
+
+	rows, err := d.conn().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	keys := make([]todo.APIKey, 0)
+
+	for rows.Next() {
+		var key todo.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return keys, nil
+}
+
+// QueryAPIKeyByID retrieves an API key from the database.
+func (d *Store) QueryAPIKeyByID(ctx context.Context, id uuid.UUID) (todo.APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	return d.queryAPIKey(ctx, query, id)
+}
+
+// QueryAPIKeyByHash retrieves an API key from the database by the hash of the
+// key.
+func (d *Store) QueryAPIKeyByHash(ctx context.Context, hash string) (todo.APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE hash = $1`
+
+	return d.queryAPIKey(ctx, query, hash)
+}
+
+// queryAPIKey retrieves the API key selected by query.
+func (d *Store) queryAPIKey(ctx context.Context, query string, args ...any) (todo.APIKey, error) {
+	var key todo.APIKey
+
+	if err := scanAPIKey(d.conn().QueryRowContext(ctx, query, args...), &key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.APIKey{}, todo.ErrAPIKeyNotFound
+		}
+		return todo.APIKey{}, fmt.Errorf("db: %w", err)
+	}
+
+	return key, nil
+}
+
+// CreateAPIKey adds an API key to the database.
+func (d *Store) CreateAPIKey(ctx context.Context, key todo.APIKey) error {
+	const query = `
+	INSERT INTO api_keys
+	  (id, user_id, name, prefix, hash, time_created, time_revoked)
+	VALUES
+	  ($1, $2, $3, $4, $5, $6, $7)`
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		key.TimeCreated,
+		key.TimeRevoked,
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAPIKey modifies an existing API key in the database.
+func (d *Store) UpdateAPIKey(ctx context.Context, key todo.APIKey) error {
+	const query = `
+	UPDATE
+	  api_keys
+	SET
+	  name = $1,
+	  time_revoked = $2
+	WHERE
+	  id = $3`
+
+	res, err := d.conn().ExecContext(ctx, query,
+		key.Name,
+		key.TimeRevoked,
+		key.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// scanUser scans a row selected with userColumns into u.
+func scanUser(row scanner, u *todo.User) error {
+	return row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.PasswordHash,
+		&u.TimeCreated,
+		&u.TimeUpdated,
+	)
+}
+
+// scanAPIKey scans a row selected with apiKeyColumns into key.
+func scanAPIKey(row scanner, key *todo.APIKey) error {
+	return row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&key.TimeCreated,
+		&key.TimeRevoked,
+	)
+}
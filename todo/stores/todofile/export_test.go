@@ -0,0 +1,31 @@
+package todofile
+
+import "errors"
+
+// FailNextWrite makes the next write to the log of s stop after n bytes with
+// an error, as when the disk fills up.
+func FailNextWrite(s *Store, n int) {
+	s.log = &failingFile{file: s.log, n: n}
+}
+
+// failingFile fails a single write to file after n bytes.
+type failingFile struct {
+	file
+	n    int
+	done bool
+}
+
+func (f *failingFile) Write(b []byte) (int, error) {
+	if f.done || f.n >= len(b) {
+		return f.file.Write(b)
+	}
+
+	f.done = true
+
+	n, err := f.file.Write(b[:f.n])
+	if err != nil {
+		return n, err
+	}
+
+	return n, errors.New("no space left on device")
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package todofile
+
+import "os"
+
+// lock does nothing on platforms without flock, where the directory is not
+// locked.
+func lock(f *os.File) error {
+	return nil
+}
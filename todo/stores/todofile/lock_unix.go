@@ -0,0 +1,23 @@
+//go:build unix
+
+package todofile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lock takes an exclusive lock on f without waiting for it. The lock is
+// released when f is closed, or by the operating system when the process
+// exits.
+func lock(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return ErrLocked
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,969 @@
+// Package todofile stores todo items in memory and persists them to a
+// directory so that they survive restarts.
+//
+// Every change is checked against the todo items in memory, appended to a
+// JSON lines log and synced to disk, and only then applied in memory and
+// acknowledged. A record that fails to be written or synced is truncated from
+// the log again, and the store refuses further changes should that fail too.
+// Once the log grows past a number of records it is compacted into a snapshot
+// of the whole store. A compaction that fails is logged and tried again after
+// the next change, since the log still holds every change. On startup the
+// snapshot is loaded and the log is replayed on top of it. A record that was
+// only partially written when the process stopped is discarded.
+//
+// The directory is locked while the store is open, so that no two processes
+// append to the same log.
+package todofile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+// File names used inside the data directory.
+const (
+	lockFile     = "lock"
+	logFile      = "log.jsonl"
+	snapshotFile = "snapshot.json"
+)
+
+// ErrLocked is returned by Open when the directory is used by another store.
+var ErrLocked = errors.New("data directory is locked by another process")
+
+// defaultCompactEvery is the number of log records after which the log is
+// compacted into a snapshot.
+const defaultCompactEvery = 1000
+
+// Operations recorded in the log.
+const (
+	opCreate     = "create"
+	opUpdate     = "update"
+	opDelete     = "delete"
+	opRestore    = "restore"
+	opPurge      = "purge"
+	opPurgeTrash = "purge_trash"
+	opCreateList = "create_list"
+	opUpdateList = "update_list"
+	opDeleteList = "delete_list"
+	opTx         = "tx"
+
+	opCreateWebhook  = "create_webhook"
+	opUpdateWebhook  = "update_webhook"
+	opDeleteWebhook  = "delete_webhook"
+	opCreateDelivery = "create_delivery"
+	opUpdateDelivery = "update_delivery"
+
+	opCreateUser   = "create_user"
+	opCreateAPIKey = "create_api_key"
+	opUpdateAPIKey = "update_api_key"
+
+	opCreateSession = "create_session"
+	opDeleteSession = "delete_session"
+	opPurgeSessions = "purge_sessions"
+
+	opCreateIdentity = "create_identity"
+
+	opPutShare    = "put_share"
+	opDeleteShare = "delete_share"
+
+	opCreateWorkspace = "create_workspace"
+	opUpdateWorkspace = "update_workspace"
+	opDeleteWorkspace = "delete_workspace"
+	opPutMember       = "put_member"
+	opDeleteMember    = "delete_member"
+)
+
+// record is a single change in the log. Records are numbered so that the ones
+// already contained in a snapshot can be skipped. The changes made in a
+// transaction are kept together in a single record so that they are written
+// atomically. Changes to todo items and lists record the workspace they were
+// made in, which is left out for the default workspace.
+type record struct {
+	Seq         uint64      `json:"seq"`
+	Op          string      `json:"op"`
+	WorkspaceID *uuid.UUID  `json:"workspace_id,omitempty"`
+	Todo        *todo.Todo  `json:"todo,omitempty"`
+	List        *todo.List  `json:"list,omitempty"`
+	Event       *todo.Event `json:"event,omitempty"`
+	Before      *time.Time  `json:"before,omitempty"`
+	Records     []record    `json:"records,omitempty"`
+
+	Webhook  *todo.Webhook  `json:"webhook,omitempty"`
+	Delivery *todo.Delivery `json:"delivery,omitempty"`
+
+	OwnerID *uuid.UUID   `json:"owner_id,omitempty"`
+	User    *todo.User   `json:"user,omitempty"`
+	APIKey  *todo.APIKey `json:"api_key,omitempty"`
+
+	Session  *todo.Session  `json:"session,omitempty"`
+	Identity *todo.Identity `json:"identity,omitempty"`
+	Share    *todo.Share    `json:"share,omitempty"`
+
+	Workspace *todo.Workspace `json:"workspace,omitempty"`
+	Member    *todo.Member    `json:"member,omitempty"`
+}
+
+// snapshot is the contents of the store along with the number of the last
+// record it contains.
+type snapshot struct {
+	Seq uint64 `json:"seq"`
+	todomemory.Snapshot
+}
+
+// Store exposes the APIs needed to interface with todo items kept in memory
+// and persisted to a directory. Queries are served from memory.
+type Store struct {
+	memory *todomemory.Store
+
+	dir          string
+	compactEvery int
+	logger       hclog.Logger
+
+	// lock is held open while the store is, keeping the directory locked.
+	lock *os.File
+
+	// mutex serializes changes so that the log has the same order as the
+	// changes applied in memory.
+	mutex   sync.Mutex
+	log     file
+	size    int64
+	seq     uint64
+	records int
+
+	// failed is set once a record that failed to be written could not be
+	// removed from the log again. No more records are written after it.
+	failed error
+
+	// tx is set on the store given to the function passed to WithTx. Its
+	// changes are collected in batch until the transaction is committed.
+	tx    bool
+	batch []record
+}
+
+// file is the part of *os.File the log is written through.
+type file interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithCompactEvery sets the number of log records after which the log is
+// compacted into a snapshot. The default is 1000.
+func WithCompactEvery(n int) Option {
+	return func(s *Store) {
+		s.compactEvery = n
+	}
+}
+
+// WithLogger sets the logger failed compactions are logged to. Nothing is
+// logged by default.
+func WithLogger(logger hclog.Logger) Option {
+	return func(s *Store) {
+		s.logger = logger
+	}
+}
+
+// Open opens the store persisted in dir, creating dir if it does not exist.
+// It returns ErrLocked when another store has dir open.
+func Open(dir string, opts ...Option) (_ *Store, err error) {
+	s := Store{
+		dir:          dir,
+		compactEvery: defaultCompactEvery,
+		logger:       hclog.NewNullLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	s.lock, err = os.OpenFile(filepath.Join(dir, lockFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			s.lock.Close()
+		}
+	}()
+
+	if err := lock(s.lock); err != nil {
+		return nil, err
+	}
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+
+	if snap == nil {
+		s.memory = todomemory.NewStore()
+	} else {
+		s.memory = todomemory.NewStoreFromSnapshot(snap.Snapshot)
+		s.seq = snap.Seq
+	}
+
+	if err := s.replay(); err != nil {
+		return nil, fmt.Errorf("replay log: %w", err)
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+
+	info, err := log.Stat()
+	if err != nil {
+		log.Close()
+		return nil, fmt.Errorf("stat log: %w", err)
+	}
+
+	s.log = log
+	s.size = info.Size()
+
+	return &s, nil
+}
+
+// Close compacts the log and closes the store, unlocking its directory.
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	defer s.lock.Close()
+
+	if s.records > 0 {
+		if err := s.compact(); err != nil {
+			s.log.Close()
+			return err
+		}
+	}
+
+	return s.log.Close()
+}
+
+// Query retrieves the todo items matching opts from memory.
+func (s *Store) Query(ctx context.Context, opts todo.QueryOptions) ([]todo.Todo, error) {
+	return s.memory.Query(ctx, opts)
+}
+
+// QueryByID retrieves a todo item from memory.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	return s.memory.QueryByID(ctx, id)
+}
+
+// QueryTrashByID retrieves a todo item in the trash from memory.
+func (s *Store) QueryTrashByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	return s.memory.QueryTrashByID(ctx, id)
+}
+
+// QuerySubtree retrieves every descendant of the todo item given by id from
+// memory, ordered by depth.
+func (s *Store) QuerySubtree(ctx context.Context, id uuid.UUID) ([]todo.Todo, error) {
+	return s.memory.QuerySubtree(ctx, id)
+}
+
+// QueryOverdue retrieves all incomplete todo items of ownerID from memory that
+// were due before now.
+func (s *Store) QueryOverdue(ctx context.Context, ownerID uuid.UUID, now time.Time) ([]todo.Todo, error) {
+	return s.memory.QueryOverdue(ctx, ownerID, now)
+}
+
+// QueryTags retrieves every tag ownerID uses from memory along with the number
+// of their todo items using it.
+func (s *Store) QueryTags(ctx context.Context, ownerID uuid.UUID) ([]todo.TagCount, error) {
+	return s.memory.QueryTags(ctx, ownerID)
+}
+
+// QueryEvents retrieves the history of a todo item from memory.
+func (s *Store) QueryEvents(ctx context.Context, todoID uuid.UUID) ([]todo.Event, error) {
+	return s.memory.QueryEvents(ctx, todoID)
+}
+
+// Search retrieves the todo items matching params from memory.
+func (s *Store) Search(ctx context.Context, params todo.SearchParams) ([]todo.SearchResult, error) {
+	return s.memory.Search(ctx, params)
+}
+
+// QueryLists retrieves all lists from memory, starting with the inbox.
+func (s *Store) QueryLists(ctx context.Context) ([]todo.List, error) {
+	return s.memory.QueryLists(ctx)
+}
+
+// QueryListByID retrieves a list from memory.
+func (s *Store) QueryListByID(ctx context.Context, id uuid.UUID) (todo.List, error) {
+	return s.memory.QueryListByID(ctx, id)
+}
+
+// Create adds a todo item to memory and the log.
+func (s *Store) Create(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	return s.change(record{Op: opCreate, WorkspaceID: workspaceID(ctx), Todo: &td, Event: &ev})
+}
+
+// Update modifies an existing todo item in memory and records it in the log.
+func (s *Store) Update(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	return s.change(record{Op: opUpdate, WorkspaceID: workspaceID(ctx), Todo: &td, Event: &ev})
+}
+
+// Delete moves a todo item and its descendants to the trash in memory and
+// records it in the log.
+func (s *Store) Delete(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	return s.change(record{Op: opDelete, WorkspaceID: workspaceID(ctx), Todo: &td, Event: &ev})
+}
+
+// Restore takes a todo item out of the trash in memory and records it in the
+// log.
+func (s *Store) Restore(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	return s.change(record{Op: opRestore, WorkspaceID: workspaceID(ctx), Todo: &td, Event: &ev})
+}
+
+// Purge permanently deletes a todo item and its descendants from memory and
+// records it in the log.
+func (s *Store) Purge(ctx context.Context, td todo.Todo) error {
+	return s.change(record{Op: opPurge, WorkspaceID: workspaceID(ctx), Todo: &td})
+}
+
+// PurgeTrash permanently deletes the todo items of ownerID, or of every owner
+// when it is nil, that were moved to the trash before the given time from
+// memory and records it in the log.
+func (s *Store) PurgeTrash(ctx context.Context, ownerID *uuid.UUID, before time.Time) (int, error) {
+	n := 0
+
+	err := s.commit(func(tx *Store) error {
+		var err error
+		if n, err = tx.memory.PurgeTrash(ctx, ownerID, before); err != nil || n == 0 {
+			return err
+		}
+
+		return tx.append(record{Op: opPurgeTrash, WorkspaceID: workspaceID(ctx), OwnerID: ownerID, Before: &before})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// WithTx calls fn with a store whose changes are made in a transaction on the
+// todo items in memory. When fn returns nil the changes are appended to the log
+// as a single record, so that either all or none of them survive a crash, and
+// the transaction is committed once the record is on disk.
+func (s *Store) WithTx(ctx context.Context, fn func(todo.Storer) error) error {
+	return s.commit(func(tx *Store) error {
+		return fn(tx)
+	})
+}
+
+// CreateList adds a list to memory and the log.
+func (s *Store) CreateList(ctx context.Context, l todo.List) error {
+	return s.change(record{Op: opCreateList, WorkspaceID: workspaceID(ctx), List: &l})
+}
+
+// UpdateList modifies an existing list in memory and records it in the log.
+func (s *Store) UpdateList(ctx context.Context, l todo.List) error {
+	return s.change(record{Op: opUpdateList, WorkspaceID: workspaceID(ctx), List: &l})
+}
+
+// DeleteList deletes a list from memory and records it in the log.
+func (s *Store) DeleteList(ctx context.Context, l todo.List) error {
+	return s.change(record{Op: opDeleteList, WorkspaceID: workspaceID(ctx), List: &l})
+}
+
+// QueryWebhooks retrieves the webhooks matching filter from memory.
+func (s *Store) QueryWebhooks(ctx context.Context, filter todo.WebhookFilter) ([]todo.Webhook, error) {
+	return s.memory.QueryWebhooks(ctx, filter)
+}
+
+// QueryWebhookByID retrieves a webhook from memory.
+func (s *Store) QueryWebhookByID(ctx context.Context, id uuid.UUID) (todo.Webhook, error) {
+	return s.memory.QueryWebhookByID(ctx, id)
+}
+
+// CreateWebhook adds a webhook to memory and the log.
+func (s *Store) CreateWebhook(ctx context.Context, wh todo.Webhook) error {
+	return s.change(record{Op: opCreateWebhook, Webhook: &wh})
+}
+
+// UpdateWebhook modifies an existing webhook in memory and records it in the
+// log.
+func (s *Store) UpdateWebhook(ctx context.Context, wh todo.Webhook) error {
+	return s.change(record{Op: opUpdateWebhook, Webhook: &wh})
+}
+
+// DeleteWebhook deletes a webhook and its deliveries from memory and records
+// it in the log.
+func (s *Store) DeleteWebhook(ctx context.Context, wh todo.Webhook) error {
+	return s.change(record{Op: opDeleteWebhook, Webhook: &wh})
+}
+
+// QueryDeliveries retrieves the deliveries of a webhook from memory.
+func (s *Store) QueryDeliveries(ctx context.Context, webhookID uuid.UUID) ([]todo.Delivery, error) {
+	return s.memory.QueryDeliveries(ctx, webhookID)
+}
+
+// QueryDeliveryByID retrieves a delivery from memory.
+func (s *Store) QueryDeliveryByID(ctx context.Context, id uuid.UUID) (todo.Delivery, error) {
+	return s.memory.QueryDeliveryByID(ctx, id)
+}
+
+// CreateDelivery adds a delivery to memory and the log.
+func (s *Store) CreateDelivery(ctx context.Context, d todo.Delivery) error {
+	return s.change(record{Op: opCreateDelivery, Delivery: &d})
+}
+
+// ClaimDeliveries claims the pending deliveries that are due in memory and
+// records their new next attempt in the log.
+func (s *Store) ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]todo.Delivery, error) {
+	var claimed []todo.Delivery
+
+	err := s.commit(func(tx *Store) error {
+		var err error
+		if claimed, err = tx.memory.ClaimDeliveries(ctx, now, until, limit); err != nil {
+			return err
+		}
+
+		for i := range claimed {
+			if err := tx.append(record{Op: opUpdateDelivery, Delivery: &claimed[i]}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// UpdateDelivery modifies an existing delivery in memory and records it in
+// the log.
+func (s *Store) UpdateDelivery(ctx context.Context, d todo.Delivery) error {
+	return s.change(record{Op: opUpdateDelivery, Delivery: &d})
+}
+
+// QueryUserByID retrieves a user from memory.
+func (s *Store) QueryUserByID(ctx context.Context, id uuid.UUID) (todo.User, error) {
+	return s.memory.QueryUserByID(ctx, id)
+}
+
+// QueryUserByName retrieves a user from memory by their name.
+func (s *Store) QueryUserByName(ctx context.Context, name string) (todo.User, error) {
+	return s.memory.QueryUserByName(ctx, name)
+}
+
+// CreateUser adds a user to memory and the log.
+func (s *Store) CreateUser(ctx context.Context, user todo.User) error {
+	return s.change(record{Op: opCreateUser, User: &user})
+}
+
+// QueryAPIKeys retrieves the API keys of a user from memory.
+func (s *Store) QueryAPIKeys(ctx context.Context, userID uuid.UUID) ([]todo.APIKey, error) {
+	return s.memory.QueryAPIKeys(ctx, userID)
+}
+
+// QueryAPIKeyByID retrieves an API key from memory.
+func (s *Store) QueryAPIKeyByID(ctx context.Context, id uuid.UUID) (todo.APIKey, error) {
+	return s.memory.QueryAPIKeyByID(ctx, id)
+}
+
+// QueryAPIKeyByHash retrieves an API key from memory by the hash of the key.
+func (s *Store) QueryAPIKeyByHash(ctx context.Context, hash string) (todo.APIKey, error) {
+	return s.memory.QueryAPIKeyByHash(ctx, hash)
+}
+
+// CreateAPIKey adds an API key to memory and the log.
+func (s *Store) CreateAPIKey(ctx context.Context, key todo.APIKey) error {
+	return s.change(record{Op: opCreateAPIKey, APIKey: &key})
+}
+
+// UpdateAPIKey modifies an existing API key in memory and records it in the
+// log.
+func (s *Store) UpdateAPIKey(ctx context.Context, key todo.APIKey) error {
+	return s.change(record{Op: opUpdateAPIKey, APIKey: &key})
+}
+
+// QuerySessionByHash retrieves a session from memory by the hash of its
+// token.
+func (s *Store) QuerySessionByHash(ctx context.Context, hash string) (todo.Session, error) {
+	return s.memory.QuerySessionByHash(ctx, hash)
+}
+
+// CreateSession adds a session to memory and the log.
+func (s *Store) CreateSession(ctx context.Context, session todo.Session) error {
+	return s.change(record{Op: opCreateSession, Session: &session})
+}
+
+// DeleteSession deletes a session from memory and records it in the log.
+func (s *Store) DeleteSession(ctx context.Context, session todo.Session) error {
+	return s.change(record{Op: opDeleteSession, Session: &session})
+}
+
+// PurgeSessions deletes the sessions that expired before the given time from
+// memory and records it in the log.
+func (s *Store) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	n := 0
+
+	err := s.commit(func(tx *Store) error {
+		var err error
+		if n, err = tx.memory.PurgeSessions(ctx, before); err != nil || n == 0 {
+			return err
+		}
+
+		return tx.append(record{Op: opPurgeSessions, Before: &before})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// QueryIdentity retrieves an identity from memory by its issuer and subject.
+func (s *Store) QueryIdentity(ctx context.Context, issuer string, subject string) (todo.Identity, error) {
+	return s.memory.QueryIdentity(ctx, issuer, subject)
+}
+
+// CreateIdentity adds an identity to memory and the log.
+func (s *Store) CreateIdentity(ctx context.Context, identity todo.Identity) error {
+	return s.change(record{Op: opCreateIdentity, Identity: &identity})
+}
+
+// QueryShares retrieves the shares of a todo item from memory, oldest first.
+func (s *Store) QueryShares(ctx context.Context, todoID uuid.UUID) ([]todo.Share, error) {
+	return s.memory.QueryShares(ctx, todoID)
+}
+
+// QuerySharesByUser retrieves the shares of a user from memory, oldest first.
+func (s *Store) QuerySharesByUser(ctx context.Context, userID uuid.UUID) ([]todo.Share, error) {
+	return s.memory.QuerySharesByUser(ctx, userID)
+}
+
+// QueryShare retrieves the share of a todo item with a user from memory.
+func (s *Store) QueryShare(ctx context.Context, todoID uuid.UUID, userID uuid.UUID) (todo.Share, error) {
+	return s.memory.QueryShare(ctx, todoID, userID)
+}
+
+// PutShare adds or replaces a share in memory and records it in the log.
+func (s *Store) PutShare(ctx context.Context, share todo.Share) error {
+	return s.change(record{Op: opPutShare, Share: &share})
+}
+
+// DeleteShare deletes a share from memory and records it in the log.
+func (s *Store) DeleteShare(ctx context.Context, share todo.Share) error {
+	return s.change(record{Op: opDeleteShare, Share: &share})
+}
+
+// CreateWorkspace adds a workspace to memory and the log.
+func (s *Store) CreateWorkspace(ctx context.Context, ws todo.Workspace) error {
+	return s.change(record{Op: opCreateWorkspace, Workspace: &ws})
+}
+
+// UpdateWorkspace modifies an existing workspace in memory and records it in
+// the log.
+func (s *Store) UpdateWorkspace(ctx context.Context, ws todo.Workspace) error {
+	return s.change(record{Op: opUpdateWorkspace, Workspace: &ws})
+}
+
+// DeleteWorkspace deletes a workspace along with its todo items, lists and
+// members from memory and records it in the log.
+func (s *Store) DeleteWorkspace(ctx context.Context, ws todo.Workspace) error {
+	return s.change(record{Op: opDeleteWorkspace, Workspace: &ws})
+}
+
+// QueryWorkspaces retrieves the workspaces userID is a member of, or every
+// workspace when it is nil, from memory.
+func (s *Store) QueryWorkspaces(ctx context.Context, userID *uuid.UUID) ([]todo.Workspace, error) {
+	return s.memory.QueryWorkspaces(ctx, userID)
+}
+
+// QueryWorkspaceByID retrieves a workspace from memory.
+func (s *Store) QueryWorkspaceByID(ctx context.Context, id uuid.UUID) (todo.Workspace, error) {
+	return s.memory.QueryWorkspaceByID(ctx, id)
+}
+
+// QueryMembers retrieves the members of a workspace from memory.
+func (s *Store) QueryMembers(ctx context.Context, workspaceID uuid.UUID) ([]todo.Member, error) {
+	return s.memory.QueryMembers(ctx, workspaceID)
+}
+
+// QueryMember retrieves the membership of a user in a workspace from memory.
+func (s *Store) QueryMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (todo.Member, error) {
+	return s.memory.QueryMember(ctx, workspaceID, userID)
+}
+
+// PutMember adds a member or replaces its role in memory and records it in
+// the log.
+func (s *Store) PutMember(ctx context.Context, member todo.Member) error {
+	return s.change(record{Op: opPutMember, Member: &member})
+}
+
+// DeleteMember deletes a member from memory and records it in the log.
+func (s *Store) DeleteMember(ctx context.Context, member todo.Member) error {
+	return s.change(record{Op: opDeleteMember, Member: &member})
+}
+
+// workspaceID returns the workspace in ctx to record, or nil for the default
+// workspace.
+func workspaceID(ctx context.Context) *uuid.UUID {
+	id := todo.WorkspaceFrom(ctx)
+	if id == todo.DefaultWorkspaceID {
+		return nil
+	}
+
+	return &id
+}
+
+// change applies r in memory and appends it to the log when it succeeds.
+func (s *Store) change(r record) error {
+	return s.commit(func(tx *Store) error {
+		if err := tx.apply(r); err != nil {
+			return err
+		}
+
+		return tx.append(r)
+	})
+}
+
+// commit calls fn with a store whose changes are applied in a transaction on
+// the todo items in memory and collected in its batch. Once fn returns nil the
+// batch is appended to the log and synced to disk, and only then is the
+// transaction committed, so that memory never holds a change the log does not.
+// Inside a transaction fn is called with the transaction itself.
+func (s *Store) commit(fn func(tx *Store) error) error {
+	if s.tx {
+		return fn(s)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.memory.Tx(func(memory *todomemory.Store) error {
+		tx := &Store{memory: memory, tx: true}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		switch len(tx.batch) {
+		case 0:
+			return nil
+		case 1:
+			return s.append(tx.batch[0])
+		default:
+			return s.append(record{Op: opTx, Records: tx.batch})
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.compactIfFull()
+
+	return nil
+}
+
+// apply applies r to the todo items in memory.
+func (s *Store) apply(r record) error {
+	ctx := context.Background()
+	if r.WorkspaceID != nil {
+		ctx = todo.WithWorkspace(ctx, *r.WorkspaceID)
+	}
+
+	switch r.Op {
+	case opCreate:
+		return s.memory.Create(ctx, *r.Todo, *r.Event)
+	case opUpdate:
+		return s.memory.Update(ctx, *r.Todo, *r.Event)
+	case opDelete:
+		return s.memory.Delete(ctx, *r.Todo, *r.Event)
+	case opRestore:
+		return s.memory.Restore(ctx, *r.Todo, *r.Event)
+	case opPurge:
+		return s.memory.Purge(ctx, *r.Todo)
+	case opPurgeTrash:
+		_, err := s.memory.PurgeTrash(ctx, r.OwnerID, *r.Before)
+		return err
+	case opCreateList:
+		return s.memory.CreateList(ctx, *r.List)
+	case opUpdateList:
+		return s.memory.UpdateList(ctx, *r.List)
+	case opDeleteList:
+		return s.memory.DeleteList(ctx, *r.List)
+	case opCreateWebhook:
+		return s.memory.CreateWebhook(ctx, *r.Webhook)
+	case opUpdateWebhook:
+		return s.memory.UpdateWebhook(ctx, *r.Webhook)
+	case opDeleteWebhook:
+		return s.memory.DeleteWebhook(ctx, *r.Webhook)
+	case opCreateDelivery:
+		return s.memory.CreateDelivery(ctx, *r.Delivery)
+	case opUpdateDelivery:
+		return s.memory.UpdateDelivery(ctx, *r.Delivery)
+	case opCreateUser:
+		return s.memory.CreateUser(ctx, *r.User)
+	case opCreateAPIKey:
+		return s.memory.CreateAPIKey(ctx, *r.APIKey)
+	case opUpdateAPIKey:
+		return s.memory.UpdateAPIKey(ctx, *r.APIKey)
+	case opCreateSession:
+		return s.memory.CreateSession(ctx, *r.Session)
+	case opDeleteSession:
+		return s.memory.DeleteSession(ctx, *r.Session)
+	case opPurgeSessions:
+		_, err := s.memory.PurgeSessions(ctx, *r.Before)
+		return err
+	case opCreateIdentity:
+		return s.memory.CreateIdentity(ctx, *r.Identity)
+	case opPutShare:
+		return s.memory.PutShare(ctx, *r.Share)
+	case opDeleteShare:
+		return s.memory.DeleteShare(ctx, *r.Share)
+	case opCreateWorkspace:
+		return s.memory.CreateWorkspace(ctx, *r.Workspace)
+	case opUpdateWorkspace:
+		return s.memory.UpdateWorkspace(ctx, *r.Workspace)
+	case opDeleteWorkspace:
+		return s.memory.DeleteWorkspace(ctx, *r.Workspace)
+	case opPutMember:
+		return s.memory.PutMember(ctx, *r.Member)
+	case opDeleteMember:
+		return s.memory.DeleteMember(ctx, *r.Member)
+	case opTx:
+		for _, r := range r.Records {
+			if err := s.apply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown operation %q", r.Op)
+}
+
+// append writes r to the log and syncs it to disk. Inside a transaction r is
+// held back until the transaction is committed instead. A record that fails
+// to be written or synced is removed from the log again.
+func (s *Store) append(r record) error {
+	if s.tx {
+		s.batch = append(s.batch, r)
+		return nil
+	}
+
+	if s.failed != nil {
+		return fmt.Errorf("log is unusable after an earlier failure: %w", s.failed)
+	}
+
+	r.Seq = s.seq + 1
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+	b = append(b, '\n')
+
+	if _, err := s.log.Write(b); err != nil {
+		return s.discard(fmt.Errorf("write log: %w", err))
+	}
+
+	if err := s.log.Sync(); err != nil {
+		return s.discard(fmt.Errorf("sync log: %w", err))
+	}
+
+	s.seq = r.Seq
+	s.size += int64(len(b))
+	s.records++
+
+	return nil
+}
+
+// discard truncates the log back to its size before a record failed to be
+// written, so that whatever part of the record made it to disk is neither
+// replayed nor followed by later records. When that fails as well the store
+// stops writing records altogether.
+func (s *Store) discard(err error) error {
+	truncErr := s.log.Truncate(s.size)
+	if truncErr == nil {
+		truncErr = s.log.Sync()
+	}
+
+	if truncErr != nil {
+		s.failed = truncErr
+		return errors.Join(err, fmt.Errorf("truncate log: %w", truncErr))
+	}
+
+	return err
+}
+
+// compactIfFull compacts the log once it has grown large enough. Failures are
+// logged rather than returned since the change that filled the log is already
+// on disk. It must not be called while the todo items in memory are locked by
+// a transaction.
+func (s *Store) compactIfFull() {
+	if s.tx || s.compactEvery <= 0 || s.records < s.compactEvery {
+		return
+	}
+
+	if err := s.compact(); err != nil {
+		s.logger.Error("compact log", "dir", s.dir, "error", err)
+	}
+}
+
+// compact writes a snapshot of the store and starts a new, empty log. The
+// snapshot is written to a temporary file and renamed into place so that a
+// crash leaves either the old or the new snapshot behind. Records that made it
+// into the snapshot are skipped on replay should the crash happen before the
+// log is emptied.
+func (s *Store) compact() error {
+	snap := snapshot{
+		Seq:      s.seq,
+		Snapshot: s.memory.Snapshot(),
+	}
+
+	if err := writeFile(filepath.Join(s.dir, snapshotFile), snap); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("truncate log: %w", err)
+	}
+
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("sync log: %w", err)
+	}
+
+	s.size = 0
+	s.records = 0
+	s.failed = nil
+
+	return nil
+}
+
+// replay applies the records in the log that are newer than the snapshot. A
+// trailing record that cannot be decoded was cut short by a crash and is
+// truncated from the log.
+func (s *Store) replay() error {
+	f, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_RDWR, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	offset := int64(0)
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(readErr, io.EOF) {
+			return nil
+		}
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+
+		var r record
+		if err := json.Unmarshal(bytes.TrimSpace(line), &r); err != nil || !bytes.HasSuffix(line, []byte("\n")) {
+			if rest, _ := reader.Peek(1); len(rest) > 0 {
+				return fmt.Errorf("corrupt record at offset %d", offset)
+			}
+
+			if err := f.Truncate(offset); err != nil {
+				return fmt.Errorf("truncate log: %w", err)
+			}
+
+			return f.Sync()
+		}
+
+		offset += int64(len(line))
+
+		if r.Seq <= s.seq {
+			continue
+		}
+
+		if err := s.apply(r); err != nil {
+			return fmt.Errorf("apply record %d: %w", r.Seq, err)
+		}
+
+		s.seq = r.Seq
+		s.records++
+	}
+}
+
+// readSnapshot reads the snapshot at path, returning nil if there is none.
+func readSnapshot(path string) (*snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return nil, err
+	}
+
+	return &snap, nil
+}
+
+// writeFile atomically replaces the file at path with v encoded as JSON.
+func writeFile(path string, v any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Sync the directory so that the rename itself is durable.
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,320 @@
+package todofile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/storertest"
+	"github.com/sudomateo/todo/todo/stores/todofile"
+)
+
+func TestStore(t *testing.T) {
+	parent := t.TempDir()
+
+	storertest.Run(t, func() todo.Storer {
+		dir, err := os.MkdirTemp(parent, "")
+		if err != nil {
+			panic(err)
+		}
+
+		// Compacting often makes the suite go through snapshots as well as
+		// the log.
+		store, err := todofile.Open(dir, todofile.WithCompactEvery(5))
+		if err != nil {
+			panic(err)
+		}
+
+		return store
+	})
+}
+
+func TestStoreRecover(t *testing.T) {
+	tests := map[string]struct {
+		opts []todofile.Option
+		// crash is called after the todo items are created and before the
+		// store is opened again.
+		crash func(t *testing.T, dir string)
+	}{
+		"log": {},
+		"snapshot": {
+			opts: []todofile.Option{todofile.WithCompactEvery(2)},
+		},
+		"truncated record": {
+			crash: func(t *testing.T, dir string) {
+				f, err := os.OpenFile(filepath.Join(dir, "log.jsonl"), os.O_WRONLY|os.O_APPEND, 0o644)
+				if err != nil {
+					t.Fatalf("open log: %v", err)
+				}
+				defer f.Close()
+
+				if _, err := f.WriteString(`{"seq":99,"op":"cre`); err != nil {
+					t.Fatalf("write log: %v", err)
+				}
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			store, err := todofile.Open(dir, tc.opts...)
+			if err != nil {
+				t.Fatalf("open: expected nil error, got %v", err)
+			}
+
+			todoCore := todo.NewCore(store)
+
+			want := make([]todo.Todo, 0)
+			for _, text := range []string{"foo", "bar", "baz"} {
+				td, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+					Text:     text,
+					Priority: todo.PriorityLow,
+					Tags:     []string{"ops"},
+				})
+				if err != nil {
+					t.Fatalf("create: expected nil error, got %v", err)
+				}
+				want = append(want, td)
+			}
+
+			completed := true
+			want[0], err = todoCore.Update(context.Background(), want[0], todo.TodoUpdateParams{Completed: &completed})
+			if err != nil {
+				t.Fatalf("update: expected nil error, got %v", err)
+			}
+
+			if err := todoCore.Delete(context.Background(), want[2]); err != nil {
+				t.Fatalf("delete: expected nil error, got %v", err)
+			}
+			want = want[:2]
+
+			// The store is not closed to simulate a crash.
+			dir = crashed(t, dir)
+			if tc.crash != nil {
+				tc.crash(t, dir)
+			}
+
+			store, err = todofile.Open(dir, tc.opts...)
+			if err != nil {
+				t.Fatalf("open: expected nil error, got %v", err)
+			}
+
+			todoCore = todo.NewCore(store)
+
+			got, err := todoCore.Query(context.Background(), todo.QueryOptions{})
+			if err != nil {
+				t.Fatalf("query: expected nil error, got %v", err)
+			}
+
+			if diff := cmp.Diff(want, got, equalTimes); diff != "" {
+				t.Fatalf("query: mismatch (-want +got):\n%s", diff)
+			}
+
+			tags, err := todoCore.QueryTags(context.Background())
+			if err != nil || len(tags) != 1 || tags[0].Count != 2 {
+				t.Fatalf("query tags: expected ops used twice, got %v, %v", tags, err)
+			}
+
+			if _, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+				Text:     "qux",
+				Priority: todo.PriorityLow,
+			}); err != nil {
+				t.Fatalf("create after recovery: expected nil error, got %v", err)
+			}
+
+			// Records written after recovery must be readable as well.
+			store, err = todofile.Open(crashed(t, dir), tc.opts...)
+			if err != nil {
+				t.Fatalf("open: expected nil error, got %v", err)
+			}
+			defer store.Close()
+
+			got, err = todo.NewCore(store).Query(context.Background(), todo.QueryOptions{})
+			if err != nil || len(got) != 3 {
+				t.Fatalf("query: expected 3 todos, got %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestStoreCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+
+	log := `{"seq":1,"op":"cre` + "\n" + `{"seq":2,"op":"delete"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "log.jsonl"), []byte(log), 0o644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+
+	if _, err := todofile.Open(dir); err == nil {
+		t.Fatalf("open: expected error for a corrupt record before the end of the log")
+	}
+}
+
+func TestStoreLocked(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := todofile.Open(dir)
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+
+	if _, err := todofile.Open(dir); !errors.Is(err, todofile.ErrLocked) {
+		t.Fatalf("open: expected %v while the store is open, got %v", todofile.ErrLocked, err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: expected nil error, got %v", err)
+	}
+
+	store, err = todofile.Open(dir)
+	if err != nil {
+		t.Fatalf("open: expected nil error after close, got %v", err)
+	}
+	store.Close()
+}
+
+func TestStoreCompactFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	var logs bytes.Buffer
+	store, err := todofile.Open(dir,
+		todofile.WithCompactEvery(1),
+		todofile.WithLogger(hclog.New(&hclog.LoggerOptions{Output: &logs})),
+	)
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+
+	// A directory in place of the snapshot makes every compaction fail.
+	blocker := filepath.Join(dir, "snapshot.json", "blocker")
+	if err := os.MkdirAll(blocker, 0o755); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+
+	// The todo item is on disk before compacting, so creating it succeeds.
+	td, err := todo.NewCore(store).Create(context.Background(), todo.TodoCreateParams{
+		Text:     "foo",
+		Priority: todo.PriorityLow,
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if !strings.Contains(logs.String(), "compact log") {
+		t.Fatalf("logs: expected the failed compaction, got %q", logs.String())
+	}
+
+	if err := os.RemoveAll(filepath.Dir(blocker)); err != nil {
+		t.Fatalf("remove directory: %v", err)
+	}
+
+	store, err = todofile.Open(crashed(t, dir))
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+	defer store.Close()
+
+	if _, err := todo.NewCore(store).QueryByID(context.Background(), td.ID); err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+}
+
+func TestStoreWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := todofile.Open(dir)
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+
+	todoCore := todo.NewCore(store)
+
+	create := func(text string) (todo.Todo, error) {
+		return todoCore.Create(context.Background(), todo.TodoCreateParams{Text: text, Priority: todo.PriorityLow})
+	}
+
+	foo, err := create("foo")
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	// Only part of the record reaches the log.
+	todofile.FailNextWrite(store, 10)
+
+	if _, err := create("bar"); err == nil {
+		t.Fatalf("create: expected error for a failed write")
+	}
+
+	baz, err := create("baz")
+	if err != nil {
+		t.Fatalf("create after failure: expected nil error, got %v", err)
+	}
+
+	want := []todo.Todo{foo, baz}
+
+	for _, store := range []todo.Storer{store, reopen(t, crashed(t, dir))} {
+		got, err := todo.NewCore(store).Query(context.Background(), todo.QueryOptions{})
+		if err != nil {
+			t.Fatalf("query: expected nil error, got %v", err)
+		}
+
+		if diff := cmp.Diff(want, got, equalTimes); diff != "" {
+			t.Fatalf("query: mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+// reopen opens the store in dir, closing it when the test ends.
+func reopen(t *testing.T, dir string) *todofile.Store {
+	t.Helper()
+
+	store, err := todofile.Open(dir)
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+// crashed returns a copy of the files in dir, as a store whose process stopped
+// without closing it would leave them behind.
+func crashed(t *testing.T, dir string) string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read directory: %v", err)
+	}
+
+	copied := t.TempDir()
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("read file: %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(copied, entry.Name()), b, 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	return copied
+}
+
+// equalTimes compares times by instant since they lose their location and
+// monotonic clock reading when they are persisted.
+var equalTimes = cmp.Comparer(func(a, b time.Time) bool {
+	return a.Equal(b)
+})
@@ -0,0 +1,37 @@
+package todomemory
+
+import (
+	"context"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryIdentity retrieves an identity from memory by its issuer and subject.
+func (d *Store) QueryIdentity(ctx context.Context, issuer string, subject string) (todo.Identity, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, identity := range d.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+
+	return todo.Identity{}, todo.ErrIdentityNotFound
+}
+
+// CreateIdentity adds an identity to memory.
+func (d *Store) CreateIdentity(ctx context.Context, identity todo.Identity) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, existing := range d.identities {
+		if existing.Issuer == identity.Issuer && existing.Subject == identity.Subject {
+			return todo.ErrIdentityExists
+		}
+	}
+
+	d.identities = append(d.identities, identity)
+
+	return nil
+}
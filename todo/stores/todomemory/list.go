@@ -0,0 +1,98 @@
+package todomemory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryLists retrieves all lists of the workspace in ctx from memory, starting
+// with the inbox.
+func (d *Store) QueryLists(ctx context.Context) ([]todo.List, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+	lists := make([]todo.List, 0, len(d.lists))
+
+	for i := range d.lists {
+		if d.lists[i].WorkspaceID == ws {
+			lists = append(lists, d.lists[i])
+		}
+	}
+
+	return lists, nil
+}
+
+// QueryListByID retrieves a list from memory.
+func (d *Store) QueryListByID(ctx context.Context, id uuid.UUID) (todo.List, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+
+	for i := range d.lists {
+		if d.lists[i].ID == id && d.lists[i].WorkspaceID == ws {
+			return d.lists[i], nil
+		}
+	}
+
+	return todo.List{}, todo.ErrListNotFound
+}
+
+// CreateList adds a list to memory.
+func (d *Store) CreateList(ctx context.Context, l todo.List) error {
+	d.mutex.Lock()
+
+	d.lists = append(d.lists, todo.List{
+		ID:          l.ID,
+		WorkspaceID: todo.WorkspaceFrom(ctx),
+		OwnerID:     l.OwnerID,
+		Name:        l.Name,
+		TimeCreated: l.TimeCreated,
+		TimeUpdated: l.TimeUpdated,
+	})
+
+	d.mutex.Unlock()
+
+	return nil
+}
+
+// UpdateList modifies an existing list in memory.
+func (d *Store) UpdateList(ctx context.Context, l todo.List) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+
+	for i := range d.lists {
+		if d.lists[i].ID == l.ID && d.lists[i].WorkspaceID == ws {
+			save(d, &d.lists[i])
+			d.lists[i].Name = l.Name
+			d.lists[i].TimeUpdated = l.TimeUpdated
+			return nil
+		}
+	}
+
+	return todo.ErrListNotFound
+}
+
+// DeleteList deletes a list from memory.
+func (d *Store) DeleteList(ctx context.Context, l todo.List) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+
+	for i := range d.lists {
+		if d.lists[i].ID == l.ID && d.lists[i].WorkspaceID == ws {
+			own(d, &d.lists)
+			d.lists = append(d.lists[:i], d.lists[i+1:]...)
+			return nil
+		}
+	}
+
+	return todo.ErrListNotFound
+}
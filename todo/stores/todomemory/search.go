@@ -0,0 +1,236 @@
+package todomemory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Weights of a word matching a search term. A word equal to the term counts
+// for more than a longer word the term is a prefix of.
+const (
+	exactWeight  = 1.0
+	prefixWeight = 0.5
+)
+
+// Snippets show at most snippetWords words, starting up to snippetContext
+// words before the first match.
+const (
+	snippetWords   = 20
+	snippetContext = 5
+)
+
+// Search retrieves the todo items matching params from memory. A todo item is
+// ranked by the number of its words that match the search terms.
+func (d *Store) Search(ctx context.Context, params todo.SearchParams) ([]todo.SearchResult, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	terms := todo.SearchTerms(params.Query)
+
+	var ranks map[uuid.UUID]float64
+
+	for i, term := range terms {
+		matches := d.matchTerm(term)
+
+		if i == 0 {
+			ranks = matches
+			continue
+		}
+
+		// Every term has to match.
+		for id := range ranks {
+			if rank, ok := matches[id]; ok {
+				ranks[id] += rank
+			} else {
+				delete(ranks, id)
+			}
+		}
+	}
+
+	ws := todo.WorkspaceFrom(ctx)
+	results := make([]todo.SearchResult, 0, len(ranks))
+
+	for i := range d.data {
+		rank, ok := ranks[d.data[i].ID]
+		if !ok || trashed(d.data[i]) || d.data[i].WorkspaceID != ws || d.data[i].OwnerID != params.OwnerID {
+			continue
+		}
+
+		results = append(results, todo.SearchResult{
+			Todo:    clone(d.data[i]),
+			Rank:    rank,
+			Snippet: snippet(d.data[i].Text, terms),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.Todo.TimeUpdated.Equal(b.Todo.TimeUpdated) {
+			return a.Todo.TimeUpdated.After(b.Todo.TimeUpdated)
+		}
+		return bytes.Compare(a.Todo.ID[:], b.Todo.ID[:]) < 0
+	})
+
+	if params.Limit > 0 && params.Limit < len(results) {
+		results = results[:params.Limit]
+	}
+
+	return results, nil
+}
+
+// matchTerm returns the rank of every todo item with a word that term is a
+// prefix of. The vocabulary is sorted, so those words are next to each other.
+func (d *Store) matchTerm(term string) map[uuid.UUID]float64 {
+	matches := make(map[uuid.UUID]float64)
+
+	for i := sort.SearchStrings(d.vocab, term); i < len(d.vocab) && strings.HasPrefix(d.vocab[i], term); i++ {
+		weight := prefixWeight
+		if d.vocab[i] == term {
+			weight = exactWeight
+		}
+
+		for id, n := range d.words[d.vocab[i]] {
+			matches[id] += weight * float64(n)
+		}
+	}
+
+	return matches
+}
+
+// indexText moves the todo item given by id from the index entries of the
+// words in oldText to those of the words in newText. Words that are no longer
+// used are removed from the index and the vocabulary.
+func (d *Store) indexText(id uuid.UUID, oldText string, newText string) {
+	if d.tx {
+		d.undo = append(d.undo, func() { d.indexText(id, newText, oldText) })
+	}
+
+	for _, word := range words(oldText) {
+		delete(d.words[word], id)
+		if len(d.words[word]) > 0 {
+			continue
+		}
+
+		delete(d.words, word)
+		if i := sort.SearchStrings(d.vocab, word); i < len(d.vocab) && d.vocab[i] == word {
+			own(d, &d.vocab)
+			d.vocab = append(d.vocab[:i], d.vocab[i+1:]...)
+		}
+	}
+
+	for _, word := range words(newText) {
+		if d.words[word] == nil {
+			d.words[word] = make(map[uuid.UUID]int)
+
+			own(d, &d.vocab)
+			i := sort.SearchStrings(d.vocab, word)
+			d.vocab = append(d.vocab, "")
+			copy(d.vocab[i+1:], d.vocab[i:])
+			d.vocab[i] = word
+		}
+		d.words[word][id]++
+	}
+}
+
+// words splits text into lowercase words the same way todo.SearchTerms splits
+// a query, keeping duplicates.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), todo.IsSeparator)
+}
+
+// snippet returns the part of text around the first word matching one of
+// terms, with every matching word highlighted.
+func snippet(text string, terms []string) string {
+	type word struct {
+		start, end int
+		match      bool
+	}
+
+	var found []word
+	first := -1
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if todo.IsSeparator(r) {
+			i += size
+			continue
+		}
+
+		w := word{start: i}
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if todo.IsSeparator(r) {
+				break
+			}
+			i += size
+		}
+		w.end = i
+
+		lower := strings.ToLower(text[w.start:w.end])
+		for _, term := range terms {
+			if strings.HasPrefix(lower, term) {
+				w.match = true
+				break
+			}
+		}
+
+		if w.match && first == -1 {
+			first = len(found)
+		}
+
+		found = append(found, w)
+	}
+
+	if first == -1 {
+		return text
+	}
+
+	from := 0
+	if first > snippetContext {
+		from = first - snippetContext
+	}
+	to := from + snippetWords
+	if to > len(found) {
+		to = len(found)
+	}
+
+	var b strings.Builder
+
+	start, end := 0, len(text)
+	if from > 0 {
+		start = found[from].start
+		b.WriteString("…")
+	}
+	if to < len(found) {
+		end = found[to-1].end
+	}
+
+	pos := start
+	for _, w := range found[from:to] {
+		if !w.match {
+			continue
+		}
+		b.WriteString(text[pos:w.start])
+		b.WriteString(todo.HighlightStart)
+		b.WriteString(text[w.start:w.end])
+		b.WriteString(todo.HighlightEnd)
+		pos = w.end
+	}
+	b.WriteString(text[pos:end])
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
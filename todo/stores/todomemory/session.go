@@ -0,0 +1,68 @@
+package todomemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QuerySessionByHash retrieves a session from memory by the hash of its
+// token.
+func (d *Store) QuerySessionByHash(ctx context.Context, hash string) (todo.Session, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.sessions {
+		if d.sessions[i].Hash == hash {
+			return d.sessions[i], nil
+		}
+	}
+
+	return todo.Session{}, todo.ErrSessionNotFound
+}
+
+// CreateSession adds a session to memory.
+func (d *Store) CreateSession(ctx context.Context, session todo.Session) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.sessions = append(d.sessions, session)
+
+	return nil
+}
+
+// DeleteSession deletes a session from memory.
+func (d *Store) DeleteSession(ctx context.Context, session todo.Session) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.sessions {
+		if d.sessions[i].ID == session.ID {
+			own(d, &d.sessions)
+			d.sessions = append(d.sessions[:i], d.sessions[i+1:]...)
+			return nil
+		}
+	}
+
+	return todo.ErrSessionNotFound
+}
+
+// PurgeSessions deletes the sessions that expired before the given time from
+// memory.
+func (d *Store) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	sessions := make([]todo.Session, 0, len(d.sessions))
+	for _, session := range d.sessions {
+		if !session.TimeExpires.Before(before) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	n := len(d.sessions) - len(sessions)
+	d.sessions = sessions
+
+	return n, nil
+}
@@ -0,0 +1,90 @@
+package todomemory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryShares retrieves the shares of a todo item of the workspace in ctx from
+// memory, oldest first.
+func (d *Store) QueryShares(ctx context.Context, todoID uuid.UUID) ([]todo.Share, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	shares := make([]todo.Share, 0)
+	for _, share := range d.shares {
+		if share.TodoID == todoID && d.index(ctx, share.TodoID) >= 0 {
+			shares = append(shares, share)
+		}
+	}
+
+	return shares, nil
+}
+
+// QuerySharesByUser retrieves the shares of a user in the workspace in ctx
+// from memory, oldest first.
+func (d *Store) QuerySharesByUser(ctx context.Context, userID uuid.UUID) ([]todo.Share, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	shares := make([]todo.Share, 0)
+	for _, share := range d.shares {
+		if share.UserID == userID && d.index(ctx, share.TodoID) >= 0 {
+			shares = append(shares, share)
+		}
+	}
+
+	return shares, nil
+}
+
+// QueryShare retrieves the share of a todo item with a user from memory.
+func (d *Store) QueryShare(ctx context.Context, todoID uuid.UUID, userID uuid.UUID) (todo.Share, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, share := range d.shares {
+		if share.TodoID == todoID && share.UserID == userID && d.index(ctx, share.TodoID) >= 0 {
+			return share, nil
+		}
+	}
+
+	return todo.Share{}, todo.ErrShareNotFound
+}
+
+// PutShare adds a share to memory, replacing the role of an existing share of
+// the todo item with the same user.
+func (d *Store) PutShare(ctx context.Context, share todo.Share) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.shares {
+		if d.shares[i].TodoID == share.TodoID && d.shares[i].UserID == share.UserID {
+			save(d, &d.shares[i])
+			d.shares[i].Role = share.Role
+			return nil
+		}
+	}
+
+	d.shares = append(d.shares, share)
+
+	return nil
+}
+
+// DeleteShare deletes a share from memory.
+func (d *Store) DeleteShare(ctx context.Context, share todo.Share) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.shares {
+		if d.shares[i].TodoID == share.TodoID && d.shares[i].UserID == share.UserID {
+			own(d, &d.shares)
+			d.shares = append(d.shares[:i], d.shares[i+1:]...)
+			return nil
+		}
+	}
+
+	return todo.ErrShareNotFound
+}
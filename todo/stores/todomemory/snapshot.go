@@ -0,0 +1,137 @@
+package todomemory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Snapshot is a copy of everything held by a Store.
+type Snapshot struct {
+	Todos      []todo.Todo      `json:"todos"`
+	Lists      []todo.List      `json:"lists"`
+	Events     []todo.Event     `json:"events"`
+	Webhooks   []todo.Webhook   `json:"webhooks"`
+	Deliveries []todo.Delivery  `json:"deliveries"`
+	Users      []todo.User      `json:"users"`
+	APIKeys    []todo.APIKey    `json:"api_keys"`
+	Sessions   []todo.Session   `json:"sessions"`
+	Identities []todo.Identity  `json:"identities"`
+	Shares     []todo.Share     `json:"shares"`
+	Workspaces []todo.Workspace `json:"workspaces"`
+	Members    []todo.Member    `json:"members"`
+}
+
+// Snapshot returns a copy of everything held by the store.
+func (d *Store) Snapshot() Snapshot {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return copySnapshot(Snapshot{
+		Todos:      d.data,
+		Lists:      d.lists,
+		Events:     d.events,
+		Webhooks:   d.webhooks,
+		Deliveries: d.deliveries,
+		Users:      d.users,
+		APIKeys:    d.apiKeys,
+		Sessions:   d.sessions,
+		Identities: d.identities,
+		Shares:     d.shares,
+		Workspaces: d.workspaces,
+		Members:    d.members,
+	})
+}
+
+// NewStoreFromSnapshot is a constructor for a Store that holds everything in
+// s. The default workspace is added when s does not have it, as in snapshots
+// taken before there were workspaces.
+func NewStoreFromSnapshot(s Snapshot) *Store {
+	s = copySnapshot(s)
+
+	if !hasWorkspace(s.Workspaces, todo.DefaultWorkspaceID) {
+		s.Workspaces = append([]todo.Workspace{defaultWorkspace(time.Now())}, s.Workspaces...)
+	}
+
+	d := Store{
+		data:       s.Todos,
+		lists:      s.Lists,
+		events:     s.Events,
+		webhooks:   s.Webhooks,
+		deliveries: s.Deliveries,
+		users:      s.Users,
+		apiKeys:    s.APIKeys,
+		sessions:   s.Sessions,
+		identities: s.Identities,
+		shares:     s.Shares,
+		workspaces: s.Workspaces,
+		members:    s.Members,
+		tags:       make(map[string]map[uuid.UUID]struct{}),
+		words:      make(map[string]map[uuid.UUID]int),
+	}
+
+	for _, td := range d.data {
+		if !trashed(td) {
+			d.indexTags(td.ID, nil, td.Tags)
+			d.indexText(td.ID, "", td.Text)
+		}
+	}
+
+	return &d
+}
+
+// copySnapshot returns a copy of s that shares no memory with it.
+func copySnapshot(s Snapshot) Snapshot {
+	c := Snapshot{
+		Todos:      make([]todo.Todo, 0, len(s.Todos)),
+		Lists:      make([]todo.List, len(s.Lists)),
+		Events:     make([]todo.Event, 0, len(s.Events)),
+		Webhooks:   make([]todo.Webhook, 0, len(s.Webhooks)),
+		Deliveries: make([]todo.Delivery, 0, len(s.Deliveries)),
+		Users:      make([]todo.User, len(s.Users)),
+		APIKeys:    make([]todo.APIKey, 0, len(s.APIKeys)),
+		Sessions:   make([]todo.Session, len(s.Sessions)),
+		Identities: make([]todo.Identity, len(s.Identities)),
+		Shares:     make([]todo.Share, len(s.Shares)),
+		Workspaces: make([]todo.Workspace, 0, len(s.Workspaces)),
+		Members:    make([]todo.Member, len(s.Members)),
+	}
+
+	for _, td := range s.Todos {
+		c.Todos = append(c.Todos, clone(td))
+	}
+
+	copy(c.Lists, s.Lists)
+
+	for _, ev := range s.Events {
+		c.Events = append(c.Events, cloneEvent(ev))
+	}
+
+	for _, wh := range s.Webhooks {
+		c.Webhooks = append(c.Webhooks, cloneWebhook(wh))
+	}
+
+	for _, dl := range s.Deliveries {
+		c.Deliveries = append(c.Deliveries, cloneDelivery(dl))
+	}
+
+	copy(c.Users, s.Users)
+
+	for _, key := range s.APIKeys {
+		c.APIKeys = append(c.APIKeys, cloneAPIKey(key))
+	}
+
+	copy(c.Sessions, s.Sessions)
+	copy(c.Identities, s.Identities)
+	copy(c.Shares, s.Shares)
+
+	for _, ws := range s.Workspaces {
+		c.Workspaces = append(c.Workspaces, cloneWorkspace(ws))
+	}
+
+	copy(c.Members, s.Members)
+
+	return c
+}
@@ -1,8 +1,13 @@
 package todomemory
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -11,23 +16,110 @@ import (
 
 // Store exposes the APIs needed to interface with todo items in memory.
 type Store struct {
-	data  []todo.Todo
-	mutex sync.RWMutex
+	data   []todo.Todo
+	lists  []todo.List
+	events []todo.Event
+	tags   map[string]map[uuid.UUID]struct{}
+	mutex  sync.RWMutex
+
+	webhooks   []todo.Webhook
+	deliveries []todo.Delivery
+	users      []todo.User
+	apiKeys    []todo.APIKey
+	sessions   []todo.Session
+	identities []todo.Identity
+	shares     []todo.Share
+	workspaces []todo.Workspace
+	members    []todo.Member
+
+	// words maps every word of the todo items outside of the trash to the
+	// number of times it occurs in each of them. vocab holds the same words
+	// in sorted order for prefix lookups.
+	words map[string]map[uuid.UUID]int
+	vocab []string
+
+	// tx is set on the store given to the function passed to Tx, which shares
+	// everything with the store Tx was called on. undo puts back what it
+	// changed in place when the transaction is rolled back, and copied holds
+	// the slices it copied before removing elements from them.
+	tx     bool
+	undo   []func()
+	copied map[any]bool
 }
 
-// NewStore is a constructor for a Store.
+// NewStore is a constructor for a Store. The store starts out with the
+// default workspace and its empty inbox list.
 func NewStore() *Store {
+	now := time.Now()
+
 	return &Store{
-		data: make([]todo.Todo, 0),
+		data:   make([]todo.Todo, 0),
+		events: make([]todo.Event, 0),
+		lists: []todo.List{
+			{
+				ID:          todo.InboxID,
+				Name:        "Inbox",
+				TimeCreated: now,
+				TimeUpdated: now,
+			},
+		},
+		tags:       make(map[string]map[uuid.UUID]struct{}),
+		words:      make(map[string]map[uuid.UUID]int),
+		webhooks:   make([]todo.Webhook, 0),
+		deliveries: make([]todo.Delivery, 0),
+		users:      make([]todo.User, 0),
+		apiKeys:    make([]todo.APIKey, 0),
+		sessions:   make([]todo.Session, 0),
+		identities: make([]todo.Identity, 0),
+		shares:     make([]todo.Share, 0),
+		workspaces: []todo.Workspace{defaultWorkspace(now)},
+		members:    make([]todo.Member, 0),
 	}
 }
 
-// Query retrieves all the todo items from memory.
-func (d *Store) Query(ctx context.Context) ([]todo.Todo, error) {
+// Query retrieves the todo items matching opts from memory.
+func (d *Store) Query(ctx context.Context, opts todo.QueryOptions) ([]todo.Todo, error) {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 
-	return d.data, nil
+	ws := todo.WorkspaceFrom(ctx)
+	todos := make([]todo.Todo, 0)
+
+	for i := range d.data {
+		if d.data[i].WorkspaceID != ws {
+			continue
+		}
+		if trashed(d.data[i]) != opts.Filter.Trashed {
+			continue
+		}
+		if !d.hasTags(d.data[i], opts.Filter.Tags) {
+			continue
+		}
+		if !matches(d.data[i], opts.Filter) {
+			continue
+		}
+		if opts.After != nil && !afterCursor(d.data[i], *opts.After, opts.Direction) {
+			continue
+		}
+		todos = append(todos, clone(d.data[i]))
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		return compare(todos[i], todos[j], opts.OrderBy, opts.Direction) < 0
+	})
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(todos) {
+			return make([]todo.Todo, 0), nil
+		}
+		todos = todos[opts.Offset:]
+	}
+
+	if opts.Limit > 0 && opts.Limit < len(todos) {
+		todos = todos[:opts.Limit]
+	}
+
+	return todos, nil
 }
 
 // QueryByID retrieves a todo item from memory.
@@ -35,27 +127,148 @@ func (d *Store) QueryByID(ctx context.Context, id uuid.UUID) (todo.Todo, error)
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 
+	i := d.index(ctx, id)
+	if i < 0 || trashed(d.data[i]) {
+		return todo.Todo{}, todo.ErrNotFound
+	}
+
+	return clone(d.data[i]), nil
+}
+
+// QueryTrashByID retrieves a todo item in the trash from memory.
+func (d *Store) QueryTrashByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	i, err := d.trashIndex(ctx, id)
+	if err != nil {
+		return todo.Todo{}, err
+	}
+
+	return clone(d.data[i]), nil
+}
+
+// QuerySubtree retrieves every descendant of the todo item given by id from
+// memory, ordered by depth.
+func (d *Store) QuerySubtree(ctx context.Context, id uuid.UUID) ([]todo.Todo, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	todos := make([]todo.Todo, 0)
+	if d.index(ctx, id) < 0 {
+		return todos, nil
+	}
+
+	for _, td := range d.subtree(id) {
+		if !trashed(td) {
+			todos = append(todos, clone(td))
+		}
+	}
+
+	return todos, nil
+}
+
+// QueryOverdue retrieves all incomplete todo items of ownerID from memory that
+// were due before now.
+func (d *Store) QueryOverdue(ctx context.Context, ownerID uuid.UUID, now time.Time) ([]todo.Todo, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+	todos := make([]todo.Todo, 0)
+
 	for i := range d.data {
-		if d.data[i].ID == id {
-			return d.data[i], nil
+		if d.data[i].WorkspaceID == ws && d.data[i].OwnerID == ownerID && d.data[i].Overdue(now) && !trashed(d.data[i]) {
+			todos = append(todos, clone(d.data[i]))
+		}
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		return todos[i].DueAt.Before(*todos[j].DueAt)
+	})
+
+	return todos, nil
+}
+
+// QueryTags retrieves every tag ownerID uses from memory along with the number
+// of their todo items using it.
+func (d *Store) QueryTags(ctx context.Context, ownerID uuid.UUID) ([]todo.TagCount, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+	counts := make(map[string]int)
+
+	for i := range d.data {
+		if d.data[i].WorkspaceID != ws || d.data[i].OwnerID != ownerID || trashed(d.data[i]) {
+			continue
+		}
+
+		for _, tag := range d.data[i].Tags {
+			counts[tag]++
 		}
 	}
 
-	return todo.Todo{}, todo.ErrNotFound
+	tags := make([]todo.TagCount, 0, len(counts))
+
+	for tag, n := range counts {
+		tags = append(tags, todo.TagCount{
+			Tag:   tag,
+			Count: n,
+		})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags, nil
+}
+
+// QueryEvents retrieves the history of a todo item from memory.
+func (d *Store) QueryEvents(ctx context.Context, todoID uuid.UUID) ([]todo.Event, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	events := make([]todo.Event, 0)
+	if d.index(ctx, todoID) < 0 {
+		return events, nil
+	}
+
+	for i := range d.events {
+		if d.events[i].TodoID == todoID {
+			events = append(events, cloneEvent(d.events[i]))
+		}
+	}
+
+	return events, nil
 }
 
 // Create adds a todo item to memory.
-func (d *Store) Create(ctx context.Context, td todo.Todo) error {
+func (d *Store) Create(ctx context.Context, td todo.Todo, ev todo.Event) error {
 	d.mutex.Lock()
 
 	d.data = append(d.data, todo.Todo{
 		ID:          td.ID,
+		WorkspaceID: todo.WorkspaceFrom(ctx),
+		OwnerID:     td.OwnerID,
 		Text:        td.Text,
 		Priority:    td.Priority,
 		Completed:   td.Completed,
+		ListID:      td.ListID,
+		ParentID:    copyID(td.ParentID),
+		DueAt:       copyTime(td.DueAt),
+		Recurrence:  td.Recurrence,
+		NextID:      copyID(td.NextID),
+		Tags:        copyTags(td.Tags),
+		Version:     td.Version,
 		TimeCreated: td.TimeCreated,
 		TimeUpdated: td.TimeUpdated,
+		TimeDeleted: copyTime(td.TimeDeleted),
 	})
+	d.indexTags(td.ID, nil, td.Tags)
+	d.indexText(td.ID, "", td.Text)
+	d.events = append(d.events, cloneEvent(ev))
 
 	d.mutex.Unlock()
 
@@ -63,35 +276,563 @@ func (d *Store) Create(ctx context.Context, td todo.Todo) error {
 }
 
 // Update modifies an existing todo item in memory.
-func (d *Store) Update(ctx context.Context, td todo.Todo) error {
+func (d *Store) Update(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+
+	for i := range d.data {
+		if d.data[i].ID != td.ID || d.data[i].WorkspaceID != ws {
+			continue
+		}
+
+		if d.data[i].Version != td.Version-1 {
+			return todo.ErrConflict
+		}
+
+		if td.ParentID != nil && (*td.ParentID == td.ID || d.isDescendant(*td.ParentID, td.ID)) {
+			return todo.ErrCycle
+		}
+
+		save(d, &d.data[i])
+		if !trashed(d.data[i]) {
+			d.indexText(td.ID, d.data[i].Text, td.Text)
+		}
+		d.data[i].Text = td.Text
+		d.data[i].Priority = td.Priority
+		d.data[i].Completed = td.Completed
+		d.data[i].ListID = td.ListID
+		d.data[i].ParentID = copyID(td.ParentID)
+		d.data[i].DueAt = copyTime(td.DueAt)
+		d.data[i].Recurrence = td.Recurrence
+		d.data[i].NextID = copyID(td.NextID)
+		if !trashed(d.data[i]) {
+			d.indexTags(td.ID, d.data[i].Tags, td.Tags)
+		}
+		d.data[i].Tags = copyTags(td.Tags)
+		d.data[i].Version = td.Version
+		d.data[i].TimeUpdated = td.TimeUpdated
+		d.events = append(d.events, cloneEvent(ev))
+
+		return nil
+	}
+
+	return todo.ErrNotFound
+}
+
+// Delete moves a todo item and its descendants to the trash in memory.
+func (d *Store) Delete(ctx context.Context, td todo.Todo, ev todo.Event) error {
 	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if i := d.index(ctx, td.ID); i < 0 || trashed(d.data[i]) {
+		return nil
+	}
+
+	ids := d.family(td.ID)
 
 	for i := range d.data {
-		if d.data[i].ID == td.ID {
-			d.data[i].Text = td.Text
-			d.data[i].Priority = td.Priority
-			d.data[i].Completed = td.Completed
-			d.data[i].TimeUpdated = td.TimeUpdated
+		if !ids[d.data[i].ID] || trashed(d.data[i]) {
+			continue
 		}
+
+		save(d, &d.data[i])
+		d.data[i].TimeDeleted = copyTime(td.TimeDeleted)
+		d.indexTags(d.data[i].ID, d.data[i].Tags, nil)
+		d.indexText(d.data[i].ID, d.data[i].Text, "")
 	}
 
-	d.mutex.Unlock()
+	d.events = append(d.events, cloneEvent(ev))
 
 	return nil
 }
 
-// Delete deletes a todo item from memory.
-func (d *Store) Delete(ctx context.Context, td todo.Todo) error {
+// Restore takes a todo item and the descendants that were moved to the trash
+// along with it out of the trash in memory.
+func (d *Store) Restore(ctx context.Context, td todo.Todo, ev todo.Event) error {
 	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, err := d.trashIndex(ctx, td.ID); err != nil {
+		return err
+	}
+
+	ids := d.family(td.ID)
 
 	for i := range d.data {
-		if d.data[i].ID == td.ID {
-			d.data = append(d.data[:i], d.data[i+1:]...)
-			break
+		if !ids[d.data[i].ID] || !trashed(d.data[i]) || !d.data[i].TimeDeleted.Equal(*td.TimeDeleted) {
+			continue
 		}
+
+		save(d, &d.data[i])
+		d.data[i].TimeDeleted = nil
+		d.indexTags(d.data[i].ID, nil, d.data[i].Tags)
+		d.indexText(d.data[i].ID, "", d.data[i].Text)
 	}
 
-	d.mutex.Unlock()
+	d.events = append(d.events, cloneEvent(ev))
+
+	return nil
+}
+
+// Purge permanently deletes a todo item and its descendants from memory.
+func (d *Store) Purge(ctx context.Context, td todo.Todo) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.index(ctx, td.ID) < 0 {
+		return nil
+	}
+
+	d.remove(d.family(td.ID))
+
+	return nil
+}
+
+// PurgeTrash permanently deletes the todo items of ownerID, or of every owner
+// when it is nil, that were moved to the trash before the given time from
+// memory.
+func (d *Store) PurgeTrash(ctx context.Context, ownerID *uuid.UUID, before time.Time) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ws := todo.WorkspaceFrom(ctx)
+	ids := make(map[uuid.UUID]bool)
+	n := 0
+
+	for i := range d.data {
+		if d.data[i].WorkspaceID != ws {
+			continue
+		}
+
+		if ownerID != nil && d.data[i].OwnerID != *ownerID {
+			continue
+		}
+
+		if trashed(d.data[i]) && d.data[i].TimeDeleted.Before(before) {
+			for id := range d.family(d.data[i].ID) {
+				ids[id] = true
+			}
+			n++
+		}
+	}
+
+	d.remove(ids)
+
+	return n, nil
+}
+
+// WithTx calls fn with the store while holding its lock, so that nothing else
+// can read or modify it in the meantime. The changes fn makes are kept when it
+// returns nil and rolled back otherwise.
+func (d *Store) WithTx(ctx context.Context, fn func(todo.Storer) error) error {
+	return d.Tx(func(tx *Store) error {
+		return fn(tx)
+	})
+}
+
+// Tx is like WithTx but gives fn the store as a *Store. Calling Tx on that
+// store runs fn against the store itself.
+func (d *Store) Tx(fn func(tx *Store) error) error {
+	if d.tx {
+		return fn(d)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	tx := &Store{
+		data:       d.data,
+		lists:      d.lists,
+		events:     d.events,
+		tags:       d.tags,
+		words:      d.words,
+		vocab:      d.vocab,
+		webhooks:   d.webhooks,
+		deliveries: d.deliveries,
+		users:      d.users,
+		apiKeys:    d.apiKeys,
+		sessions:   d.sessions,
+		identities: d.identities,
+		shares:     d.shares,
+		workspaces: d.workspaces,
+		members:    d.members,
+		tx:         true,
+		copied:     make(map[any]bool),
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	committed = true
+
+	d.data = tx.data
+	d.lists = tx.lists
+	d.events = tx.events
+	d.tags = tx.tags
+	d.words = tx.words
+	d.vocab = tx.vocab
+	d.webhooks = tx.webhooks
+	d.deliveries = tx.deliveries
+	d.users = tx.users
+	d.apiKeys = tx.apiKeys
+	d.sessions = tx.sessions
+	d.identities = tx.identities
+	d.shares = tx.shares
+	d.workspaces = tx.workspaces
+	d.members = tx.members
 
 	return nil
 }
+
+// rollback undoes the changes a transaction made in place, latest first.
+// Everything else it changed was its own and is dropped along with it.
+func (d *Store) rollback() {
+	undo := d.undo
+	d.undo = nil
+
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i]()
+	}
+}
+
+// save records the value p points to when d is a transaction, so that it is
+// put back when the transaction is rolled back. It has to be called before
+// anything the transaction shares with its store is changed in place.
+func save[T any](d *Store, p *T) {
+	if !d.tx {
+		return
+	}
+
+	old := *p
+	d.undo = append(d.undo, func() { *p = old })
+}
+
+// own gives d its own copy of the slice s points to when d is a transaction
+// that still shares it with its store. It has to be called before elements are
+// removed from or inserted into the slice in place.
+func own[T any](d *Store, s *[]T) {
+	if !d.tx || d.copied[s] {
+		return
+	}
+
+	*s = append([]T(nil), *s...)
+	d.copied[s] = true
+}
+
+// index returns the index of the todo item given by id when it belongs to the
+// workspace in ctx, or -1 otherwise.
+func (d *Store) index(ctx context.Context, id uuid.UUID) int {
+	ws := todo.WorkspaceFrom(ctx)
+
+	for i := range d.data {
+		if d.data[i].ID == id {
+			if d.data[i].WorkspaceID != ws {
+				return -1
+			}
+			return i
+		}
+	}
+
+	return -1
+}
+
+// trashIndex returns the index of the todo item given by id when it is in the
+// trash of the workspace in ctx.
+func (d *Store) trashIndex(ctx context.Context, id uuid.UUID) (int, error) {
+	i := d.index(ctx, id)
+	if i < 0 || !trashed(d.data[i]) {
+		return 0, todo.ErrNotFound
+	}
+
+	return i, nil
+}
+
+// family returns the IDs of the todo item given by id and its descendants.
+func (d *Store) family(id uuid.UUID) map[uuid.UUID]bool {
+	ids := map[uuid.UUID]bool{id: true}
+	for _, child := range d.subtree(id) {
+		ids[child.ID] = true
+	}
+	return ids
+}
+
+// remove drops the todo items given by ids along with their history and shares
+// from memory.
+func (d *Store) remove(ids map[uuid.UUID]bool) {
+	own(d, &d.data)
+	own(d, &d.events)
+	own(d, &d.shares)
+
+	data := d.data[:0]
+	for i := range d.data {
+		if ids[d.data[i].ID] {
+			d.indexTags(d.data[i].ID, d.data[i].Tags, nil)
+			d.indexText(d.data[i].ID, d.data[i].Text, "")
+			continue
+		}
+		data = append(data, d.data[i])
+	}
+	d.data = data
+
+	events := d.events[:0]
+	for i := range d.events {
+		if !ids[d.events[i].TodoID] {
+			events = append(events, d.events[i])
+		}
+	}
+	d.events = events
+
+	shares := d.shares[:0]
+	for i := range d.shares {
+		if !ids[d.shares[i].TodoID] {
+			shares = append(shares, d.shares[i])
+		}
+	}
+	d.shares = shares
+}
+
+// subtree walks the tree below the todo item given by id breadth first,
+// returning every descendant ordered by depth and then creation time.
+func (d *Store) subtree(id uuid.UUID) []todo.Todo {
+	todos := make([]todo.Todo, 0)
+	visited := map[uuid.UUID]bool{id: true}
+	level := []uuid.UUID{id}
+
+	for len(level) > 0 {
+		parents := make(map[uuid.UUID]bool, len(level))
+		for _, p := range level {
+			parents[p] = true
+		}
+
+		children := make([]todo.Todo, 0)
+		for i := range d.data {
+			td := d.data[i]
+			if td.ParentID != nil && parents[*td.ParentID] && !visited[td.ID] {
+				visited[td.ID] = true
+				children = append(children, td)
+			}
+		}
+
+		sort.SliceStable(children, func(i, j int) bool {
+			return compare(children[i], children[j], todo.OrderByTimeCreated, todo.DirectionAsc) < 0
+		})
+
+		level = level[:0]
+		for _, child := range children {
+			level = append(level, child.ID)
+		}
+
+		todos = append(todos, children...)
+	}
+
+	return todos
+}
+
+// isDescendant reports whether the todo item given by id is below the todo
+// item given by ancestor.
+func (d *Store) isDescendant(id uuid.UUID, ancestor uuid.UUID) bool {
+	for _, td := range d.subtree(ancestor) {
+		if td.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// indexTags moves the todo item given by id from the index entries of its old
+// tags to those of its new tags. Tags that are no longer used are removed from
+// the index.
+func (d *Store) indexTags(id uuid.UUID, oldTags []string, newTags []string) {
+	if d.tx {
+		d.undo = append(d.undo, func() { d.indexTags(id, newTags, oldTags) })
+	}
+
+	for _, tag := range oldTags {
+		delete(d.tags[tag], id)
+		if len(d.tags[tag]) == 0 {
+			delete(d.tags, tag)
+		}
+	}
+
+	for _, tag := range newTags {
+		if d.tags[tag] == nil {
+			d.tags[tag] = make(map[uuid.UUID]struct{})
+		}
+		d.tags[tag][id] = struct{}{}
+	}
+}
+
+// hasTags reports whether td has every tag in tags. The tag index only holds
+// todo items outside of the trash, so the tags of trashed todo items are
+// checked directly.
+func (d *Store) hasTags(td todo.Todo, tags []string) bool {
+	for _, tag := range tags {
+		if trashed(td) {
+			if !contains(td.Tags, tag) {
+				return false
+			}
+			continue
+		}
+		if _, ok := d.tags[tag][td.ID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether tags contains tag.
+func contains(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// trashed reports whether td is in the trash.
+func trashed(td todo.Todo) bool {
+	return td.TimeDeleted != nil
+}
+
+// clone returns a copy of td that shares no memory with the store.
+func clone(td todo.Todo) todo.Todo {
+	td.ParentID = copyID(td.ParentID)
+	td.NextID = copyID(td.NextID)
+	td.DueAt = copyTime(td.DueAt)
+	td.Tags = copyTags(td.Tags)
+	td.TimeDeleted = copyTime(td.TimeDeleted)
+	return td
+}
+
+// copyID returns a copy of id, or nil when id is nil.
+func copyID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	return &c
+}
+
+// copyTime returns a copy of t, or nil when t is nil.
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// cloneEvent returns a copy of ev that shares no memory with the store.
+func cloneEvent(ev todo.Event) todo.Event {
+	changes := make([]todo.Change, len(ev.Changes))
+	for i, c := range ev.Changes {
+		changes[i] = todo.Change{
+			Field:  c.Field,
+			Before: append(json.RawMessage(nil), c.Before...),
+			After:  append(json.RawMessage(nil), c.After...),
+		}
+	}
+	ev.Changes = changes
+	return ev
+}
+
+// copyTags returns a copy of tags that is never nil.
+func copyTags(tags []string) []string {
+	c := make([]string, len(tags))
+	copy(c, tags)
+	return c
+}
+
+// matches reports whether td satisfies every condition of f.
+func matches(td todo.Todo, f todo.QueryFilter) bool {
+	if f.OwnerID != nil && td.OwnerID != *f.OwnerID {
+		return false
+	}
+	if f.ListID != nil && td.ListID != *f.ListID {
+		return false
+	}
+	if f.Completed != nil && td.Completed != *f.Completed {
+		return false
+	}
+	if f.Priority != nil && td.Priority != *f.Priority {
+		return false
+	}
+	if f.CreatedAfter != nil && td.TimeCreated.Before(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !td.TimeCreated.Before(*f.CreatedBefore) {
+		return false
+	}
+	if f.UpdatedAfter != nil && td.TimeUpdated.Before(*f.UpdatedAfter) {
+		return false
+	}
+	if f.UpdatedBefore != nil && !td.TimeUpdated.Before(*f.UpdatedBefore) {
+		return false
+	}
+	return true
+}
+
+// afterCursor reports whether td is positioned after c in a listing ordered by
+// creation time in the given direction.
+func afterCursor(td todo.Todo, c todo.Cursor, direction todo.Direction) bool {
+	cmp := td.TimeCreated.Compare(c.TimeCreated)
+	if cmp == 0 {
+		cmp = bytes.Compare(td.ID[:], c.ID[:])
+	}
+
+	if direction == todo.DirectionDesc {
+		return cmp < 0
+	}
+
+	return cmp > 0
+}
+
+// compare orders a and b by field in the given direction, breaking ties by
+// creation time and then ID to match the database store. Todo items without a
+// due date sort last in either direction when ordering by due date.
+func compare(a, b todo.Todo, field todo.OrderField, direction todo.Direction) int {
+	c := 0
+
+	switch field {
+	case todo.OrderByTimeUpdated:
+		c = a.TimeUpdated.Compare(b.TimeUpdated)
+	case todo.OrderByDueAt:
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+		case a.DueAt == nil:
+			return 1
+		case b.DueAt == nil:
+			return -1
+		default:
+			c = a.DueAt.Compare(*b.DueAt)
+		}
+	case todo.OrderByPriority:
+		c = a.Priority.Rank() - b.Priority.Rank()
+	case todo.OrderByText:
+		c = strings.Compare(a.Text, b.Text)
+	}
+
+	if c == 0 {
+		c = a.TimeCreated.Compare(b.TimeCreated)
+	}
+	if c == 0 {
+		c = bytes.Compare(a.ID[:], b.ID[:])
+	}
+
+	if direction == todo.DirectionDesc {
+		return -c
+	}
+
+	return c
+}
@@ -0,0 +1,118 @@
+package todomemory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/storertest"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestStore(t *testing.T) {
+	storertest.Run(t, func() todo.Storer {
+		return todomemory.NewStore()
+	})
+}
+
+func TestStoreRollback(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+	errRollback := errors.New("rollback")
+
+	s := todomemory.NewStore()
+
+	todos := make([]todo.Todo, 0)
+	for _, text := range []string{"buy milk", "walk dog", "call mom"} {
+		td := todo.Todo{
+			ID:          uuid.New(),
+			Text:        text,
+			Priority:    todo.PriorityLow,
+			ListID:      todo.InboxID,
+			Tags:        []string{"home"},
+			Version:     1,
+			TimeCreated: now,
+			TimeUpdated: now,
+		}
+		if err := s.Create(ctx, td, todo.Event{ID: uuid.New(), TodoID: td.ID, Type: todo.EventCreated, TimeCreated: now}); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+		todos = append(todos, td)
+	}
+
+	l := todo.List{ID: uuid.New(), Name: "errands", TimeCreated: now, TimeUpdated: now}
+	if err := s.CreateList(ctx, l); err != nil {
+		t.Fatalf("create list: expected nil error, got %v", err)
+	}
+
+	before := s.Snapshot()
+
+	// Every kind of change made in place is undone when the transaction fails.
+	err := s.WithTx(ctx, func(tx todo.Storer) error {
+		updated := todos[0]
+		updated.Text = "buy oat milk"
+		updated.Tags = []string{"shop"}
+		updated.Version++
+		if err := tx.Update(ctx, updated, todo.Event{ID: uuid.New(), TodoID: updated.ID, Type: todo.EventUpdated, TimeCreated: now}); err != nil {
+			t.Fatalf("update: expected nil error, got %v", err)
+		}
+
+		trashed := todos[1]
+		trashed.TimeDeleted = &now
+		if err := tx.Delete(ctx, trashed, todo.Event{ID: uuid.New(), TodoID: trashed.ID, Type: todo.EventDeleted, TimeCreated: now}); err != nil {
+			t.Fatalf("delete: expected nil error, got %v", err)
+		}
+
+		if err := tx.Purge(ctx, todos[2]); err != nil {
+			t.Fatalf("purge: expected nil error, got %v", err)
+		}
+
+		l.Name = "chores"
+		if err := tx.UpdateList(ctx, l); err != nil {
+			t.Fatalf("update list: expected nil error, got %v", err)
+		}
+
+		if err := tx.DeleteList(ctx, l); err != nil {
+			t.Fatalf("delete list: expected nil error, got %v", err)
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("with tx: expected %v, got %v", errRollback, err)
+	}
+
+	if diff := cmp.Diff(before, s.Snapshot()); diff != "" {
+		t.Fatalf("snapshot: mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := map[string]int{
+		"milk": 1,
+		"oat":  0,
+		"dog":  1,
+		"mom":  1,
+	}
+
+	for query, want := range tests {
+		results, err := s.Search(ctx, todo.SearchParams{Query: query, Limit: 10})
+		if err != nil {
+			t.Fatalf("search: expected nil error, got %v", err)
+		}
+		if len(results) != want {
+			t.Errorf("search %q: expected %d results, got %d", query, want, len(results))
+		}
+	}
+
+	tags, err := s.QueryTags(ctx, uuid.Nil)
+	if err != nil {
+		t.Fatalf("query tags: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.TagCount{{Tag: "home", Count: 3}}, tags); diff != "" {
+		t.Errorf("query tags: mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,134 @@
+package todomemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryUserByID retrieves a user from memory.
+func (d *Store) QueryUserByID(ctx context.Context, id uuid.UUID) (todo.User, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.users {
+		if d.users[i].ID == id {
+			return d.users[i], nil
+		}
+	}
+
+	return todo.User{}, todo.ErrUserNotFound
+}
+
+// QueryUserByName retrieves a user from memory by their name.
+func (d *Store) QueryUserByName(ctx context.Context, name string) (todo.User, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.users {
+		if d.users[i].Name == name {
+			return d.users[i], nil
+		}
+	}
+
+	return todo.User{}, todo.ErrUserNotFound
+}
+
+// CreateUser adds a user to memory.
+func (d *Store) CreateUser(ctx context.Context, user todo.User) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.users {
+		if d.users[i].Name == user.Name {
+			return todo.ErrUserExists
+		}
+	}
+
+	d.users = append(d.users, user)
+
+	return nil
+}
+
+// QueryAPIKeys retrieves the API keys of a user from memory in the order they
+// were created.
+func (d *Store) QueryAPIKeys(ctx context.Context, userID uuid.UUID) ([]todo.APIKey, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	keys := make([]todo.APIKey, 0)
+	for _, key := range d.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, cloneAPIKey(key))
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].TimeCreated.Before(keys[j].TimeCreated)
+	})
+
+	return keys, nil
+}
+
+// QueryAPIKeyByID retrieves an API key from memory.
+func (d *Store) QueryAPIKeyByID(ctx context.Context, id uuid.UUID) (todo.APIKey, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.apiKeys {
+		if d.apiKeys[i].ID == id {
+			return cloneAPIKey(d.apiKeys[i]), nil
+		}
+	}
+
+	return todo.APIKey{}, todo.ErrAPIKeyNotFound
+}
+
+// QueryAPIKeyByHash retrieves an API key from memory by the hash of the key.
+func (d *Store) QueryAPIKeyByHash(ctx context.Context, hash string) (todo.APIKey, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.apiKeys {
+		if d.apiKeys[i].Hash == hash {
+			return cloneAPIKey(d.apiKeys[i]), nil
+		}
+	}
+
+	return todo.APIKey{}, todo.ErrAPIKeyNotFound
+}
+
+// CreateAPIKey adds an API key to memory.
+func (d *Store) CreateAPIKey(ctx context.Context, key todo.APIKey) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.apiKeys = append(d.apiKeys, cloneAPIKey(key))
+
+	return nil
+}
+
+// UpdateAPIKey modifies an existing API key in memory.
+func (d *Store) UpdateAPIKey(ctx context.Context, key todo.APIKey) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.apiKeys {
+		if d.apiKeys[i].ID == key.ID {
+			save(d, &d.apiKeys[i])
+			d.apiKeys[i] = cloneAPIKey(key)
+			return nil
+		}
+	}
+
+	return todo.ErrAPIKeyNotFound
+}
+
+// cloneAPIKey returns a copy of key that shares no memory with it.
+func cloneAPIKey(key todo.APIKey) todo.APIKey {
+	key.TimeRevoked = copyTime(key.TimeRevoked)
+	return key
+}
@@ -0,0 +1,242 @@
+package todomemory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryWebhooks retrieves the webhooks matching filter from memory in the
+// order they were created.
+func (d *Store) QueryWebhooks(ctx context.Context, filter todo.WebhookFilter) ([]todo.Webhook, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	webhooks := make([]todo.Webhook, 0)
+	for _, wh := range d.webhooks {
+		if filter.OwnerID != nil && wh.OwnerID != *filter.OwnerID {
+			continue
+		}
+
+		if filter.Subscribed != nil && !wh.Subscribed(*filter.Subscribed) {
+			continue
+		}
+
+		webhooks = append(webhooks, cloneWebhook(wh))
+	}
+
+	sort.SliceStable(webhooks, func(i, j int) bool {
+		a, b := webhooks[i], webhooks[j]
+		if !a.TimeCreated.Equal(b.TimeCreated) {
+			return a.TimeCreated.Before(b.TimeCreated)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	return webhooks, nil
+}
+
+// QueryWebhookByID retrieves a webhook from memory.
+func (d *Store) QueryWebhookByID(ctx context.Context, id uuid.UUID) (todo.Webhook, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.webhooks {
+		if d.webhooks[i].ID == id {
+			return cloneWebhook(d.webhooks[i]), nil
+		}
+	}
+
+	return todo.Webhook{}, todo.ErrWebhookNotFound
+}
+
+// CreateWebhook adds a webhook to memory.
+func (d *Store) CreateWebhook(ctx context.Context, wh todo.Webhook) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.webhooks = append(d.webhooks, cloneWebhook(wh))
+
+	return nil
+}
+
+// UpdateWebhook modifies an existing webhook in memory.
+func (d *Store) UpdateWebhook(ctx context.Context, wh todo.Webhook) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.webhooks {
+		if d.webhooks[i].ID == wh.ID {
+			wh.TimeCreated = d.webhooks[i].TimeCreated
+			save(d, &d.webhooks[i])
+			d.webhooks[i] = cloneWebhook(wh)
+			return nil
+		}
+	}
+
+	return todo.ErrWebhookNotFound
+}
+
+// DeleteWebhook deletes a webhook and its deliveries from memory.
+func (d *Store) DeleteWebhook(ctx context.Context, wh todo.Webhook) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.webhooks {
+		if d.webhooks[i].ID != wh.ID {
+			continue
+		}
+
+		own(d, &d.webhooks)
+		own(d, &d.deliveries)
+
+		d.webhooks = append(d.webhooks[:i], d.webhooks[i+1:]...)
+
+		deliveries := d.deliveries[:0]
+		for _, dl := range d.deliveries {
+			if dl.WebhookID != wh.ID {
+				deliveries = append(deliveries, dl)
+			}
+		}
+		d.deliveries = deliveries
+
+		return nil
+	}
+
+	return todo.ErrWebhookNotFound
+}
+
+// QueryDeliveries retrieves the deliveries of a webhook from memory, newest
+// first.
+func (d *Store) QueryDeliveries(ctx context.Context, webhookID uuid.UUID) ([]todo.Delivery, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	deliveries := make([]todo.Delivery, 0)
+	for _, dl := range d.deliveries {
+		if dl.WebhookID == webhookID {
+			deliveries = append(deliveries, cloneDelivery(dl))
+		}
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if !a.TimeCreated.Equal(b.TimeCreated) {
+			return a.TimeCreated.After(b.TimeCreated)
+		}
+		return bytes.Compare(a.ID[:], b.ID[:]) > 0
+	})
+
+	return deliveries, nil
+}
+
+// QueryDeliveryByID retrieves a delivery from memory.
+func (d *Store) QueryDeliveryByID(ctx context.Context, id uuid.UUID) (todo.Delivery, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for i := range d.deliveries {
+		if d.deliveries[i].ID == id {
+			return cloneDelivery(d.deliveries[i]), nil
+		}
+	}
+
+	return todo.Delivery{}, todo.ErrDeliveryNotFound
+}
+
+// CreateDelivery adds a delivery to memory.
+func (d *Store) CreateDelivery(ctx context.Context, dl todo.Delivery) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.deliveries = append(d.deliveries, cloneDelivery(dl))
+
+	return nil
+}
+
+// ClaimDeliveries retrieves the pending deliveries that are due from memory
+// and moves their next attempt to until.
+func (d *Store) ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]todo.Delivery, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	due := make([]int, 0)
+	for i, dl := range d.deliveries {
+		if dl.Status == todo.DeliveryPending && dl.NextAttemptAt != nil && !dl.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		a, b := d.deliveries[due[i]], d.deliveries[due[j]]
+		if !a.NextAttemptAt.Equal(*b.NextAttemptAt) {
+			return a.NextAttemptAt.Before(*b.NextAttemptAt)
+		}
+		return a.TimeCreated.Before(b.TimeCreated)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]todo.Delivery, 0, len(due))
+	for _, i := range due {
+		save(d, &d.deliveries[i])
+		d.deliveries[i].NextAttemptAt = copyTime(&until)
+		claimed = append(claimed, cloneDelivery(d.deliveries[i]))
+	}
+
+	return claimed, nil
+}
+
+// UpdateDelivery modifies an existing delivery in memory, appending the
+// attempts it does not have yet.
+func (d *Store) UpdateDelivery(ctx context.Context, dl todo.Delivery) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.deliveries {
+		if d.deliveries[i].ID != dl.ID {
+			continue
+		}
+
+		stored := &d.deliveries[i]
+		save(d, stored)
+		stored.Status = dl.Status
+		stored.Tries = dl.Tries
+		stored.NextAttemptAt = copyTime(dl.NextAttemptAt)
+		stored.TimeUpdated = dl.TimeUpdated
+
+		if len(dl.Attempts) > len(stored.Attempts) {
+			stored.Attempts = append(stored.Attempts, dl.Attempts[len(stored.Attempts):]...)
+		}
+
+		return nil
+	}
+
+	return todo.ErrDeliveryNotFound
+}
+
+// cloneWebhook returns a copy of wh that shares no memory with the store.
+func cloneWebhook(wh todo.Webhook) todo.Webhook {
+	events := make([]todo.EventType, len(wh.Events))
+	copy(events, wh.Events)
+	wh.Events = events
+	return wh
+}
+
+// cloneDelivery returns a copy of dl that shares no memory with the store.
+func cloneDelivery(dl todo.Delivery) todo.Delivery {
+	dl.Payload = append(json.RawMessage(nil), dl.Payload...)
+	dl.NextAttemptAt = copyTime(dl.NextAttemptAt)
+	attempts := make([]todo.DeliveryAttempt, len(dl.Attempts))
+	copy(attempts, dl.Attempts)
+	dl.Attempts = attempts
+	return dl
+}
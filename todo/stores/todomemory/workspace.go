@@ -0,0 +1,232 @@
+package todomemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryWorkspaces retrieves the workspaces userID is a member of, or every
+// workspace when it is nil, from memory ordered by name.
+func (d *Store) QueryWorkspaces(ctx context.Context, userID *uuid.UUID) ([]todo.Workspace, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	workspaces := make([]todo.Workspace, 0)
+
+	for _, ws := range d.workspaces {
+		if userID != nil && d.memberIndex(ws.ID, *userID) < 0 {
+			continue
+		}
+
+		workspaces = append(workspaces, cloneWorkspace(ws))
+	}
+
+	sort.SliceStable(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+
+	return workspaces, nil
+}
+
+// QueryWorkspaceByID retrieves a workspace from memory.
+func (d *Store) QueryWorkspaceByID(ctx context.Context, id uuid.UUID) (todo.Workspace, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, ws := range d.workspaces {
+		if ws.ID == id {
+			return cloneWorkspace(ws), nil
+		}
+	}
+
+	return todo.Workspace{}, todo.ErrWorkspaceNotFound
+}
+
+// CreateWorkspace adds a workspace to memory.
+func (d *Store) CreateWorkspace(ctx context.Context, ws todo.Workspace) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.workspaces = append(d.workspaces, cloneWorkspace(ws))
+
+	return nil
+}
+
+// UpdateWorkspace modifies an existing workspace in memory.
+func (d *Store) UpdateWorkspace(ctx context.Context, ws todo.Workspace) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := range d.workspaces {
+		if d.workspaces[i].ID == ws.ID {
+			save(d, &d.workspaces[i])
+			d.workspaces[i].Name = ws.Name
+			d.workspaces[i].Settings = cloneWorkspace(ws).Settings
+			d.workspaces[i].TimeUpdated = ws.TimeUpdated
+			return nil
+		}
+	}
+
+	return todo.ErrWorkspaceNotFound
+}
+
+// DeleteWorkspace deletes a workspace along with its todo items, lists and
+// members from memory.
+func (d *Store) DeleteWorkspace(ctx context.Context, ws todo.Workspace) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	i := 0
+	for i < len(d.workspaces) && d.workspaces[i].ID != ws.ID {
+		i++
+	}
+
+	if i == len(d.workspaces) {
+		return todo.ErrWorkspaceNotFound
+	}
+
+	own(d, &d.workspaces)
+	own(d, &d.lists)
+	own(d, &d.members)
+
+	d.workspaces = append(d.workspaces[:i], d.workspaces[i+1:]...)
+
+	ids := make(map[uuid.UUID]bool)
+	for i := range d.data {
+		if d.data[i].WorkspaceID == ws.ID {
+			ids[d.data[i].ID] = true
+		}
+	}
+	d.remove(ids)
+
+	lists := d.lists[:0]
+	for i := range d.lists {
+		if d.lists[i].WorkspaceID != ws.ID {
+			lists = append(lists, d.lists[i])
+		}
+	}
+	d.lists = lists
+
+	members := d.members[:0]
+	for i := range d.members {
+		if d.members[i].WorkspaceID != ws.ID {
+			members = append(members, d.members[i])
+		}
+	}
+	d.members = members
+
+	return nil
+}
+
+// QueryMembers retrieves the members of a workspace from memory in the order
+// they joined.
+func (d *Store) QueryMembers(ctx context.Context, workspaceID uuid.UUID) ([]todo.Member, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	members := make([]todo.Member, 0)
+	for _, member := range d.members {
+		if member.WorkspaceID == workspaceID {
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
+
+// QueryMember retrieves the membership of a user in a workspace from memory.
+func (d *Store) QueryMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (todo.Member, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	i := d.memberIndex(workspaceID, userID)
+	if i < 0 {
+		return todo.Member{}, todo.ErrMemberNotFound
+	}
+
+	return d.members[i], nil
+}
+
+// PutMember adds a member to memory, replacing the role of an existing member
+// of the workspace with the same user.
+func (d *Store) PutMember(ctx context.Context, member todo.Member) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if i := d.memberIndex(member.WorkspaceID, member.UserID); i >= 0 {
+		save(d, &d.members[i])
+		d.members[i].Role = member.Role
+		return nil
+	}
+
+	d.members = append(d.members, member)
+
+	return nil
+}
+
+// DeleteMember deletes a member from memory.
+func (d *Store) DeleteMember(ctx context.Context, member todo.Member) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	i := d.memberIndex(member.WorkspaceID, member.UserID)
+	if i < 0 {
+		return todo.ErrMemberNotFound
+	}
+
+	own(d, &d.members)
+	d.members = append(d.members[:i], d.members[i+1:]...)
+
+	return nil
+}
+
+// memberIndex returns the index of the membership of a user in a workspace,
+// or -1 when they are not a member.
+func (d *Store) memberIndex(workspaceID uuid.UUID, userID uuid.UUID) int {
+	for i := range d.members {
+		if d.members[i].WorkspaceID == workspaceID && d.members[i].UserID == userID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// defaultWorkspace returns the default workspace of a new store, whose default
+// list is the inbox.
+func defaultWorkspace(now time.Time) todo.Workspace {
+	return todo.Workspace{
+		ID:   todo.DefaultWorkspaceID,
+		Name: "Default",
+		Settings: todo.WorkspaceSettings{
+			AllowedPriorities: make([]todo.Priority, 0),
+			DefaultListID:     todo.InboxID,
+		},
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+}
+
+// hasWorkspace reports whether workspaces contains the workspace given by id.
+func hasWorkspace(workspaces []todo.Workspace, id uuid.UUID) bool {
+	for _, ws := range workspaces {
+		if ws.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cloneWorkspace returns a copy of ws that shares no memory with the store.
+func cloneWorkspace(ws todo.Workspace) todo.Workspace {
+	priorities := make([]todo.Priority, len(ws.Settings.AllowedPriorities))
+	copy(priorities, ws.Settings.AllowedPriorities)
+	ws.Settings.AllowedPriorities = priorities
+	return ws
+}
@@ -0,0 +1,71 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryIdentity retrieves an identity from the database by its issuer and
+// subject.
+func (d *Store) QueryIdentity(ctx context.Context, issuer string, subject string) (todo.Identity, error) {
+	const query = `
+	SELECT
+	  id, user_id, issuer, subject, time_created
+	FROM
+	  identities
+	WHERE
+	  issuer = ?1 AND subject = ?2`
+
+	var i todo.Identity
+
+	if err := d.conn().QueryRowContext(ctx, query, issuer, subject).Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Issuer,
+		&i.Subject,
+		timeScanner{&i.TimeCreated},
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Identity{}, todo.ErrIdentityNotFound
+		}
+		return todo.Identity{}, fmt.Errorf("db: %w", err)
+	}
+
+	return i, nil
+}
+
+// CreateIdentity adds an identity to the database.
+func (d *Store) CreateIdentity(ctx context.Context, identity todo.Identity) error {
+	const query = `
+	INSERT INTO identities
+	  (id, user_id, issuer, subject, time_created)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5)
+	ON CONFLICT (issuer, subject) DO NOTHING`
+
+	res, err := d.conn().ExecContext(ctx, query,
+		identity.ID,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		formatTime(identity.TimeCreated),
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrIdentityExists
+	}
+
+	return nil
+}
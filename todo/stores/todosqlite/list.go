@@ -0,0 +1,166 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryLists retrieves all lists in the workspace from the database, starting
+// with the inbox.
+func (d *Store) QueryLists(ctx context.Context) ([]todo.List, error) {
+	const query = `
+	SELECT
+	  id, workspace_id, owner_id, name, time_created, time_updated
+	FROM
+	  lists
+	WHERE
+	  workspace_id = ?2
+	ORDER BY
+	  id <> ?1, time_created, id`
+
+	rows, err := d.conn().QueryContext(ctx, query, todo.InboxID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	lists := make([]todo.List, 0)
+
+	for rows.Next() {
+		var l todo.List
+		if err := rows.Scan(
+			&l.ID,
+			&l.WorkspaceID,
+			&l.OwnerID,
+			&l.Name,
+			timeScanner{&l.TimeCreated},
+			timeScanner{&l.TimeUpdated},
+		); err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// QueryListByID retrieves a list from the database.
+func (d *Store) QueryListByID(ctx context.Context, id uuid.UUID) (todo.List, error) {
+	const query = `
+	SELECT
+	  id, workspace_id, owner_id, name, time_created, time_updated
+	FROM
+	  lists
+	WHERE
+	  id = ?1 AND workspace_id = ?2`
+
+	var l todo.List
+
+	if err := d.conn().QueryRowContext(ctx, query, id, todo.WorkspaceFrom(ctx)).Scan(
+		&l.ID,
+		&l.WorkspaceID,
+		&l.OwnerID,
+		&l.Name,
+		timeScanner{&l.TimeCreated},
+		timeScanner{&l.TimeUpdated},
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.List{}, todo.ErrListNotFound
+		}
+		return todo.List{}, fmt.Errorf("db: %w", err)
+	}
+
+	return l, nil
+}
+
+// CreateList adds a list to the database.
+func (d *Store) CreateList(ctx context.Context, l todo.List) error {
+	const query = `
+	INSERT INTO lists
+	  (id, workspace_id, owner_id, name, time_created, time_updated)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6)`
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		l.ID,
+		todo.WorkspaceFrom(ctx),
+		l.OwnerID,
+		l.Name,
+		formatTime(l.TimeCreated),
+		formatTime(l.TimeUpdated),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateList modifies an existing list in the database.
+func (d *Store) UpdateList(ctx context.Context, l todo.List) error {
+	const query = `
+	UPDATE
+	  lists
+	SET
+		name = ?1,
+		time_updated = ?2
+	WHERE
+	  id = ?3 AND workspace_id = ?4`
+
+	res, err := d.conn().ExecContext(ctx, query,
+		l.Name,
+		formatTime(l.TimeUpdated),
+		l.ID,
+		todo.WorkspaceFrom(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return listAffected(res)
+}
+
+// DeleteList deletes a list from the database. The list must no longer
+// contain any todo items.
+func (d *Store) DeleteList(ctx context.Context, l todo.List) error {
+	const query = `
+	DELETE FROM
+	  lists
+	WHERE
+	  id = ?1 AND workspace_id = ?2`
+
+	res, err := d.conn().ExecContext(ctx, query,
+		l.ID,
+		todo.WorkspaceFrom(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return listAffected(res)
+}
+
+// listAffected returns todo.ErrListNotFound if res did not affect any rows.
+func listAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrListNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package todosqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Search retrieves the todo items matching params from the database using the
+// todos_search full-text index. Todo items are ranked with bm25, negated so
+// that a higher rank is a better match.
+func (d *Store) Search(ctx context.Context, params todo.SearchParams) ([]todo.SearchResult, error) {
+	const query = `
+	SELECT ` + todoColumns + `, search_rank, search_snippet
+	FROM
+	  todos
+	JOIN (
+	  SELECT
+	    id AS search_id,
+	    -bm25(todos_search) AS search_rank,
+	    snippet(todos_search, 1, ?2, ?3, '…', 20) AS search_snippet
+	  FROM todos_search
+	  WHERE todos_search MATCH ?1
+	) ON search_id = todos.id
+	WHERE
+	  workspace_id = ?6 AND owner_id = ?5 AND deleted_at IS NULL
+	ORDER BY
+	  search_rank DESC, time_updated DESC, id
+	LIMIT ?4`
+
+	rows, err := d.conn().QueryContext(ctx, query, match(params.Query), todo.HighlightStart, todo.HighlightEnd, params.Limit, params.OwnerID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	results := make([]todo.SearchResult, 0)
+
+	for rows.Next() {
+		var res todo.SearchResult
+		if err := scanTodo(rows, &res.Todo, &res.Rank, &res.Snippet); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return results, nil
+}
+
+// match builds an FTS5 query that matches the words starting with every term
+// of query. Terms only hold letters and digits, but they are quoted so that
+// words such as AND or NOT are not read as operators.
+func match(query string) string {
+	terms := todo.SearchTerms(query)
+	for i := range terms {
+		terms[i] = `"` + terms[i] + `"*`
+	}
+
+	return strings.Join(terms, " AND ")
+}
@@ -0,0 +1,102 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QuerySessionByHash retrieves a session from the database by the hash of its
+// token.
+func (d *Store) QuerySessionByHash(ctx context.Context, hash string) (todo.Session, error) {
+	const query = `
+	SELECT
+	  id, user_id, hash, csrf_token, time_created, time_expires
+	FROM
+	  sessions
+	WHERE
+	  hash = ?1`
+
+	var s todo.Session
+
+	if err := d.conn().QueryRowContext(ctx, query, hash).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.Hash,
+		&s.CSRFToken,
+		timeScanner{&s.TimeCreated},
+		timeScanner{&s.TimeExpires},
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Session{}, todo.ErrSessionNotFound
+		}
+		return todo.Session{}, fmt.Errorf("db: %w", err)
+	}
+
+	return s, nil
+}
+
+// CreateSession adds a session to the database.
+func (d *Store) CreateSession(ctx context.Context, session todo.Session) error {
+	const query = `
+	INSERT INTO sessions
+	  (id, user_id, hash, csrf_token, time_created, time_expires)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6)`
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.Hash,
+		session.CSRFToken,
+		formatTime(session.TimeCreated),
+		formatTime(session.TimeExpires),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSession deletes a session from the database.
+func (d *Store) DeleteSession(ctx context.Context, session todo.Session) error {
+	const query = `DELETE FROM sessions WHERE id = ?1`
+
+	res, err := d.conn().ExecContext(ctx, query, session.ID)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// PurgeSessions deletes the sessions that expired before the given time from
+// the database.
+func (d *Store) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	const query = `DELETE FROM sessions WHERE time_expires < ?1`
+
+	res, err := d.conn().ExecContext(ctx, query, formatTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,142 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// QueryShares retrieves the shares of a todo item from the database, oldest
+// first.
+func (d *Store) QueryShares(ctx context.Context, todoID uuid.UUID) ([]todo.Share, error) {
+	const query = `
+	SELECT
+	  todo_id, user_id, role, time_created
+	FROM
+	  shares
+	WHERE
+	  todo_id = ?1 AND todo_id IN (SELECT id FROM todos WHERE workspace_id = ?2)
+	ORDER BY
+	  time_created, user_id`
+
+	return d.queryShares(ctx, query, todoID, todo.WorkspaceFrom(ctx))
+}
+
+// QuerySharesByUser retrieves the shares of a user from the database, oldest
+// first.
+func (d *Store) QuerySharesByUser(ctx context.Context, userID uuid.UUID) ([]todo.Share, error) {
+	const query = `
+	SELECT
+	  todo_id, user_id, role, time_created
+	FROM
+	  shares
+	WHERE
+	  user_id = ?1 AND todo_id IN (SELECT id FROM todos WHERE workspace_id = ?2)
+	ORDER BY
+	  time_created, todo_id`
+
+	return d.queryShares(ctx, query, userID, todo.WorkspaceFrom(ctx))
+}
+
+// queryShares runs a query returning shares.
+func (d *Store) queryShares(ctx context.Context, query string, args ...any) ([]todo.Share, error) {
+	rows, err := d.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+	defer rows.Close()
+
+	shares := make([]todo.Share, 0)
+
+	for rows.Next() {
+		var s todo.Share
+
+		if err := rows.Scan(&s.TodoID, &s.UserID, &s.Role, timeScanner{&s.TimeCreated}); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		shares = append(shares, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return shares, nil
+}
+
+// QueryShare retrieves the share of a todo item with a user from the database.
+func (d *Store) QueryShare(ctx context.Context, todoID uuid.UUID, userID uuid.UUID) (todo.Share, error) {
+	const query = `
+	SELECT
+	  todo_id, user_id, role, time_created
+	FROM
+	  shares
+	WHERE
+	  todo_id = ?1 AND user_id = ?2 AND todo_id IN (SELECT id FROM todos WHERE workspace_id = ?3)`
+
+	var s todo.Share
+
+	if err := d.conn().QueryRowContext(ctx, query, todoID, userID, todo.WorkspaceFrom(ctx)).Scan(
+		&s.TodoID,
+		&s.UserID,
+		&s.Role,
+		timeScanner{&s.TimeCreated},
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Share{}, todo.ErrShareNotFound
+		}
+		return todo.Share{}, fmt.Errorf("db: %w", err)
+	}
+
+	return s, nil
+}
+
+// PutShare adds a share to the database, replacing the role of an existing
+// share of the todo item with the same user.
+func (d *Store) PutShare(ctx context.Context, share todo.Share) error {
+	const query = `
+	INSERT INTO shares
+	  (todo_id, user_id, role, time_created)
+	VALUES
+	  (?1, ?2, ?3, ?4)
+	ON CONFLICT (todo_id, user_id) DO UPDATE SET
+	  role = excluded.role`
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		share.TodoID,
+		share.UserID,
+		share.Role,
+		formatTime(share.TimeCreated),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteShare deletes a share from the database.
+func (d *Store) DeleteShare(ctx context.Context, share todo.Share) error {
+	const query = `DELETE FROM shares WHERE todo_id = ?1 AND user_id = ?2`
+
+	res, err := d.conn().ExecContext(ctx, query, share.TodoID, share.UserID)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrShareNotFound
+	}
+
+	return nil
+}
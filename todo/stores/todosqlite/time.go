@@ -0,0 +1,78 @@
+package todosqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeLayout is the format times are stored in. SQLite has no timestamp type,
+// so times are stored as fixed width UTC text that sorts chronologically.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// formatTime formats t for storage.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// formatNullTime formats t for storage, or returns nil when t is nil.
+func formatNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// parseTime parses a time stored by formatTime.
+func parseTime(src any) (time.Time, error) {
+	var s string
+
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time value of type %T", src)
+	}
+
+	return time.Parse(timeLayout, s)
+}
+
+// timeScanner scans a time stored by formatTime into t.
+type timeScanner struct {
+	t *time.Time
+}
+
+// Scan implements the sql.Scanner interface.
+func (s timeScanner) Scan(src any) error {
+	t, err := parseTime(src)
+	if err != nil {
+		return err
+	}
+
+	*s.t = t
+
+	return nil
+}
+
+// nullTimeScanner scans a nullable time stored by formatTime into t.
+type nullTimeScanner struct {
+	t **time.Time
+}
+
+// Scan implements the sql.Scanner interface.
+func (s nullTimeScanner) Scan(src any) error {
+	if src == nil {
+		*s.t = nil
+		return nil
+	}
+
+	t, err := parseTime(src)
+	if err != nil {
+		return err
+	}
+
+	*s.t = &t
+
+	return nil
+}
@@ -0,0 +1,758 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Store exposes the APIs needed to interface with todo items in a SQLite
+// database.
+type Store struct {
+	db *sql.DB
+
+	// tx is set on the store given to the function passed to WithTx.
+	tx *sql.Tx
+}
+
+// NewStore is a constructor for a Store.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Query retrieves the todo items matching opts from the database.
+func (d *Store) Query(ctx context.Context, opts todo.QueryOptions) ([]todo.Todo, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return "?" + strconv.Itoa(len(args))
+	}
+
+	where = append(where, "workspace_id = "+arg(todo.WorkspaceFrom(ctx)))
+
+	f := opts.Filter
+	if f.OwnerID != nil {
+		where = append(where, "owner_id = "+arg(*f.OwnerID))
+	}
+	if f.Trashed {
+		where = append(where, "deleted_at IS NOT NULL")
+	} else {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if f.ListID != nil {
+		where = append(where, "list_id = "+arg(*f.ListID))
+	}
+	if f.Completed != nil {
+		where = append(where, "completed = "+arg(*f.Completed))
+	}
+	if f.Priority != nil {
+		where = append(where, "priority = "+arg(*f.Priority))
+	}
+	if len(f.Tags) > 0 {
+		placeholders := make([]string, 0, len(f.Tags))
+		for _, tag := range f.Tags {
+			placeholders = append(placeholders, arg(tag))
+		}
+		where = append(where, fmt.Sprintf(
+			"id IN (SELECT todo_id FROM todo_tags WHERE tag IN (%s) GROUP BY todo_id HAVING COUNT(*) = %s)",
+			strings.Join(placeholders, ", "),
+			arg(len(f.Tags)),
+		))
+	}
+	if f.CreatedAfter != nil {
+		where = append(where, "time_created >= "+arg(formatTime(*f.CreatedAfter)))
+	}
+	if f.CreatedBefore != nil {
+		where = append(where, "time_created < "+arg(formatTime(*f.CreatedBefore)))
+	}
+	if f.UpdatedAfter != nil {
+		where = append(where, "time_updated >= "+arg(formatTime(*f.UpdatedAfter)))
+	}
+	if f.UpdatedBefore != nil {
+		where = append(where, "time_updated < "+arg(formatTime(*f.UpdatedBefore)))
+	}
+
+	if opts.After != nil {
+		cmp := ">"
+		if opts.Direction == todo.DirectionDesc {
+			cmp = "<"
+		}
+		where = append(where, fmt.Sprintf("(time_created, id) %s (%s, %s)",
+			cmp,
+			arg(formatTime(opts.After.TimeCreated)),
+			arg(opts.After.ID),
+		))
+	}
+
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE ` + strings.Join(where, ` AND `)
+	query += ` ORDER BY ` + orderBy(opts.OrderBy, opts.Direction)
+	if opts.Limit > 0 {
+		query += ` LIMIT ` + arg(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		if opts.Limit == 0 {
+			// SQLite does not support OFFSET without LIMIT.
+			query += ` LIMIT -1`
+		}
+		query += ` OFFSET ` + arg(opts.Offset)
+	}
+
+	rows, err := d.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanTodos(rows)
+}
+
+// QueryByID retrieves a todo item from the database.
+func (d *Store) QueryByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	const query = `SELECT ` + todoColumns + ` FROM todos WHERE id = ?1 AND workspace_id = ?2 AND deleted_at IS NULL LIMIT 1`
+
+	var t todo.Todo
+
+	if err := scanTodo(d.conn().QueryRowContext(ctx, query, id.String(), todo.WorkspaceFrom(ctx)), &t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Todo{}, todo.ErrNotFound
+		}
+		return todo.Todo{}, fmt.Errorf("db: %w", err)
+	}
+
+	return t, nil
+}
+
+// QueryTrashByID retrieves a todo item in the trash from the database.
+func (d *Store) QueryTrashByID(ctx context.Context, id uuid.UUID) (todo.Todo, error) {
+	const query = `SELECT ` + todoColumns + ` FROM todos WHERE id = ?1 AND workspace_id = ?2 AND deleted_at IS NOT NULL LIMIT 1`
+
+	var t todo.Todo
+
+	if err := scanTodo(d.conn().QueryRowContext(ctx, query, id.String(), todo.WorkspaceFrom(ctx)), &t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Todo{}, todo.ErrNotFound
+		}
+		return todo.Todo{}, fmt.Errorf("db: %w", err)
+	}
+
+	return t, nil
+}
+
+// QuerySubtree retrieves every descendant of the todo item given by id from
+// the database, ordered by depth.
+func (d *Store) QuerySubtree(ctx context.Context, id uuid.UUID) ([]todo.Todo, error) {
+	const query = `
+	WITH RECURSIVE subtree (id, depth) AS (
+	  SELECT id, 1 FROM todos WHERE parent_id = ?1 AND workspace_id = ?2 AND deleted_at IS NULL
+	  UNION
+	  SELECT todos.id, subtree.depth + 1 FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	  WHERE todos.deleted_at IS NULL
+	)
+	SELECT ` + todoColumns + `
+	FROM
+	  todos JOIN subtree USING (id)
+	ORDER BY
+	  subtree.depth, time_created, id`
+
+	rows, err := d.conn().QueryContext(ctx, query, id, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanTodos(rows)
+}
+
+// QueryOverdue retrieves all incomplete todo items owned by ownerID from the
+// database that were due before now.
+func (d *Store) QueryOverdue(ctx context.Context, ownerID uuid.UUID, now time.Time) ([]todo.Todo, error) {
+	const query = `
+	SELECT ` + todoColumns + `
+	FROM
+	  todos
+	WHERE
+	  workspace_id = ?3 AND owner_id = ?1 AND completed = false AND due_at < ?2 AND deleted_at IS NULL
+	ORDER BY
+	  due_at`
+
+	rows, err := d.conn().QueryContext(ctx, query, ownerID, formatTime(now), todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanTodos(rows)
+}
+
+// QueryTags retrieves every tag in use by the todo items owned by ownerID
+// from the database along with the number of todo items using it.
+func (d *Store) QueryTags(ctx context.Context, ownerID uuid.UUID) ([]todo.TagCount, error) {
+	const query = `
+	SELECT
+	  tag, COUNT(*)
+	FROM
+	  todo_tags JOIN todos ON todos.id = todo_tags.todo_id
+	WHERE
+	  todos.workspace_id = ?2 AND todos.owner_id = ?1 AND todos.deleted_at IS NULL
+	GROUP BY
+	  tag
+	ORDER BY
+	  tag`
+
+	rows, err := d.conn().QueryContext(ctx, query, ownerID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	tags := make([]todo.TagCount, 0)
+
+	for rows.Next() {
+		var tc todo.TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// QueryEvents retrieves the history of a todo item from the database.
+func (d *Store) QueryEvents(ctx context.Context, todoID uuid.UUID) ([]todo.Event, error) {
+	const query = `
+	SELECT
+	  id, todo_id, type, actor, changes, time_created
+	FROM
+	  todo_events
+	WHERE
+	  todo_id = ?1 AND todo_id IN (SELECT id FROM todos WHERE workspace_id = ?2)
+	ORDER BY
+	  seq`
+
+	rows, err := d.conn().QueryContext(ctx, query, todoID, todo.WorkspaceFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	events := make([]todo.Event, 0)
+
+	for rows.Next() {
+		var (
+			ev      todo.Event
+			changes string
+		)
+
+		if err := rows.Scan(
+			&ev.ID,
+			&ev.TodoID,
+			&ev.Type,
+			&ev.Actor,
+			&changes,
+			timeScanner{&ev.TimeCreated},
+		); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(changes), &ev.Changes); err != nil {
+			return nil, fmt.Errorf("decode changes: %w", err)
+		}
+
+		events = append(events, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Create adds a todo item to the database.
+func (d *Store) Create(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	const query = `
+	INSERT INTO todos
+	  (id, workspace_id, owner_id, text, priority, completed, list_id, parent_id, due_at, recurrence, next_id, version, time_created, time_updated, deleted_at)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15)`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query,
+			td.ID,
+			todo.WorkspaceFrom(ctx),
+			td.OwnerID,
+			td.Text,
+			td.Priority,
+			td.Completed,
+			td.ListID,
+			td.ParentID,
+			formatNullTime(td.DueAt),
+			td.Recurrence,
+			td.NextID,
+			td.Version,
+			formatTime(td.TimeCreated),
+			formatTime(td.TimeUpdated),
+			formatNullTime(td.TimeDeleted),
+		); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if err := setTags(ctx, tx, td); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// Update modifies an existing todo item in the database.
+func (d *Store) Update(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	const query = `
+	UPDATE
+	  todos
+	SET
+		text = ?1,
+		priority = ?2,
+		completed = ?3,
+		list_id = ?4,
+		parent_id = ?5,
+		due_at = ?6,
+		recurrence = ?7,
+		next_id = ?8,
+		version = ?9,
+		time_updated = ?10
+	WHERE
+	  id = ?11 AND workspace_id = ?12 AND version = ?9 - 1`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		if td.ParentID != nil {
+			if err := checkCycle(ctx, tx, td); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, query,
+			td.Text,
+			td.Priority,
+			td.Completed,
+			td.ListID,
+			td.ParentID,
+			formatNullTime(td.DueAt),
+			td.Recurrence,
+			td.NextID,
+			td.Version,
+			formatTime(td.TimeUpdated),
+			td.ID,
+			todo.WorkspaceFrom(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if n == 0 {
+			// The connection is held by tx, so the existence check has to
+			// run inside of it.
+			const existsQuery = `SELECT EXISTS (SELECT 1 FROM todos WHERE id = ?1 AND workspace_id = ?2 AND deleted_at IS NULL)`
+
+			var exists bool
+			if err := tx.QueryRowContext(ctx, existsQuery, td.ID, todo.WorkspaceFrom(ctx)).Scan(&exists); err != nil {
+				return fmt.Errorf("db: %w", err)
+			}
+
+			if !exists {
+				return todo.ErrNotFound
+			}
+			return todo.ErrConflict
+		}
+
+		if err := setTags(ctx, tx, td); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// Delete moves a todo item and its descendants to the trash in the database.
+func (d *Store) Delete(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	const query = `
+	WITH RECURSIVE subtree (id) AS (
+	  SELECT id FROM todos WHERE id = ?1 AND workspace_id = ?3 AND deleted_at IS NULL
+	  UNION
+	  SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	)
+	UPDATE
+	  todos
+	SET
+	  deleted_at = ?2
+	WHERE
+	  id IN (SELECT id FROM subtree) AND deleted_at IS NULL`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query,
+			td.ID,
+			formatNullTime(td.TimeDeleted),
+			todo.WorkspaceFrom(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if n == 0 {
+			return nil
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// Restore takes a todo item and the descendants that were moved to the trash
+// along with it out of the trash in the database.
+func (d *Store) Restore(ctx context.Context, td todo.Todo, ev todo.Event) error {
+	const query = `
+	WITH RECURSIVE subtree (id) AS (
+	  SELECT id FROM todos WHERE id = ?1 AND workspace_id = ?3 AND deleted_at = ?2
+	  UNION
+	  SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	  WHERE todos.deleted_at = ?2
+	)
+	UPDATE
+	  todos
+	SET
+	  deleted_at = NULL
+	WHERE
+	  id IN (SELECT id FROM subtree)`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query,
+			td.ID,
+			formatNullTime(td.TimeDeleted),
+			todo.WorkspaceFrom(ctx),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if n == 0 {
+			return todo.ErrNotFound
+		}
+
+		return insertEvent(ctx, tx, ev)
+	})
+}
+
+// Purge permanently deletes a todo item and, through the foreign keys on
+// parent_id and todo_events, its descendants and their history from the
+// database.
+func (d *Store) Purge(ctx context.Context, td todo.Todo) error {
+	const query = `
+	DELETE FROM
+	  todos
+	WHERE
+	  id = ?1 AND workspace_id = ?2`
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		td.ID,
+		todo.WorkspaceFrom(ctx),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeTrash permanently deletes the todo items of ownerID, or of every owner
+// when it is nil, that were moved to the trash before the given time from the
+// database.
+func (d *Store) PurgeTrash(ctx context.Context, ownerID *uuid.UUID, before time.Time) (int, error) {
+	// Rows removed by the foreign key on parent_id are not counted as
+	// affected, so the todo items are counted before they are deleted.
+	const countQuery = `
+	SELECT COUNT(*) FROM todos
+	WHERE workspace_id = ?3 AND deleted_at < ?1 AND (?2 IS NULL OR owner_id = ?2)`
+
+	const deleteQuery = `
+	DELETE FROM
+	  todos
+	WHERE
+	  workspace_id = ?3 AND deleted_at < ?1 AND (?2 IS NULL OR owner_id = ?2)`
+
+	var n int
+
+	err := d.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, countQuery, formatTime(before), ownerID, todo.WorkspaceFrom(ctx)).Scan(&n); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteQuery, formatTime(before), ownerID, todo.WorkspaceFrom(ctx)); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// WithTx calls fn with a store that runs every query in a database
+// transaction. SQLite has no row locks, so the database opened by
+// database.OpenSQLite takes the write lock as the transaction begins instead.
+func (d *Store) WithTx(ctx context.Context, fn func(todo.Storer) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		return fn(&Store{db: d.db, tx: tx})
+	})
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction the store is bound to, or the database when
+// there is none.
+func (d *Store) conn() querier {
+	if d.tx != nil {
+		return d.tx
+	}
+
+	return d.db
+}
+
+// inTx runs fn in a database transaction that is committed when fn returns nil
+// and rolled back otherwise. Inside a transaction started by WithTx, fn runs in
+// that transaction instead.
+func (d *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if d.tx != nil {
+		return fn(d.tx)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// checkCycle returns todo.ErrCycle if the new parent of td is td itself or one
+// of its descendants.
+func checkCycle(ctx context.Context, tx *sql.Tx, td todo.Todo) error {
+	const query = `
+	WITH RECURSIVE subtree (id) AS (
+	  SELECT id FROM todos WHERE id = ?1
+	  UNION
+	  SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+	)
+	SELECT EXISTS (SELECT 1 FROM subtree WHERE id = ?2)`
+
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, query, td.ID, *td.ParentID).Scan(&cycle); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if cycle {
+		return todo.ErrCycle
+	}
+
+	return nil
+}
+
+// setTags replaces the tags of td in the database with td.Tags.
+func setTags(ctx context.Context, tx *sql.Tx, td todo.Todo) error {
+	const deleteQuery = `DELETE FROM todo_tags WHERE todo_id = ?1`
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, td.ID); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	const insertQuery = `
+	INSERT INTO todo_tags
+	  (todo_id, tag)
+	VALUES
+	  (?1, ?2)`
+
+	for _, tag := range td.Tags {
+		if _, err := tx.ExecContext(ctx, insertQuery, td.ID, tag); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertEvent records ev in the history of its todo item.
+func insertEvent(ctx context.Context, tx *sql.Tx, ev todo.Event) error {
+	const query = `
+	INSERT INTO todo_events
+	  (id, todo_id, type, actor, changes, time_created)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6)`
+
+	changes, err := json.Marshal(ev.Changes)
+	if err != nil {
+		return fmt.Errorf("encode changes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, query,
+		ev.ID,
+		ev.TodoID,
+		ev.Type,
+		ev.Actor,
+		string(changes),
+		formatTime(ev.TimeCreated),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// todoColumns lists the columns of the todos table in the order expected by
+// scanTodo. Tags are aggregated from the todo_tags table as a JSON array.
+const todoColumns = `
+	  id, workspace_id, owner_id, text, priority, completed, list_id, parent_id, due_at, recurrence, next_id,
+	  (SELECT json_group_array(tag) FROM (SELECT tag FROM todo_tags WHERE todo_id = todos.id ORDER BY tag)),
+	  version, time_created, time_updated, deleted_at`
+
+// orderColumns maps the fields todo items can be sorted by to SQL expressions.
+var orderColumns = map[todo.OrderField]string{
+	todo.OrderByTimeCreated: "time_created",
+	todo.OrderByTimeUpdated: "time_updated",
+	todo.OrderByDueAt:       "due_at",
+	todo.OrderByText:        "text",
+	todo.OrderByPriority:    "CASE priority WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 ELSE 0 END",
+}
+
+// orderBy builds an ORDER BY clause for field and direction. Ties are broken
+// by creation time and then ID so that the order is always deterministic.
+func orderBy(field todo.OrderField, direction todo.Direction) string {
+	dir := "ASC"
+	if direction == todo.DirectionDesc {
+		dir = "DESC"
+	}
+
+	clause := orderColumns[field] + " " + dir
+	if field == todo.OrderByDueAt {
+		clause += " NULLS LAST"
+	}
+	if field != todo.OrderByTimeCreated {
+		clause += ", time_created " + dir
+	}
+
+	return clause + ", id " + dir
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTodo scans a row selected with todoColumns into td. Any columns selected
+// after todoColumns are scanned into extra.
+func scanTodo(row scanner, td *todo.Todo, extra ...any) error {
+	var tags string
+
+	dest := []any{
+		&td.ID,
+		&td.WorkspaceID,
+		&td.OwnerID,
+		&td.Text,
+		&td.Priority,
+		&td.Completed,
+		&td.ListID,
+		&td.ParentID,
+		nullTimeScanner{&td.DueAt},
+		&td.Recurrence,
+		&td.NextID,
+		&tags,
+		&td.Version,
+		timeScanner{&td.TimeCreated},
+		timeScanner{&td.TimeUpdated},
+		nullTimeScanner{&td.TimeDeleted},
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(tags), &td.Tags)
+}
+
+// scanTodos scans all rows selected with todoColumns.
+func scanTodos(rows *sql.Rows) ([]todo.Todo, error) {
+	todos := make([]todo.Todo, 0)
+
+	for rows.Next() {
+		var td todo.Todo
+		if err := scanTodo(rows, &td); err != nil {
+			return nil, err
+		}
+
+		todos = append(todos, td)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
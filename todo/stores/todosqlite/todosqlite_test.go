@@ -0,0 +1,191 @@
+package todosqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/database"
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/storertest"
+	"github.com/sudomateo/todo/todo/stores/todosqlite"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+
+	// The databases are closed once every test is done, since the stores
+	// do not own them.
+	dbs := make([]*sql.DB, 0)
+	defer func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}()
+
+	storertest.Run(t, func() todo.Storer {
+		db, err := database.OpenSQLite(filepath.Join(dir, fmt.Sprintf("todo-%d.db", len(dbs))))
+		if err != nil {
+			panic(err)
+		}
+		dbs = append(dbs, db)
+
+		if err := database.MigrateSQLite(context.Background(), db); err != nil {
+			panic(err)
+		}
+
+		return todosqlite.NewStore(db)
+	})
+}
+
+func TestStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+
+	todoCore := todo.NewCore(todosqlite.NewStore(open(t, path)))
+
+	want, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "foo",
+		Priority: todo.PriorityLow,
+		Tags:     []string{"ops"},
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	// Migrating a database that is up to date does nothing.
+	todoCore = todo.NewCore(todosqlite.NewStore(open(t, path)))
+
+	got, err := todoCore.QueryByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+
+	if diff := cmp.Diff(want, got, equalTimes); diff != "" {
+		t.Fatalf("query by id: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStoreForeignKeys(t *testing.T) {
+	s := todosqlite.NewStore(open(t, filepath.Join(t.TempDir(), "todo.db")))
+
+	now := time.Now()
+	td := todo.Todo{
+		ID:          uuid.New(),
+		Text:        "foo",
+		Priority:    todo.PriorityLow,
+		ListID:      uuid.New(),
+		Version:     1,
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	ev := todo.Event{ID: uuid.New(), TodoID: td.ID, Type: todo.EventCreated, Changes: []todo.Change{}, TimeCreated: now}
+
+	err := s.Create(context.Background(), td, ev)
+	if err == nil || !strings.Contains(err.Error(), "FOREIGN KEY") {
+		t.Fatalf("create: expected a foreign key error for a list that does not exist, got %v", err)
+	}
+}
+
+func TestStoreTimes(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "todo.db"))
+	s := todosqlite.NewStore(db)
+
+	// Times in other zones are stored in UTC with every digit of their
+	// nanoseconds so that they sort chronologically as text.
+	east := time.FixedZone("UTC+2", 2*60*60)
+	times := []time.Time{
+		time.Date(2026, time.January, 2, 10, 0, 0, 5, east),
+		time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, time.January, 2, 8, 59, 59, 999999999, time.UTC),
+	}
+
+	for i, at := range times {
+		td := todo.Todo{
+			ID:          uuid.New(),
+			Text:        at.String(),
+			Priority:    todo.PriorityLow,
+			DueAt:       &times[i],
+			Version:     1,
+			TimeCreated: at,
+			TimeUpdated: at,
+		}
+
+		ev := todo.Event{ID: uuid.New(), TodoID: td.ID, Type: todo.EventCreated, Changes: []todo.Change{}, TimeCreated: at}
+
+		if err := s.Create(context.Background(), td, ev); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+	}
+
+	rows, err := db.QueryContext(context.Background(), `SELECT time_created FROM todos ORDER BY time_created`)
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	defer rows.Close()
+
+	got := make([]string, 0)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("scan: expected nil error, got %v", err)
+		}
+		got = append(got, s)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+
+	want := []string{
+		"2026-01-02T08:00:00.000000005Z",
+		"2026-01-02T08:59:59.999999999Z",
+		"2026-01-02T09:00:00.000000000Z",
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("time_created: mismatch (-want +got):\n%s", diff)
+	}
+
+	todos, err := s.Query(context.Background(), todo.QueryOptions{OrderBy: todo.OrderByDueAt, Direction: todo.DirectionAsc})
+	if err != nil || len(todos) != len(times) {
+		t.Fatalf("query: expected %d todos, got %v, %v", len(times), todos, err)
+	}
+
+	for i, want := range []time.Time{times[0], times[2], times[1]} {
+		td := todos[i]
+		if !td.TimeCreated.Equal(want) || td.DueAt == nil || !td.DueAt.Equal(want) {
+			t.Errorf("query: expected todo %d at %v, got %v and %v", i, want, td.TimeCreated, td.DueAt)
+		}
+	}
+}
+
+// open opens and migrates the SQLite database at path, closing it when the
+// test is done.
+func open(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := database.OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("open: expected nil error, got %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.MigrateSQLite(context.Background(), db); err != nil {
+		t.Fatalf("migrate: expected nil error, got %v", err)
+	}
+
+	return db
+}
+
+// equalTimes compares times by instant since they lose their location and
+// monotonic clock reading when they are stored.
+var equalTimes = cmp.Comparer(func(a, b time.Time) bool {
+	return a.Equal(b)
+})
@@ -0,0 +1,437 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// webhookColumns lists the columns of the webhooks table in the order
+// expected by scanWebhook.
+const webhookColumns = `id, owner_id, url, secret, events, active, time_created, time_updated`
+
+// deliveryColumns lists the columns of the webhook_deliveries table in the
+// order expected by scanDelivery. Attempts are aggregated from the
+// webhook_attempts table as a JSON array.
+const deliveryColumns = `
+	  id, webhook_id, event_id, event_type, payload, status, tries, next_attempt_at,
+	  (SELECT json_group_array(json_object('number', number, 'status_code', status_code, 'error', error, 'time_created', time_created))
+	   FROM (SELECT * FROM webhook_attempts WHERE delivery_id = webhook_deliveries.id ORDER BY number)),
+	  time_created, time_updated`
+
+// QueryWebhooks retrieves the webhooks matching filter from the database in
+// the order they were created.
+func (d *Store) QueryWebhooks(ctx context.Context, filter todo.WebhookFilter) ([]todo.Webhook, error) {
+	const query = `
+	SELECT ` + webhookColumns + ` FROM webhooks
+	WHERE
+	  (?1 IS NULL OR owner_id = ?1) AND
+	  (?2 IS NULL OR (active AND (json_array_length(events) = 0 OR EXISTS (SELECT 1 FROM json_each(events) WHERE value = ?2))))
+	ORDER BY time_created, id`
+
+	var subscribed *string
+	if filter.Subscribed != nil {
+		s := string(*filter.Subscribed)
+		subscribed = &s
+	}
+
+	rows, err := d.conn().QueryContext(ctx, query, filter.OwnerID, subscribed)
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	webhooks := make([]todo.Webhook, 0)
+
+	for rows.Next() {
+		var wh todo.Webhook
+		if err := scanWebhook(rows, &wh); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		webhooks = append(webhooks, wh)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// QueryWebhookByID retrieves a webhook from the database.
+func (d *Store) QueryWebhookByID(ctx context.Context, id uuid.UUID) (todo.Webhook, error) {
+	const query = `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ?1`
+
+	var wh todo.Webhook
+
+	if err := scanWebhook(d.conn().QueryRowContext(ctx, query, id.String()), &wh); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Webhook{}, todo.ErrWebhookNotFound
+		}
+		return todo.Webhook{}, fmt.Errorf("db: %w", err)
+	}
+
+	return wh, nil
+}
+
+// CreateWebhook adds a webhook to the database.
+func (d *Store) CreateWebhook(ctx context.Context, wh todo.Webhook) error {
+	const query = `
+	INSERT INTO webhooks
+	  (id, owner_id, url, secret, events, active, time_created, time_updated)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`
+
+	events, err := json.Marshal(eventTypes(wh.Events))
+	if err != nil {
+		return fmt.Errorf("encode events: %w", err)
+	}
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		wh.ID.String(),
+		wh.OwnerID.String(),
+		wh.URL,
+		wh.Secret,
+		string(events),
+		wh.Active,
+		formatTime(wh.TimeCreated),
+		formatTime(wh.TimeUpdated),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWebhook modifies an existing webhook in the database.
+func (d *Store) UpdateWebhook(ctx context.Context, wh todo.Webhook) error {
+	const query = `
+	UPDATE
+	  webhooks
+	SET
+	  url = ?1,
+	  secret = ?2,
+	  events = ?3,
+	  active = ?4,
+	  time_updated = ?5
+	WHERE
+	  id = ?6`
+
+	events, err := json.Marshal(eventTypes(wh.Events))
+	if err != nil {
+		return fmt.Errorf("encode events: %w", err)
+	}
+
+	res, err := d.conn().ExecContext(ctx, query,
+		wh.URL,
+		wh.Secret,
+		string(events),
+		wh.Active,
+		formatTime(wh.TimeUpdated),
+		wh.ID.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return webhookAffected(res)
+}
+
+// DeleteWebhook deletes a webhook from the database. Its deliveries are
+// deleted along with it.
+func (d *Store) DeleteWebhook(ctx context.Context, wh todo.Webhook) error {
+	const query = `DELETE FROM webhooks WHERE id = ?1`
+
+	res, err := d.conn().ExecContext(ctx, query, wh.ID.String())
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return webhookAffected(res)
+}
+
+// QueryDeliveries retrieves the deliveries of a webhook from the database,
+// newest first.
+func (d *Store) QueryDeliveries(ctx context.Context, webhookID uuid.UUID) ([]todo.Delivery, error) {
+	const query = `
+	SELECT ` + deliveryColumns + `
+	FROM
+	  webhook_deliveries
+	WHERE
+	  webhook_id = ?1
+	ORDER BY
+	  time_created DESC, id DESC`
+
+	rows, err := d.conn().QueryContext(ctx, query, webhookID.String())
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+// QueryDeliveryByID retrieves a delivery from the database.
+func (d *Store) QueryDeliveryByID(ctx context.Context, id uuid.UUID) (todo.Delivery, error) {
+	const query = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = ?1`
+
+	var dl todo.Delivery
+
+	if err := scanDelivery(d.conn().QueryRowContext(ctx, query, id.String()), &dl); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Delivery{}, todo.ErrDeliveryNotFound
+		}
+		return todo.Delivery{}, fmt.Errorf("db: %w", err)
+	}
+
+	return dl, nil
+}
+
+// CreateDelivery adds a delivery to the database.
+func (d *Store) CreateDelivery(ctx context.Context, dl todo.Delivery) error {
+	const query = `
+	INSERT INTO webhook_deliveries
+	  (id, webhook_id, event_id, event_type, payload, status, tries, next_attempt_at, time_created, time_updated)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query,
+			dl.ID.String(),
+			dl.WebhookID.String(),
+			dl.EventID.String(),
+			dl.EventType,
+			string(dl.Payload),
+			dl.Status,
+			dl.Tries,
+			formatNullTime(dl.NextAttemptAt),
+			formatTime(dl.TimeCreated),
+			formatTime(dl.TimeUpdated),
+		); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		return insertAttempts(ctx, tx, dl)
+	})
+}
+
+// ClaimDeliveries retrieves the pending deliveries that are due from the
+// database and moves their next attempt to until.
+func (d *Store) ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]todo.Delivery, error) {
+	const (
+		selectQuery = `
+		SELECT ` + deliveryColumns + `
+		FROM
+		  webhook_deliveries
+		WHERE
+		  status = ?1 AND next_attempt_at <= ?2
+		ORDER BY
+		  next_attempt_at, time_created
+		LIMIT ?3`
+
+		updateQuery = `UPDATE webhook_deliveries SET next_attempt_at = ?1 WHERE id = ?2`
+	)
+
+	var deliveries []todo.Delivery
+
+	// Transactions take the write lock up front, so no one else can claim
+	// the same deliveries in between.
+	err := d.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, selectQuery, todo.DeliveryPending, formatTime(now), limit)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		deliveries, err = scanDeliveries(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for i := range deliveries {
+			if _, err := tx.ExecContext(ctx, updateQuery, formatTime(until), deliveries[i].ID.String()); err != nil {
+				return fmt.Errorf("db: %w", err)
+			}
+
+			deliveries[i].NextAttemptAt = &until
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDelivery modifies an existing delivery in the database, adding the
+// attempts it does not have yet.
+func (d *Store) UpdateDelivery(ctx context.Context, dl todo.Delivery) error {
+	const query = `
+	UPDATE
+	  webhook_deliveries
+	SET
+	  status = ?1,
+	  tries = ?2,
+	  next_attempt_at = ?3,
+	  time_updated = ?4
+	WHERE
+	  id = ?5`
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query,
+			dl.Status,
+			dl.Tries,
+			formatNullTime(dl.NextAttemptAt),
+			formatTime(dl.TimeUpdated),
+			dl.ID.String(),
+		)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		if err := deliveryAffected(res); err != nil {
+			return err
+		}
+
+		return insertAttempts(ctx, tx, dl)
+	})
+}
+
+// insertAttempts adds the attempts of dl that are not in the database yet.
+func insertAttempts(ctx context.Context, tx *sql.Tx, dl todo.Delivery) error {
+	const query = `
+	INSERT INTO webhook_attempts
+	  (delivery_id, number, status_code, error, time_created)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5)
+	ON CONFLICT DO NOTHING`
+
+	for _, a := range dl.Attempts {
+		if _, err := tx.ExecContext(ctx, query,
+			dl.ID.String(),
+			a.Number,
+			a.StatusCode,
+			a.Error,
+			formatTime(a.TimeCreated),
+		); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// webhookAffected returns todo.ErrWebhookNotFound if res did not affect any
+// rows.
+func webhookAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// deliveryAffected returns todo.ErrDeliveryNotFound if res did not affect any
+// rows.
+func deliveryAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+// eventTypes returns events, or an empty slice when events is nil.
+func eventTypes(events []todo.EventType) []todo.EventType {
+	if events == nil {
+		return []todo.EventType{}
+	}
+	return events
+}
+
+// scanWebhook scans a row selected with webhookColumns into wh.
+func scanWebhook(row scanner, wh *todo.Webhook) error {
+	var events string
+
+	if err := row.Scan(
+		&wh.ID,
+		&wh.OwnerID,
+		&wh.URL,
+		&wh.Secret,
+		&events,
+		&wh.Active,
+		timeScanner{&wh.TimeCreated},
+		timeScanner{&wh.TimeUpdated},
+	); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(events), &wh.Events)
+}
+
+// scanDelivery scans a row selected with deliveryColumns into dl.
+func scanDelivery(row scanner, dl *todo.Delivery) error {
+	var payload, attempts string
+
+	if err := row.Scan(
+		&dl.ID,
+		&dl.WebhookID,
+		&dl.EventID,
+		&dl.EventType,
+		&payload,
+		&dl.Status,
+		&dl.Tries,
+		nullTimeScanner{&dl.NextAttemptAt},
+		&attempts,
+		timeScanner{&dl.TimeCreated},
+		timeScanner{&dl.TimeUpdated},
+	); err != nil {
+		return err
+	}
+
+	dl.Payload = json.RawMessage(payload)
+
+	return json.Unmarshal([]byte(attempts), &dl.Attempts)
+}
+
+// scanDeliveries scans all rows selected with deliveryColumns.
+func scanDeliveries(rows *sql.Rows) ([]todo.Delivery, error) {
+	deliveries := make([]todo.Delivery, 0)
+
+	for rows.Next() {
+		var dl todo.Delivery
+		if err := scanDelivery(rows, &dl); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		deliveries = append(deliveries, dl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,311 @@
+package todosqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// workspaceColumns lists the columns of the workspaces table in the order
+// expected by scanWorkspace.
+const workspaceColumns = `id, name, allowed_priorities, default_list_id, time_created, time_updated`
+
+// QueryWorkspaces retrieves the workspaces userID is a member of, or every
+// workspace when it is nil, from the database ordered by name.
+func (d *Store) QueryWorkspaces(ctx context.Context, userID *uuid.UUID) ([]todo.Workspace, error) {
+	const query = `
+	SELECT ` + workspaceColumns + `
+	FROM
+	  workspaces
+	WHERE
+	  ?1 IS NULL OR id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ?1)
+	ORDER BY
+	  name, time_created, id`
+
+	rows, err := d.conn().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	workspaces := make([]todo.Workspace, 0)
+
+	for rows.Next() {
+		var ws todo.Workspace
+		if err := scanWorkspace(rows, &ws); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		workspaces = append(workspaces, ws)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+// QueryWorkspaceByID retrieves a workspace from the database.
+func (d *Store) QueryWorkspaceByID(ctx context.Context, id uuid.UUID) (todo.Workspace, error) {
+	const query = `SELECT ` + workspaceColumns + ` FROM workspaces WHERE id = ?1`
+
+	var ws todo.Workspace
+
+	if err := scanWorkspace(d.conn().QueryRowContext(ctx, query, id), &ws); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Workspace{}, todo.ErrWorkspaceNotFound
+		}
+		return todo.Workspace{}, fmt.Errorf("db: %w", err)
+	}
+
+	return ws, nil
+}
+
+// CreateWorkspace adds a workspace to the database.
+func (d *Store) CreateWorkspace(ctx context.Context, ws todo.Workspace) error {
+	const query = `
+	INSERT INTO workspaces
+	  (id, name, allowed_priorities, default_list_id, time_created, time_updated)
+	VALUES
+	  (?1, ?2, ?3, ?4, ?5, ?6)`
+
+	priorities, err := json.Marshal(allowedPriorities(ws.Settings.AllowedPriorities))
+	if err != nil {
+		return fmt.Errorf("encode allowed priorities: %w", err)
+	}
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		ws.ID,
+		ws.Name,
+		string(priorities),
+		ws.Settings.DefaultListID,
+		formatTime(ws.TimeCreated),
+		formatTime(ws.TimeUpdated),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWorkspace modifies an existing workspace in the database.
+func (d *Store) UpdateWorkspace(ctx context.Context, ws todo.Workspace) error {
+	const query = `
+	UPDATE
+	  workspaces
+	SET
+	  name = ?1,
+	  allowed_priorities = ?2,
+	  default_list_id = ?3,
+	  time_updated = ?4
+	WHERE
+	  id = ?5`
+
+	priorities, err := json.Marshal(allowedPriorities(ws.Settings.AllowedPriorities))
+	if err != nil {
+		return fmt.Errorf("encode allowed priorities: %w", err)
+	}
+
+	res, err := d.conn().ExecContext(ctx, query,
+		ws.Name,
+		string(priorities),
+		ws.Settings.DefaultListID,
+		formatTime(ws.TimeUpdated),
+		ws.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return workspaceAffected(res)
+}
+
+// DeleteWorkspace deletes a workspace along with its todo items, lists and
+// members from the database. Members are deleted through their foreign key,
+// but the todo items and lists have to be deleted here.
+func (d *Store) DeleteWorkspace(ctx context.Context, ws todo.Workspace) error {
+	const (
+		todosQuery     = `DELETE FROM todos WHERE workspace_id = ?1`
+		listsQuery     = `DELETE FROM lists WHERE workspace_id = ?1`
+		workspaceQuery = `DELETE FROM workspaces WHERE id = ?1`
+	)
+
+	return d.inTx(ctx, func(tx *sql.Tx) error {
+		for _, query := range []string{todosQuery, listsQuery} {
+			if _, err := tx.ExecContext(ctx, query, ws.ID); err != nil {
+				return fmt.Errorf("db: %w", err)
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, workspaceQuery, ws.ID)
+		if err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+
+		return workspaceAffected(res)
+	})
+}
+
+// QueryMembers retrieves the members of a workspace from the database in the
+// order they joined.
+func (d *Store) QueryMembers(ctx context.Context, workspaceID uuid.UUID) ([]todo.Member, error) {
+	const query = `
+	SELECT
+	  workspace_id, user_id, role, time_created
+	FROM
+	  workspace_members
+	WHERE
+	  workspace_id = ?1
+	ORDER BY
+	  time_created, user_id`
+
+	rows, err := d.conn().QueryContext(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	defer rows.Close()
+
+	members := make([]todo.Member, 0)
+
+	for rows.Next() {
+		var m todo.Member
+		if err := rows.Scan(&m.WorkspaceID, &m.UserID, &m.Role, timeScanner{&m.TimeCreated}); err != nil {
+			return nil, fmt.Errorf("db: %w", err)
+		}
+
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db: %w", err)
+	}
+
+	return members, nil
+}
+
+// QueryMember retrieves the membership of a user in a workspace from the
+// database.
+func (d *Store) QueryMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (todo.Member, error) {
+	const query = `
+	SELECT
+	  workspace_id, user_id, role, time_created
+	FROM
+	  workspace_members
+	WHERE
+	  workspace_id = ?1 AND user_id = ?2`
+
+	var m todo.Member
+
+	if err := d.conn().QueryRowContext(ctx, query, workspaceID, userID).Scan(
+		&m.WorkspaceID,
+		&m.UserID,
+		&m.Role,
+		timeScanner{&m.TimeCreated},
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return todo.Member{}, todo.ErrMemberNotFound
+		}
+		return todo.Member{}, fmt.Errorf("db: %w", err)
+	}
+
+	return m, nil
+}
+
+// PutMember adds a member to the database, replacing the role of an existing
+// member of the workspace with the same user.
+func (d *Store) PutMember(ctx context.Context, member todo.Member) error {
+	const query = `
+	INSERT INTO workspace_members
+	  (workspace_id, user_id, role, time_created)
+	VALUES
+	  (?1, ?2, ?3, ?4)
+	ON CONFLICT (workspace_id, user_id) DO UPDATE SET
+	  role = excluded.role`
+
+	if _, err := d.conn().ExecContext(ctx, query,
+		member.WorkspaceID,
+		member.UserID,
+		member.Role,
+		formatTime(member.TimeCreated),
+	); err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMember deletes a member from the database.
+func (d *Store) DeleteMember(ctx context.Context, member todo.Member) error {
+	const query = `DELETE FROM workspace_members WHERE workspace_id = ?1 AND user_id = ?2`
+
+	res, err := d.conn().ExecContext(ctx, query, member.WorkspaceID, member.UserID)
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// scanWorkspace scans a row selected with workspaceColumns into ws.
+func scanWorkspace(row scanner, ws *todo.Workspace) error {
+	var priorities string
+
+	if err := row.Scan(
+		&ws.ID,
+		&ws.Name,
+		&priorities,
+		&ws.Settings.DefaultListID,
+		timeScanner{&ws.TimeCreated},
+		timeScanner{&ws.TimeUpdated},
+	); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(priorities), &ws.Settings.AllowedPriorities); err != nil {
+		return fmt.Errorf("decode allowed priorities: %w", err)
+	}
+
+	return nil
+}
+
+// allowedPriorities returns priorities, or an empty slice when priorities is
+// nil.
+func allowedPriorities(priorities []todo.Priority) []todo.Priority {
+	if priorities == nil {
+		return []todo.Priority{}
+	}
+	return priorities
+}
+
+// workspaceAffected returns todo.ErrWorkspaceNotFound if res did not affect
+// any rows.
+func workspaceAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if n == 0 {
+		return todo.ErrWorkspaceNotFound
+	}
+
+	return nil
+}
@@ -4,39 +4,159 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrNotFound = errors.New("todo not found")
+	ErrNotFound     = errors.New("todo not found")
+	ErrConflict     = errors.New("todo was modified concurrently")
+	ErrCycle        = errors.New("todo cannot be a descendant of itself")
+	ErrOpenChildren = errors.New("todo has incomplete children")
 )
 
 // Storer represents the behavior this package needs to manage todo items.
+//
+// Update must only modify the stored todo item when its version is one less
+// than the version of the given todo item, returning ErrConflict otherwise.
+// It must also return ErrCycle when the new parent of the todo item is the
+// todo item itself or one of its descendants.
+//
+// Todo items in the trash must be left out of every query except for
+// QueryTrashByID and Query with QueryFilter.Trashed set. QuerySubtree returns
+// every descendant of the todo item given by id ordered by depth.
+//
+// Delete moves the todo item and its descendants to the trash, marking them
+// with the TimeDeleted of the todo item. Deleting a todo item that is already
+// in the trash does nothing. Restore takes them back out of the trash. Purge
+// permanently deletes a todo item, its descendants and their history, and
+// PurgeTrash permanently deletes every todo item that was moved to the trash
+// before the given time, returning how many were deleted.
+//
+// Every todo item belongs to the user given by its OwnerID. Query only returns
+// the todo items of QueryFilter.OwnerID when it is set, and QueryOverdue and
+// QueryTags only consider the todo items of ownerID. PurgeTrash only purges
+// the todo items of ownerID, or those of every user when it is nil.
+//
+// Todo items and lists belong to the workspace they were created in, given by
+// WorkspaceFrom. Every operation on them, including searches and the shares
+// of todo items, must leave out those of other workspaces.
+//
+// Create, Update, Delete and Restore must record the given event in the
+// history of the todo item atomically with the change itself. QueryEvents
+// returns the history of a todo item in the order it was recorded.
+//
+// WithTx calls fn with a Storer whose operations all happen in a single
+// transaction, which is committed when fn returns nil and rolled back
+// otherwise. Todo items and lists read by ID through that Storer must not be
+// modified by anyone else until the transaction ends. Calling WithTx on the
+// Storer given to fn runs the function in the same transaction.
 type Storer interface {
-	Query(ctx context.Context) ([]Todo, error)
+	ListStorer
+	WebhookStorer
+	UserStorer
+	SessionStorer
+	IdentityStorer
+	ShareStorer
+	WorkspaceStorer
+	Query(ctx context.Context, opts QueryOptions) ([]Todo, error)
 	QueryByID(ctx context.Context, id uuid.UUID) (Todo, error)
-	Create(ctx context.Context, todo Todo) error
-	Update(ctx context.Context, todo Todo) error
-	Delete(ctx context.Context, todo Todo) error
+	QueryTrashByID(ctx context.Context, id uuid.UUID) (Todo, error)
+	QuerySubtree(ctx context.Context, id uuid.UUID) ([]Todo, error)
+	QueryOverdue(ctx context.Context, ownerID uuid.UUID, now time.Time) ([]Todo, error)
+	QueryTags(ctx context.Context, ownerID uuid.UUID) ([]TagCount, error)
+	QueryEvents(ctx context.Context, todoID uuid.UUID) ([]Event, error)
+	Create(ctx context.Context, todo Todo, ev Event) error
+	Update(ctx context.Context, todo Todo, ev Event) error
+	Delete(ctx context.Context, todo Todo, ev Event) error
+	Restore(ctx context.Context, todo Todo, ev Event) error
+	Purge(ctx context.Context, todo Todo) error
+	PurgeTrash(ctx context.Context, ownerID *uuid.UUID, before time.Time) (int, error)
+	WithTx(ctx context.Context, fn func(Storer) error) error
 }
 
 // Core exposes the APIs needed to interface with todo items.
 type Core struct {
-	storer Storer
+	storer           Storer
+	completionPolicy CompletionPolicy
+	publisher        Publisher
+	webhookMaxTries  int
+	webhookBackoff   time.Duration
+	sessionLifetime  time.Duration
+
+	// pending holds the notifications of the transaction the Core is part
+	// of until it commits.
+	pending *[]Notification
+}
+
+// Option configures a Core.
+type Option func(*Core)
+
+// WithCompletionPolicy sets what happens when a todo item with incomplete
+// children is completed. The default is CompletionBlock.
+func WithCompletionPolicy(policy CompletionPolicy) Option {
+	return func(c *Core) {
+		c.completionPolicy = policy
+	}
 }
 
 // NewCore is a constructor for a Core.
-func NewCore(storer Storer) *Core {
-	return &Core{
-		storer: storer,
+func NewCore(storer Storer, opts ...Option) *Core {
+	c := Core{
+		storer:           storer,
+		completionPolicy: CompletionBlock,
+		webhookMaxTries:  DefaultWebhookMaxTries,
+		webhookBackoff:   DefaultWebhookBackoff,
+		sessionLifetime:  DefaultSessionLifetime,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
 	}
+
+	return &c
 }
 
-// Query retrieves all todo items.
-func (s *Core) Query(ctx context.Context) ([]Todo, error) {
-	todos, err := s.storer.Query(ctx)
+// WithTx calls fn with a Core that runs every operation in a single
+// transaction. The transaction is committed when fn returns nil and rolled back
+// otherwise, in which case the error from fn is returned. Notifications are
+// published once the outermost transaction commits.
+func (s *Core) WithTx(ctx context.Context, fn func(txCore *Core) error) error {
+	var notifications []Notification
+
+	pending := s.pending
+	if pending == nil {
+		pending = &notifications
+	}
+
+	err := s.storer.WithTx(ctx, func(storer Storer) error {
+		txCore := *s
+		txCore.storer = storer
+		txCore.pending = pending
+		return fn(&txCore)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		s.publisher.Publish(n)
+	}
+
+	return nil
+}
+
+// Query retrieves the todo items of the user in ctx matching opts.
+func (s *Core) Query(ctx context.Context, opts QueryOptions) ([]Todo, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	opts.Filter.OwnerID = ownerFilter(ctx)
+
+	todos, err := s.storer.Query(ctx, opts.withDefaults())
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -44,70 +164,581 @@ func (s *Core) Query(ctx context.Context) ([]Todo, error) {
 	return todos, nil
 }
 
-// QueryByID retrieves a todo item by its ID.
+// QueryByID retrieves a todo item the user in ctx may see by its ID.
 func (s *Core) QueryByID(ctx context.Context, id uuid.UUID) (Todo, error) {
 	t, err := s.storer.QueryByID(ctx, id)
 	if err != nil {
 		return Todo{}, fmt.Errorf("query by id: %w", err)
 	}
 
+	if err := s.authorize(ctx, t, RoleViewer); err != nil {
+		return Todo{}, fmt.Errorf("query by id: %w", err)
+	}
+
 	return t, nil
 }
 
+// QuerySubtree retrieves every descendant of the todo item given by id,
+// ordered by depth. Whoever may see the todo item may see its descendants.
+func (s *Core) QuerySubtree(ctx context.Context, id uuid.UUID) ([]Todo, error) {
+	if _, err := s.QueryByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	todos, err := s.storer.QuerySubtree(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("query subtree: %w", err)
+	}
+
+	return todos, nil
+}
+
+// QueryOverdue retrieves all incomplete todo items of the user in ctx whose due
+// date has passed.
+func (s *Core) QueryOverdue(ctx context.Context) ([]Todo, error) {
+	todos, err := s.storer.QueryOverdue(ctx, OwnerFrom(ctx), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("query overdue: %w", err)
+	}
+
+	return todos, nil
+}
+
+// QueryTags retrieves every tag the user in ctx uses along with the number of
+// their todo items using it, ordered by tag.
+func (s *Core) QueryTags(ctx context.Context) ([]TagCount, error) {
+	tags, err := s.storer.QueryTags(ctx, OwnerFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("query tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// QueryEvents retrieves the history of the todo item given by id, oldest
+// first. The history of todo items in the trash is available until they are
+// purged.
+func (s *Core) QueryEvents(ctx context.Context, id uuid.UUID) ([]Event, error) {
+	td, err := s.storer.QueryByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		td, err = s.storer.QueryTrashByID(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query todo: %w", err)
+	}
+
+	if err := s.authorize(ctx, td, RoleViewer); err != nil {
+		return nil, fmt.Errorf("query todo: %w", err)
+	}
+
+	events, err := s.storer.QueryEvents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+
+	return events, nil
+}
+
 // Create adds a todo item into the store.
 func (s *Core) Create(ctx context.Context, params TodoCreateParams) (Todo, error) {
 	if err := params.Validate(); err != nil {
 		return Todo{}, fmt.Errorf("validate: %w", err)
 	}
 
+	var todo Todo
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		var err error
+		todo, err = txCore.create(ctx, params)
+		return err
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// create adds a todo item with already validated params. Todo items are
+// created in the default list of the workspace unless told otherwise.
+func (s *Core) create(ctx context.Context, params TodoCreateParams) (Todo, error) {
+	ws, err := s.workspace(ctx)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	if err := ws.Settings.checkPriority(params.Priority); err != nil {
+		return Todo{}, err
+	}
+
+	listID := ws.Settings.DefaultListID
+	if params.ListID != nil {
+		listID = *params.ListID
+	}
+
+	// Subtasks belong to the owner of their parent so that they stay with
+	// it, whoever added them.
+	ownerID := OwnerFrom(ctx)
+
+	if params.ParentID != nil {
+		parent, err := s.checkParent(ctx, *params.ParentID)
+		if err != nil {
+			return Todo{}, err
+		}
+
+		ownerID = parent.OwnerID
+
+		// Subtasks live in the list of their parent unless told otherwise.
+		if params.ListID == nil {
+			listID = parent.ListID
+		}
+	}
+
+	if err := s.checkList(ctx, listID, ownerID); err != nil {
+		return Todo{}, err
+	}
+
 	now := time.Now()
 
 	todo := Todo{
 		ID:          uuid.New(),
+		WorkspaceID: ws.ID,
+		OwnerID:     ownerID,
 		Text:        params.Text,
 		Priority:    params.Priority,
 		Completed:   false,
+		ListID:      listID,
+		ParentID:    params.ParentID,
+		DueAt:       params.DueAt,
+		Recurrence:  params.Recurrence,
+		Tags:        sortedTags(params.Tags),
+		Version:     1,
 		TimeCreated: now,
 		TimeUpdated: now,
 	}
 
-	if err := s.storer.Create(ctx, todo); err != nil {
+	ev := newEvent(ctx, EventCreated, todo, created(todo), now)
+
+	if err := s.storer.Create(ctx, todo, ev); err != nil {
 		return Todo{}, fmt.Errorf("create: %w", err)
 	}
 
+	if err := s.changed(ctx, ev, todo); err != nil {
+		return Todo{}, err
+	}
+
 	return todo, nil
 }
 
-// Update modifies an existing todo item.
+// Update modifies an existing todo item. The todo item must be the latest
+// version known to the caller, otherwise ErrConflict is returned. Subtasks
+// completed along with the todo item and the next instance of a recurring todo
+// item are stored in the same transaction as the todo item itself.
 func (s *Core) Update(ctx context.Context, todo Todo, params TodoUpdateParams) (Todo, error) {
 	if err := params.Validate(); err != nil {
 		return Todo{}, fmt.Errorf("validate: %w", err)
 	}
 
+	// Moving a todo item to another parent can take it away from the
+	// collaborators of its current one.
+	need := RoleEditor
+	if params.ParentID != nil || params.ClearParent {
+		need = RoleOwner
+	}
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		current, err := txCore.current(ctx, todo, false, need)
+		if err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+
+		if current.Version != todo.Version {
+			return fmt.Errorf("update: %w", ErrConflict)
+		}
+
+		todo, err = txCore.update(ctx, current, params)
+		return err
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// update modifies an existing todo item with already validated params.
+func (s *Core) update(ctx context.Context, todo Todo, params TodoUpdateParams) (Todo, error) {
+	now := time.Now()
+	before := todo
+
 	if params.Text != nil {
 		todo.Text = *params.Text
 	}
-	if params.Priority != nil {
+	if params.Priority != nil && *params.Priority != todo.Priority {
+		ws, err := s.workspace(ctx)
+		if err != nil {
+			return Todo{}, err
+		}
+
+		if err := ws.Settings.checkPriority(*params.Priority); err != nil {
+			return Todo{}, err
+		}
+
 		todo.Priority = *params.Priority
 	}
+	completing := params.Completed != nil && *params.Completed && !todo.Completed
 	if params.Completed != nil {
 		todo.Completed = *params.Completed
 	}
+	if params.ListID != nil && *params.ListID != todo.ListID {
+		if err := s.checkList(ctx, *params.ListID, todo.OwnerID); err != nil {
+			return Todo{}, err
+		}
+		todo.ListID = *params.ListID
+	}
+	if params.ParentID != nil {
+		if _, err := s.checkParent(ctx, *params.ParentID); err != nil {
+			return Todo{}, err
+		}
+		todo.ParentID = params.ParentID
+	}
+	if params.ClearParent {
+		todo.ParentID = nil
+	}
+	if params.DueAt != nil {
+		todo.DueAt = params.DueAt
+	}
+	if params.ClearDueAt {
+		todo.DueAt = nil
+	}
+	if params.Recurrence != nil {
+		todo.Recurrence = *params.Recurrence
+	}
+	if params.Tags != nil {
+		todo.Tags = sortedTags(*params.Tags)
+	}
+
+	if completing {
+		if err := s.completeChildren(ctx, todo, now); err != nil {
+			return Todo{}, err
+		}
+	}
+
+	// A recurring todo item that is completed again after being reopened
+	// already has its next instance.
+	var next *Todo
+	if completing && todo.Recurrence != "" && todo.NextID == nil {
+		n, ok, err := nextInstance(todo, now)
+		if err != nil {
+			return Todo{}, err
+		}
+		if ok {
+			next = &n
+			todo.NextID = &n.ID
+		}
+	}
+
+	todo.Version++
+	todo.TimeUpdated = now
 
-	todo.TimeUpdated = time.Now()
+	ev := newEvent(ctx, EventUpdated, todo, diff(before, todo), now)
 
-	if err := s.storer.Update(ctx, todo); err != nil {
+	if err := s.storer.Update(ctx, todo, ev); err != nil {
+		if errors.Is(err, ErrCycle) {
+			return Todo{}, NewValidationError(err)
+		}
 		return Todo{}, fmt.Errorf("update: %w", err)
 	}
 
+	if err := s.changed(ctx, ev, todo); err != nil {
+		return Todo{}, err
+	}
+
+	if next != nil {
+		if err := s.createNextInstance(ctx, *next, now); err != nil {
+			return Todo{}, err
+		}
+	}
+
 	return todo, nil
 }
 
-// Delete deletes the specified todo item.
+// nextInstance returns the next instance of a recurring todo item that is
+// being completed. The next due date follows the due date of the completed
+// instance, or its creation time if it had no due date. It reports false once
+// the schedule has ended.
+func nextInstance(todo Todo, now time.Time) (Todo, bool, error) {
+	r, err := ParseRecurrence(todo.Recurrence)
+	if err != nil {
+		return Todo{}, false, fmt.Errorf("parse recurrence: %w", err)
+	}
+
+	prev := todo.TimeCreated
+	if todo.DueAt != nil {
+		prev = *todo.DueAt
+	}
+
+	due, r, ok := r.Next(prev)
+	if !ok {
+		return Todo{}, false, nil
+	}
+
+	next := Todo{
+		ID:          uuid.New(),
+		WorkspaceID: todo.WorkspaceID,
+		OwnerID:     todo.OwnerID,
+		Text:        todo.Text,
+		Priority:    todo.Priority,
+		Completed:   false,
+		ListID:      todo.ListID,
+		ParentID:    todo.ParentID,
+		DueAt:       &due,
+		Recurrence:  r.String(),
+		Tags:        sortedTags(todo.Tags),
+		Version:     1,
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	return next, true, nil
+}
+
+// createNextInstance creates next, the next instance of a recurring todo item
+// that was just completed.
+func (s *Core) createNextInstance(ctx context.Context, next Todo, now time.Time) error {
+	ev := newEvent(ctx, EventCreated, next, created(next), now)
+
+	if err := s.storer.Create(ctx, next, ev); err != nil {
+		return fmt.Errorf("create next instance: %w", err)
+	}
+
+	if err := s.changed(ctx, ev, next); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// completeChildren applies the completion policy to the incomplete
+// descendants of a todo item that is about to be completed.
+func (s *Core) completeChildren(ctx context.Context, todo Todo, now time.Time) error {
+	children, err := s.storer.QuerySubtree(ctx, todo.ID)
+	if err != nil {
+		return fmt.Errorf("query subtree: %w", err)
+	}
+
+	for _, child := range children {
+		if child.Completed {
+			continue
+		}
+
+		if s.completionPolicy == CompletionBlock {
+			return ErrOpenChildren
+		}
+
+		before := child
+
+		child.Completed = true
+		child.Version++
+		child.TimeUpdated = now
+
+		ev := newEvent(ctx, EventUpdated, child, diff(before, child), now)
+
+		if err := s.storer.Update(ctx, child, ev); err != nil {
+			return fmt.Errorf("update child [%s]: %w", child.ID, err)
+		}
+
+		if err := s.changed(ctx, ev, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkParent returns the parent todo item given by id, or a validation error
+// if it does not exist or the user in ctx may not see it. It returns
+// ErrForbidden when they may see it but not add subtasks to it.
+func (s *Core) checkParent(ctx context.Context, id uuid.UUID) (Todo, error) {
+	parent, err := s.storer.QueryByID(ctx, id)
+	if err == nil {
+		err = s.authorize(ctx, parent, RoleEditor)
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Todo{}, NewValidationError(fmt.Errorf("parent %s does not exist", id))
+		}
+		return Todo{}, fmt.Errorf("query parent: %w", err)
+	}
+
+	return parent, nil
+}
+
+// Delete moves the specified todo item along with its descendants to the
+// trash.
 func (s *Core) Delete(ctx context.Context, todo Todo) error {
-	if err := s.storer.Delete(ctx, todo); err != nil {
+	return s.WithTx(ctx, func(txCore *Core) error {
+		current, err := txCore.current(ctx, todo, false, RoleOwner)
+		if errors.Is(err, ErrNotFound) {
+			// Todo items that are already in the trash, such as the
+			// descendants of one moved there before them, are left alone.
+			if _, err := txCore.current(ctx, todo, true, RoleOwner); err == nil {
+				return nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+
+		return txCore.delete(ctx, current)
+	})
+}
+
+// delete moves the specified todo item to the trash within a transaction.
+func (s *Core) delete(ctx context.Context, todo Todo) error {
+	now := time.Now()
+	before := todo
+	todo.TimeDeleted = &now
+
+	ev := newEvent(ctx, EventDeleted, todo, diff(before, todo), now)
+
+	if err := s.storer.Delete(ctx, todo, ev); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
+	if err := s.changed(ctx, ev, todo); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// QueryTrashByID retrieves a todo item the user in ctx may see in the trash by
+// its ID.
+func (s *Core) QueryTrashByID(ctx context.Context, id uuid.UUID) (Todo, error) {
+	t, err := s.storer.QueryTrashByID(ctx, id)
+	if err != nil {
+		return Todo{}, fmt.Errorf("query trash by id: %w", err)
+	}
+
+	if err := s.authorize(ctx, t, RoleViewer); err != nil {
+		return Todo{}, fmt.Errorf("query trash by id: %w", err)
+	}
+
+	return t, nil
+}
+
+// Restore takes the specified todo item out of the trash along with the
+// descendants that were moved to the trash with it. A todo item cannot be
+// restored while its parent is in the trash.
+func (s *Core) Restore(ctx context.Context, todo Todo) (Todo, error) {
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		current, err := txCore.current(ctx, todo, true, RoleOwner)
+		if err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+
+		todo, err = txCore.restore(ctx, current)
+		return err
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// restore takes the specified todo item out of the trash once its parent has
+// been checked.
+func (s *Core) restore(ctx context.Context, todo Todo) (Todo, error) {
+	if todo.ParentID != nil {
+		if _, err := s.storer.QueryByID(ctx, *todo.ParentID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return Todo{}, NewValidationError(fmt.Errorf("parent %s must be restored first", *todo.ParentID))
+			}
+			return Todo{}, fmt.Errorf("query parent: %w", err)
+		}
+	}
+
+	before := todo
+	todo.TimeDeleted = nil
+
+	ev := newEvent(ctx, EventRestored, todo, diff(before, todo), time.Now())
+
+	if err := s.storer.Restore(ctx, before, ev); err != nil {
+		return Todo{}, fmt.Errorf("restore: %w", err)
+	}
+
+	if err := s.changed(ctx, ev, todo); err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// Purge permanently deletes the specified todo item along with its
+// descendants.
+func (s *Core) Purge(ctx context.Context, todo Todo) error {
+	return s.WithTx(ctx, func(txCore *Core) error {
+		todo, err := txCore.current(ctx, todo, true, RoleOwner)
+		if err != nil {
+			return fmt.Errorf("purge: %w", err)
+		}
+
+		if err := txCore.storer.Purge(ctx, todo); err != nil {
+			return fmt.Errorf("purge: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// PurgeTrash permanently deletes every todo item of the user in ctx that was
+// moved to the trash before the given time and returns how many were deleted.
+func (s *Core) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	owner := OwnerFrom(ctx)
+
+	n, err := s.storer.PurgeTrash(ctx, &owner, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge trash: %w", err)
+	}
+
+	return n, nil
+}
+
+// PurgeExpired permanently deletes every todo item of every user in every
+// workspace that was moved to the trash before the given time and returns how
+// many were deleted.
+func (s *Core) PurgeExpired(ctx context.Context, before time.Time) (int, error) {
+	workspaces, err := s.storer.QueryWorkspaces(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("query workspaces: %w", err)
+	}
+
+	total := 0
+
+	for _, ws := range workspaces {
+		n, err := s.storer.PurgeTrash(WithWorkspace(ctx, ws.ID), nil, before)
+		if err != nil {
+			return total, fmt.Errorf("purge trash [%s]: %w", ws.ID, err)
+		}
+
+		total += n
+	}
+
+	return total, nil
+}
+
+// ownerFilter returns the QueryFilter.OwnerID for the user in ctx.
+func ownerFilter(ctx context.Context) *uuid.UUID {
+	owner := OwnerFrom(ctx)
+	return &owner
+}
+
+// sortedTags returns a sorted copy of tags that is never nil.
+func sortedTags(tags []string) []string {
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	return sorted
+}
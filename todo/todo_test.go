@@ -2,9 +2,13 @@ package todo_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
 
 	"github.com/sudomateo/todo/todo"
 	"github.com/sudomateo/todo/todo/stores/todomemory"
@@ -13,7 +17,7 @@ import (
 func TestTodo(t *testing.T) {
 	todoCore := todo.NewCore(todomemory.NewStore())
 
-	todos, err := todoCore.Query(context.Background())
+	todos, err := todoCore.Query(context.Background(), todo.QueryOptions{})
 	if err != nil {
 		t.Fatalf("query: expected nil error, got %v", err)
 	}
@@ -39,7 +43,7 @@ func TestTodo(t *testing.T) {
 		t.Fatalf("compare: %v", diff)
 	}
 
-	todos, err = todoCore.Query(context.Background())
+	todos, err = todoCore.Query(context.Background(), todo.QueryOptions{})
 	if err != nil {
 		t.Fatalf("query: expected nil error, got %v", err)
 	}
@@ -55,3 +59,807 @@ func TestTodo(t *testing.T) {
 		t.Fatalf("delete: expected nil error, got %v", err)
 	}
 }
+
+func TestTodoOverdue(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	overdue, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "overdue",
+		Priority: todo.PriorityHigh,
+		DueAt:    &past,
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "upcoming",
+		Priority: todo.PriorityLow,
+		DueAt:    &future,
+	}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	todos, err := todoCore.QueryOverdue(context.Background())
+	if err != nil {
+		t.Fatalf("query overdue: expected nil error, got %v", err)
+	}
+	if len(todos) != 1 || todos[0].ID != overdue.ID {
+		t.Fatalf("query overdue: expected only %v, got %v", overdue.ID, todos)
+	}
+
+	overdue, err = todoCore.Update(context.Background(), overdue, todo.TodoUpdateParams{
+		ClearDueAt: true,
+	})
+	if err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+	if overdue.DueAt != nil {
+		t.Fatalf("update: expected due date to be cleared, got %v", overdue.DueAt)
+	}
+
+	todos, err = todoCore.QueryOverdue(context.Background())
+	if err != nil {
+		t.Fatalf("query overdue: expected nil error, got %v", err)
+	}
+	if len(todos) != 0 {
+		t.Fatalf("query overdue: expected 0 todos, got %v", len(todos))
+	}
+}
+
+func TestTodoQuery(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	for _, params := range []todo.TodoCreateParams{
+		{Text: "a", Priority: todo.PriorityLow},
+		{Text: "b", Priority: todo.PriorityHigh},
+		{Text: "c", Priority: todo.PriorityMedium},
+		{Text: "d", Priority: todo.PriorityHigh},
+	} {
+		if _, err := todoCore.Create(context.Background(), params); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+	}
+
+	texts := func(todos []todo.Todo) []string {
+		s := make([]string, 0, len(todos))
+		for _, td := range todos {
+			s = append(s, td.Text)
+		}
+		return s
+	}
+
+	high := todo.PriorityHigh
+
+	tests := map[string]struct {
+		opts todo.QueryOptions
+		want []string
+	}{
+		"default": {
+			opts: todo.QueryOptions{},
+			want: []string{"a", "b", "c", "d"},
+		},
+		"priority filter": {
+			opts: todo.QueryOptions{Filter: todo.QueryFilter{Priority: &high}},
+			want: []string{"b", "d"},
+		},
+		"priority descending": {
+			opts: todo.QueryOptions{OrderBy: todo.OrderByPriority, Direction: todo.DirectionDesc},
+			want: []string{"d", "b", "c", "a"},
+		},
+		"limit and offset": {
+			opts: todo.QueryOptions{Limit: 2, Offset: 1},
+			want: []string{"b", "c"},
+		},
+		"offset past end": {
+			opts: todo.QueryOptions{Offset: 10},
+			want: []string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			todos, err := todoCore.Query(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("query: expected nil error, got %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, texts(todos)); diff != "" {
+				t.Fatalf("query: %v", diff)
+			}
+		})
+	}
+
+	if _, err := todoCore.Query(context.Background(), todo.QueryOptions{OrderBy: "bogus"}); err == nil {
+		t.Fatalf("query: expected validation error for invalid order_by")
+	}
+}
+
+func TestTodoQueryCursor(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	for _, text := range []string{"a", "b", "c"} {
+		if _, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+			Text:     text,
+			Priority: todo.PriorityLow,
+		}); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+	}
+
+	page, err := todoCore.Query(context.Background(), todo.QueryOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("query: expected 2 todos, got %v", len(page))
+	}
+
+	// Deleting an item from the first page must not shift the second page.
+	if err := todoCore.Delete(context.Background(), page[0]); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	cursor, err := todo.ParseCursor(todo.CursorFor(page[1]).String())
+	if err != nil {
+		t.Fatalf("parse cursor: expected nil error, got %v", err)
+	}
+
+	page, err = todoCore.Query(context.Background(), todo.QueryOptions{Limit: 2, After: &cursor})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(page) != 1 || page[0].Text != "c" {
+		t.Fatalf("query: expected only todo c, got %v", page)
+	}
+
+	if _, err := todoCore.Query(context.Background(), todo.QueryOptions{
+		After:   &cursor,
+		OrderBy: todo.OrderByPriority,
+	}); err == nil {
+		t.Fatalf("query: expected validation error for cursor with order_by priority")
+	}
+}
+
+func TestTodoUpdateConflict(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	td, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "foo",
+		Priority: todo.PriorityHigh,
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	text := "bar"
+	updated, err := todoCore.Update(context.Background(), td, todo.TodoUpdateParams{Text: &text})
+	if err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+	if updated.Version != td.Version+1 {
+		t.Fatalf("update: expected version %v, got %v", td.Version+1, updated.Version)
+	}
+
+	// Updating the stale copy must not overwrite the first update.
+	text = "baz"
+	if _, err := todoCore.Update(context.Background(), td, todo.TodoUpdateParams{Text: &text}); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("update: expected %v, got %v", todo.ErrConflict, err)
+	}
+
+	tdQuery, err := todoCore.QueryByID(context.Background(), td.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(updated, tdQuery); diff != "" {
+		t.Fatalf("compare: %v", diff)
+	}
+}
+
+func TestTodoTags(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	if _, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "invalid",
+		Priority: todo.PriorityLow,
+		Tags:     []string{"Ops"},
+	}); err == nil {
+		t.Fatalf("create: expected validation error for invalid tag")
+	}
+
+	deploy, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "deploy",
+		Priority: todo.PriorityHigh,
+		Tags:     []string{"ops", "release"},
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "rotate keys",
+		Priority: todo.PriorityMedium,
+		Tags:     []string{"ops"},
+	}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	todos, err := todoCore.Query(context.Background(), todo.QueryOptions{
+		Filter: todo.QueryFilter{Tags: []string{"ops", "release"}},
+	})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 1 || todos[0].ID != deploy.ID {
+		t.Fatalf("query: expected only %v, got %v", deploy.ID, todos)
+	}
+
+	tags := []string{"ops"}
+	if _, err := todoCore.Update(context.Background(), deploy, todo.TodoUpdateParams{Tags: &tags}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	counts, err := todoCore.QueryTags(context.Background())
+	if err != nil {
+		t.Fatalf("query tags: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]todo.TagCount{{Tag: "ops", Count: 2}}, counts); diff != "" {
+		t.Fatalf("query tags: %v", diff)
+	}
+}
+
+func TestListDelete(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	if err := todoCore.DeleteList(context.Background(), todo.List{ID: todo.InboxID}, todo.DeletePolicyCascade); err == nil {
+		t.Fatalf("delete list: expected error deleting the inbox")
+	}
+
+	for _, policy := range []todo.DeletePolicy{todo.DeletePolicyInbox, todo.DeletePolicyCascade} {
+		t.Run(string(policy), func(t *testing.T) {
+			l, err := todoCore.CreateList(context.Background(), todo.ListCreateParams{Name: "work"})
+			if err != nil {
+				t.Fatalf("create list: expected nil error, got %v", err)
+			}
+
+			td, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+				Text:     "foo",
+				Priority: todo.PriorityLow,
+				ListID:   &l.ID,
+			})
+			if err != nil {
+				t.Fatalf("create: expected nil error, got %v", err)
+			}
+
+			if err := todoCore.DeleteList(context.Background(), l, policy); err != nil {
+				t.Fatalf("delete list: expected nil error, got %v", err)
+			}
+
+			if _, err := todoCore.QueryListByID(context.Background(), l.ID); !errors.Is(err, todo.ErrListNotFound) {
+				t.Fatalf("query list by id: expected %v, got %v", todo.ErrListNotFound, err)
+			}
+
+			td, err = todoCore.QueryByID(context.Background(), td.ID)
+			switch policy {
+			case todo.DeletePolicyInbox:
+				if err != nil || td.ListID != todo.InboxID {
+					t.Fatalf("query by id: expected todo in inbox, got %v, %v", td, err)
+				}
+			case todo.DeletePolicyCascade:
+				if !errors.Is(err, todo.ErrNotFound) {
+					t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+				}
+			}
+		})
+	}
+}
+
+func TestListOwners(t *testing.T) {
+	for _, policy := range []todo.DeletePolicy{todo.DeletePolicyInbox, todo.DeletePolicyCascade} {
+		t.Run(string(policy), func(t *testing.T) {
+			ctx := context.Background()
+
+			todoCore := todo.NewCore(todomemory.NewStore())
+
+			users := make(map[string]context.Context)
+			for _, name := range []string{"alice", "bob"} {
+				user, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: name})
+				if err != nil {
+					t.Fatalf("create user: expected nil error, got %v", err)
+				}
+				users[name] = todo.WithUser(ctx, user)
+			}
+
+			alice, bob := users["alice"], users["bob"]
+
+			l, err := todoCore.CreateList(alice, todo.ListCreateParams{Name: "secret plans"})
+			if err != nil {
+				t.Fatalf("create list: expected nil error, got %v", err)
+			}
+			if l.OwnerID != todo.OwnerFrom(alice) {
+				t.Fatalf("create list: expected owner %v, got %v", todo.OwnerFrom(alice), l.OwnerID)
+			}
+
+			mine, err := todoCore.Create(alice, todo.TodoCreateParams{Text: "write report", Priority: todo.PriorityLow, ListID: &l.ID})
+			if err != nil {
+				t.Fatalf("create: expected nil error, got %v", err)
+			}
+
+			// Nobody else sees the list or may use it, including anonymous
+			// users.
+			name := "mine"
+			for _, ctx := range []context.Context{bob, ctx} {
+				lists, err := todoCore.QueryLists(ctx)
+				if err != nil {
+					t.Fatalf("query lists: expected nil error, got %v", err)
+				}
+				for _, got := range lists {
+					if got.ID == l.ID {
+						t.Fatalf("query lists: expected the list of alice to be hidden, got %v", lists)
+					}
+				}
+
+				if _, err := todoCore.QueryListByID(ctx, l.ID); !errors.Is(err, todo.ErrListNotFound) {
+					t.Fatalf("query list by id: expected %v, got %v", todo.ErrListNotFound, err)
+				}
+
+				if _, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "review report", Priority: todo.PriorityLow, ListID: &l.ID}); !errors.Is(err, todo.ErrListNotFound) {
+					t.Fatalf("create: expected %v, got %v", todo.ErrListNotFound, err)
+				}
+
+				if _, err := todoCore.UpdateList(ctx, l, todo.ListUpdateParams{Name: &name}); !errors.Is(err, todo.ErrListNotFound) {
+					t.Fatalf("update list: expected %v, got %v", todo.ErrListNotFound, err)
+				}
+
+				if err := todoCore.DeleteList(ctx, l, policy); !errors.Is(err, todo.ErrListNotFound) {
+					t.Fatalf("delete list: expected %v, got %v", todo.ErrListNotFound, err)
+				}
+			}
+
+			// Collaborators may not move the todo items of others into
+			// their own lists either.
+			if _, err := todoCore.Share(alice, mine, todo.ShareParams{User: "bob", Role: todo.RoleEditor}); err != nil {
+				t.Fatalf("share: expected nil error, got %v", err)
+			}
+
+			theirs, err := todoCore.CreateList(bob, todo.ListCreateParams{Name: "reviews"})
+			if err != nil {
+				t.Fatalf("create list: expected nil error, got %v", err)
+			}
+
+			if _, err := todoCore.Update(bob, mine, todo.TodoUpdateParams{ListID: &theirs.ID}); !errors.Is(err, todo.ErrForbidden) {
+				t.Fatalf("update: expected %v, got %v", todo.ErrForbidden, err)
+			}
+
+			if err := todoCore.DeleteList(alice, l, policy); err != nil {
+				t.Fatalf("delete list: expected nil error, got %v", err)
+			}
+
+			got, err := todoCore.QueryByID(alice, mine.ID)
+			if policy == todo.DeletePolicyCascade {
+				got, err = todoCore.QueryTrashByID(alice, mine.ID)
+			}
+			if err != nil || got.ListID != todo.InboxID {
+				t.Fatalf("query: expected the todo in the inbox, got %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestTodoSubtasks(t *testing.T) {
+	for _, policy := range []todo.CompletionPolicy{todo.CompletionBlock, todo.CompletionCascade} {
+		t.Run(string(policy), func(t *testing.T) {
+			ctx := context.Background()
+			todoCore := todo.NewCore(todomemory.NewStore(), todo.WithCompletionPolicy(policy))
+
+			create := func(text string, parent *todo.Todo) todo.Todo {
+				params := todo.TodoCreateParams{Text: text, Priority: todo.PriorityLow}
+				if parent != nil {
+					params.ParentID = &parent.ID
+				}
+
+				td, err := todoCore.Create(ctx, params)
+				if err != nil {
+					t.Fatalf("create: expected nil error, got %v", err)
+				}
+				return td
+			}
+
+			root := create("root", nil)
+			child := create("child", &root)
+			grandchild := create("grandchild", &child)
+
+			subtree, err := todoCore.QuerySubtree(ctx, root.ID)
+			if err != nil {
+				t.Fatalf("query subtree: expected nil error, got %v", err)
+			}
+			if len(subtree) != 2 || subtree[0].ID != child.ID || subtree[1].ID != grandchild.ID {
+				t.Fatalf("query subtree: expected child and grandchild, got %v", subtree)
+			}
+
+			var vErr todo.ValidationError
+			if _, err := todoCore.Update(ctx, root, todo.TodoUpdateParams{ParentID: &grandchild.ID}); !errors.As(err, &vErr) {
+				t.Fatalf("update: expected validation error for cycle, got %v", err)
+			}
+
+			completed := true
+			_, err = todoCore.Update(ctx, root, todo.TodoUpdateParams{Completed: &completed})
+
+			switch policy {
+			case todo.CompletionBlock:
+				if !errors.Is(err, todo.ErrOpenChildren) {
+					t.Fatalf("update: expected %v, got %v", todo.ErrOpenChildren, err)
+				}
+			case todo.CompletionCascade:
+				if err != nil {
+					t.Fatalf("update: expected nil error, got %v", err)
+				}
+
+				grandchild, err = todoCore.QueryByID(ctx, grandchild.ID)
+				if err != nil {
+					t.Fatalf("query by id: expected nil error, got %v", err)
+				}
+				if !grandchild.Completed {
+					t.Fatalf("update: expected grandchild to be completed")
+				}
+			}
+
+			if err := todoCore.Delete(ctx, root); err != nil {
+				t.Fatalf("delete: expected nil error, got %v", err)
+			}
+
+			todos, err := todoCore.Query(ctx, todo.QueryOptions{})
+			if err != nil {
+				t.Fatalf("query: expected nil error, got %v", err)
+			}
+			if len(todos) != 0 {
+				t.Fatalf("query: expected descendants to be deleted, got %v", todos)
+			}
+		})
+	}
+}
+
+func TestTodoTrash(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	parent, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "parent",
+		Priority: todo.PriorityLow,
+		Tags:     []string{"ops"},
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	child, err := todoCore.Create(context.Background(), todo.TodoCreateParams{
+		Text:     "child",
+		Priority: todo.PriorityLow,
+		ParentID: &parent.ID,
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.Delete(context.Background(), parent); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.QueryByID(context.Background(), child.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	todos, err := todoCore.Query(context.Background(), todo.QueryOptions{})
+	if err != nil || len(todos) != 0 {
+		t.Fatalf("query: expected no todos, got %v, %v", todos, err)
+	}
+
+	tags, err := todoCore.QueryTags(context.Background())
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("query tags: expected no tags, got %v, %v", tags, err)
+	}
+
+	trash, err := todoCore.Query(context.Background(), todo.QueryOptions{
+		Filter: todo.QueryFilter{Trashed: true, Tags: []string{"ops"}},
+	})
+	if err != nil || len(trash) != 1 || trash[0].ID != parent.ID {
+		t.Fatalf("query trash: expected parent, got %v, %v", trash, err)
+	}
+
+	trashedChild, err := todoCore.QueryTrashByID(context.Background(), child.ID)
+	if err != nil {
+		t.Fatalf("query trash by id: expected nil error, got %v", err)
+	}
+
+	var vErr todo.ValidationError
+	if _, err := todoCore.Restore(context.Background(), trashedChild); !errors.As(err, &vErr) {
+		t.Fatalf("restore: expected validation error restoring below a trashed parent, got %v", err)
+	}
+
+	if _, err := todoCore.Restore(context.Background(), trash[0]); err != nil {
+		t.Fatalf("restore: expected nil error, got %v", err)
+	}
+
+	for _, id := range []uuid.UUID{parent.ID, child.ID} {
+		if _, err := todoCore.QueryByID(context.Background(), id); err != nil {
+			t.Fatalf("query by id: expected nil error, got %v", err)
+		}
+	}
+
+	if err := todoCore.Delete(context.Background(), parent); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	n, err := todoCore.PurgeTrash(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Fatalf("purge trash: expected nothing purged, got %d, %v", n, err)
+	}
+
+	n, err = todoCore.PurgeTrash(context.Background(), time.Now().Add(time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("purge trash: expected 2 purged, got %d, %v", n, err)
+	}
+
+	if _, err := todoCore.QueryTrashByID(context.Background(), child.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query trash by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+}
+
+func TestTodoHistory(t *testing.T) {
+	todoCore := todo.NewCore(todomemory.NewStore())
+	ctx := todo.WithActor(context.Background(), "alice")
+
+	td, err := todoCore.Create(ctx, todo.TodoCreateParams{
+		Text:     "foo",
+		Priority: todo.PriorityLow,
+	})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	text := "bar"
+	td, err = todoCore.Update(todo.WithActor(context.Background(), "bob"), td, todo.TodoUpdateParams{
+		Text: &text,
+	})
+	if err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.Delete(ctx, td); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	events, err := todoCore.QueryEvents(context.Background(), td.ID)
+	if err != nil {
+		t.Fatalf("query events: expected nil error, got %v", err)
+	}
+
+	type summary struct {
+		Type   todo.EventType
+		Actor  string
+		Fields []string
+	}
+
+	got := make([]summary, 0, len(events))
+	for _, ev := range events {
+		s := summary{Type: ev.Type, Actor: ev.Actor}
+		for _, c := range ev.Changes {
+			s.Fields = append(s.Fields, c.Field)
+		}
+		got = append(got, s)
+	}
+
+	want := []summary{
+		{Type: todo.EventCreated, Actor: "alice", Fields: []string{"text", "priority"}},
+		{Type: todo.EventUpdated, Actor: "bob", Fields: []string{"text"}},
+		{Type: todo.EventDeleted, Actor: "alice", Fields: []string{"time_deleted"}},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("query events: mismatch (-want +got):\n%s", diff)
+	}
+
+	change := events[1].Changes[0]
+	if string(change.Before) != `"foo"` || string(change.After) != `"bar"` {
+		t.Fatalf("query events: expected text to change from \"foo\" to \"bar\", got %s to %s", change.Before, change.After)
+	}
+}
+
+func TestTodoWithTx(t *testing.T) {
+	ctx := context.Background()
+	todoCore := todo.NewCore(todomemory.NewStore(), todo.WithCompletionPolicy(todo.CompletionCascade))
+
+	root, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "root", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	child, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "child", Priority: todo.PriorityLow, ParentID: &root.ID})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	errRollback := errors.New("rollback")
+
+	err = todoCore.WithTx(ctx, func(txCore *todo.Core) error {
+		if _, err := txCore.Create(ctx, todo.TodoCreateParams{Text: "foo", Priority: todo.PriorityLow}); err != nil {
+			return err
+		}
+
+		if err := txCore.Delete(ctx, root); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("with tx: expected %v, got %v", errRollback, err)
+	}
+
+	todos, err := todoCore.Query(ctx, todo.QueryOptions{})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 2 {
+		t.Fatalf("query: expected the transaction to be rolled back, got %v", todos)
+	}
+
+	// Completing a stale copy of the root fails, and so must completing its
+	// child along with it.
+	text := "bar"
+	if _, err := todoCore.Update(ctx, root, todo.TodoUpdateParams{Text: &text}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	completed := true
+	if _, err := todoCore.Update(ctx, root, todo.TodoUpdateParams{Completed: &completed}); !errors.Is(err, todo.ErrConflict) {
+		t.Fatalf("update: expected %v, got %v", todo.ErrConflict, err)
+	}
+
+	child, err = todoCore.QueryByID(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("query by id: expected nil error, got %v", err)
+	}
+	if child.Completed {
+		t.Fatalf("update: expected child to stay incomplete")
+	}
+}
+
+func TestTodoBatch(t *testing.T) {
+	ctx := context.Background()
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	existing, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "foo", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	missing := uuid.New()
+	text := "bar"
+
+	ops := []todo.BatchOperation{
+		{Op: todo.BatchCreate, Create: &todo.TodoCreateParams{Text: "baz", Priority: todo.PriorityHigh}},
+		{Op: todo.BatchUpdate, ID: &existing.ID, Version: existing.Version, Update: &todo.TodoUpdateParams{Text: &text}},
+		{Op: todo.BatchDelete, ID: &missing},
+	}
+
+	var bErr todo.BatchError
+	_, err = todoCore.Batch(ctx, todo.BatchParams{Operations: ops})
+	if !errors.As(err, &bErr) || bErr.Index != 2 || !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("batch: expected operation 2 to fail with %v, got %v", todo.ErrNotFound, err)
+	}
+
+	todos, err := todoCore.Query(ctx, todo.QueryOptions{})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 1 || todos[0].Text != "foo" {
+		t.Fatalf("batch: expected atomic batch to be rolled back, got %v", todos)
+	}
+
+	results, err := todoCore.Batch(ctx, todo.BatchParams{Mode: todo.BatchBestEffort, Operations: ops})
+	if err != nil {
+		t.Fatalf("batch: expected nil error, got %v", err)
+	}
+
+	if len(results) != 3 || results[0].Err != nil || results[1].Err != nil || !errors.Is(results[2].Err, todo.ErrNotFound) {
+		t.Fatalf("batch: expected only operation 2 to fail, got %v", results)
+	}
+	if results[1].Todo == nil || results[1].Todo.Text != "bar" {
+		t.Fatalf("batch: expected updated todo in result, got %v", results[1].Todo)
+	}
+
+	// The update is stale now that the batch has been applied once.
+	results, err = todoCore.Batch(ctx, todo.BatchParams{Mode: todo.BatchBestEffort, Operations: ops[1:2]})
+	if err != nil || !errors.Is(results[0].Err, todo.ErrConflict) {
+		t.Fatalf("batch: expected %v, got %v, %v", todo.ErrConflict, results, err)
+	}
+
+	var vErr todo.ValidationError
+	if _, err := todoCore.Batch(ctx, todo.BatchParams{Operations: []todo.BatchOperation{{Op: todo.BatchUpdate}}}); !errors.As(err, &vErr) {
+		t.Fatalf("batch: expected validation error, got %v", err)
+	}
+}
+
+func TestTodoCompleteAll(t *testing.T) {
+	ctx := context.Background()
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	create := func(text string, priority todo.Priority, parent *todo.Todo) todo.Todo {
+		params := todo.TodoCreateParams{Text: text, Priority: priority}
+		if parent != nil {
+			params.ParentID = &parent.ID
+		}
+
+		td, err := todoCore.Create(ctx, params)
+		if err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+		return td
+	}
+
+	root := create("root", todo.PriorityHigh, nil)
+	create("child", todo.PriorityHigh, &root)
+	create("other", todo.PriorityLow, nil)
+
+	high := todo.PriorityHigh
+	completed, err := todoCore.CompleteAll(ctx, todo.QueryFilter{Priority: &high})
+	if err != nil {
+		t.Fatalf("complete all: expected nil error, got %v", err)
+	}
+	if len(completed) != 2 {
+		t.Fatalf("complete all: expected 2 todos, got %v", completed)
+	}
+
+	done := true
+	n, err := todoCore.DeleteAll(ctx, todo.QueryFilter{Completed: &done})
+	if err != nil || n != 2 {
+		t.Fatalf("delete all: expected 2 todos, got %d, %v", n, err)
+	}
+
+	todos, err := todoCore.Query(ctx, todo.QueryOptions{})
+	if err != nil {
+		t.Fatalf("query: expected nil error, got %v", err)
+	}
+	if len(todos) != 1 || todos[0].Text != "other" {
+		t.Fatalf("query: expected only the incomplete todo to be left, got %v", todos)
+	}
+}
+
+func TestTodoSearch(t *testing.T) {
+	ctx := context.Background()
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	for i := 0; i < todo.DefaultSearchLimit+1; i++ {
+		if _, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: fmt.Sprintf("water plant %d", i), Priority: todo.PriorityLow}); err != nil {
+			t.Fatalf("create: expected nil error, got %v", err)
+		}
+	}
+
+	results, err := todoCore.Search(ctx, todo.SearchParams{Query: "Plant, water!"})
+	if err != nil {
+		t.Fatalf("search: expected nil error, got %v", err)
+	}
+	if len(results) != todo.DefaultSearchLimit {
+		t.Fatalf("search: expected %d results, got %d", todo.DefaultSearchLimit, len(results))
+	}
+
+	var vErr todo.ValidationError
+
+	for _, params := range []todo.SearchParams{
+		{Query: " -- "},
+		{Query: "plant", Limit: todo.MaxSearchLimit + 1},
+	} {
+		if _, err := todoCore.Search(ctx, params); !errors.As(err, &vErr) {
+			t.Fatalf("search %+v: expected validation error, got %v", params, err)
+		}
+	}
+
+	// Embedding the Storer interface hides the Search method of the store.
+	unsupported := todo.NewCore(struct{ todo.Storer }{todomemory.NewStore()})
+	if _, err := unsupported.Search(ctx, todo.SearchParams{Query: "plant"}); !errors.Is(err, todo.ErrSearchUnsupported) {
+		t.Fatalf("search: expected %v, got %v", todo.ErrSearchUnsupported, err)
+	}
+}
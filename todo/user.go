@@ -0,0 +1,358 @@
+package todo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUserExists      = errors.New("user already exists")
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+	ErrUnauthenticated = errors.New("invalid or revoked api key")
+
+	ErrInvalidCredentials = errors.New("invalid name or password")
+)
+
+// APIKeyPrefix starts every API key so that leaked keys are easy to spot.
+const APIKeyPrefix = "todo_"
+
+// Passwords are hashed with bcrypt, which only uses their first 72 bytes.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// dummyPasswordHash is compared against when logging in as a user that does
+// not exist so that it takes as long as a wrong password.
+const dummyPasswordHash = "$2a$10$cRIZyu5d4tPIMn4128Br8ex5P3TZKzxaOEa3/OqUUVwI0bFzxPY7y"
+
+// apiKeyDisplayLength is how much of an API key is kept in plain text to tell
+// keys apart.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// UserStorer represents the behavior this package needs to manage users and
+// their API keys.
+//
+// CreateUser returns ErrUserExists when another user has the same name, and
+// QueryUserByName returns ErrUserNotFound when there is none. API keys are
+// stored by the SHA-256 hash of the key and QueryAPIKeyByHash looks
+// them up by that hash, including revoked keys. QueryAPIKeys returns the keys
+// of a user in the order they were created.
+type UserStorer interface {
+	QueryUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	QueryUserByName(ctx context.Context, name string) (User, error)
+	CreateUser(ctx context.Context, user User) error
+	QueryAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error)
+	QueryAPIKeyByID(ctx context.Context, id uuid.UUID) (APIKey, error)
+	QueryAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	CreateAPIKey(ctx context.Context, key APIKey) error
+	UpdateAPIKey(ctx context.Context, key APIKey) error
+}
+
+// User represents someone who owns todo items. Users with a PasswordHash can
+// log in to the web application, which Core never returns.
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	TimeCreated  time.Time `json:"time_created"`
+	TimeUpdated  time.Time `json:"time_updated"`
+}
+
+// UserCreateParams are what we require from clients to create a user. Users
+// without a password can only authenticate with API keys.
+type UserCreateParams struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// Validate validates the UserCreateParams.
+func (p UserCreateParams) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return NewValidationError(errors.New("missing required field name"))
+	}
+
+	if p.Password != "" && len(p.Password) < MinPasswordLength {
+		return NewValidationError(fmt.Errorf("password must be at least %d characters", MinPasswordLength))
+	}
+
+	if len(p.Password) > MaxPasswordLength {
+		return NewValidationError(fmt.Errorf("password must be at most %d bytes", MaxPasswordLength))
+	}
+
+	return nil
+}
+
+// APIKey is a credential that authenticates requests as its user. Only a hash
+// of the key is stored, along with its first few characters in Prefix. The
+// hash is left out of API responses.
+type APIKey struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Hash        string     `json:"hash,omitempty"`
+	TimeCreated time.Time  `json:"time_created"`
+	TimeRevoked *time.Time `json:"time_revoked"`
+}
+
+// NewAPIKey is an API key that was just created along with the key itself,
+// which cannot be retrieved again.
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Registration is a user that was just created along with their first API
+// key.
+type Registration struct {
+	User   User      `json:"user"`
+	APIKey NewAPIKey `json:"api_key"`
+}
+
+// APIKeyCreateParams are what we require from clients to create an API key.
+type APIKeyCreateParams struct {
+	Name string `json:"name"`
+}
+
+// Validate validates the APIKeyCreateParams.
+func (p APIKeyCreateParams) Validate() error {
+	if p.Name == "" {
+		return NewValidationError(errors.New("missing required field name"))
+	}
+
+	return nil
+}
+
+// userKey is the context key for the authenticated user.
+type userKey struct{}
+
+// WithUser returns a copy of ctx that makes changes on behalf of user, who
+// owns the todo items created with it and only sees their own.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the user stored in ctx by WithUser, if any.
+func UserFrom(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey{}).(User)
+	return user, ok
+}
+
+// OwnerFrom returns the ID of the user stored in ctx by WithUser. Without a
+// user it returns uuid.Nil, which owns the todo items of anonymous requests.
+func OwnerFrom(ctx context.Context) uuid.UUID {
+	user, _ := UserFrom(ctx)
+	return user.ID
+}
+
+// HashAPIKey returns the hash API keys are stored and looked up by.
+func HashAPIKey(key string) string {
+	return hashSecret(key)
+}
+
+// hashSecret returns the hex encoded SHA-256 hash of a randomly generated
+// secret. Secrets have enough entropy that they need no salt or stretching.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns prefix followed by n random bytes, hex encoded.
+func newToken(prefix string, n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// QueryUserByID retrieves a user by their ID.
+func (s *Core) QueryUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	user, err := s.storer.QueryUserByID(ctx, id)
+	if err != nil {
+		return User{}, fmt.Errorf("query user by id: %w", err)
+	}
+
+	user.PasswordHash = ""
+
+	return user, nil
+}
+
+// CreateUser adds a user into the store.
+func (s *Core) CreateUser(ctx context.Context, params UserCreateParams) (User, error) {
+	if err := params.Validate(); err != nil {
+		return User{}, fmt.Errorf("validate: %w", err)
+	}
+
+	now := time.Now()
+
+	user := User{
+		ID:          uuid.New(),
+		Name:        strings.TrimSpace(params.Name),
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	if params.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return User{}, fmt.Errorf("hash password: %w", err)
+		}
+		user.PasswordHash = string(hash)
+	}
+
+	if err := s.storer.CreateUser(ctx, user); err != nil {
+		return User{}, fmt.Errorf("create user: %w", err)
+	}
+
+	user.PasswordHash = ""
+
+	return user, nil
+}
+
+// Register creates a user along with a first API key, named "default", which
+// is how a new user authenticates.
+func (s *Core) Register(ctx context.Context, params UserCreateParams) (Registration, error) {
+	var reg Registration
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		user, err := txCore.CreateUser(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		key, err := txCore.CreateAPIKey(WithUser(ctx, user), APIKeyCreateParams{Name: "default"})
+		if err != nil {
+			return err
+		}
+
+		reg = Registration{User: user, APIKey: key}
+
+		return nil
+	})
+	if err != nil {
+		return Registration{}, err
+	}
+
+	return reg, nil
+}
+
+// QueryAPIKeys retrieves the API keys of the user in ctx, including revoked
+// ones.
+func (s *Core) QueryAPIKeys(ctx context.Context) ([]APIKey, error) {
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	keys, err := s.storer.QueryAPIKeys(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// QueryAPIKeyByID retrieves an API key of the user in ctx by its ID.
+func (s *Core) QueryAPIKeyByID(ctx context.Context, id uuid.UUID) (APIKey, error) {
+	key, err := s.storer.QueryAPIKeyByID(ctx, id)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("query api key by id: %w", err)
+	}
+
+	if user, ok := UserFrom(ctx); !ok || key.UserID != user.ID {
+		return APIKey{}, fmt.Errorf("query api key by id: %w", ErrAPIKeyNotFound)
+	}
+
+	return key, nil
+}
+
+// CreateAPIKey creates an API key for the user in ctx.
+func (s *Core) CreateAPIKey(ctx context.Context, params APIKeyCreateParams) (NewAPIKey, error) {
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return NewAPIKey{}, ErrUnauthenticated
+	}
+
+	if err := params.Validate(); err != nil {
+		return NewAPIKey{}, fmt.Errorf("validate: %w", err)
+	}
+
+	secret, err := newToken(APIKeyPrefix, 24)
+	if err != nil {
+		return NewAPIKey{}, fmt.Errorf("generate api key: %w", err)
+	}
+
+	key := APIKey{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Name:        params.Name,
+		Prefix:      secret[:apiKeyDisplayLength],
+		Hash:        HashAPIKey(secret),
+		TimeCreated: time.Now(),
+	}
+
+	if err := s.storer.CreateAPIKey(ctx, key); err != nil {
+		return NewAPIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+
+	return NewAPIKey{APIKey: key, Key: secret}, nil
+}
+
+// RevokeAPIKey revokes an API key so that it no longer authenticates
+// requests. Revoking a key that is already revoked does nothing.
+func (s *Core) RevokeAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	if key.TimeRevoked != nil {
+		return key, nil
+	}
+
+	now := time.Now()
+	key.TimeRevoked = &now
+
+	if err := s.storer.UpdateAPIKey(ctx, key); err != nil {
+		return APIKey{}, fmt.Errorf("update api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Authenticate returns the user the API key given by secret belongs to. It
+// returns ErrUnauthenticated when the key is unknown or revoked.
+func (s *Core) Authenticate(ctx context.Context, secret string) (User, error) {
+	if !strings.HasPrefix(secret, APIKeyPrefix) {
+		return User{}, ErrUnauthenticated
+	}
+
+	key, err := s.storer.QueryAPIKeyByHash(ctx, HashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return User{}, ErrUnauthenticated
+		}
+		return User{}, fmt.Errorf("query api key: %w", err)
+	}
+
+	if key.TimeRevoked != nil {
+		return User{}, ErrUnauthenticated
+	}
+
+	user, err := s.storer.QueryUserByID(ctx, key.UserID)
+	if err != nil {
+		return User{}, fmt.Errorf("query user: %w", err)
+	}
+
+	user.PasswordHash = ""
+
+	return user, nil
+}
@@ -0,0 +1,201 @@
+package todo_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestUserAPIKeys(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	reg, err := todoCore.Register(ctx, todo.UserCreateParams{Name: " alice "})
+	if err != nil {
+		t.Fatalf("register: expected nil error, got %v", err)
+	}
+
+	user := reg.User
+	if user.Name != "alice" {
+		t.Fatalf("register: expected name to be trimmed, got %q", user.Name)
+	}
+
+	if _, err := todoCore.Register(ctx, todo.UserCreateParams{Name: "alice"}); !errors.Is(err, todo.ErrUserExists) {
+		t.Fatalf("register: expected %v, got %v", todo.ErrUserExists, err)
+	}
+
+	if _, err := todoCore.CreateAPIKey(ctx, todo.APIKeyCreateParams{Name: "laptop"}); !errors.Is(err, todo.ErrUnauthenticated) {
+		t.Fatalf("create api key: expected %v without a user, got %v", todo.ErrUnauthenticated, err)
+	}
+
+	userCtx := todo.WithUser(ctx, user)
+
+	key, err := todoCore.CreateAPIKey(userCtx, todo.APIKeyCreateParams{Name: "laptop"})
+	if err != nil {
+		t.Fatalf("create api key: expected nil error, got %v", err)
+	}
+	if !strings.HasPrefix(key.Key, todo.APIKeyPrefix) || !strings.HasPrefix(key.Key, key.Prefix) {
+		t.Fatalf("create api key: expected key %q to start with prefix %q", key.Key, key.Prefix)
+	}
+	if key.Hash == key.Key || strings.Contains(key.Hash, key.Key) {
+		t.Fatal("create api key: expected the key to be stored hashed")
+	}
+
+	got, err := todoCore.Authenticate(ctx, key.Key)
+	if err != nil {
+		t.Fatalf("authenticate: expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(user, got); diff != "" {
+		t.Fatalf("authenticate: mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, secret := range []string{"", "nope", todo.APIKeyPrefix + "nope", key.Hash} {
+		if _, err := todoCore.Authenticate(ctx, secret); !errors.Is(err, todo.ErrUnauthenticated) {
+			t.Fatalf("authenticate %q: expected %v, got %v", secret, todo.ErrUnauthenticated, err)
+		}
+	}
+
+	// Other users cannot see or revoke the key.
+	other, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "bob"})
+	if err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.QueryAPIKeyByID(todo.WithUser(ctx, other), key.ID); !errors.Is(err, todo.ErrAPIKeyNotFound) {
+		t.Fatalf("query api key by id: expected %v for another user, got %v", todo.ErrAPIKeyNotFound, err)
+	}
+
+	keys, err := todoCore.QueryAPIKeys(todo.WithUser(ctx, other))
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("query api keys: expected no keys for another user, got %v, %v", keys, err)
+	}
+
+	stored, err := todoCore.QueryAPIKeyByID(userCtx, key.ID)
+	if err != nil {
+		t.Fatalf("query api key by id: expected nil error, got %v", err)
+	}
+
+	revoked, err := todoCore.RevokeAPIKey(userCtx, stored)
+	if err != nil || revoked.TimeRevoked == nil {
+		t.Fatalf("revoke api key: expected a revoked key, got %v, %v", revoked, err)
+	}
+
+	// Revoking a key again keeps the original revocation time.
+	again, err := todoCore.RevokeAPIKey(userCtx, revoked)
+	if err != nil || !again.TimeRevoked.Equal(*revoked.TimeRevoked) {
+		t.Fatalf("revoke api key: expected nothing to change, got %v, %v", again, err)
+	}
+
+	if _, err := todoCore.Authenticate(ctx, key.Key); !errors.Is(err, todo.ErrUnauthenticated) {
+		t.Fatalf("authenticate: expected %v for a revoked key, got %v", todo.ErrUnauthenticated, err)
+	}
+
+	// The key created on registration still works.
+	if _, err := todoCore.Authenticate(ctx, reg.APIKey.Key); err != nil {
+		t.Fatalf("authenticate: expected nil error, got %v", err)
+	}
+
+	keys, err = todoCore.QueryAPIKeys(userCtx)
+	if err != nil || len(keys) != 2 || keys[1].TimeRevoked == nil {
+		t.Fatalf("query api keys: expected the registration key and the revoked key, got %v, %v", keys, err)
+	}
+}
+
+func TestTodoOwners(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	alice, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "alice"})
+	if err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	bob, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: "bob"})
+	if err != nil {
+		t.Fatalf("create user: expected nil error, got %v", err)
+	}
+
+	aliceCtx := todo.WithUser(ctx, alice)
+	bobCtx := todo.WithUser(ctx, bob)
+
+	td, err := todoCore.Create(aliceCtx, todo.TodoCreateParams{Text: "mine", Priority: todo.PriorityLow, Tags: []string{"home"}})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+	if td.OwnerID != alice.ID {
+		t.Fatalf("create: expected owner %v, got %v", alice.ID, td.OwnerID)
+	}
+
+	if _, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "anonymous", Priority: todo.PriorityLow}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	for name, c := range map[string]context.Context{"bob": bobCtx, "anonymous": ctx} {
+		if _, err := todoCore.QueryByID(c, td.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("query by id as %s: expected %v, got %v", name, todo.ErrNotFound, err)
+		}
+
+		if _, err := todoCore.Update(c, td, todo.TodoUpdateParams{}); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("update as %s: expected %v, got %v", name, todo.ErrNotFound, err)
+		}
+
+		if err := todoCore.Delete(c, td); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("delete as %s: expected %v, got %v", name, todo.ErrNotFound, err)
+		}
+
+		if _, err := todoCore.QueryEvents(c, td.ID); !errors.Is(err, todo.ErrNotFound) {
+			t.Fatalf("query events as %s: expected %v, got %v", name, todo.ErrNotFound, err)
+		}
+
+		// A todo item of another user cannot be used as a parent.
+		var vErr todo.ValidationError
+		parent := td.ID
+		if _, err := todoCore.Create(c, todo.TodoCreateParams{Text: "child", Priority: todo.PriorityLow, ParentID: &parent}); !errors.As(err, &vErr) {
+			t.Fatalf("create child as %s: expected validation error, got %v", name, err)
+		}
+	}
+
+	todos, err := todoCore.Query(bobCtx, todo.QueryOptions{})
+	if err != nil || len(todos) != 0 {
+		t.Fatalf("query: expected no todos for bob, got %v, %v", todos, err)
+	}
+
+	todos, err = todoCore.Query(ctx, todo.QueryOptions{})
+	if err != nil || len(todos) != 1 || todos[0].Text != "anonymous" {
+		t.Fatalf("query: expected only the anonymous todo, got %v, %v", todos, err)
+	}
+
+	tags, err := todoCore.QueryTags(bobCtx)
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("query tags: expected no tags for bob, got %v, %v", tags, err)
+	}
+
+	if err := todoCore.Delete(aliceCtx, td); err != nil {
+		t.Fatalf("delete: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.QueryTrashByID(bobCtx, td.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query trash by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	trashed, err := todoCore.QueryTrashByID(aliceCtx, td.ID)
+	if err != nil {
+		t.Fatalf("query trash by id: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.Restore(bobCtx, trashed); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("restore: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := todoCore.Restore(aliceCtx, trashed); err != nil {
+		t.Fatalf("restore: expected nil error, got %v", err)
+	}
+}
@@ -0,0 +1,603 @@
+package todo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// Headers sent with every webhook delivery. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp, a period and the request body, keyed with the
+// secret of the webhook and prefixed with "sha256=".
+const (
+	HeaderWebhookEvent     = "X-Todo-Event"
+	HeaderWebhookDelivery  = "X-Todo-Delivery"
+	HeaderWebhookTimestamp = "X-Todo-Timestamp"
+	HeaderWebhookSignature = "X-Todo-Signature"
+)
+
+// Webhook delivery defaults.
+const (
+	DefaultWebhookMaxTries = 8
+	DefaultWebhookBackoff  = 30 * time.Second
+	maxWebhookBackoff      = 6 * time.Hour
+
+	// deliveryBatch is the number of deliveries claimed at once and
+	// deliveryLease is how long they are kept from other workers while
+	// they are attempted.
+	deliveryBatch = 20
+	deliveryLease = 5 * time.Minute
+)
+
+// WebhookStorer represents the behavior this package needs to manage webhooks
+// and their deliveries.
+//
+// DeleteWebhook also deletes the deliveries of the webhook. QueryDeliveries
+// returns the deliveries of a webhook newest first.
+//
+// ClaimDeliveries returns up to limit pending deliveries whose next attempt is
+// due at now, oldest first, and moves their next attempt to until so that no
+// one else claims them in the meantime. UpdateDelivery stores the status and
+// next attempt of a delivery along with any attempts the store does not have
+// yet. Attempts are only ever appended.
+type WebhookStorer interface {
+	QueryWebhooks(ctx context.Context, filter WebhookFilter) ([]Webhook, error)
+	QueryWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error)
+	CreateWebhook(ctx context.Context, wh Webhook) error
+	UpdateWebhook(ctx context.Context, wh Webhook) error
+	DeleteWebhook(ctx context.Context, wh Webhook) error
+	QueryDeliveries(ctx context.Context, webhookID uuid.UUID) ([]Delivery, error)
+	QueryDeliveryByID(ctx context.Context, id uuid.UUID) (Delivery, error)
+	CreateDelivery(ctx context.Context, d Delivery) error
+	ClaimDeliveries(ctx context.Context, now time.Time, until time.Time, limit int) ([]Delivery, error)
+	UpdateDelivery(ctx context.Context, d Delivery) error
+}
+
+// WebhookFilter narrows down the webhooks returned by QueryWebhooks. Fields
+// that are nil match every webhook.
+type WebhookFilter struct {
+	OwnerID *uuid.UUID
+	// Subscribed only matches the webhooks that are subscribed to the given
+	// type of event, as reported by Webhook.Subscribed.
+	Subscribed *EventType
+}
+
+// Webhook represents a subscription to the changes of todo items. Changes are
+// sent to URL for every type in Events, or for every type of change when
+// Events is empty.
+type Webhook struct {
+	ID          uuid.UUID   `json:"id"`
+	OwnerID     uuid.UUID   `json:"owner_id"`
+	URL         string      `json:"url"`
+	Secret      string      `json:"secret,omitempty"`
+	Events      []EventType `json:"events"`
+	Active      bool        `json:"active"`
+	TimeCreated time.Time   `json:"time_created"`
+	TimeUpdated time.Time   `json:"time_updated"`
+}
+
+// Subscribed reports whether the webhook receives changes of type typ.
+func (wh Webhook) Subscribed(typ EventType) bool {
+	if !wh.Active {
+		return false
+	}
+
+	if len(wh.Events) == 0 {
+		return true
+	}
+
+	for _, t := range wh.Events {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookCreateParams are what we require from clients to create a webhook. A
+// secret is generated when none is given.
+type WebhookCreateParams struct {
+	URL    string      `json:"url"`
+	Secret string      `json:"secret"`
+	Events []EventType `json:"events"`
+	Active *bool       `json:"active"`
+}
+
+// Validate validates the WebhookCreateParams.
+func (p WebhookCreateParams) Validate() error {
+	errs := []error{validateWebhookURL(p.URL), validateEvents(p.Events)}
+
+	if err := errors.Join(errs...); err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// WebhookUpdateParams represents the information that clients can modify for
+// a webhook.
+type WebhookUpdateParams struct {
+	URL    *string      `json:"url"`
+	Secret *string      `json:"secret"`
+	Events *[]EventType `json:"events"`
+	Active *bool        `json:"active"`
+}
+
+// Validate validates the WebhookUpdateParams.
+func (p WebhookUpdateParams) Validate() error {
+	errs := make([]error, 0)
+
+	if p.URL != nil {
+		errs = append(errs, validateWebhookURL(*p.URL))
+	}
+	if p.Secret != nil && *p.Secret == "" {
+		errs = append(errs, errors.New("secret must not be empty"))
+	}
+	if p.Events != nil {
+		errs = append(errs, validateEvents(*p.Events))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// validateWebhookURL checks that s is an absolute HTTP or HTTPS URL.
+func validateWebhookURL(s string) error {
+	if s == "" {
+		return errors.New("missing required field url")
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q: must be an absolute http or https URL", s)
+	}
+
+	return nil
+}
+
+// validateEvents checks that every type in events is known.
+func validateEvents(events []EventType) error {
+	for _, t := range events {
+		switch t {
+		case EventCreated, EventUpdated, EventDeleted, EventRestored:
+		default:
+			return fmt.Errorf("invalid event %q: must be one of created, updated, deleted or restored", t)
+		}
+	}
+
+	return nil
+}
+
+// DeliveryStatus is an enum that represents the state of a webhook delivery.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are waiting for their next attempt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded deliveries were accepted by the receiver.
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead deliveries failed too many times and are no longer
+	// attempted unless they are redelivered.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Delivery is a change to a todo item queued for a webhook. Tries counts the
+// attempts since the delivery was last queued, while Attempts logs every
+// attempt.
+type Delivery struct {
+	ID            uuid.UUID         `json:"id"`
+	WebhookID     uuid.UUID         `json:"webhook_id"`
+	EventID       uuid.UUID         `json:"event_id"`
+	EventType     EventType         `json:"event_type"`
+	Payload       json.RawMessage   `json:"payload"`
+	Status        DeliveryStatus    `json:"status"`
+	Tries         int               `json:"tries"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at"`
+	Attempts      []DeliveryAttempt `json:"attempts"`
+	TimeCreated   time.Time         `json:"time_created"`
+	TimeUpdated   time.Time         `json:"time_updated"`
+}
+
+// DeliveryAttempt is the outcome of sending a delivery once. StatusCode is 0
+// when no response was received.
+type DeliveryAttempt struct {
+	Number      int       `json:"number"`
+	StatusCode  int       `json:"status_code"`
+	Error       string    `json:"error"`
+	TimeCreated time.Time `json:"time_created"`
+}
+
+// WebhookPayload is the body of a webhook delivery.
+type WebhookPayload struct {
+	Event Event `json:"event"`
+	Todo  Todo  `json:"todo"`
+}
+
+// WithWebhookRetries sets how many times a delivery is attempted before it is
+// dead and the delay before the first retry, which doubles with every further
+// retry. The defaults are DefaultWebhookMaxTries and DefaultWebhookBackoff.
+func WithWebhookRetries(maxTries int, backoff time.Duration) Option {
+	return func(c *Core) {
+		c.webhookMaxTries = maxTries
+		c.webhookBackoff = backoff
+	}
+}
+
+// SignWebhook returns the signature of a webhook delivery sent at timestamp,
+// in seconds since the Unix epoch, as found in HeaderWebhookSignature.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reports whether the signature in header matches body and was
+// made with secret. Receivers should also reject timestamps too far in the
+// past to guard against replays.
+func VerifyWebhook(secret string, header http.Header, body []byte) bool {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderWebhookTimestamp), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	want := SignWebhook(secret, timestamp, body)
+
+	return hmac.Equal([]byte(want), []byte(header.Get(HeaderWebhookSignature)))
+}
+
+// QueryWebhooks retrieves the webhooks of the user in ctx.
+func (s *Core) QueryWebhooks(ctx context.Context) ([]Webhook, error) {
+	webhooks, err := s.storer.QueryWebhooks(ctx, WebhookFilter{OwnerID: ownerFilter(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// QueryWebhookByID retrieves a webhook of the user in ctx by its ID.
+func (s *Core) QueryWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error) {
+	wh, err := s.storer.QueryWebhookByID(ctx, id)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("query webhook by id: %w", err)
+	}
+
+	if wh.OwnerID != OwnerFrom(ctx) {
+		return Webhook{}, fmt.Errorf("query webhook by id: %w", ErrWebhookNotFound)
+	}
+
+	return wh, nil
+}
+
+// CreateWebhook adds a webhook into the store for the user in ctx. It receives
+// changes to their todo items only. Webhooks are active unless params says
+// otherwise.
+func (s *Core) CreateWebhook(ctx context.Context, params WebhookCreateParams) (Webhook, error) {
+	if err := params.Validate(); err != nil {
+		return Webhook{}, fmt.Errorf("validate: %w", err)
+	}
+
+	secret := params.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return Webhook{}, fmt.Errorf("generate secret: %w", err)
+		}
+	}
+
+	now := time.Now()
+
+	wh := Webhook{
+		ID:          uuid.New(),
+		OwnerID:     OwnerFrom(ctx),
+		URL:         params.URL,
+		Secret:      secret,
+		Events:      params.Events,
+		Active:      params.Active == nil || *params.Active,
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	if wh.Events == nil {
+		wh.Events = []EventType{}
+	}
+
+	if err := s.storer.CreateWebhook(ctx, wh); err != nil {
+		return Webhook{}, fmt.Errorf("create webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// UpdateWebhook modifies an existing webhook.
+func (s *Core) UpdateWebhook(ctx context.Context, wh Webhook, params WebhookUpdateParams) (Webhook, error) {
+	if err := params.Validate(); err != nil {
+		return Webhook{}, fmt.Errorf("validate: %w", err)
+	}
+
+	if wh.OwnerID != OwnerFrom(ctx) {
+		return Webhook{}, fmt.Errorf("update webhook: %w", ErrWebhookNotFound)
+	}
+
+	if params.URL != nil {
+		wh.URL = *params.URL
+	}
+	if params.Secret != nil {
+		wh.Secret = *params.Secret
+	}
+	if params.Events != nil {
+		wh.Events = *params.Events
+	}
+	if params.Active != nil {
+		wh.Active = *params.Active
+	}
+
+	wh.TimeUpdated = time.Now()
+
+	if err := s.storer.UpdateWebhook(ctx, wh); err != nil {
+		return Webhook{}, fmt.Errorf("update webhook: %w", err)
+	}
+
+	return wh, nil
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries.
+func (s *Core) DeleteWebhook(ctx context.Context, wh Webhook) error {
+	if wh.OwnerID != OwnerFrom(ctx) {
+		return fmt.Errorf("delete webhook: %w", ErrWebhookNotFound)
+	}
+
+	if err := s.storer.DeleteWebhook(ctx, wh); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDeliveries retrieves the deliveries of a webhook of the user in ctx,
+// newest first.
+func (s *Core) QueryDeliveries(ctx context.Context, webhookID uuid.UUID) ([]Delivery, error) {
+	if _, err := s.QueryWebhookByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.storer.QueryDeliveries(ctx, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("query deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// QueryDeliveryByID retrieves a delivery of a webhook of the user in ctx by
+// its ID.
+func (s *Core) QueryDeliveryByID(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	d, err := s.storer.QueryDeliveryByID(ctx, id)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("query delivery by id: %w", err)
+	}
+
+	if _, err := s.QueryWebhookByID(ctx, d.WebhookID); err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return Delivery{}, fmt.Errorf("query delivery by id: %w", ErrDeliveryNotFound)
+		}
+		return Delivery{}, err
+	}
+
+	return d, nil
+}
+
+// Redeliver queues a delivery that is not pending again, to be attempted as
+// soon as possible with a fresh number of tries.
+func (s *Core) Redeliver(ctx context.Context, d Delivery) (Delivery, error) {
+	if d.Status == DeliveryPending {
+		return Delivery{}, NewValidationError(errors.New("delivery is already pending"))
+	}
+
+	now := time.Now()
+
+	d.Status = DeliveryPending
+	d.Tries = 0
+	d.NextAttemptAt = &now
+	d.TimeUpdated = now
+
+	if err := s.storer.UpdateDelivery(ctx, d); err != nil {
+		return Delivery{}, fmt.Errorf("update delivery: %w", err)
+	}
+
+	return d, nil
+}
+
+// DeliverWebhooks attempts the deliveries that are due using client and
+// returns how many were attempted. Failed deliveries are retried with
+// exponential backoff until they run out of tries.
+func (s *Core) DeliverWebhooks(ctx context.Context, client *http.Client) (int, error) {
+	now := time.Now()
+
+	deliveries, err := s.storer.ClaimDeliveries(ctx, now, now.Add(deliveryLease), deliveryBatch)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	for _, d := range deliveries {
+		if err := s.deliver(ctx, client, d); err != nil {
+			return 0, fmt.Errorf("deliver [%s]: %w", d.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// deliver attempts a single delivery and records the outcome.
+func (s *Core) deliver(ctx context.Context, client *http.Client, d Delivery) error {
+	wh, err := s.storer.QueryWebhookByID(ctx, d.WebhookID)
+	if err != nil {
+		// The delivery was deleted along with its webhook.
+		if errors.Is(err, ErrWebhookNotFound) {
+			return nil
+		}
+		return fmt.Errorf("query webhook: %w", err)
+	}
+
+	var statusCode int
+
+	if wh.Active {
+		statusCode, err = send(ctx, client, wh, d)
+	} else {
+		err = errors.New("webhook is not active")
+	}
+
+	now := time.Now()
+
+	attempt := DeliveryAttempt{
+		Number:      len(d.Attempts) + 1,
+		StatusCode:  statusCode,
+		TimeCreated: now,
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	d.Attempts = append(d.Attempts, attempt)
+	d.Tries++
+	d.TimeUpdated = now
+
+	switch {
+	case err == nil:
+		d.Status = DeliverySucceeded
+		d.NextAttemptAt = nil
+	case d.Tries >= s.webhookMaxTries:
+		d.Status = DeliveryDead
+		d.NextAttemptAt = nil
+	default:
+		next := now.Add(s.backoff(d.Tries))
+		d.NextAttemptAt = &next
+	}
+
+	if err := s.storer.UpdateDelivery(ctx, d); err != nil {
+		return fmt.Errorf("update delivery: %w", err)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt of a delivery that failed
+// tries times.
+func (s *Core) backoff(tries int) time.Duration {
+	delay := s.webhookBackoff
+	for i := 1; i < tries && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxWebhookBackoff {
+		delay = maxWebhookBackoff
+	}
+
+	return delay
+}
+
+// send posts the payload of d to the webhook and returns the status code of
+// the response. Any status code other than 2xx is an error.
+func send(ctx context.Context, client *http.Client, wh Webhook, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-webhooks")
+	req.Header.Set(HeaderWebhookEvent, string(d.EventType))
+	req.Header.Set(HeaderWebhookDelivery, d.ID.String())
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(wh.Secret, timestamp, d.Payload))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Read some of the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// enqueue queues a delivery of ev for every webhook of the owner of todo that
+// is subscribed to its type.
+func (s *Core) enqueue(ctx context.Context, ev Event, todo Todo) error {
+	webhooks, err := s.storer.QueryWebhooks(ctx, WebhookFilter{OwnerID: &todo.OwnerID, Subscribed: &ev.Type})
+	if err != nil {
+		return fmt.Errorf("query webhooks: %w", err)
+	}
+
+	var payload json.RawMessage
+
+	for _, wh := range webhooks {
+		if payload == nil {
+			if payload, err = json.Marshal(WebhookPayload{Event: ev, Todo: todo}); err != nil {
+				return fmt.Errorf("encode payload: %w", err)
+			}
+		}
+
+		d := Delivery{
+			ID:            uuid.New(),
+			WebhookID:     wh.ID,
+			EventID:       ev.ID,
+			EventType:     ev.Type,
+			Payload:       payload,
+			Status:        DeliveryPending,
+			NextAttemptAt: &ev.TimeCreated,
+			Attempts:      []DeliveryAttempt{},
+			TimeCreated:   ev.TimeCreated,
+			TimeUpdated:   ev.TimeCreated,
+		}
+
+		if err := s.storer.CreateDelivery(ctx, d); err != nil {
+			return fmt.Errorf("create delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// newSecret returns a random webhook secret.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
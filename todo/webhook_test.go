@@ -0,0 +1,184 @@
+package todo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+// receiver is a webhook receiver that records the requests it gets.
+type receiver struct {
+	mutex    sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) respond(status int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.status = status
+}
+
+func TestTodoWebhooks(t *testing.T) {
+	ctx := context.Background()
+
+	recv := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	// Without a backoff, failed deliveries are due again right away.
+	todoCore := todo.NewCore(todomemory.NewStore(), todo.WithWebhookRetries(2, 0))
+
+	wh, err := todoCore.CreateWebhook(ctx, todo.WebhookCreateParams{
+		URL:    server.URL,
+		Events: []todo.EventType{todo.EventCreated},
+	})
+	if err != nil {
+		t.Fatalf("create webhook: expected nil error, got %v", err)
+	}
+	if wh.Secret == "" || !wh.Active {
+		t.Fatalf("create webhook: expected an active webhook with a secret, got %+v", wh)
+	}
+
+	deliver := func(want int) {
+		t.Helper()
+
+		n, err := todoCore.DeliverWebhooks(ctx, server.Client())
+		if err != nil {
+			t.Fatalf("deliver webhooks: expected nil error, got %v", err)
+		}
+		if n != want {
+			t.Fatalf("deliver webhooks: expected %d deliveries, got %d", want, n)
+		}
+	}
+
+	td, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "deliver", Priority: todo.PriorityLow})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	// The webhook is not subscribed to updates.
+	text := "not delivered"
+	if _, err := todoCore.Update(ctx, td, todo.TodoUpdateParams{Text: &text}); err != nil {
+		t.Fatalf("update: expected nil error, got %v", err)
+	}
+
+	deliver(1)
+	deliver(0)
+
+	req, body := recv.requests[0], recv.bodies[0]
+
+	if !todo.VerifyWebhook(wh.Secret, req.Header, body) {
+		t.Fatal("verify webhook: expected a valid signature")
+	}
+	if todo.VerifyWebhook("wrong", req.Header, body) {
+		t.Fatal("verify webhook: expected an invalid signature for the wrong secret")
+	}
+	if got := req.Header.Get(todo.HeaderWebhookEvent); got != string(todo.EventCreated) {
+		t.Fatalf("deliver: expected event header %q, got %q", todo.EventCreated, got)
+	}
+
+	var payload todo.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("decode payload: expected nil error, got %v", err)
+	}
+	if payload.Event.Type != todo.EventCreated || payload.Todo.ID != td.ID || payload.Todo.Text != "deliver" {
+		t.Fatalf("decode payload: unexpected payload %+v", payload)
+	}
+
+	// Failed deliveries are retried until they run out of tries.
+	recv.respond(http.StatusInternalServerError)
+
+	if _, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "fail", Priority: todo.PriorityLow}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	deliver(1)
+	deliver(1)
+	deliver(0)
+
+	deliveries, err := todoCore.QueryDeliveries(ctx, wh.ID)
+	if err != nil {
+		t.Fatalf("query deliveries: expected nil error, got %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("query deliveries: expected 2 deliveries, got %d", len(deliveries))
+	}
+
+	failed, succeeded := deliveries[0], deliveries[1]
+
+	if succeeded.Status != todo.DeliverySucceeded || len(succeeded.Attempts) != 1 || succeeded.Attempts[0].StatusCode != http.StatusNoContent {
+		t.Fatalf("query deliveries: expected a successful delivery, got %+v", succeeded)
+	}
+	if failed.Status != todo.DeliveryDead || len(failed.Attempts) != 2 || failed.Attempts[1].StatusCode != http.StatusInternalServerError || failed.NextAttemptAt != nil {
+		t.Fatalf("query deliveries: expected a dead delivery, got %+v", failed)
+	}
+
+	// Dead deliveries can be redelivered by hand.
+	recv.respond(http.StatusOK)
+
+	if _, err := todoCore.Redeliver(ctx, failed); err != nil {
+		t.Fatalf("redeliver: expected nil error, got %v", err)
+	}
+
+	deliver(1)
+
+	redelivered, err := todoCore.QueryDeliveryByID(ctx, failed.ID)
+	if err != nil {
+		t.Fatalf("query delivery by id: expected nil error, got %v", err)
+	}
+	if redelivered.Status != todo.DeliverySucceeded || redelivered.Tries != 1 || len(redelivered.Attempts) != 3 {
+		t.Fatalf("redeliver: expected a successful third attempt, got %+v", redelivered)
+	}
+
+	// Pending deliveries cannot be redelivered.
+	pending, err := todoCore.Redeliver(ctx, redelivered)
+	if err != nil {
+		t.Fatalf("redeliver: expected nil error, got %v", err)
+	}
+
+	var vErr todo.ValidationError
+	if _, err := todoCore.Redeliver(ctx, pending); !errors.As(err, &vErr) {
+		t.Fatalf("redeliver: expected a validation error, got %v", err)
+	}
+
+	// Deliveries are not queued for inactive webhooks.
+	active := false
+	if _, err := todoCore.UpdateWebhook(ctx, wh, todo.WebhookUpdateParams{Active: &active}); err != nil {
+		t.Fatalf("update webhook: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.Create(ctx, todo.TodoCreateParams{Text: "inactive", Priority: todo.PriorityLow}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	deliveries, err = todoCore.QueryDeliveries(ctx, wh.ID)
+	if err != nil {
+		t.Fatalf("query deliveries: expected nil error, got %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("query deliveries: expected no new deliveries, got %d", len(deliveries))
+	}
+}
@@ -0,0 +1,598 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrMemberNotFound    = errors.New("member not found")
+)
+
+// DefaultWorkspaceID is the ID of the default workspace. It always exists and
+// is open to every user, including anonymous ones. Its settings cannot be
+// changed and it cannot be deleted.
+var DefaultWorkspaceID = uuid.Nil
+
+// WorkspaceStorer represents the behavior this package needs to manage
+// workspaces and their members.
+//
+// Every todo item and list belongs to a workspace. Stores must only create,
+// find, change and delete the todo items and lists of the workspace in ctx,
+// as given by WorkspaceFrom, including those reached through their history or
+// their shares. Workspaces and members themselves are not scoped.
+//
+// QueryWorkspaces returns the workspaces userID is a member of, or every
+// workspace when it is nil, ordered by name. QueryMembers returns the members
+// of a workspace in the order they joined. PutMember adds a member or replaces
+// the role of an existing one. DeleteWorkspace also deletes the todo items,
+// lists and members of the workspace.
+type WorkspaceStorer interface {
+	QueryWorkspaces(ctx context.Context, userID *uuid.UUID) ([]Workspace, error)
+	QueryWorkspaceByID(ctx context.Context, id uuid.UUID) (Workspace, error)
+	CreateWorkspace(ctx context.Context, ws Workspace) error
+	UpdateWorkspace(ctx context.Context, ws Workspace) error
+	DeleteWorkspace(ctx context.Context, ws Workspace) error
+	QueryMembers(ctx context.Context, workspaceID uuid.UUID) ([]Member, error)
+	QueryMember(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (Member, error)
+	PutMember(ctx context.Context, member Member) error
+	DeleteMember(ctx context.Context, member Member) error
+}
+
+// Workspace is a tenant, such as a team, that keeps its todo items and lists
+// apart from those of every other workspace.
+type Workspace struct {
+	ID          uuid.UUID         `json:"id"`
+	Name        string            `json:"name"`
+	Settings    WorkspaceSettings `json:"settings"`
+	TimeCreated time.Time         `json:"time_created"`
+	TimeUpdated time.Time         `json:"time_updated"`
+}
+
+// WorkspaceSettings change how todo items behave in a workspace.
+type WorkspaceSettings struct {
+	// AllowedPriorities are the priorities todo items may be given. Every
+	// priority is allowed when it is empty.
+	AllowedPriorities []Priority `json:"allowed_priorities"`
+
+	// DefaultListID is the list todo items are created in unless told
+	// otherwise, and where the todo items of deleted lists are moved to. It
+	// cannot be deleted.
+	DefaultListID uuid.UUID `json:"default_list_id"`
+}
+
+// Allows reports whether todo items may be given priority p.
+func (s WorkspaceSettings) Allows(p Priority) bool {
+	if len(s.AllowedPriorities) == 0 {
+		return true
+	}
+
+	for _, allowed := range s.AllowedPriorities {
+		if allowed == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPriority returns a validation error if todo items may not be given
+// priority p in the workspace.
+func (s WorkspaceSettings) checkPriority(p Priority) error {
+	if s.Allows(p) {
+		return nil
+	}
+
+	return NewValidationError(fmt.Errorf("priority %q is not allowed in this workspace", p))
+}
+
+// MemberRole is what a member may do in a workspace.
+type MemberRole string
+
+// Roles members of a workspace can have. Members may use the workspace, and
+// admins may also change its settings and members and delete it.
+const (
+	MemberRoleMember MemberRole = "member"
+	MemberRoleAdmin  MemberRole = "admin"
+)
+
+// Valid reports whether r is a known role.
+func (r MemberRole) Valid() bool {
+	return r == MemberRoleMember || r == MemberRoleAdmin
+}
+
+// Member gives a user a role in a workspace.
+type Member struct {
+	WorkspaceID uuid.UUID  `json:"workspace_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Role        MemberRole `json:"role"`
+	TimeCreated time.Time  `json:"time_created"`
+}
+
+// MemberWithName is a member along with the name of their user.
+type MemberWithName struct {
+	Member
+	Name string `json:"name"`
+}
+
+// WorkspaceCreateParams are what we require from clients to create a
+// workspace.
+type WorkspaceCreateParams struct {
+	Name              string     `json:"name"`
+	AllowedPriorities []Priority `json:"allowed_priorities"`
+}
+
+// Validate validates the WorkspaceCreateParams.
+func (p WorkspaceCreateParams) Validate() error {
+	errs := make([]error, 0)
+
+	if strings.TrimSpace(p.Name) == "" {
+		errs = append(errs, errors.New("missing required field name"))
+	}
+
+	errs = append(errs, validatePriorities(p.AllowedPriorities)...)
+
+	if err := errors.Join(errs...); err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// WorkspaceUpdateParams represents the information that clients can modify
+// for a workspace.
+type WorkspaceUpdateParams struct {
+	Name              *string     `json:"name"`
+	AllowedPriorities *[]Priority `json:"allowed_priorities"`
+	DefaultListID     *uuid.UUID  `json:"default_list_id"`
+}
+
+// Validate validates the WorkspaceUpdateParams.
+func (p WorkspaceUpdateParams) Validate() error {
+	errs := make([]error, 0)
+
+	if p.Name != nil && strings.TrimSpace(*p.Name) == "" {
+		errs = append(errs, errors.New("missing required field name"))
+	}
+
+	if p.AllowedPriorities != nil {
+		errs = append(errs, validatePriorities(*p.AllowedPriorities)...)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return NewValidationError(err)
+	}
+
+	return nil
+}
+
+// validatePriorities returns an error for each priority that is unknown or
+// repeated.
+func validatePriorities(priorities []Priority) []error {
+	errs := make([]error, 0)
+
+	seen := make(map[Priority]bool, len(priorities))
+	for _, p := range priorities {
+		if p.Rank() == 0 {
+			errs = append(errs, fmt.Errorf(
+				"invalid priority %q: must be one of [%v, %v, %v]",
+				string(p),
+				PriorityLow,
+				PriorityMedium,
+				PriorityHigh,
+			))
+		}
+		if seen[p] {
+			errs = append(errs, fmt.Errorf("duplicate priority %q", p))
+		}
+		seen[p] = true
+	}
+
+	return errs
+}
+
+// MemberParams represents the parameters for adding the user named User to a
+// workspace.
+type MemberParams struct {
+	User string     `json:"user"`
+	Role MemberRole `json:"role"`
+}
+
+// Validate validates MemberParams.
+func (p MemberParams) Validate() error {
+	if p.User == "" {
+		return NewValidationError(errors.New("missing required field user"))
+	}
+
+	if !p.Role.Valid() {
+		return NewValidationError(fmt.Errorf(
+			"invalid role %q: must be one of [%v, %v]",
+			string(p.Role),
+			MemberRoleMember,
+			MemberRoleAdmin,
+		))
+	}
+
+	return nil
+}
+
+// workspaceKey is the context key for the workspace of a request.
+type workspaceKey struct{}
+
+// WithWorkspace returns a copy of ctx that works in the workspace given by id.
+// Use Core.EnterWorkspace to check that the user in ctx may do so first.
+func WithWorkspace(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, id)
+}
+
+// WorkspaceFrom returns the ID of the workspace stored in ctx by
+// WithWorkspace, or DefaultWorkspaceID when there is none.
+func WorkspaceFrom(ctx context.Context) uuid.UUID {
+	id, ok := ctx.Value(workspaceKey{}).(uuid.UUID)
+	if !ok {
+		return DefaultWorkspaceID
+	}
+
+	return id
+}
+
+// EnterWorkspace returns a copy of ctx that works in the workspace given by
+// id. The user in ctx must be a member of it unless it is the default
+// workspace. It returns ErrWorkspaceNotFound otherwise so that workspaces are
+// not given away.
+func (s *Core) EnterWorkspace(ctx context.Context, id uuid.UUID) (context.Context, error) {
+	if _, err := s.QueryWorkspaceByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return WithWorkspace(ctx, id), nil
+}
+
+// QueryWorkspaces retrieves the default workspace followed by the workspaces
+// the user in ctx is a member of, ordered by name.
+func (s *Core) QueryWorkspaces(ctx context.Context) ([]Workspace, error) {
+	def, err := s.storer.QueryWorkspaceByID(ctx, DefaultWorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("query default workspace: %w", err)
+	}
+
+	workspaces := []Workspace{def}
+
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return workspaces, nil
+	}
+
+	member, err := s.storer.QueryWorkspaces(ctx, &user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query workspaces: %w", err)
+	}
+
+	return append(workspaces, member...), nil
+}
+
+// QueryWorkspaceByID retrieves a workspace the user in ctx may use by its ID.
+func (s *Core) QueryWorkspaceByID(ctx context.Context, id uuid.UUID) (Workspace, error) {
+	ws, err := s.storer.QueryWorkspaceByID(ctx, id)
+	if err != nil {
+		return Workspace{}, fmt.Errorf("query workspace by id: %w", err)
+	}
+
+	if _, err := s.workspaceRole(ctx, ws); err != nil {
+		return Workspace{}, fmt.Errorf("query workspace by id: %w", err)
+	}
+
+	return ws, nil
+}
+
+// CreateWorkspace creates a workspace with the user in ctx as its admin,
+// along with an inbox that is its default list.
+func (s *Core) CreateWorkspace(ctx context.Context, params WorkspaceCreateParams) (Workspace, error) {
+	if err := params.Validate(); err != nil {
+		return Workspace{}, fmt.Errorf("validate: %w", err)
+	}
+
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return Workspace{}, ErrUnauthenticated
+	}
+
+	now := time.Now()
+
+	ws := Workspace{
+		ID:   uuid.New(),
+		Name: strings.TrimSpace(params.Name),
+		Settings: WorkspaceSettings{
+			AllowedPriorities: sortedPriorities(params.AllowedPriorities),
+			DefaultListID:     uuid.New(),
+		},
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	inbox := List{
+		ID:          ws.Settings.DefaultListID,
+		WorkspaceID: ws.ID,
+		OwnerID:     user.ID,
+		Name:        "Inbox",
+		TimeCreated: now,
+		TimeUpdated: now,
+	}
+
+	admin := Member{
+		WorkspaceID: ws.ID,
+		UserID:      user.ID,
+		Role:        MemberRoleAdmin,
+		TimeCreated: now,
+	}
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		if err := txCore.storer.CreateWorkspace(ctx, ws); err != nil {
+			return fmt.Errorf("create workspace: %w", err)
+		}
+
+		if err := txCore.storer.CreateList(WithWorkspace(ctx, ws.ID), inbox); err != nil {
+			return fmt.Errorf("create inbox: %w", err)
+		}
+
+		if err := txCore.storer.PutMember(ctx, admin); err != nil {
+			return fmt.Errorf("put member: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Workspace{}, err
+	}
+
+	return ws, nil
+}
+
+// UpdateWorkspace changes the name or settings of a workspace. Only its admins
+// may do so. The default list must be a list of the workspace.
+func (s *Core) UpdateWorkspace(ctx context.Context, ws Workspace, params WorkspaceUpdateParams) (Workspace, error) {
+	if err := params.Validate(); err != nil {
+		return Workspace{}, fmt.Errorf("validate: %w", err)
+	}
+
+	if err := s.authorizeWorkspace(ctx, ws); err != nil {
+		return Workspace{}, fmt.Errorf("update workspace: %w", err)
+	}
+
+	if params.Name != nil {
+		ws.Name = strings.TrimSpace(*params.Name)
+	}
+	if params.AllowedPriorities != nil {
+		ws.Settings.AllowedPriorities = sortedPriorities(*params.AllowedPriorities)
+	}
+	if params.DefaultListID != nil {
+		if err := s.checkList(WithWorkspace(ctx, ws.ID), *params.DefaultListID, OwnerFrom(ctx)); err != nil {
+			return Workspace{}, err
+		}
+		ws.Settings.DefaultListID = *params.DefaultListID
+	}
+
+	ws.TimeUpdated = time.Now()
+
+	if err := s.storer.UpdateWorkspace(ctx, ws); err != nil {
+		return Workspace{}, fmt.Errorf("update workspace: %w", err)
+	}
+
+	return ws, nil
+}
+
+// DeleteWorkspace permanently deletes a workspace along with its todo items
+// and lists. Only its admins may do so.
+func (s *Core) DeleteWorkspace(ctx context.Context, ws Workspace) error {
+	if err := s.authorizeWorkspace(ctx, ws); err != nil {
+		return fmt.Errorf("delete workspace: %w", err)
+	}
+
+	if err := s.storer.DeleteWorkspace(ctx, ws); err != nil {
+		return fmt.Errorf("delete workspace: %w", err)
+	}
+
+	return nil
+}
+
+// QueryMembers retrieves the members of a workspace in the order they joined.
+// The default workspace has no members.
+func (s *Core) QueryMembers(ctx context.Context, ws Workspace) ([]MemberWithName, error) {
+	if _, err := s.workspaceRole(ctx, ws); err != nil {
+		return nil, fmt.Errorf("query members: %w", err)
+	}
+
+	members, err := s.storer.QueryMembers(ctx, ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query members: %w", err)
+	}
+
+	named := make([]MemberWithName, 0, len(members))
+
+	for _, member := range members {
+		user, err := s.storer.QueryUserByID(ctx, member.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("query user [%s]: %w", member.UserID, err)
+		}
+
+		named = append(named, MemberWithName{Member: member, Name: user.Name})
+	}
+
+	return named, nil
+}
+
+// AddMember adds a user to a workspace, or changes their role when they are
+// already a member. Only admins of the workspace may do so.
+func (s *Core) AddMember(ctx context.Context, ws Workspace, params MemberParams) (MemberWithName, error) {
+	if err := params.Validate(); err != nil {
+		return MemberWithName{}, fmt.Errorf("validate: %w", err)
+	}
+
+	if err := s.authorizeWorkspace(ctx, ws); err != nil {
+		return MemberWithName{}, fmt.Errorf("add member: %w", err)
+	}
+
+	var added MemberWithName
+
+	err := s.WithTx(ctx, func(txCore *Core) error {
+		user, err := txCore.storer.QueryUserByName(ctx, params.User)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return NewValidationError(fmt.Errorf("user %s does not exist", params.User))
+			}
+			return fmt.Errorf("query user by name: %w", err)
+		}
+
+		member, err := txCore.storer.QueryMember(ctx, ws.ID, user.ID)
+		if err != nil {
+			if !errors.Is(err, ErrMemberNotFound) {
+				return fmt.Errorf("query member: %w", err)
+			}
+
+			member = Member{
+				WorkspaceID: ws.ID,
+				UserID:      user.ID,
+				TimeCreated: time.Now(),
+			}
+		}
+
+		if member.Role == MemberRoleAdmin && params.Role != MemberRoleAdmin {
+			if err := txCore.checkOtherAdmin(ctx, ws, user.ID); err != nil {
+				return err
+			}
+		}
+
+		member.Role = params.Role
+
+		if err := txCore.storer.PutMember(ctx, member); err != nil {
+			return fmt.Errorf("put member: %w", err)
+		}
+
+		added = MemberWithName{Member: member, Name: user.Name}
+
+		return nil
+	})
+	if err != nil {
+		return MemberWithName{}, err
+	}
+
+	return added, nil
+}
+
+// RemoveMember removes the user given by userID from a workspace. Admins may
+// remove anyone and members may remove themselves, as long as the workspace
+// keeps an admin. It returns ErrMemberNotFound when the user is not a member.
+func (s *Core) RemoveMember(ctx context.Context, ws Workspace, userID uuid.UUID) error {
+	role, err := s.workspaceRole(ctx, ws)
+	if err != nil {
+		return fmt.Errorf("remove member: %w", err)
+	}
+
+	if role != MemberRoleAdmin && userID != OwnerFrom(ctx) {
+		return fmt.Errorf("remove member: %w", ErrForbidden)
+	}
+
+	return s.WithTx(ctx, func(txCore *Core) error {
+		member, err := txCore.storer.QueryMember(ctx, ws.ID, userID)
+		if err != nil {
+			return fmt.Errorf("query member: %w", err)
+		}
+
+		if member.Role == MemberRoleAdmin {
+			if err := txCore.checkOtherAdmin(ctx, ws, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := txCore.storer.DeleteMember(ctx, member); err != nil {
+			return fmt.Errorf("delete member: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// checkOtherAdmin returns a validation error unless the workspace has an admin
+// other than the user given by userID.
+func (s *Core) checkOtherAdmin(ctx context.Context, ws Workspace, userID uuid.UUID) error {
+	members, err := s.storer.QueryMembers(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("query members: %w", err)
+	}
+
+	for _, member := range members {
+		if member.Role == MemberRoleAdmin && member.UserID != userID {
+			return nil
+		}
+	}
+
+	return NewValidationError(errors.New("a workspace must keep at least one admin"))
+}
+
+// workspace returns the workspace in ctx.
+func (s *Core) workspace(ctx context.Context) (Workspace, error) {
+	ws, err := s.storer.QueryWorkspaceByID(ctx, WorkspaceFrom(ctx))
+	if err != nil {
+		return Workspace{}, fmt.Errorf("query workspace: %w", err)
+	}
+
+	return ws, nil
+}
+
+// workspaceRole returns the role of the user in ctx in a workspace. Everyone
+// is a member of the default workspace. It returns ErrWorkspaceNotFound when
+// they are not a member at all.
+func (s *Core) workspaceRole(ctx context.Context, ws Workspace) (MemberRole, error) {
+	if ws.ID == DefaultWorkspaceID {
+		return MemberRoleMember, nil
+	}
+
+	user, ok := UserFrom(ctx)
+	if !ok {
+		return "", ErrWorkspaceNotFound
+	}
+
+	member, err := s.storer.QueryMember(ctx, ws.ID, user.ID)
+	if err != nil {
+		if errors.Is(err, ErrMemberNotFound) {
+			return "", ErrWorkspaceNotFound
+		}
+		return "", fmt.Errorf("query member: %w", err)
+	}
+
+	return member.Role, nil
+}
+
+// authorizeWorkspace returns ErrWorkspaceNotFound when the user in ctx is not
+// a member of a workspace and ErrForbidden when they are not one of its
+// admins. Nobody may change the default workspace.
+func (s *Core) authorizeWorkspace(ctx context.Context, ws Workspace) error {
+	role, err := s.workspaceRole(ctx, ws)
+	if err != nil {
+		return err
+	}
+
+	if ws.ID == DefaultWorkspaceID || role != MemberRoleAdmin {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// sortedPriorities returns a copy of priorities ordered from lowest to highest
+// that is never nil.
+func sortedPriorities(priorities []Priority) []Priority {
+	sorted := make([]Priority, len(priorities))
+	copy(sorted, priorities)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Rank() < sorted[j].Rank()
+	})
+	return sorted
+}
@@ -0,0 +1,197 @@
+package todo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sudomateo/todo/todo"
+	"github.com/sudomateo/todo/todo/stores/todomemory"
+)
+
+func TestWorkspaces(t *testing.T) {
+	ctx := context.Background()
+
+	todoCore := todo.NewCore(todomemory.NewStore())
+
+	users := make(map[string]context.Context)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user, err := todoCore.CreateUser(ctx, todo.UserCreateParams{Name: name})
+		if err != nil {
+			t.Fatalf("create user: expected nil error, got %v", err)
+		}
+		users[name] = todo.WithUser(ctx, user)
+	}
+
+	alice, bob, carol := users["alice"], users["bob"], users["carol"]
+
+	var vErr todo.ValidationError
+
+	for _, params := range []todo.WorkspaceCreateParams{
+		{Name: ""},
+		{Name: "ops", AllowedPriorities: []todo.Priority{"urgent"}},
+		{Name: "ops", AllowedPriorities: []todo.Priority{todo.PriorityLow, todo.PriorityLow}},
+	} {
+		if _, err := todoCore.CreateWorkspace(alice, params); !errors.As(err, &vErr) {
+			t.Fatalf("create workspace %+v: expected validation error, got %v", params, err)
+		}
+	}
+
+	if _, err := todoCore.CreateWorkspace(ctx, todo.WorkspaceCreateParams{Name: "ops"}); !errors.Is(err, todo.ErrUnauthenticated) {
+		t.Fatalf("create workspace: expected %v, got %v", todo.ErrUnauthenticated, err)
+	}
+
+	ws, err := todoCore.CreateWorkspace(alice, todo.WorkspaceCreateParams{
+		Name:              "ops",
+		AllowedPriorities: []todo.Priority{todo.PriorityHigh, todo.PriorityMedium},
+	})
+	if err != nil {
+		t.Fatalf("create workspace: expected nil error, got %v", err)
+	}
+
+	// Non-members may not tell the workspace exists.
+	if _, err := todoCore.EnterWorkspace(bob, ws.ID); !errors.Is(err, todo.ErrWorkspaceNotFound) {
+		t.Fatalf("enter workspace: expected %v, got %v", todo.ErrWorkspaceNotFound, err)
+	}
+
+	if _, err := todoCore.EnterWorkspace(ctx, ws.ID); !errors.Is(err, todo.ErrWorkspaceNotFound) {
+		t.Fatalf("enter workspace: expected %v, got %v", todo.ErrWorkspaceNotFound, err)
+	}
+
+	aliceOps, err := todoCore.EnterWorkspace(alice, ws.ID)
+	if err != nil {
+		t.Fatalf("enter workspace: expected nil error, got %v", err)
+	}
+
+	// Todo items go to the default list of the workspace and must use one of
+	// its priorities.
+	if _, err := todoCore.Create(aliceOps, todo.TodoCreateParams{Text: "deploy", Priority: todo.PriorityLow}); !errors.As(err, &vErr) {
+		t.Fatalf("create: expected validation error, got %v", err)
+	}
+
+	deploy, err := todoCore.Create(aliceOps, todo.TodoCreateParams{Text: "deploy", Priority: todo.PriorityHigh})
+	if err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+	if deploy.WorkspaceID != ws.ID || deploy.ListID != ws.Settings.DefaultListID {
+		t.Fatalf("create: expected workspace %s and list %s, got %s and %s", ws.ID, ws.Settings.DefaultListID, deploy.WorkspaceID, deploy.ListID)
+	}
+
+	low := todo.PriorityLow
+	if _, err := todoCore.Update(aliceOps, deploy, todo.TodoUpdateParams{Priority: &low}); !errors.As(err, &vErr) {
+		t.Fatalf("update: expected validation error, got %v", err)
+	}
+
+	// Todo items of the workspace are not seen from the default workspace.
+	if _, err := todoCore.QueryByID(alice, deploy.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+
+	if _, err := todoCore.Create(alice, todo.TodoCreateParams{Text: "groceries", Priority: todo.PriorityLow}); err != nil {
+		t.Fatalf("create: expected nil error, got %v", err)
+	}
+
+	// The default list of the workspace may not be deleted.
+	inbox, err := todoCore.QueryListByID(aliceOps, ws.Settings.DefaultListID)
+	if err != nil {
+		t.Fatalf("query list by id: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.DeleteList(aliceOps, inbox, todo.DeletePolicyInbox); !errors.As(err, &vErr) {
+		t.Fatalf("delete list: expected validation error, got %v", err)
+	}
+
+	// Only members may be shared with.
+	if _, err := todoCore.Share(aliceOps, deploy, todo.ShareParams{User: "bob", Role: todo.RoleViewer}); !errors.As(err, &vErr) {
+		t.Fatalf("share: expected validation error, got %v", err)
+	}
+
+	if _, err := todoCore.AddMember(alice, ws, todo.MemberParams{User: "nobody", Role: todo.MemberRoleMember}); !errors.As(err, &vErr) {
+		t.Fatalf("add member: expected validation error, got %v", err)
+	}
+
+	if _, err := todoCore.AddMember(alice, ws, todo.MemberParams{User: "bob", Role: todo.MemberRoleMember}); err != nil {
+		t.Fatalf("add member: expected nil error, got %v", err)
+	}
+
+	// Members may use the workspace, but only admins may manage it.
+	if _, err := todoCore.EnterWorkspace(bob, ws.ID); err != nil {
+		t.Fatalf("enter workspace: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.AddMember(bob, ws, todo.MemberParams{User: "carol", Role: todo.MemberRoleMember}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("add member: expected %v, got %v", todo.ErrForbidden, err)
+	}
+
+	name := "operations"
+	if _, err := todoCore.UpdateWorkspace(bob, ws, todo.WorkspaceUpdateParams{Name: &name}); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("update workspace: expected %v, got %v", todo.ErrForbidden, err)
+	}
+
+	if err := todoCore.RemoveMember(carol, ws, uuid.Nil); !errors.Is(err, todo.ErrWorkspaceNotFound) {
+		t.Fatalf("remove member: expected %v, got %v", todo.ErrWorkspaceNotFound, err)
+	}
+
+	workspaces, err := todoCore.QueryWorkspaces(bob)
+	if err != nil {
+		t.Fatalf("query workspaces: expected nil error, got %v", err)
+	}
+	if len(workspaces) != 2 || workspaces[0].ID != todo.DefaultWorkspaceID || workspaces[1].ID != ws.ID {
+		t.Fatalf("query workspaces: expected the default workspace and %s, got %v", ws.ID, workspaces)
+	}
+
+	// The default list must be a list of the workspace.
+	other := uuid.New()
+	if _, err := todoCore.UpdateWorkspace(alice, ws, todo.WorkspaceUpdateParams{DefaultListID: &other}); !errors.As(err, &vErr) {
+		t.Fatalf("update workspace: expected validation error, got %v", err)
+	}
+
+	priorities := []todo.Priority{}
+	ws, err = todoCore.UpdateWorkspace(alice, ws, todo.WorkspaceUpdateParams{Name: &name, AllowedPriorities: &priorities})
+	if err != nil {
+		t.Fatalf("update workspace: expected nil error, got %v", err)
+	}
+	if ws.Name != name || !ws.Settings.Allows(todo.PriorityLow) {
+		t.Fatalf("update workspace: unexpected workspace %+v", ws)
+	}
+
+	// A workspace must keep at least one admin.
+	aliceUser, _ := todo.UserFrom(alice)
+	if err := todoCore.RemoveMember(alice, ws, aliceUser.ID); !errors.As(err, &vErr) {
+		t.Fatalf("remove member: expected validation error, got %v", err)
+	}
+
+	if _, err := todoCore.AddMember(alice, ws, todo.MemberParams{User: "alice", Role: todo.MemberRoleMember}); !errors.As(err, &vErr) {
+		t.Fatalf("add member: expected validation error, got %v", err)
+	}
+
+	// Members may leave on their own.
+	bobUser, _ := todo.UserFrom(bob)
+	if err := todoCore.RemoveMember(bob, ws, bobUser.ID); err != nil {
+		t.Fatalf("remove member: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.RemoveMember(alice, ws, bobUser.ID); !errors.Is(err, todo.ErrMemberNotFound) {
+		t.Fatalf("remove member: expected %v, got %v", todo.ErrMemberNotFound, err)
+	}
+
+	// Nobody may change the default workspace.
+	def, err := todoCore.QueryWorkspaceByID(alice, todo.DefaultWorkspaceID)
+	if err != nil {
+		t.Fatalf("query workspace by id: expected nil error, got %v", err)
+	}
+
+	if err := todoCore.DeleteWorkspace(alice, def); !errors.Is(err, todo.ErrForbidden) {
+		t.Fatalf("delete workspace: expected %v, got %v", todo.ErrForbidden, err)
+	}
+
+	if err := todoCore.DeleteWorkspace(alice, ws); err != nil {
+		t.Fatalf("delete workspace: expected nil error, got %v", err)
+	}
+
+	if _, err := todoCore.QueryByID(aliceOps, deploy.ID); !errors.Is(err, todo.ErrNotFound) {
+		t.Fatalf("query by id: expected %v, got %v", todo.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// trashPurgeInterval is how often todos older than the retention period are
+// purged from the trash.
+const trashPurgeInterval = time.Hour
+
+// QueryTrash fetches the todos in the trash matching the filter, sort and
+// pagination query parameters.
+func (a *App) QueryTrash(c echo.Context) error {
+	opts, err := todo.ParseQueryOptions(c.QueryParams())
+	if err != nil {
+		return err
+	}
+
+	opts.Filter.Trashed = true
+
+	todos, err := a.TodoCore.Query(c.Request().Context(), opts)
+	if err != nil {
+		return fmt.Errorf("query trash: %w", err)
+	}
+
+	setNextLink(c, opts, todos)
+
+	return c.JSON(http.StatusOK, todos)
+}
+
+// Restore takes a todo and the subtasks deleted along with it out of the
+// trash.
+func (a *App) Restore(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	t, err := a.TodoCore.QueryTrashByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query trash by id [%s]: %w", id, err)
+		}
+	}
+
+	t, err = a.TodoCore.Restore(c.Request().Context(), t)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("restore [%s]: %w", id, err)
+		}
+	}
+
+	c.Response().Header().Set("ETag", etag(t))
+
+	return c.JSON(http.StatusOK, t)
+}
+
+// Purge permanently deletes a todo in the trash along with its subtasks.
+func (a *App) Purge(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	t, err := a.TodoCore.QueryTrashByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query trash by id [%s]: %w", id, err)
+		}
+	}
+
+	if err := a.TodoCore.Purge(c.Request().Context(), t); err != nil {
+		return fmt.Errorf("purge [%s]: %w", id, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PurgeTrash permanently deletes every todo of the current user in the trash.
+func (a *App) PurgeTrash(c echo.Context) error {
+	if _, err := a.TodoCore.PurgeTrash(c.Request().Context(), time.Now()); err != nil {
+		return fmt.Errorf("purge trash: %w", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// purgeTrash permanently deletes todos that have been in the trash for longer
+// than retention until ctx is canceled.
+func (a *App) purgeTrash(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := a.TodoCore.PurgeExpired(ctx, time.Now().Add(-retention))
+		if err != nil {
+			a.Log.Error("purge trash", "error", err)
+		} else if n > 0 {
+			a.Log.Info("purge trash", "purged", n, "retention", retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// authenticate authenticates requests that carry an API key in an
+// Authorization: Bearer header, or the session cookie of a browser that logged
+// in, making their changes on behalf of its user. Requests without either are
+// anonymous, or rejected when required is set, apart from static files, the
+// API documentation, logging in and registering a user.
+func (a *App) authenticate(required bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+
+			if header == "" {
+				authenticated, err := a.authenticateSession(c)
+				if err != nil {
+					return err
+				}
+
+				if !authenticated && required && !anonymousAllowed(c) {
+					// Browsers are sent to log in rather than shown an error.
+					if c.Request().Method == http.MethodGet && c.Request().URL.Path == "/" {
+						return c.Redirect(http.StatusFound, "/login")
+					}
+
+					return unauthorized(c, "missing api key")
+				}
+
+				return next(c)
+			}
+
+			scheme, secret, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") {
+				return unauthorized(c, "invalid authorization header")
+			}
+
+			ctx := c.Request().Context()
+
+			user, err := a.TodoCore.Authenticate(ctx, strings.TrimSpace(secret))
+			if err != nil {
+				switch {
+				case errors.Is(err, todo.ErrUnauthenticated):
+					return unauthorized(c, todo.ErrUnauthenticated.Error())
+				default:
+					return fmt.Errorf("authenticate: %w", err)
+				}
+			}
+
+			ctx = todo.WithUser(ctx, user)
+			ctx = todo.WithActor(ctx, user.Name)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// anonymousAllowed reports whether the request can be served without an API
+// key or session when they are required.
+func anonymousAllowed(c echo.Context) bool {
+	req := c.Request()
+
+	if strings.HasPrefix(req.URL.Path, "/static/") {
+		return true
+	}
+
+	switch req.URL.Path {
+	case "/login", "/signup", "/logout", "/login/oidc", "/login/oidc/callback":
+		return true
+	}
+
+	if req.Method == http.MethodGet && req.URL.Path == "/api/openapi.json" {
+		return true
+	}
+
+	return req.Method == http.MethodPost && req.URL.Path == "/api/users"
+}
+
+// unauthorized returns a 401 response asking for an API key.
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return echo.NewHTTPError(http.StatusUnauthorized, message)
+}
+
+// Register creates a user. The response includes their first API key, which
+// is the only time it can be read.
+func (a *App) Register(c echo.Context) error {
+	var params todo.UserCreateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	reg, err := a.TodoCore.Register(c.Request().Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUserExists):
+			return echo.NewHTTPError(http.StatusConflict, todo.ErrUserExists.Error())
+		default:
+			return fmt.Errorf("register: %w", err)
+		}
+	}
+
+	reg.APIKey.Hash = ""
+
+	return c.JSON(http.StatusCreated, reg)
+}
+
+// CurrentUser fetches the user the request is authenticated as.
+func (a *App) CurrentUser(c echo.Context) error {
+	user, ok := todo.UserFrom(c.Request().Context())
+	if !ok {
+		return unauthorized(c, "missing api key")
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// QueryAPIKeys fetches the API keys of the current user, including revoked
+// ones. Only their prefixes are included, not their hashes.
+func (a *App) QueryAPIKeys(c echo.Context) error {
+	keys, err := a.TodoCore.QueryAPIKeys(c.Request().Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUnauthenticated):
+			return unauthorized(c, "missing api key")
+		default:
+			return fmt.Errorf("query api keys: %w", err)
+		}
+	}
+
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey creates an API key for the current user. The response is the
+// only one that includes the key.
+func (a *App) CreateAPIKey(c echo.Context) error {
+	var params todo.APIKeyCreateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	key, err := a.TodoCore.CreateAPIKey(c.Request().Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUnauthenticated):
+			return unauthorized(c, "missing api key")
+		default:
+			return fmt.Errorf("create api key: %w", err)
+		}
+	}
+
+	key.Hash = ""
+
+	return c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey revokes an API key of the current user so that it no longer
+// authenticates requests.
+func (a *App) RevokeAPIKey(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	ctx := c.Request().Context()
+
+	err = a.TodoCore.WithTx(ctx, func(txCore *todo.Core) error {
+		key, err := txCore.QueryAPIKeyByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = txCore.RevokeAPIKey(ctx, key)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrAPIKeyNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("revoke api key [%s]: %w", id, err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
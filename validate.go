@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/openapi"
+)
+
+// validateRequests rejects requests that do not match their operation in doc
+// before they reach the handlers, responding with every field that is wrong.
+// Routes that doc does not describe are passed through.
+func validateRequests(doc *openapi.Document) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			op, ok := doc.Operation(c.Request().Method, openAPIPath(c.Path()))
+			if !ok {
+				return next(c)
+			}
+
+			req := openapi.Request{
+				Path:  make(map[string]string),
+				Query: c.QueryParams(),
+			}
+
+			for i, name := range c.ParamNames() {
+				req.Path[name] = c.ParamValues()[i]
+			}
+
+			if c.Request().Body != nil {
+				body, err := io.ReadAll(c.Request().Body)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+				}
+
+				// The handlers decode the body again.
+				c.Request().Body = io.NopCloser(bytes.NewReader(body))
+				req.Body = body
+			}
+
+			if err := op.Validate(req); err != nil {
+				var vErr openapi.ValidationError
+				if errors.As(err, &vErr) {
+					return echo.NewHTTPError(http.StatusBadRequest, vErr)
+				}
+
+				return fmt.Errorf("validate request: %w", err)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// openAPIPath converts an echo route path such as /api/todo/:id to an OpenAPI
+// path template such as /api/todo/{id}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
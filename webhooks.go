@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// Webhook delivery settings.
+const (
+	webhookDeliveryInterval = 5 * time.Second
+	webhookTimeout          = 10 * time.Second
+)
+
+// QueryWebhooks fetches all webhooks. Their secrets are not included.
+func (a *App) QueryWebhooks(c echo.Context) error {
+	webhooks, err := a.TodoCore.QueryWebhooks(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("query webhooks: %w", err)
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+// QueryWebhookByID fetches a single webhook by its ID. Its secret is not
+// included.
+func (a *App) QueryWebhookByID(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	wh, err := a.TodoCore.QueryWebhookByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrWebhookNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query webhook by id [%s]: %w", id, err)
+		}
+	}
+
+	wh.Secret = ""
+
+	return c.JSON(http.StatusOK, wh)
+}
+
+// CreateWebhook creates a webhook. The response is the only one that includes
+// the secret used to sign its deliveries.
+func (a *App) CreateWebhook(c echo.Context) error {
+	var params todo.WebhookCreateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	wh, err := a.TodoCore.CreateWebhook(c.Request().Context(), params)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+
+	return c.JSON(http.StatusCreated, wh)
+}
+
+// UpdateWebhook updates a webhook. Its secret is not included in the response.
+func (a *App) UpdateWebhook(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	wh, err := a.TodoCore.QueryWebhookByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrWebhookNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query webhook by id [%s]: %w", id, err)
+		}
+	}
+
+	var params todo.WebhookUpdateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	wh, err = a.TodoCore.UpdateWebhook(c.Request().Context(), wh, params)
+	if err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+
+	wh.Secret = ""
+
+	return c.JSON(http.StatusOK, wh)
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries.
+func (a *App) DeleteWebhook(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	wh, err := a.TodoCore.QueryWebhookByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrWebhookNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query webhook by id [%s]: %w", id, err)
+		}
+	}
+
+	if err := a.TodoCore.DeleteWebhook(c.Request().Context(), wh); err != nil {
+		return fmt.Errorf("delete webhook [%s]: %w", id, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// QueryDeliveries fetches the deliveries of a webhook, newest first, along
+// with the log of their attempts.
+func (a *App) QueryDeliveries(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	wh, err := a.TodoCore.QueryWebhookByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrWebhookNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query webhook by id [%s]: %w", id, err)
+		}
+	}
+
+	deliveries, err := a.TodoCore.QueryDeliveries(c.Request().Context(), wh.ID)
+	if err != nil {
+		return fmt.Errorf("query deliveries [%s]: %w", id, err)
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver queues a delivery that succeeded or is dead to be sent again.
+func (a *App) Redeliver(c echo.Context) error {
+	idParam := c.Param("id")
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	wh, err := a.TodoCore.QueryWebhookByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrWebhookNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query webhook by id [%s]: %w", id, err)
+		}
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid delivery id format")
+	}
+
+	d, err := a.TodoCore.QueryDeliveryByID(c.Request().Context(), deliveryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrDeliveryNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("query delivery by id [%s]: %w", deliveryID, err)
+		}
+	}
+
+	if d.WebhookID != wh.ID {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	d, err = a.TodoCore.Redeliver(c.Request().Context(), d)
+	if err != nil {
+		return fmt.Errorf("redeliver [%s]: %w", deliveryID, err)
+	}
+
+	return c.JSON(http.StatusOK, d)
+}
+
+// deliverWebhooks sends the webhook deliveries that are due until ctx is
+// canceled.
+func (a *App) deliverWebhooks(ctx context.Context) {
+	client := &http.Client{Timeout: webhookTimeout}
+
+	ticker := time.NewTicker(webhookDeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while there are deliveries that are due.
+		for {
+			n, err := a.TodoCore.DeliverWebhooks(ctx, client)
+			if err != nil {
+				a.Log.Error("deliver webhooks", "error", err)
+				break
+			}
+			if n == 0 {
+				break
+			}
+
+			a.Log.Debug("deliver webhooks", "attempted", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sudomateo/todo/todo"
+)
+
+// workspaceHeader selects the workspace a request works in. Requests without
+// it work in the default workspace.
+const workspaceHeader = "X-Workspace-ID"
+
+// enterWorkspace moves requests into the workspace given by their
+// X-Workspace-ID header. Workspaces the current user is not a member of are
+// reported as not found.
+func (a *App) enterWorkspace(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(workspaceHeader)
+		if header == "" {
+			return next(c)
+		}
+
+		id, err := uuid.Parse(header)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid workspace id format")
+		}
+
+		ctx, err := a.TodoCore.EnterWorkspace(c.Request().Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, todo.ErrWorkspaceNotFound):
+				return echo.NewHTTPError(http.StatusNotFound, todo.ErrWorkspaceNotFound.Error())
+			default:
+				return fmt.Errorf("enter workspace [%s]: %w", id, err)
+			}
+		}
+
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
+
+// QueryWorkspaces fetches the default workspace followed by the workspaces
+// the current user is a member of.
+func (a *App) QueryWorkspaces(c echo.Context) error {
+	workspaces, err := a.TodoCore.QueryWorkspaces(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("query workspaces: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, workspaces)
+}
+
+// QueryWorkspaceByID fetches a single workspace by its ID.
+func (a *App) QueryWorkspaceByID(c echo.Context) error {
+	ws, err := a.workspace(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, ws)
+}
+
+// CreateWorkspace creates a workspace with the current user as its admin.
+func (a *App) CreateWorkspace(c echo.Context) error {
+	var params todo.WorkspaceCreateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	ws, err := a.TodoCore.CreateWorkspace(c.Request().Context(), params)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrUnauthenticated):
+			return unauthorized(c, "missing api key")
+		default:
+			return fmt.Errorf("create workspace: %w", err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, ws)
+}
+
+// UpdateWorkspace updates the name or settings of a workspace.
+func (a *App) UpdateWorkspace(c echo.Context) error {
+	ws, err := a.workspace(c)
+	if err != nil {
+		return err
+	}
+
+	var params todo.WorkspaceUpdateParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	ws, err = a.TodoCore.UpdateWorkspace(c.Request().Context(), ws, params)
+	if err != nil {
+		return fmt.Errorf("update workspace [%s]: %w", ws.ID, err)
+	}
+
+	return c.JSON(http.StatusOK, ws)
+}
+
+// DeleteWorkspace permanently deletes a workspace along with its todos and
+// lists.
+func (a *App) DeleteWorkspace(c echo.Context) error {
+	ws, err := a.workspace(c)
+	if err != nil {
+		return err
+	}
+
+	if err := a.TodoCore.DeleteWorkspace(c.Request().Context(), ws); err != nil {
+		return fmt.Errorf("delete workspace [%s]: %w", ws.ID, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// QueryMembers fetches the members of a workspace.
+func (a *App) QueryMembers(c echo.Context) error {
+	ws, err := a.workspace(c)
+	if err != nil {
+		return err
+	}
+
+	members, err := a.TodoCore.QueryMembers(c.Request().Context(), ws)
+	if err != nil {
+		return fmt.Errorf("query members [%s]: %w", ws.ID, err)
+	}
+
+	return c.JSON(http.StatusOK, members)
+}
+
+// AddMember adds a user to a workspace, or changes their role when they are
+// already a member.
+func (a *App) AddMember(c echo.Context) error {
+	ws, err := a.workspace(c)
+	if err != nil {
+		return err
+	}
+
+	var params todo.MemberParams
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	member, err := a.TodoCore.AddMember(c.Request().Context(), ws, params)
+	if err != nil {
+		return fmt.Errorf("add member [%s]: %w", ws.ID, err)
+	}
+
+	return c.JSON(http.StatusCreated, member)
+}
+
+// RemoveMember removes a user from a workspace.
+func (a *App) RemoveMember(c echo.Context) error {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid user id format")
+	}
+
+	ws, err := a.workspace(c)
+	if err != nil {
+		return err
+	}
+
+	if err := a.TodoCore.RemoveMember(c.Request().Context(), ws, userID); err != nil {
+		switch {
+		case errors.Is(err, todo.ErrMemberNotFound):
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return fmt.Errorf("remove member [%s]: %w", ws.ID, err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// workspace fetches the workspace given by the id path parameter, responding
+// with a 404 when the current user is not a member of it.
+func (a *App) workspace(c echo.Context) (todo.Workspace, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return todo.Workspace{}, echo.NewHTTPError(http.StatusBadRequest, "invalid id format")
+	}
+
+	ws, err := a.TodoCore.QueryWorkspaceByID(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, todo.ErrWorkspaceNotFound):
+			return todo.Workspace{}, echo.NewHTTPError(http.StatusNotFound)
+		default:
+			return todo.Workspace{}, fmt.Errorf("query workspace by id [%s]: %w", id, err)
+		}
+	}
+
+	return ws, nil
+}